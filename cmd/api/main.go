@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"scoreapp/config"
 	"scoreapp/domain"
+	"scoreapp/infrastructure/actions"
+	"scoreapp/infrastructure/events"
+	"scoreapp/infrastructure/leaderboard"
+	"scoreapp/infrastructure/metrics"
 	"scoreapp/infrastructure/repository"
 	httpiface "scoreapp/interfaces/http"
 	"scoreapp/usecase"
@@ -15,32 +26,53 @@ import (
 // DummyActionService provides test data based on user ID patterns.
 type DummyActionService struct{}
 
-func (d *DummyActionService) GetActions(userID string) ([]domain.UserAction, error) {
+func (d *DummyActionService) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	now := time.Now().UTC()
+
 	switch userID {
 	case "user_beginner":
 		return []domain.UserAction{
-			{Type: "login", Amount: 1},
-			{Type: "challenge_completed", Amount: 0},
-			{Type: "quiz_answer", Amount: 0},
+			{ID: "beginner-1", Type: "login", Amount: 1, OccurredAt: now.Add(-2 * time.Hour)},
+			{ID: "beginner-2", Type: "challenge_completed", Amount: 0, OccurredAt: now.Add(-time.Hour)},
+			{ID: "beginner-3", Type: "quiz_answer", Amount: 0, OccurredAt: now.Add(-30 * time.Minute)},
 		}, nil
 
 	case "user_active":
 		return []domain.UserAction{
-			{Type: "login", Amount: 1},
-			{Type: "challenge_completed", Amount: 2},
-			{Type: "quiz_answer", Amount: 3},
+			{ID: "active-1", Type: "login", Amount: 1, OccurredAt: now.Add(-72 * time.Hour)},
+			{ID: "active-2", Type: "challenge_completed", Amount: 2, OccurredAt: now.Add(-48 * time.Hour), Metadata: map[string]string{"challenge": "daily"}},
+			{ID: "active-3", Type: "quiz_answer", Amount: 3, OccurredAt: now.Add(-24 * time.Hour)},
+			// Replayed event, scored once
+			{ID: "active-3", Type: "quiz_answer", Amount: 3, OccurredAt: now.Add(-24 * time.Hour)},
 		}, nil
 
 	case "user_power":
 		return []domain.UserAction{
-			{Type: "login", Amount: 0},
-			{Type: "challenge_completed", Amount: 10},
-			{Type: "quiz_answer", Amount: 25},
+			{ID: "power-1", Type: "login", Amount: 0, OccurredAt: now.Add(-40 * 24 * time.Hour)},
+			{ID: "power-2", Type: "challenge_completed", Amount: 10, OccurredAt: now.Add(-10 * 24 * time.Hour)},
+			{ID: "power-3", Type: "quiz_answer", Amount: 25, OccurredAt: now.Add(-time.Hour)},
+		}, nil
+
+	case "user_legacy":
+		// Actions from before IDs and timestamps were tracked
+		return []domain.UserAction{
+			{Type: "login", Amount: 1},
+			{Type: "quiz_answer", Amount: 2},
+			{Type: "quiz_answer", Amount: 2},
 		}, nil
 
 	case "user_empty":
 		return []domain.UserAction{}, nil
 
+	case "user_slow":
+		// Answers after 10 seconds, unless the request gives up first
+		select {
+		case <-time.After(10 * time.Second):
+			return []domain.UserAction{{ID: "slow-1", Type: "login", Amount: 1, OccurredAt: now}}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
 	case "user_error":
 		return nil, fmt.Errorf("simulated service error")
 
@@ -49,6 +81,25 @@ func (d *DummyActionService) GetActions(userID string) ([]domain.UserAction, err
 	}
 }
 
+// DummyUserDirectory provides segment metadata for the users of
+// DummyActionService.
+type DummyUserDirectory struct{}
+
+func (d *DummyUserDirectory) GetMetadata(ctx context.Context, userID string) (map[string]string, error) {
+	switch userID {
+	case "user_beginner":
+		return map[string]string{"country": "TR", "cohort": "2025-06", "team": "red"}, nil
+	case "user_active":
+		return map[string]string{"country": "TR", "cohort": "2025-01", "team": "blue"}, nil
+	case "user_power":
+		return map[string]string{"country": "DE", "cohort": "2025-01", "team": "red"}, nil
+	case "user_legacy":
+		return map[string]string{"country": "US"}, nil
+	default:
+		return map[string]string{}, nil
+	}
+}
+
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -56,29 +107,262 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Stop serving on SIGINT or SIGTERM so the repository can be closed cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize the repository based on configuration
-	repo := repository.NewMemoryRepository()
+	var repo interface {
+		usecase.ScoreRepository
+		usecase.ScoreReader
+		usecase.ScoreLister
+		usecase.ScoreHistoryRepository
+		usecase.JobRepository
+		usecase.UserLister
+		usecase.ActionStore
+		usecase.ActionService
+		usecase.ActionLog
+		usecase.ScoreCursorRepository
+		usecase.ScoreEventOutbox
+		usecase.RuleSetStore
+	}
+	switch cfg.Repository.Driver {
+	case "file":
+		fileRepo, err := repository.NewFileRepository(cfg.Repository.Dir, repository.FileOptions{
+			Sync:          repository.SyncPolicy(cfg.Repository.Sync),
+			SyncInterval:  cfg.Repository.SyncInterval,
+			SnapshotEvery: cfg.Repository.SnapshotEvery,
+			OnSnapshotError: func(err error) {
+				log.Printf("Failed to compact score log: %v", err)
+			},
+		})
+		if err != nil {
+			log.Fatalf("Failed to open score repository: %v", err)
+		}
+		defer func() {
+			if err := fileRepo.Close(); err != nil {
+				log.Printf("Failed to close score repository: %v", err)
+			}
+		}()
+		repo = fileRepo
+	case "sqlite":
+		sqliteRepo, err := repository.NewSQLiteRepository(filepath.Join(cfg.Repository.Dir, "scores.db"))
+		if err != nil {
+			log.Fatalf("Failed to open score repository: %v", err)
+		}
+		defer func() {
+			if err := sqliteRepo.Close(); err != nil {
+				log.Printf("Failed to close score repository: %v", err)
+			}
+		}()
+		repo = sqliteRepo
+	default:
+		repo = repository.NewMemoryRepository()
+	}
+
+	// Rank the stored scores, then keep the ranking current on every save
+	board := usecase.NewLeaderboard(func() usecase.LeaderboardIndex { return leaderboard.NewSkipList() },
+		usecase.WithLeaderboardLocation(cfg.Scoring.Location),
+		usecase.WithSegments(&DummyUserDirectory{}, cfg.Leaderboard.Segments...),
+		usecase.WithLeaderboardRetention(cfg.Leaderboard.Retention),
+		usecase.WithLeaderboardErrorHandler(func(err error) {
+			log.Printf("Failed to rank score: %v", err)
+		}),
+	)
+	if err := board.Load(ctx, repo); err != nil {
+		log.Fatalf("Failed to load leaderboard: %v", err)
+	}
+
+	// Load scoring rules, falling back to the built-in defaults
+	rules := usecase.DefaultScoringRules()
+	if cfg.Scoring.RulesFile != "" {
+		rules, err = usecase.LoadScoringRules(cfg.Scoring.RulesFile)
+		if err != nil {
+			log.Fatalf("Failed to load scoring rules: %v", err)
+		}
+	}
 
-	// Initialize services
-	actionService := &DummyActionService{}
-	calculator := usecase.NewScoreCalculator(actionService, repo)
+	// Keep the active rule set in a registry so it can be swapped at runtime,
+	// continuing the versions stored before
+	ruleRegistry, err := usecase.LoadRuleRegistry(ctx, repo, rules)
+	if err != nil {
+		log.Fatalf("Failed to load scoring rules: %v", err)
+	}
+	if cfg.Scoring.RulesFile != "" && cfg.Scoring.RulesReloadInterval > 0 {
+		go ruleRegistry.Watch(ctx, cfg.Scoring.RulesFile, cfg.Scoring.RulesReloadInterval, func(err error) {
+			log.Printf("Failed to reload scoring rules: %v", err)
+		})
+	}
+
+	// Initialize services, reading actions from the action service or from
+	// action logs when either is configured
+	var actionService usecase.ActionService = &DummyActionService{}
+	switch {
+	case cfg.Actions.Dir != "":
+		actionService, err = actions.NewFileService(cfg.Actions.Dir)
+		if err != nil {
+			log.Fatalf("Failed to load action logs: %v", err)
+		}
+	case cfg.Actions.URL != "":
+		actionService, err = actions.NewClient(cfg.Actions.URL,
+			actions.WithAttemptTimeout(cfg.Actions.AttemptTimeout),
+			actions.WithRetries(cfg.Actions.MaxAttempts, cfg.Actions.RetryBackoff),
+			actions.WithCircuitBreaker(cfg.Actions.BreakerThreshold, cfg.Actions.BreakerCooldown),
+		)
+		if err != nil {
+			log.Fatalf("Failed to create action service client: %v", err)
+		}
+	}
+	// Score the actions ingested through POST /actions as well; ingesting
+	// drops the user's cached actions
+	actionService = actions.NewCombined(actionService, repo)
+	var ingestOpts []usecase.IngestOption
+	if cfg.Actions.CacheTTL > 0 {
+		cache := actions.NewCache(actionService,
+			actions.WithCacheTTL(cfg.Actions.CacheTTL),
+			actions.WithNegativeCacheTTL(cfg.Actions.CacheNegativeTTL),
+			actions.WithCacheSize(cfg.Actions.CacheSize),
+		)
+		actionService = cache
+		ingestOpts = append(ingestOpts, usecase.WithIngestInvalidator(cache))
+	}
+	recorder := usecase.NewLeaderboardRecorder(repo, board)
+	// All-time scores are saved both by full calculations and incremental
+	// updates, which must not overwrite each other
+	userLocks := usecase.NewUserLocks()
+	calculator := usecase.NewScoreCalculator(actionService, recorder, ruleRegistry,
+		usecase.WithLocation(cfg.Scoring.Location),
+		usecase.WithMetrics(metrics.NewExpvarMetrics()),
+		usecase.WithOverflowPolicy(usecase.OverflowPolicy(cfg.Scoring.OverflowPolicy)),
+		usecase.WithActionServiceTimeout(cfg.Timeouts.ActionService),
+		usecase.WithRepositoryTimeout(cfg.Timeouts.Repository),
+		usecase.WithBatchWorkers(cfg.Batch.Workers),
+		usecase.WithMaxBatchSize(cfg.Batch.MaxSize),
+		usecase.WithBatchTimeout(cfg.Batch.Timeout),
+		usecase.WithUserLocks(userLocks),
+	)
+
+	// Update all-time scores as actions are ingested, adding the points of
+	// the new actions where the rules allow, and compare a sample of them
+	// with full recalculations now and then
+	scorer := usecase.NewIncrementalScorer(repo, repo, recorder, repo, calculator, ruleRegistry, usecase.WithIncrementalLocks(userLocks))
+	// The updates run in the background, after POST /actions answered; the
+	// repository is closed only once the updates in progress have finished
+	updates := usecase.NewScoreUpdateQueue(scorer,
+		usecase.WithScoreUpdateErrorHandler(func(err error) {
+			log.Printf("Failed to update score: %v", err)
+		}),
+	)
+	updatesDone := make(chan struct{})
+	defer func() { <-updatesDone }()
+	go func() {
+		defer close(updatesDone)
+		updates.Run(ctx)
+	}()
+	ingestOpts = append(ingestOpts, usecase.WithScoreUpdates(updates))
+	ingester := usecase.NewActionIngester(repo, ruleRegistry, ingestOpts...)
+	if cfg.Scoring.ConsistencyCheckInterval > 0 {
+		go scorer.WatchConsistency(ctx, cfg.Scoring.ConsistencyCheckInterval, cfg.Scoring.ConsistencyCheckSample,
+			func(drift usecase.ScoreDrift) {
+				log.Printf("Score of user %q drifted: incremental %d, full %d; recalculated", drift.UserID, drift.Incremental, drift.Full)
+			},
+			func(err error) {
+				log.Printf("Failed to check scores: %v", err)
+			},
+		)
+	}
+
+	// Recalculate users in the background, resuming jobs left unfinished by
+	// the last run; the repository is closed only once the queue has stopped
+	jobs := usecase.NewJobQueue(calculator, repo, repo,
+		usecase.WithJobConcurrency(cfg.Jobs.Concurrency),
+		usecase.WithJobRetries(cfg.Jobs.MaxAttempts, cfg.Jobs.RetryBackoff),
+		usecase.WithJobLocation(cfg.Scoring.Location),
+		usecase.WithJobErrorHandler(func(err error) {
+			log.Printf("Failed to run recalculation job: %v", err)
+		}),
+	)
+	jobsDone := make(chan struct{})
+	defer func() { <-jobsDone }()
+	go func() {
+		defer close(jobsDone)
+		if err := jobs.Run(ctx); err != nil {
+			log.Printf("Failed to run recalculation jobs: %v", err)
+		}
+	}()
+
+	// Publish the score changes every save records in the repository's
+	// outbox, to the webhook if one is configured; the repository is closed
+	// only once the relay has stopped
+	var publisher usecase.ScoreEventPublisher = events.NewMemory(events.DefaultMemoryCapacity)
+	if cfg.Events.WebhookURL != "" {
+		publisher, err = events.NewWebhook(cfg.Events.WebhookURL, events.WithWebhookTimeout(cfg.Events.WebhookTimeout))
+		if err != nil {
+			log.Fatalf("Failed to create score event webhook: %v", err)
+		}
+	}
+	relay := usecase.NewScoreEventRelay(repo, publisher,
+		usecase.WithScoreEventPollInterval(cfg.Events.PollInterval),
+		usecase.WithScoreEventBatchSize(cfg.Events.BatchSize),
+		usecase.WithScoreEventErrorHandler(func(err error) {
+			log.Printf("Failed to publish score events: %v", err)
+		}),
+	)
+	relayDone := make(chan struct{})
+	defer func() { <-relayDone }()
+	go func() {
+		defer close(relayDone)
+		relay.Run(ctx)
+	}()
 
 	// Initialize health checker
 	healthChecker := usecase.NewHealthChecker()
 
 	// Initialize handlers
 	scoreHandler := httpiface.NewScoreHandler(calculator)
+	batchHandler := httpiface.NewBatchHandler(calculator)
+	scoreQueryHandler := httpiface.NewScoreQueryHandler(usecase.NewScoreQuery(repo, cfg.Scoring.Location))
+	historyHandler := httpiface.NewHistoryHandler(usecase.NewScoreHistory(repo))
+	leaderboardHandler := httpiface.NewLeaderboardHandler(board)
+	jobsHandler := httpiface.NewJobsHandler(jobs)
+	actionsHandler := httpiface.NewActionsHandler(ingester)
 	healthHandler := httpiface.NewHealthHandler(healthChecker)
 
 	// Register routes
 	http.HandleFunc("/scores/calculate", scoreHandler.Handle)
+	http.HandleFunc("/scores/explain", scoreHandler.Explain)
+	http.HandleFunc("/scores/calculate:batch", batchHandler.Handle)
+	// Routes with a fixed path take precedence over the user ID pattern
+	http.HandleFunc("/scores/{user_id}", scoreQueryHandler.Handle)
+	http.HandleFunc("/scores/{user_id}/history", historyHandler.Handle)
+	http.HandleFunc("/leaderboard", leaderboardHandler.Top)
+	http.HandleFunc("/leaderboard/rank/{user_id}", leaderboardHandler.Rank)
+	http.HandleFunc("/leaderboard/around/{user_id}", leaderboardHandler.Around)
+	http.HandleFunc("/jobs/recalculate", jobsHandler.Recalculate)
+	http.HandleFunc("/jobs/{id}", jobsHandler.Get)
+	http.HandleFunc("/actions", actionsHandler.Handle)
 	http.HandleFunc("/health", healthHandler.Handle)
+	if cfg.Server.AdminToken != "" {
+		rulesHandler := httpiface.NewRulesHandler(ruleRegistry, cfg.Server.AdminToken)
+		http.HandleFunc("/admin/rules", rulesHandler.Handle)
+	}
 
 	// Start server
 	addr := ":" + cfg.Server.Port
 	log.Printf("Starting server on %s", addr)
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	server := &http.Server{Addr: addr, Handler: httpiface.RequestID(http.DefaultServeMux)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to shut down server: %v", err)
+		}
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal(err)
 	}
+	log.Printf("Server stopped")
 }
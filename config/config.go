@@ -1,33 +1,461 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all application configuration.
 type Config struct {
-	Server ServerConfig
+	Server      ServerConfig
+	Scoring     ScoringConfig
+	Repository  RepositoryConfig
+	Leaderboard LeaderboardConfig
+	Batch       BatchConfig
+	Jobs        JobsConfig
+	Actions     ActionsConfig
+	Events      EventsConfig
+	Timeouts    TimeoutConfig
 }
 
 // ServerConfig holds server-related configuration.
 type ServerConfig struct {
 	Port string
+	// AdminToken protects the admin endpoints. When empty, they are disabled.
+	AdminToken string
+}
+
+// ScoringConfig holds score calculation configuration.
+type ScoringConfig struct {
+	// RulesFile is the path to a JSON or YAML rule set. When empty, the
+	// built-in default rules are used.
+	RulesFile string
+	// RulesReloadInterval is how often RulesFile is checked for changes.
+	// Zero disables hot reloading.
+	RulesReloadInterval time.Duration
+	// Location is the default timezone calendar scoring windows are aligned to.
+	Location *time.Location
+	// OverflowPolicy is "error" to reject scores that do not fit in 64 bits
+	// or "saturate" to clamp them.
+	OverflowPolicy string
+	// ConsistencyCheckInterval is how often incrementally updated scores
+	// are compared with full recalculations. Zero disables the checks.
+	ConsistencyCheckInterval time.Duration
+	// ConsistencyCheckSample is how many randomly chosen users each check
+	// compares. Zero compares every user.
+	ConsistencyCheckSample int
+}
+
+// RepositoryConfig holds score storage configuration.
+type RepositoryConfig struct {
+	// Driver is "memory", "file" or "sqlite".
+	Driver string
+	// Dir is where the file driver keeps its log and snapshots and the
+	// sqlite driver its database.
+	Dir string
+	// Sync is the file driver's fsync policy: "always", "interval" or "never".
+	Sync string
+	// SyncInterval is how often the log is flushed under the "interval" policy.
+	SyncInterval time.Duration
+	// SnapshotEvery is how many saves the log holds before it is compacted.
+	SnapshotEvery int
+}
+
+// LeaderboardConfig holds leaderboard configuration.
+type LeaderboardConfig struct {
+	// Segments are the user metadata dimensions, such as country, that get
+	// a leaderboard per value.
+	Segments []string
+	// Retention is how long leaderboards of a window period are kept after
+	// the period ends.
+	Retention time.Duration
+}
+
+// BatchConfig holds batch score calculation configuration.
+type BatchConfig struct {
+	// Workers is how many users of a batch are calculated at once.
+	Workers int
+	// MaxSize is the largest number of users a batch may list. Zero
+	// disables the limit.
+	MaxSize int
+	// Timeout bounds the calculation of a whole batch. Zero disables it.
+	Timeout time.Duration
+}
+
+// JobsConfig holds background recalculation job configuration.
+type JobsConfig struct {
+	// Concurrency is how many users of a job are recalculated at once.
+	Concurrency int
+	// MaxAttempts is how often a user is tried before a job gives up on it.
+	MaxAttempts int
+	// RetryBackoff is the wait before the first retry of a user. Each
+	// further retry waits twice as long, up to a minute.
+	RetryBackoff time.Duration
+}
+
+// ActionsConfig holds configuration of the client for the action service.
+type ActionsConfig struct {
+	// URL is the base URL of the action service. When empty, built-in demo
+	// users are served instead.
+	URL string
+	// Dir is a directory of JSONL or CSV action logs to serve actions from
+	// instead of the action service. It cannot be combined with URL.
+	Dir string
+	// AttemptTimeout bounds each request to the action service.
+	AttemptTimeout time.Duration
+	// MaxAttempts is how often a failing request is tried.
+	MaxAttempts int
+	// RetryBackoff is the longest wait before the first retry; the range
+	// doubles with every further retry.
+	RetryBackoff time.Duration
+	// BreakerThreshold is how many consecutive failed calls open the circuit
+	// breaker. Zero disables the breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long an open circuit breaker fails calls fast.
+	BreakerCooldown time.Duration
+	// CacheTTL is how long a user's actions are served from the cache. Zero
+	// disables the cache.
+	CacheTTL time.Duration
+	// CacheNegativeTTL is how long an unknown user is remembered.
+	CacheNegativeTTL time.Duration
+	// CacheSize is how many users the cache holds.
+	CacheSize int
+}
+
+// EventsConfig holds configuration of score change event publication.
+type EventsConfig struct {
+	// WebhookURL receives a POST for every score change. When empty, events
+	// are only kept in memory.
+	WebhookURL string
+	// WebhookTimeout bounds each delivery to WebhookURL.
+	WebhookTimeout time.Duration
+	// PollInterval is how often the outbox is checked for new events.
+	PollInterval time.Duration
+	// BatchSize is how many events are read from the outbox at once.
+	BatchSize int
+}
+
+// TimeoutConfig holds how long each dependency may take per call. Zero
+// disables the respective timeout.
+type TimeoutConfig struct {
+	ActionService time.Duration
+	Repository    time.Duration
 }
 
 // Load reads configuration from environment variables with sensible defaults.
 func Load() (*Config, error) {
+	reloadInterval, err := getDurationEnv("SCORING_RULES_RELOAD_INTERVAL", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := getLocationEnv("SCORING_TIMEZONE", time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	overflowPolicy, err := getChoiceEnv("SCORING_OVERFLOW_POLICY", "error", "error", "saturate")
+	if err != nil {
+		return nil, err
+	}
+
+	consistencyCheckInterval, err := getDurationEnv("SCORING_CONSISTENCY_CHECK_INTERVAL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	consistencyCheckSample, err := getIntEnv("SCORING_CONSISTENCY_CHECK_SAMPLE", 100)
+	if err != nil {
+		return nil, err
+	}
+
+	actionServiceTimeout, err := getDurationEnv("ACTION_SERVICE_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	repositoryTimeout, err := getDurationEnv("REPOSITORY_TIMEOUT", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	repositoryDriver, err := getChoiceEnv("REPOSITORY_DRIVER", "memory", "memory", "file", "sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	repositorySync, err := getChoiceEnv("REPOSITORY_FSYNC", "always", "always", "interval", "never")
+	if err != nil {
+		return nil, err
+	}
+
+	repositorySyncInterval, err := getDurationEnv("REPOSITORY_FSYNC_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotEvery, err := getIntEnv("REPOSITORY_SNAPSHOT_EVERY", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderboardRetention, err := getDurationEnv("LEADERBOARD_RETENTION", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	batchWorkers, err := getIntEnv("BATCH_WORKERS", 8)
+	if err != nil {
+		return nil, err
+	}
+
+	batchMaxSize, err := getIntEnv("BATCH_MAX_SIZE", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	batchTimeout, err := getDurationEnv("BATCH_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	jobsConcurrency, err := getIntEnv("JOBS_CONCURRENCY", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	jobsMaxAttempts, err := getIntEnv("JOBS_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	jobsRetryBackoff, err := getDurationEnv("JOBS_RETRY_BACKOFF", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	actionsAttemptTimeout, err := getDurationEnv("ACTION_SERVICE_ATTEMPT_TIMEOUT", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	actionsMaxAttempts, err := getIntEnv("ACTION_SERVICE_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	actionsRetryBackoff, err := getDurationEnv("ACTION_SERVICE_RETRY_BACKOFF", 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
+	actionsBreakerThreshold, err := getIntEnv("ACTION_SERVICE_BREAKER_THRESHOLD", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	actionsBreakerCooldown, err := getDurationEnv("ACTION_SERVICE_BREAKER_COOLDOWN", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	actionsCacheTTL, err := getDurationEnv("ACTION_CACHE_TTL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	actionsCacheNegativeTTL, err := getDurationEnv("ACTION_CACHE_NEGATIVE_TTL", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	actionsCacheSize, err := getIntEnv("ACTION_CACHE_SIZE", 10000)
+	if err != nil {
+		return nil, err
+	}
+
+	eventsWebhookTimeout, err := getDurationEnv("SCORE_EVENTS_WEBHOOK_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	eventsPollInterval, err := getDurationEnv("SCORE_EVENTS_POLL_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	eventsBatchSize, err := getIntEnv("SCORE_EVENTS_BATCH_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
+			Port:       getEnv("SERVER_PORT", "8080"),
+			AdminToken: getEnv("ADMIN_TOKEN", ""),
+		},
+		Scoring: ScoringConfig{
+			RulesFile:                getEnv("SCORING_RULES_FILE", ""),
+			RulesReloadInterval:      reloadInterval,
+			Location:                 location,
+			OverflowPolicy:           overflowPolicy,
+			ConsistencyCheckInterval: consistencyCheckInterval,
+			ConsistencyCheckSample:   consistencyCheckSample,
+		},
+		Repository: RepositoryConfig{
+			Driver:        repositoryDriver,
+			Dir:           getEnv("REPOSITORY_DIR", "data"),
+			Sync:          repositorySync,
+			SyncInterval:  repositorySyncInterval,
+			SnapshotEvery: snapshotEvery,
+		},
+		Leaderboard: LeaderboardConfig{
+			Segments:  getListEnv("LEADERBOARD_SEGMENTS", "country,cohort,team"),
+			Retention: leaderboardRetention,
+		},
+		Batch: BatchConfig{
+			Workers: batchWorkers,
+			MaxSize: batchMaxSize,
+			Timeout: batchTimeout,
+		},
+		Jobs: JobsConfig{
+			Concurrency:  jobsConcurrency,
+			MaxAttempts:  jobsMaxAttempts,
+			RetryBackoff: jobsRetryBackoff,
+		},
+		Actions: ActionsConfig{
+			URL:              getEnv("ACTION_SERVICE_URL", ""),
+			Dir:              getEnv("ACTION_SERVICE_DIR", ""),
+			AttemptTimeout:   actionsAttemptTimeout,
+			MaxAttempts:      actionsMaxAttempts,
+			RetryBackoff:     actionsRetryBackoff,
+			BreakerThreshold: actionsBreakerThreshold,
+			BreakerCooldown:  actionsBreakerCooldown,
+			CacheTTL:         actionsCacheTTL,
+			CacheNegativeTTL: actionsCacheNegativeTTL,
+			CacheSize:        actionsCacheSize,
+		},
+		Events: EventsConfig{
+			WebhookURL:     getEnv("SCORE_EVENTS_WEBHOOK_URL", ""),
+			WebhookTimeout: eventsWebhookTimeout,
+			PollInterval:   eventsPollInterval,
+			BatchSize:      eventsBatchSize,
+		},
+		Timeouts: TimeoutConfig{
+			ActionService: actionServiceTimeout,
+			Repository:    repositoryTimeout,
 		},
 	}
 
+	if cfg.Actions.URL != "" && cfg.Actions.Dir != "" {
+		return nil, errors.New("ACTION_SERVICE_URL and ACTION_SERVICE_DIR cannot both be set")
+	}
+	if err := validateActionTimeouts(cfg.Actions, cfg.Timeouts.ActionService); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
+// actionsMaxRetryBackoff is the longest wait between retries of the action
+// service client.
+const actionsMaxRetryBackoff = 2 * time.Second
+
+// validateActionTimeouts checks that a call to the action service has time
+// for every attempt: all of them timing out, with the longest waits between
+// them, must still fit in the timeout of the whole call.
+func validateActionTimeouts(actions ActionsConfig, timeout time.Duration) error {
+	if actions.URL == "" || timeout <= 0 || actions.AttemptTimeout <= 0 {
+		return nil
+	}
+
+	var worst time.Duration
+	backoff := actions.RetryBackoff
+	for attempt := 1; attempt <= actions.MaxAttempts && worst < timeout; attempt++ {
+		worst += actions.AttemptTimeout
+		if attempt < actions.MaxAttempts {
+			worst += min(backoff, actionsMaxRetryBackoff)
+			backoff = min(backoff*2, actionsMaxRetryBackoff)
+		}
+	}
+	if worst >= timeout {
+		return fmt.Errorf("ACTION_SERVICE_TIMEOUT %s leaves no time for %d attempts of ACTION_SERVICE_ATTEMPT_TIMEOUT %s and the waits between them",
+			timeout, actions.MaxAttempts, actions.AttemptTimeout)
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+// getListEnv splits a comma-separated value, dropping empty items.
+func getListEnv(key, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative duration", key, value)
+	}
+	return d, nil
+}
+
+func getIntEnv(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative integer", key, value)
+	}
+	return n, nil
+}
+
+func getChoiceEnv(key, defaultValue string, choices ...string) (string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	for _, choice := range choices {
+		if value == choice {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid %s %q: must be one of %v", key, value, choices)
+}
+
+func getLocationEnv(key string, defaultValue *time.Location) (*time.Location, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", key, value, err)
+	}
+	return loc, nil
+}
@@ -13,6 +13,13 @@
 // Produces:
 // - application/json
 //
+// SecurityDefinitions:
+// bearer:
+//
+//	type: apiKey
+//	name: Authorization
+//	in: header
+//
 // swagger:meta
 package docs
 
@@ -36,6 +43,110 @@ type healthResponseWrapper struct {
 	Body models.HealthResponse
 }
 
+// swagger:response breakdownResponse
+//
+//nolint:unused
+type breakdownResponseWrapper struct {
+	// in: body
+	Body models.BreakdownResponse
+}
+
+// swagger:response ruleSetResponse
+//
+//nolint:unused
+type ruleSetResponseWrapper struct {
+	// in: body
+	Body models.RuleSetResponse
+}
+
+// swagger:response batchResponse
+//
+//nolint:unused
+type batchResponseWrapper struct {
+	// in: body
+	Body models.BatchResponse
+}
+
+// swagger:parameters calculateScoreBatch
+//
+//nolint:unused
+type batchRequestParams struct {
+	// The IDs of the users to calculate scores for
+	//
+	// in: body
+	// required: true
+	Body []string
+}
+
+// swagger:response jobResponse
+//
+//nolint:unused
+type jobResponseWrapper struct {
+	// in: body
+	Body models.JobResponse
+}
+
+// swagger:parameters recalculateScores
+//
+//nolint:unused
+type recalculateRequestParams struct {
+	// The users to recalculate: a list of user IDs, or all users with a stored score
+	//
+	// in: body
+	// required: true
+	Body models.RecalculateRequest
+}
+
+// swagger:response ingestResponse
+//
+//nolint:unused
+type ingestResponseWrapper struct {
+	// in: body
+	Body models.IngestResponse
+}
+
+// swagger:parameters ingestActions
+//
+//nolint:unused
+type ingestActionsParams struct {
+	// An action, or an array of actions
+	//
+	// in: body
+	// required: true
+	Body []models.ActionRequest
+}
+
+// swagger:response historyResponse
+//
+//nolint:unused
+type historyResponseWrapper struct {
+	// in: body
+	Body models.HistoryResponse
+}
+
+// swagger:response leaderboardResponse
+//
+//nolint:unused
+type leaderboardResponseWrapper struct {
+	// in: body
+	Body models.LeaderboardResponse
+}
+
+// swagger:response leaderboardEntryResponse
+//
+//nolint:unused
+type leaderboardEntryResponseWrapper struct {
+	// in: body
+	Body models.LeaderboardEntry
+}
+
+// The resource has not changed since the ETag in If-None-Match.
+//
+// swagger:response notModifiedResponse
+//
+//nolint:unused
+type notModifiedResponseWrapper struct{}
+
 // swagger:response errorResponse
 //
 //nolint:unused
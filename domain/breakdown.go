@@ -0,0 +1,99 @@
+package domain
+
+import "time"
+
+// SkipReason explains why an action did not contribute to a score.
+type SkipReason string
+
+const (
+	// SkipNonPositiveAmount marks actions with a zero or negative amount.
+	SkipNonPositiveAmount SkipReason = "non_positive_amount"
+	// SkipUnknownActionType marks actions without a matching scoring rule.
+	SkipUnknownActionType SkipReason = "unknown_action_type"
+	// SkipDuplicateActionID marks replays of an action already scored.
+	SkipDuplicateActionID SkipReason = "duplicate_action_id"
+	// SkipOutsideWindow marks actions that happened outside the scoring window.
+	SkipOutsideWindow SkipReason = "outside_window"
+	// SkipMissingTimestamp marks actions without a timestamp, which cannot be
+	// placed in a time-bounded scoring window.
+	SkipMissingTimestamp SkipReason = "missing_timestamp"
+)
+
+// CapKind identifies a limit on the points actions can earn.
+type CapKind string
+
+const (
+	// CapMaxAmount limits the amount of a single action taken into account.
+	CapMaxAmount CapKind = "max_amount"
+	// CapMaxPoints limits the points of a single action.
+	CapMaxPoints CapKind = "max_points"
+	// CapMaxPointsPerDay limits the points of one action type per calendar day.
+	CapMaxPointsPerDay CapKind = "max_points_per_day"
+	// CapMaxTotalPoints limits the score of a single calculation.
+	CapMaxTotalPoints CapKind = "max_total_points"
+)
+
+// CapHit records the points a cap removed.
+type CapHit struct {
+	Kind    CapKind
+	Removed int64
+}
+
+// ActionContribution describes the points a single action added to a score.
+type ActionContribution struct {
+	Action UserAction
+	// Rule is the name of the scoring rule that matched the action.
+	Rule string
+	// RawPoints is what the rule produced before caps and decay.
+	RawPoints int64
+	// Caps lists the caps that removed points from the action.
+	Caps []CapHit
+	// DecayFactor is the fraction of the capped points kept after decay, 1
+	// when the action did not decay.
+	DecayFactor float64
+	// Points is what the action added to the score.
+	Points int64
+}
+
+// SkippedAction describes an action that did not contribute to a score.
+type SkippedAction struct {
+	Action UserAction
+	Reason SkipReason
+}
+
+// BonusKind identifies the kind of sequence a bonus rewards.
+type BonusKind string
+
+const (
+	// BonusStreak rewards activity on consecutive days.
+	BonusStreak BonusKind = "streak"
+	// BonusCombo rewards several actions within a short time.
+	BonusCombo BonusKind = "combo"
+)
+
+// Bonus describes points awarded for a sequence of actions rather than a
+// single action.
+type Bonus struct {
+	Kind BonusKind
+	// Rule is the name of the bonus rule that was triggered.
+	Rule   string
+	Points int64
+	// From and To delimit the period whose actions earned the bonus.
+	From time.Time
+	To   time.Time
+}
+
+// ScoreBreakdown explains how a user's score was calculated.
+type ScoreBreakdown struct {
+	UserID      string
+	Score       int64
+	RuleVersion int
+	Window      string
+	// Streak is the current number of consecutive active days.
+	Streak        int
+	Contributions []ActionContribution
+	Bonuses       []Bonus
+	Skipped       []SkippedAction
+	// Caps lists the caps applied to the score as a whole.
+	Caps []CapHit
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// ScoreCursor records how far the incrementally updated all-time score of a
+// user has applied the user's ingested actions.
+type ScoreCursor struct {
+	UserID string
+	// Position is where the last applied action is in the user's ingested
+	// actions; zero means none has been applied.
+	Position int64
+	// Score is the all-time score including every action up to Position.
+	Score int64
+	// RuleVersion is the version of the scoring rule set that produced Score.
+	RuleVersion int
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// ScoreSnapshot is an entry in a user's score history, recorded each time a
+// score is saved.
+type ScoreSnapshot struct {
+	// Seq orders the history: a later save has a greater Seq.
+	Seq         int64
+	UserID      string
+	Window      string
+	Score       int64
+	RuleVersion int
+	Streak      int
+	RecordedAt  time.Time
+	RequestID   string
+}
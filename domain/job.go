@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// JobStatus is the lifecycle state of a recalculation job.
+type JobStatus string
+
+const (
+	// JobQueued jobs wait for a worker.
+	JobQueued JobStatus = "queued"
+	// JobRunning jobs are recalculating their users.
+	JobRunning JobStatus = "running"
+	// JobCompleted jobs went through every user. Users whose score could not
+	// be recalculated are listed in Failures.
+	JobCompleted JobStatus = "completed"
+	// JobFailed jobs stopped before going through their users, see Error.
+	JobFailed JobStatus = "failed"
+)
+
+// Finished reports whether a job in the status will not run again.
+func (s JobStatus) Finished() bool {
+	return s == JobCompleted || s == JobFailed
+}
+
+// Job recalculates the scores of a list of users in the background.
+type Job struct {
+	ID     string
+	Status JobStatus
+	// Window is the key of the scoring window that is recalculated.
+	Window string
+	// AllUsers selects every user with a stored score. UserIDs is filled in
+	// with them when the job starts.
+	AllUsers bool
+	UserIDs  []string
+	// Processed is the number of users, from the start of UserIDs, that
+	// were recalculated or failed. A resumed job continues after them.
+	Processed int
+	Succeeded int
+	Failures  []JobFailure
+	// Error is why a failed job stopped.
+	Error string
+
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// JobFailure is a user whose score a job could not recalculate.
+type JobFailure struct {
+	UserID   string
+	Error    string
+	Attempts int
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// LeaderboardEntry is a user's position on a leaderboard.
+type LeaderboardEntry struct {
+	// Rank is the 1-based position of the user.
+	Rank   int
+	UserID string
+	Score  int64
+	// ReachedAt is when the user reached the score. Among equal scores the
+	// user who reached it first ranks higher.
+	ReachedAt time.Time
+}
+
+// LeaderboardKey identifies a leaderboard: the users of one segment ranked
+// by their score in one period of a scoring window.
+type LeaderboardKey struct {
+	// Segment is "<dimension>:<value>", e.g. "country:TR". Empty means
+	// every user.
+	Segment string
+	// Window is the key of the scoring window that is ranked, e.g. "week@UTC".
+	Window string
+	// Period is the start of the window period the board covers. It is
+	// zero for all-time boards, which never reset.
+	Period time.Time
+}
+
+// String returns a unique name for the key, e.g. "country:TR/week@UTC/2025-06-09T00:00:00+03:00".
+func (k LeaderboardKey) String() string {
+	period := ""
+	if !k.Period.IsZero() {
+		period = k.Period.Format(time.RFC3339)
+	}
+	return k.Segment + "/" + k.Window + "/" + period
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// ScoreChanged is raised when a save changes the score of a user in a
+// scoring window.
+type ScoreChanged struct {
+	// ID orders the events of a repository. Events may be delivered more
+	// than once, so consumers should drop IDs they have seen.
+	ID     int64
+	UserID string
+	Window string
+	// OldScore is zero for the first score of the user in the window.
+	OldScore int64
+	NewScore int64
+	// Delta is NewScore - OldScore, clamped to the int64 range.
+	Delta int64
+	// RuleVersion is the version of the scoring rule set that produced
+	// NewScore.
+	RuleVersion int
+	ChangedAt   time.Time
+	// RequestID identifies the request that triggered the change, if any.
+	RequestID string
+}
+
+// NewScoreChanged returns the event for saving score over previous, the
+// user's score in the window until then, or the zero score if there was
+// none. It reports false if the save leaves the score unchanged. Scores
+// without a calculation time change at now.
+func NewScoreChanged(previous, score UserScore, now time.Time) (ScoreChanged, bool) {
+	if score.Score == previous.Score {
+		return ScoreChanged{}, false
+	}
+
+	delta := score.Score - previous.Score
+	// The difference of two int64 overflows when their signs differ
+	if (score.Score >= 0) != (previous.Score >= 0) && (delta >= 0) != (score.Score >= 0) {
+		delta = math.MaxInt64
+		if score.Score < 0 {
+			delta = math.MinInt64
+		}
+	}
+
+	changedAt := score.CalculatedAt
+	if changedAt.IsZero() {
+		changedAt = now
+	}
+	return ScoreChanged{
+		UserID:      score.UserID,
+		Window:      score.WindowKey(),
+		OldScore:    previous.Score,
+		NewScore:    score.Score,
+		Delta:       delta,
+		RuleVersion: score.RuleVersion,
+		ChangedAt:   changedAt.UTC(),
+		RequestID:   score.RequestID,
+	}, true
+}
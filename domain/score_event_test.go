@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewScoreChanged(t *testing.T) {
+	calculatedAt := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	previous := UserScore{UserID: "alice", Score: 10, RuleVersion: 1}
+	score := UserScore{UserID: "alice", Score: 4, RuleVersion: 2, CalculatedAt: calculatedAt, RequestID: "req-1"}
+
+	event, changed := NewScoreChanged(previous, score, time.Now())
+
+	assert.True(t, changed)
+	assert.Equal(t, ScoreChanged{
+		UserID:      "alice",
+		Window:      "all_time",
+		OldScore:    10,
+		NewScore:    4,
+		Delta:       -6,
+		RuleVersion: 2,
+		ChangedAt:   calculatedAt,
+		RequestID:   "req-1",
+	}, event)
+}
+
+func TestNewScoreChanged_Unchanged(t *testing.T) {
+	_, changed := NewScoreChanged(UserScore{Score: 7, RuleVersion: 1}, UserScore{Score: 7, RuleVersion: 2}, time.Now())
+	assert.False(t, changed)
+}
+
+func TestNewScoreChanged_WithoutCalculationTime(t *testing.T) {
+	now := time.Date(2025, 6, 2, 10, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	event, changed := NewScoreChanged(UserScore{}, UserScore{Score: 1}, now)
+
+	assert.True(t, changed)
+	assert.Equal(t, now.UTC(), event.ChangedAt)
+	assert.Equal(t, int64(1), event.Delta)
+}
+
+func TestNewScoreChanged_DeltaSaturates(t *testing.T) {
+	tests := []struct {
+		name      string
+		old, new  int64
+		wantDelta int64
+	}{
+		{"up", math.MinInt64, math.MaxInt64, math.MaxInt64},
+		{"down", math.MaxInt64, math.MinInt64, math.MinInt64},
+		{"to zero", math.MinInt64, 0, math.MaxInt64},
+		{"within range", -5, math.MaxInt64 - 5, math.MaxInt64},
+		{"negative", math.MinInt64 + 1, -1, math.MaxInt64 - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, changed := NewScoreChanged(UserScore{Score: tt.old}, UserScore{Score: tt.new}, time.Now())
+			assert.True(t, changed)
+			assert.Equal(t, tt.wantDelta, event.Delta)
+		})
+	}
+}
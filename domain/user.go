@@ -1,14 +1,47 @@
 package domain
 
+import "time"
+
 // UserAction represents a single action performed by a user.
-// Candidate can extend this if needed.
 type UserAction struct {
-	Type   string
-	Amount int
+	// ID uniquely identifies the action. Actions sharing a non-empty ID are
+	// replays of the same event and are scored once. Legacy actions may
+	// have no ID and are never deduplicated.
+	ID         string
+	Type       string
+	Amount     int64
+	OccurredAt time.Time
+	// Metadata holds free-form attributes of the action.
+	Metadata map[string]string
+}
+
+// ActionEvent is an action of a user, as ingested by the service.
+type ActionEvent struct {
+	UserID string
+	UserAction
 }
 
 // UserScore represents the calculated score for a given user.
 type UserScore struct {
 	UserID string
-	Score  int
+	Score  int64
+	// RuleVersion is the version of the scoring rule set that produced Score.
+	RuleVersion int
+	// Window is the key of the scoring window that produced Score.
+	Window string
+	// Streak is the number of consecutive active days at calculation time.
+	Streak int
+	// CalculatedAt is when the score was calculated.
+	CalculatedAt time.Time
+	// RequestID identifies the request that triggered the calculation, if any.
+	RequestID string
+}
+
+// WindowKey returns the key of the scoring window that produced the score.
+// Scores without a window are all-time scores.
+func (s UserScore) WindowKey() string {
+	if s.Window == "" {
+		return AllTime().Key()
+	}
+	return s.Window
 }
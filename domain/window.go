@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidWindow is returned when a scoring window cannot be built.
+var ErrInvalidWindow = errors.New("invalid window")
+
+// WindowKind identifies how a scoring window is bounded.
+type WindowKind string
+
+const (
+	// WindowAllTime covers every action.
+	WindowAllTime WindowKind = "all_time"
+	// WindowRolling covers the last Days × 24 hours.
+	WindowRolling WindowKind = "rolling"
+	// WindowDay covers the current calendar day.
+	WindowDay WindowKind = "day"
+	// WindowWeek covers the current calendar week, starting on Monday.
+	WindowWeek WindowKind = "week"
+	// WindowMonth covers the current calendar month.
+	WindowMonth WindowKind = "month"
+	// WindowSeason covers the current season, which is a calendar quarter.
+	WindowSeason WindowKind = "season"
+)
+
+// Window selects the actions that count toward a score.
+type Window struct {
+	Kind WindowKind
+	// Days is the length of a rolling window.
+	Days int
+	// Location is the timezone calendar windows are aligned to. A nil
+	// Location is resolved by the calculator's default timezone.
+	Location *time.Location
+}
+
+// AllTime returns the window that covers every action.
+func AllTime() Window {
+	return Window{Kind: WindowAllTime}
+}
+
+// NewWindow builds and validates a window.
+func NewWindow(kind WindowKind, days int, loc *time.Location) (Window, error) {
+	w := Window{Kind: kind, Days: days, Location: loc}
+	if err := w.Validate(); err != nil {
+		return Window{}, err
+	}
+	return w, nil
+}
+
+// Validate checks that the window is well formed.
+func (w Window) Validate() error {
+	switch w.Kind {
+	case WindowAllTime, WindowDay, WindowWeek, WindowMonth, WindowSeason:
+		if w.Days != 0 {
+			return fmt.Errorf("%w: days only applies to rolling windows", ErrInvalidWindow)
+		}
+	case WindowRolling:
+		if w.Days <= 0 {
+			return fmt.Errorf("%w: rolling window needs a positive number of days", ErrInvalidWindow)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidWindow, w.Kind)
+	}
+	return nil
+}
+
+// Key identifies the window for storage, e.g. "all_time", "rolling_7d" or
+// "week@Europe/Istanbul". Calendar windows include their timezone because
+// the same week covers different instants in different timezones.
+func (w Window) Key() string {
+	switch w.Kind {
+	case WindowRolling:
+		return fmt.Sprintf("%s_%dd", w.Kind, w.Days)
+	case WindowDay, WindowWeek, WindowMonth, WindowSeason:
+		return fmt.Sprintf("%s@%s", w.Kind, w.location())
+	default:
+		return string(w.Kind)
+	}
+}
+
+// ParseWindowKey rebuilds the window a key returned by Key identifies.
+func ParseWindowKey(key string) (Window, error) {
+	if kind, zone, ok := strings.Cut(key, "@"); ok {
+		w := Window{Kind: WindowKind(kind)}
+		if !w.Bounded() || w.Kind == WindowRolling {
+			return Window{}, fmt.Errorf("%w: malformed key %q", ErrInvalidWindow, key)
+		}
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return Window{}, fmt.Errorf("%w: unknown timezone %q", ErrInvalidWindow, zone)
+		}
+		return NewWindow(w.Kind, 0, loc)
+	}
+	if days, ok := strings.CutPrefix(key, string(WindowRolling)+"_"); ok {
+		n, err := strconv.Atoi(strings.TrimSuffix(days, "d"))
+		if err != nil || !strings.HasSuffix(days, "d") {
+			return Window{}, fmt.Errorf("%w: malformed key %q", ErrInvalidWindow, key)
+		}
+		return NewWindow(WindowRolling, n, nil)
+	}
+	if key != string(WindowAllTime) {
+		return Window{}, fmt.Errorf("%w: malformed key %q", ErrInvalidWindow, key)
+	}
+	return AllTime(), nil
+}
+
+// MaxClockSkew is how far past the current time a rolling window extends, so
+// actions stamped by a source whose clock runs slightly ahead still count.
+// Actions dated further in the future do not.
+const MaxClockSkew = time.Minute
+
+// Span is a half-open time interval [Start, End). A zero Start or End means
+// the span is unbounded on that side.
+type Span struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether t falls within the span.
+func (s Span) Contains(t time.Time) bool {
+	if !s.Start.IsZero() && t.Before(s.Start) {
+		return false
+	}
+	if !s.End.IsZero() && !t.Before(s.End) {
+		return false
+	}
+	return true
+}
+
+// Span returns the interval the window covers at the given instant.
+func (w Window) Span(now time.Time) Span {
+	local := now.In(w.location())
+	y, m, d := local.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, local.Location())
+
+	switch w.Kind {
+	case WindowRolling:
+		return Span{Start: now.AddDate(0, 0, -w.Days), End: now.Add(MaxClockSkew)}
+	case WindowDay:
+		return Span{Start: midnight, End: midnight.AddDate(0, 0, 1)}
+	case WindowWeek:
+		// Weeks start on Monday
+		start := midnight.AddDate(0, 0, -((int(midnight.Weekday()) + 6) % 7))
+		return Span{Start: start, End: start.AddDate(0, 0, 7)}
+	case WindowMonth:
+		start := time.Date(y, m, 1, 0, 0, 0, 0, local.Location())
+		return Span{Start: start, End: start.AddDate(0, 1, 0)}
+	case WindowSeason:
+		start := time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, local.Location())
+		return Span{Start: start, End: start.AddDate(0, 3, 0)}
+	default:
+		return Span{}
+	}
+}
+
+// Bounded reports whether the window excludes any actions by time.
+func (w Window) Bounded() bool {
+	return w.Kind != WindowAllTime
+}
+
+func (w Window) location() *time.Location {
+	if w.Location == nil {
+		return time.UTC
+	}
+	return w.Location
+}
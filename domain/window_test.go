@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWindow_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    WindowKind
+		days    int
+		wantErr bool
+	}{
+		{"all time", WindowAllTime, 0, false},
+		{"rolling", WindowRolling, 7, false},
+		{"rolling without days", WindowRolling, 0, true},
+		{"rolling negative days", WindowRolling, -1, true},
+		{"day", WindowDay, 0, false},
+		{"week with days", WindowWeek, 3, true},
+		{"season", WindowSeason, 0, false},
+		{"unknown kind", WindowKind("fortnight"), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWindow(tt.kind, tt.days, nil)
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidWindow)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWindow_Key(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	require.NoError(t, err)
+
+	assert.Equal(t, "all_time", AllTime().Key())
+	assert.Equal(t, "rolling_7d", Window{Kind: WindowRolling, Days: 7}.Key())
+	assert.Equal(t, "day@UTC", Window{Kind: WindowDay}.Key())
+	assert.Equal(t, "week@Europe/Istanbul", Window{Kind: WindowWeek, Location: istanbul}.Key())
+	assert.Equal(t, "month@UTC", Window{Kind: WindowMonth, Location: time.UTC}.Key())
+	assert.Equal(t, "season@UTC", Window{Kind: WindowSeason}.Key())
+}
+
+func TestParseWindowKey(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	require.NoError(t, err)
+
+	for _, w := range []Window{
+		AllTime(),
+		{Kind: WindowRolling, Days: 7},
+		{Kind: WindowDay, Location: time.UTC},
+		{Kind: WindowWeek, Location: istanbul},
+		{Kind: WindowSeason, Location: time.UTC},
+	} {
+		parsed, err := ParseWindowKey(w.Key())
+		assert.NoError(t, err, w.Key())
+		assert.Equal(t, w.Key(), parsed.Key())
+		assert.Equal(t, w.Kind, parsed.Kind)
+	}
+
+	for _, key := range []string{"", "forever", "rolling_7", "rolling_xd", "rolling_0d", "week@Mars/Olympus", "fortnight@UTC", "all_time@UTC"} {
+		_, err := ParseWindowKey(key)
+		assert.ErrorIs(t, err, ErrInvalidWindow, key)
+	}
+}
+
+func TestWindow_Span(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	require.NoError(t, err)
+
+	// Wednesday 2025-06-11 22:30 UTC is Thursday 01:30 in Istanbul (UTC+3)
+	now := time.Date(2025, 6, 11, 22, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		window   Window
+		expected Span
+	}{
+		{
+			name:     "all time",
+			window:   AllTime(),
+			expected: Span{},
+		},
+		{
+			name:   "rolling",
+			window: Window{Kind: WindowRolling, Days: 7},
+			expected: Span{
+				Start: time.Date(2025, 6, 4, 22, 30, 0, 0, time.UTC),
+				End:   time.Date(2025, 6, 11, 22, 31, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "day utc",
+			window: Window{Kind: WindowDay},
+			expected: Span{
+				Start: time.Date(2025, 6, 11, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2025, 6, 12, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "day istanbul",
+			window: Window{Kind: WindowDay, Location: istanbul},
+			expected: Span{
+				Start: time.Date(2025, 6, 12, 0, 0, 0, 0, istanbul),
+				End:   time.Date(2025, 6, 13, 0, 0, 0, 0, istanbul),
+			},
+		},
+		{
+			name:   "week starts on monday",
+			window: Window{Kind: WindowWeek},
+			expected: Span{
+				Start: time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "month",
+			window: Window{Kind: WindowMonth},
+			expected: Span{
+				Start: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "season is a quarter",
+			window: Window{Kind: WindowSeason},
+			expected: Span{
+				Start: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := tt.window.Span(now)
+
+			assert.True(t, tt.expected.Start.Equal(span.Start), "start: want %v, got %v", tt.expected.Start, span.Start)
+			assert.True(t, tt.expected.End.Equal(span.End), "end: want %v, got %v", tt.expected.End, span.End)
+		})
+	}
+}
+
+func TestWindow_Span_WeekOnSunday(t *testing.T) {
+	sunday := time.Date(2025, 6, 15, 23, 59, 0, 0, time.UTC)
+
+	span := Window{Kind: WindowWeek}.Span(sunday)
+
+	assert.Equal(t, time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC), span.Start)
+}
+
+func TestSpan_Contains(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	span := Span{Start: start, End: end}
+
+	assert.True(t, span.Contains(start))
+	assert.True(t, span.Contains(end.Add(-time.Nanosecond)))
+	assert.False(t, span.Contains(end))
+	assert.False(t, span.Contains(start.Add(-time.Nanosecond)))
+	assert.True(t, Span{}.Contains(time.Time{}))
+	assert.True(t, Span{Start: start}.Contains(end.AddDate(10, 0, 0)))
+}
+
+func TestUserScore_WindowKey(t *testing.T) {
+	assert.Equal(t, "all_time", UserScore{}.WindowKey())
+	assert.Equal(t, "week@UTC", UserScore{Window: "week@UTC"}.WindowKey())
+}
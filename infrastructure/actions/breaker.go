@@ -0,0 +1,78 @@
+package actions
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is a circuit breaker. It opens after a number of consecutive
+// failures and then rejects calls until a cooldown has passed. The first
+// call after the cooldown is let through as a probe: its success closes the
+// breaker and its failure opens it for another cooldown.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	probing  bool
+}
+
+// allow reports whether a call may go ahead. A caller that is allowed must
+// report the outcome with success or failure.
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return true
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// success records a call that reached a healthy upstream.
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.open = false
+	b.probing = false
+}
+
+// failure records a call that failed because of the upstream.
+func (b *breaker) failure() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.probing || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = b.now()
+	}
+	b.probing = false
+}
+
+// release gives up a call that ended without telling anything about the
+// upstream, e.g. because its caller went away.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
@@ -0,0 +1,37 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker_SingleProbe(t *testing.T) {
+	now := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	b := &breaker{threshold: 1, cooldown: time.Second, now: func() time.Time { return now }}
+
+	b.failure()
+	assert.False(t, b.allow())
+
+	// Only one call probes the upstream at a time
+	now = now.Add(time.Second)
+	assert.True(t, b.allow())
+	assert.False(t, b.allow())
+
+	// A probe that ends without an answer lets the next call probe
+	b.release()
+	assert.True(t, b.allow())
+	b.success()
+	assert.True(t, b.allow())
+	assert.True(t, b.allow())
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := &breaker{now: time.Now}
+
+	for range 10 {
+		b.failure()
+	}
+	assert.True(t, b.allow())
+}
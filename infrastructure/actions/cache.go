@@ -0,0 +1,234 @@
+package actions
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+const (
+	// DefaultCacheTTL is how long fetched actions are served from the cache
+	// when no TTL is configured.
+	DefaultCacheTTL = 30 * time.Second
+	// DefaultNegativeCacheTTL is how long an unknown user is remembered when
+	// no TTL is configured.
+	DefaultNegativeCacheTTL = 5 * time.Second
+	// DefaultCacheSize is how many users the cache holds when no size is
+	// configured.
+	DefaultCacheSize = 10000
+)
+
+// cacheEntry is the cached outcome of fetching a user's actions: the
+// actions, or usecase.ErrUserNotFound.
+type cacheEntry struct {
+	userID    string
+	actions   []domain.UserAction
+	err       error
+	expiresAt time.Time
+}
+
+// fetch is an upstream call shared by every caller asking for the same user
+// while it runs.
+type fetch struct {
+	userID  string
+	done    chan struct{}
+	actions []domain.UserAction
+	err     error
+	// waiters is the number of callers still waiting for the fetch. The
+	// fetch is canceled when the last of them gives up.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Cache is a usecase.ActionService decorator that keeps users' actions for
+// a while. Concurrent calls for a user that is not cached share one upstream
+// call. Unknown users are cached as well, for a shorter time, while other
+// errors are not cached. The least recently used users are evicted when the
+// cache is full.
+//
+// Call Invalidate when a user's actions change, so the next call fetches
+// them again.
+type Cache struct {
+	next        usecase.ActionService
+	ttl         time.Duration
+	negativeTTL time.Duration
+	size        int
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *cacheEntry, most recently used first
+	fetches map[string]*fetch
+}
+
+// CacheOption configures optional Cache behavior.
+type CacheOption func(*Cache)
+
+// WithCacheTTL sets how long fetched actions are served from the cache.
+// Defaults to DefaultCacheTTL.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithNegativeCacheTTL sets how long an unknown user is remembered. Zero
+// disables negative caching. Defaults to DefaultNegativeCacheTTL.
+func WithNegativeCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// WithCacheSize sets how many users the cache holds. Defaults to
+// DefaultCacheSize.
+func WithCacheSize(n int) CacheOption {
+	return func(c *Cache) {
+		c.size = n
+	}
+}
+
+// WithCacheClock sets the source of the current time. Defaults to time.Now.
+func WithCacheClock(now func() time.Time) CacheOption {
+	return func(c *Cache) {
+		c.now = now
+	}
+}
+
+// NewCache creates a Cache in front of next.
+func NewCache(next usecase.ActionService, opts ...CacheOption) *Cache {
+	c := &Cache{
+		next:        next,
+		ttl:         DefaultCacheTTL,
+		negativeTTL: DefaultNegativeCacheTTL,
+		size:        DefaultCacheSize,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		fetches:     make(map[string]*fetch),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetActions returns the user's cached actions, fetching them if they are
+// not cached or have expired.
+func (c *Cache) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[userID]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if c.now().Before(entry.expiresAt) {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			return slices.Clone(entry.actions), entry.err
+		}
+		c.remove(elem)
+	}
+
+	f, ok := c.fetches[userID]
+	if !ok {
+		// The fetch outlives the caller that started it when others wait
+		// for it too, so it only keeps the caller's values
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &fetch{userID: userID, done: make(chan struct{}), cancel: cancel}
+		c.fetches[userID] = f
+		go c.run(fetchCtx, userID, f)
+	}
+	f.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		c.leave(f)
+		return slices.Clone(f.actions), f.err
+	case <-ctx.Done():
+		c.leave(f)
+		return nil, ctx.Err()
+	}
+}
+
+// Invalidate drops the user from the cache. A fetch for the user that is
+// running may have missed the change, so its result is not cached.
+func (c *Cache) Invalidate(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[userID]; ok {
+		c.remove(elem)
+	}
+	delete(c.fetches, userID)
+}
+
+func (c *Cache) run(ctx context.Context, userID string, f *fetch) {
+	defer f.cancel()
+
+	actions, err := c.next.GetActions(ctx, userID)
+
+	c.mu.Lock()
+	f.actions, f.err = actions, err
+	if c.fetches[userID] == f {
+		delete(c.fetches, userID)
+		switch {
+		case err == nil:
+			c.store(userID, actions, nil, c.ttl)
+		case errors.Is(err, usecase.ErrUserNotFound):
+			c.store(userID, nil, err, c.negativeTTL)
+		}
+	}
+	c.mu.Unlock()
+	close(f.done)
+}
+
+// leave records that a caller stopped waiting for the fetch, and cancels the
+// fetch once nobody waits for it anymore. Later callers start a new fetch
+// rather than joining the canceled one.
+func (c *Cache) leave(f *fetch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f.waiters--
+	if f.waiters == 0 {
+		f.cancel()
+		if c.fetches[f.userID] == f {
+			delete(c.fetches, f.userID)
+		}
+	}
+}
+
+func (c *Cache) store(userID string, actions []domain.UserAction, err error, ttl time.Duration) {
+	if ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	if elem, ok := c.entries[userID]; ok {
+		c.remove(elem)
+	}
+	entry := &cacheEntry{
+		userID:    userID,
+		actions:   slices.Clone(actions),
+		err:       err,
+		expiresAt: c.now().Add(ttl),
+	}
+	c.entries[userID] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.userID)
+}
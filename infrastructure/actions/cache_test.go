@@ -0,0 +1,261 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+// countingService is an ActionService stand-in that counts its calls per
+// user and answers with get.
+type countingService struct {
+	mu    sync.Mutex
+	calls map[string]int
+	get   func(ctx context.Context, userID string) ([]domain.UserAction, error)
+}
+
+func newCountingService(get func(ctx context.Context, userID string) ([]domain.UserAction, error)) *countingService {
+	return &countingService{calls: make(map[string]int), get: get}
+}
+
+func (s *countingService) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	s.mu.Lock()
+	s.calls[userID]++
+	s.mu.Unlock()
+	return s.get(ctx, userID)
+}
+
+func (s *countingService) callsFor(userID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[userID]
+}
+
+// oneAction answers every known user with a single action and "nobody" with
+// usecase.ErrUserNotFound.
+func oneAction(_ context.Context, userID string) ([]domain.UserAction, error) {
+	if userID == "nobody" {
+		return nil, usecase.ErrUserNotFound
+	}
+	return []domain.UserAction{{ID: userID + "-1", Type: "login", Amount: 1}}, nil
+}
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)}
+}
+
+func TestCache_ServesWithinTTL(t *testing.T) {
+	service := newCountingService(oneAction)
+	clock := newTestClock()
+	cache := NewCache(service, WithCacheTTL(time.Minute), WithCacheClock(clock.Now))
+
+	for range 3 {
+		actions, err := cache.GetActions(context.Background(), "alice")
+		require.NoError(t, err)
+		assert.Equal(t, []domain.UserAction{{ID: "alice-1", Type: "login", Amount: 1}}, actions)
+	}
+	assert.Equal(t, 1, service.callsFor("alice"))
+
+	clock.Advance(time.Minute)
+	_, err := cache.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 2, service.callsFor("alice"))
+}
+
+func TestCache_ReturnsCopies(t *testing.T) {
+	cache := NewCache(newCountingService(oneAction))
+
+	actions, err := cache.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	actions[0].Amount = 100
+
+	actions, err = cache.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), actions[0].Amount)
+}
+
+func TestCache_CoalescesConcurrentCalls(t *testing.T) {
+	release := make(chan struct{})
+	service := newCountingService(func(ctx context.Context, userID string) ([]domain.UserAction, error) {
+		<-release
+		return oneAction(ctx, userID)
+	})
+	cache := NewCache(service)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			actions, err := cache.GetActions(context.Background(), "alice")
+			if err == nil && len(actions) == 1 {
+				succeeded.Add(1)
+			}
+		}()
+	}
+
+	// Let every caller join the fetch before it answers
+	require.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		f := cache.fetches["alice"]
+		return f != nil && f.waiters == callers
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(callers), succeeded.Load())
+	assert.Equal(t, 1, service.callsFor("alice"))
+}
+
+func TestCache_NegativeCaching(t *testing.T) {
+	service := newCountingService(oneAction)
+	clock := newTestClock()
+	cache := NewCache(service, WithNegativeCacheTTL(5*time.Second), WithCacheClock(clock.Now))
+
+	for range 2 {
+		_, err := cache.GetActions(context.Background(), "nobody")
+		assert.ErrorIs(t, err, usecase.ErrUserNotFound)
+	}
+	assert.Equal(t, 1, service.callsFor("nobody"))
+
+	clock.Advance(5 * time.Second)
+	_, err := cache.GetActions(context.Background(), "nobody")
+	assert.ErrorIs(t, err, usecase.ErrUserNotFound)
+	assert.Equal(t, 2, service.callsFor("nobody"))
+}
+
+func TestCache_DoesNotCacheOtherErrors(t *testing.T) {
+	serviceErr := errors.New("service unavailable")
+	service := newCountingService(func(context.Context, string) ([]domain.UserAction, error) {
+		return nil, serviceErr
+	})
+	cache := NewCache(service)
+
+	for range 2 {
+		_, err := cache.GetActions(context.Background(), "alice")
+		assert.ErrorIs(t, err, serviceErr)
+	}
+	assert.Equal(t, 2, service.callsFor("alice"))
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	service := newCountingService(oneAction)
+	cache := NewCache(service, WithCacheSize(2))
+
+	for _, userID := range []string{"alice", "bob", "alice", "carol"} {
+		_, err := cache.GetActions(context.Background(), userID)
+		require.NoError(t, err)
+	}
+
+	// bob was used least recently when carol was added
+	for _, userID := range []string{"alice", "carol", "bob"} {
+		_, err := cache.GetActions(context.Background(), userID)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, service.callsFor("alice"))
+	assert.Equal(t, 1, service.callsFor("carol"))
+	assert.Equal(t, 2, service.callsFor("bob"))
+	assert.Equal(t, 2, cache.lru.Len())
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	service := newCountingService(oneAction)
+	cache := NewCache(service)
+
+	_, err := cache.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+
+	cache.Invalidate("alice")
+	cache.Invalidate("nobody")
+
+	_, err = cache.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 2, service.callsFor("alice"))
+}
+
+func TestCache_InvalidateDuringFetch(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	service := newCountingService(func(ctx context.Context, userID string) ([]domain.UserAction, error) {
+		started <- struct{}{}
+		<-release
+		return oneAction(ctx, userID)
+	})
+	cache := NewCache(service)
+
+	done := make(chan error)
+	go func() {
+		_, err := cache.GetActions(context.Background(), "alice")
+		done <- err
+	}()
+	<-started
+
+	// The running fetch may have read the actions before they changed
+	cache.Invalidate("alice")
+	close(release)
+	require.NoError(t, <-done)
+
+	_, err := cache.GetActions(context.Background(), "alice")
+	<-started
+	require.NoError(t, err)
+	assert.Equal(t, 2, service.callsFor("alice"))
+}
+
+func TestCache_CallerGivesUp(t *testing.T) {
+	service := newCountingService(func(ctx context.Context, userID string) ([]domain.UserAction, error) {
+		if userID == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return oneAction(ctx, userID)
+	})
+	cache := NewCache(service)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := cache.GetActions(ctx, "slow")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The abandoned fetch is canceled and not joined by later callers
+	require.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return len(cache.fetches) == 0
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = cache.GetActions(ctx, "slow")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, service.callsFor("slow"))
+	assert.Zero(t, cache.lru.Len())
+}
@@ -0,0 +1,289 @@
+// Package actions provides an ActionService that fetches user actions from
+// the action service over HTTP, and a cache to put in front of it.
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+const (
+	// DefaultAttemptTimeout bounds a single request when no timeout is
+	// configured.
+	DefaultAttemptTimeout = 2 * time.Second
+	// DefaultMaxAttempts is how often a request is tried when no limit is
+	// configured.
+	DefaultMaxAttempts = 3
+	// DefaultRetryBackoff is the longest wait before the first retry when no
+	// backoff is configured. Each further retry may wait twice as long.
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// DefaultMaxRetryBackoff caps the wait between retries when no cap is
+	// configured.
+	DefaultMaxRetryBackoff = 2 * time.Second
+	// DefaultBreakerThreshold is how many consecutive failed calls open the
+	// circuit breaker when no threshold is configured.
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCooldown is how long an open circuit breaker rejects
+	// calls when no cooldown is configured.
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// maxResponseSize bounds the size of an action list read from the upstream.
+const maxResponseSize = 16 << 20
+
+// ErrCircuitOpen is returned without calling the upstream while the circuit
+// breaker is open.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", usecase.ErrActionServiceUnavailable)
+
+// actionRecord is the wire form of a domain.UserAction.
+type actionRecord struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Amount     int64             `json:"amount"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Metadata   map[string]string `json:"metadata"`
+}
+
+// Client is a usecase.ActionService that reads a user's actions from
+// GET <base URL>/users/{user_id}/actions, which answers with a JSON array of
+// actions and 404 for unknown users.
+//
+// Requests failing with a 5xx status or a transport error are retried with
+// jittered exponential backoff. Calls that still fail count towards the
+// circuit breaker, which fails calls fast while the upstream is down.
+type Client struct {
+	baseURL        *url.URL
+	httpClient     *http.Client
+	attemptTimeout time.Duration
+	maxAttempts    int
+	backoff        time.Duration
+	maxBackoff     time.Duration
+	breaker        *breaker
+	// jitter returns a random wait of at most d.
+	jitter func(d time.Duration) time.Duration
+}
+
+// Option configures optional Client behavior.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are sent with. Defaults to
+// http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) {
+		cl.httpClient = c
+	}
+}
+
+// WithAttemptTimeout bounds each request; every retry gets a timeout of its
+// own. Zero disables the timeout. Defaults to DefaultAttemptTimeout.
+func WithAttemptTimeout(d time.Duration) Option {
+	return func(cl *Client) {
+		cl.attemptTimeout = d
+	}
+}
+
+// WithRetries sets how often a request is tried and the longest wait before
+// the first retry. Defaults to DefaultMaxAttempts and DefaultRetryBackoff.
+func WithRetries(maxAttempts int, backoff time.Duration) Option {
+	return func(cl *Client) {
+		cl.maxAttempts = maxAttempts
+		cl.backoff = backoff
+	}
+}
+
+// WithMaxRetryBackoff caps the wait between retries. Defaults to
+// DefaultMaxRetryBackoff.
+func WithMaxRetryBackoff(d time.Duration) Option {
+	return func(cl *Client) {
+		cl.maxBackoff = d
+	}
+}
+
+// WithCircuitBreaker sets how many consecutive failed calls open the circuit
+// breaker and how long it then stays open. A threshold of zero disables the
+// breaker. Defaults to DefaultBreakerThreshold and DefaultBreakerCooldown.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(cl *Client) {
+		cl.breaker.threshold = threshold
+		cl.breaker.cooldown = cooldown
+	}
+}
+
+// WithClock sets the source of the current time. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(cl *Client) {
+		cl.breaker.now = now
+	}
+}
+
+// NewClient creates a Client for the action service at baseURL.
+func NewClient(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid action service URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid action service URL %q: must be an absolute http or https URL", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:        u,
+		httpClient:     http.DefaultClient,
+		attemptTimeout: DefaultAttemptTimeout,
+		maxAttempts:    DefaultMaxAttempts,
+		backoff:        DefaultRetryBackoff,
+		maxBackoff:     DefaultMaxRetryBackoff,
+		breaker: &breaker{
+			threshold: DefaultBreakerThreshold,
+			cooldown:  DefaultBreakerCooldown,
+			now:       time.Now,
+		},
+		jitter: func(d time.Duration) time.Duration {
+			return rand.N(d + 1)
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// GetActions fetches the user's actions. It returns usecase.ErrUserNotFound
+// for users the upstream does not know, and an error wrapping
+// usecase.ErrActionServiceUnavailable when the upstream keeps failing or the
+// circuit breaker is open.
+func (c *Client) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	if !c.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	actions, err := c.getWithRetries(ctx, userID)
+	switch {
+	case err == nil, errors.Is(err, usecase.ErrUserNotFound):
+		c.breaker.success()
+	case ctx.Err() != nil:
+		// The caller gave up, which says nothing about the upstream
+		c.breaker.release()
+	case errors.Is(err, usecase.ErrActionServiceUnavailable):
+		c.breaker.failure()
+	default:
+		// The upstream answered, but not with something we understand
+		c.breaker.success()
+	}
+	return actions, err
+}
+
+func (c *Client) getWithRetries(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	for attempt := 1; ; attempt++ {
+		actions, err := c.get(ctx, userID)
+		if err == nil || !errors.Is(err, usecase.ErrActionServiceUnavailable) || attempt >= c.maxAttempts {
+			return actions, err
+		}
+		if err := sleep(ctx, c.jitter(c.retryBackoff(attempt))); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryBackoff returns the longest wait before retrying after the attempt.
+func (c *Client) retryBackoff(attempt int) time.Duration {
+	d := c.backoff
+	for range attempt - 1 {
+		if d >= c.maxBackoff/2 {
+			return c.maxBackoff
+		}
+		d *= 2
+	}
+	return min(d, c.maxBackoff)
+}
+
+// get makes a single request. Failures worth retrying wrap
+// usecase.ErrActionServiceUnavailable.
+func (c *Client) get(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	attemptCtx := ctx
+	if c.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.attemptTimeout)
+		defer cancel()
+	}
+
+	// Escape the user ID, so it stays a single path segment
+	u := *c.baseURL
+	u.Path += "/users/" + userID + "/actions"
+	u.RawPath = c.baseURL.EscapedPath() + "/users/" + url.PathEscape(userID) + "/actions"
+	req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if id := usecase.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Report the caller's own cancellation or deadline as is
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %w", usecase.ErrActionServiceUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, usecase.ErrUserNotFound
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: upstream answered %s", usecase.ErrActionServiceUnavailable, resp.Status)
+	default:
+		return nil, fmt.Errorf("action service answered %s", resp.Status)
+	}
+
+	var records []actionRecord
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&records); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attemptCtx.Err() != nil {
+			return nil, fmt.Errorf("%w: %w", usecase.ErrActionServiceUnavailable, attemptCtx.Err())
+		}
+		return nil, fmt.Errorf("failed to decode actions: %w", err)
+	}
+
+	actions := make([]domain.UserAction, len(records))
+	for i, rec := range records {
+		actions[i] = domain.UserAction{
+			ID:         rec.ID,
+			Type:       rec.Type,
+			Amount:     rec.Amount,
+			OccurredAt: rec.OccurredAt,
+			Metadata:   rec.Metadata,
+		}
+	}
+	return actions, nil
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
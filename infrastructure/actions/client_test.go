@@ -0,0 +1,255 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+// upstream is an httptest.Server stand-in for the action service that
+// counts the requests it serves.
+type upstream struct {
+	*httptest.Server
+	calls atomic.Int32
+}
+
+func newUpstream(t *testing.T, handler http.HandlerFunc) *upstream {
+	t.Helper()
+	u := &upstream{}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u.calls.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+// statuses answers with the given statuses in turn and then keeps answering
+// with the last one. 200 answers carry one action.
+func statuses(codes ...int) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		code := codes[0]
+		if len(codes) > 1 {
+			codes = codes[1:]
+		}
+		mu.Unlock()
+
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			_, _ = w.Write([]byte(`[{"id":"a1","type":"login","amount":1,"occurred_at":"2025-06-02T08:00:00Z"}]`))
+		}
+	}
+}
+
+func newTestClient(t *testing.T, baseURL string, opts ...Option) (*Client, *[]time.Duration) {
+	t.Helper()
+	c, err := NewClient(baseURL, opts...)
+	require.NoError(t, err)
+
+	// Record the backoff instead of waiting for it
+	var waits []time.Duration
+	c.jitter = func(d time.Duration) time.Duration {
+		waits = append(waits, d)
+		return 0
+	}
+	return c, &waits
+}
+
+func TestClient_GetActions(t *testing.T) {
+	var request *http.Request
+	server := newUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		_, _ = w.Write([]byte(`[
+			{"id":"a1","type":"login","amount":1,"occurred_at":"2025-06-02T08:00:00Z","metadata":{"device":"ios"}},
+			{"type":"quiz_answer","amount":3,"occurred_at":"2025-06-02T09:00:00Z"}
+		]`))
+	})
+	c, _ := newTestClient(t, server.URL+"/v1/")
+
+	ctx := usecase.ContextWithRequestID(context.Background(), "req-1")
+	actions, err := c.GetActions(ctx, "user/1 ü")
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{
+		{ID: "a1", Type: "login", Amount: 1, OccurredAt: time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC), Metadata: map[string]string{"device": "ios"}},
+		{Type: "quiz_answer", Amount: 3, OccurredAt: time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)},
+	}, actions)
+
+	// The user ID stays a single path segment
+	assert.Equal(t, "/v1/users/user%2F1%20%C3%BC/actions", request.URL.EscapedPath())
+	assert.Equal(t, "application/json", request.Header.Get("Accept"))
+	assert.Equal(t, "req-1", request.Header.Get("X-Request-ID"))
+}
+
+func TestClient_UserNotFound(t *testing.T) {
+	server := newUpstream(t, statuses(http.StatusNotFound))
+	c, _ := newTestClient(t, server.URL)
+
+	actions, err := c.GetActions(context.Background(), "nobody")
+
+	assert.ErrorIs(t, err, usecase.ErrUserNotFound)
+	assert.Nil(t, actions)
+	assert.Equal(t, int32(1), server.calls.Load())
+}
+
+func TestClient_RetriesServerErrors(t *testing.T) {
+	server := newUpstream(t, statuses(http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK))
+	c, waits := newTestClient(t, server.URL, WithRetries(3, 100*time.Millisecond))
+
+	actions, err := c.GetActions(context.Background(), "user")
+
+	require.NoError(t, err)
+	assert.Len(t, actions, 1)
+	assert.Equal(t, int32(3), server.calls.Load())
+	// The jitter is drawn from an exponentially growing range
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, *waits)
+}
+
+func TestClient_GivesUp(t *testing.T) {
+	server := newUpstream(t, statuses(http.StatusServiceUnavailable))
+	c, waits := newTestClient(t, server.URL, WithRetries(4, time.Second), WithMaxRetryBackoff(3*time.Second))
+
+	_, err := c.GetActions(context.Background(), "user")
+
+	assert.ErrorIs(t, err, usecase.ErrActionServiceUnavailable)
+	assert.ErrorContains(t, err, "503 Service Unavailable")
+	assert.Equal(t, int32(4), server.calls.Load())
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, *waits)
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"bad request", statuses(http.StatusBadRequest)},
+		{"malformed body", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"actions":`))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newUpstream(t, tt.handler)
+			c, _ := newTestClient(t, server.URL)
+
+			_, err := c.GetActions(context.Background(), "user")
+
+			assert.Error(t, err)
+			assert.NotErrorIs(t, err, usecase.ErrActionServiceUnavailable)
+			assert.Equal(t, int32(1), server.calls.Load())
+		})
+	}
+}
+
+func TestClient_AttemptTimeout(t *testing.T) {
+	var calls atomic.Int32
+	server := newUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt hangs until the client gives up on it
+		if calls.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		statuses(http.StatusOK)(w, r)
+	})
+	c, _ := newTestClient(t, server.URL, WithAttemptTimeout(20*time.Millisecond))
+
+	actions, err := c.GetActions(context.Background(), "user")
+
+	require.NoError(t, err)
+	assert.Len(t, actions, 1)
+	assert.Equal(t, int32(2), server.calls.Load())
+}
+
+func TestClient_CallerCanceled(t *testing.T) {
+	server := newUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	c, _ := newTestClient(t, server.URL, WithCircuitBreaker(1, time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := c.GetActions(ctx, "user")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, usecase.ErrActionServiceUnavailable)
+	assert.Equal(t, int32(1), server.calls.Load())
+	// The caller's deadline does not count against the upstream
+	assert.True(t, c.breaker.allow())
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	now := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	var healthy atomic.Bool
+	server := newUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if healthy.Load() {
+			statuses(http.StatusOK)(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	c, _ := newTestClient(t, server.URL,
+		WithRetries(1, 0),
+		WithCircuitBreaker(2, time.Minute),
+		WithClock(func() time.Time { return now }))
+
+	for range 2 {
+		_, err := c.GetActions(context.Background(), "user")
+		assert.ErrorIs(t, err, usecase.ErrActionServiceUnavailable)
+	}
+
+	// Open: calls fail fast without reaching the upstream
+	_, err := c.GetActions(context.Background(), "user")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, err, usecase.ErrActionServiceUnavailable)
+	assert.Equal(t, int32(2), server.calls.Load())
+
+	// A failed probe after the cooldown opens the breaker again
+	now = now.Add(time.Minute)
+	_, err = c.GetActions(context.Background(), "user")
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	_, err = c.GetActions(context.Background(), "user")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(3), server.calls.Load())
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	for range 3 {
+		_, err = c.GetActions(context.Background(), "user")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(6), server.calls.Load())
+}
+
+func TestClient_NotFoundKeepsBreakerClosed(t *testing.T) {
+	server := newUpstream(t, statuses(http.StatusNotFound))
+	c, _ := newTestClient(t, server.URL, WithCircuitBreaker(1, time.Minute))
+
+	for range 3 {
+		_, err := c.GetActions(context.Background(), "nobody")
+		assert.ErrorIs(t, err, usecase.ErrUserNotFound)
+	}
+	assert.Equal(t, int32(3), server.calls.Load())
+}
+
+func TestNewClient_InvalidURL(t *testing.T) {
+	for _, baseURL := range []string{"", "actions.internal:8080", "ftp://actions.internal", "http://", "http://a b"} {
+		c, err := NewClient(baseURL)
+
+		assert.Error(t, err, baseURL)
+		assert.Nil(t, c)
+	}
+}
@@ -0,0 +1,44 @@
+package actions
+
+import (
+	"context"
+	"errors"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+// Combined is a usecase.ActionService that serves the actions of several
+// services together, e.g. those of the action service and those ingested
+// by this one. A user is unknown only if no service knows the user, and any
+// other error of a service fails the call.
+type Combined struct {
+	services []usecase.ActionService
+}
+
+// NewCombined creates a Combined serving the actions of services, in order.
+func NewCombined(services ...usecase.ActionService) *Combined {
+	return &Combined{services: services}
+}
+
+// GetActions returns the user's actions from every service that knows the
+// user.
+func (c *Combined) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	var actions []domain.UserAction
+	found := false
+	for _, service := range c.services {
+		got, err := service.GetActions(ctx, userID)
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		actions = append(actions, got...)
+	}
+	if !found {
+		return nil, usecase.ErrUserNotFound
+	}
+	return actions, nil
+}
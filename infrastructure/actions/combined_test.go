@@ -0,0 +1,66 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+// fixedService answers every user with the same actions or error.
+type fixedService struct {
+	actions []domain.UserAction
+	err     error
+}
+
+func (s fixedService) GetActions(context.Context, string) ([]domain.UserAction, error) {
+	return s.actions, s.err
+}
+
+func TestCombined_GetActions(t *testing.T) {
+	upstream := fixedService{actions: []domain.UserAction{{ID: "a1", Type: "login", Amount: 1}}}
+	ingested := fixedService{actions: []domain.UserAction{{ID: "a2", Type: "quiz_answer", Amount: 2}}}
+	unknown := fixedService{err: usecase.ErrUserNotFound}
+
+	tests := []struct {
+		name     string
+		services []usecase.ActionService
+		want     []domain.UserAction
+	}{
+		{"both", []usecase.ActionService{upstream, ingested}, []domain.UserAction{upstream.actions[0], ingested.actions[0]}},
+		{"only upstream", []usecase.ActionService{upstream, unknown}, upstream.actions},
+		{"only ingested", []usecase.ActionService{unknown, ingested}, ingested.actions},
+		{"no actions", []usecase.ActionService{fixedService{actions: []domain.UserAction{}}, unknown}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := NewCombined(tt.services...).GetActions(context.Background(), "alice")
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, actions)
+		})
+	}
+}
+
+func TestCombined_UnknownUser(t *testing.T) {
+	unknown := fixedService{err: usecase.ErrUserNotFound}
+
+	_, err := NewCombined(unknown, unknown).GetActions(context.Background(), "nobody")
+
+	assert.ErrorIs(t, err, usecase.ErrUserNotFound)
+}
+
+func TestCombined_Fails(t *testing.T) {
+	serviceErr := errors.New("service unavailable")
+	upstream := fixedService{actions: []domain.UserAction{{ID: "a1", Type: "login", Amount: 1}}}
+
+	_, err := NewCombined(upstream, fixedService{err: serviceErr}).GetActions(context.Background(), "alice")
+
+	assert.ErrorIs(t, err, serviceErr)
+}
@@ -0,0 +1,278 @@
+package actions
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+// ErrMalformedAction is wrapped by the errors reporting lines of an action
+// log that cannot be read.
+var ErrMalformedAction = errors.New("malformed action")
+
+// maxReportedLines bounds how many malformed lines NewFileService reports.
+const maxReportedLines = 20
+
+// maxLineSize bounds the length of a line of a JSONL action log.
+const maxLineSize = 1 << 20
+
+// csvColumns are the columns a CSV action log may have. Only user_id, type
+// and amount are required.
+var csvColumns = []string{"user_id", "id", "type", "amount", "occurred_at", "metadata"}
+
+// logRecord is the form of an action in an action log.
+type logRecord struct {
+	UserID     string            `json:"user_id"`
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Amount     *int64            `json:"amount"`
+	OccurredAt *time.Time        `json:"occurred_at"`
+	Metadata   map[string]string `json:"metadata"`
+}
+
+// FileService is a usecase.ActionService that serves actions from the action
+// logs in a directory, e.g. exports of the action service. Every *.jsonl and
+// *.csv file is read once, when the FileService is created; other files are
+// ignored.
+//
+// A JSONL log holds one JSON object per line with the fields user_id, id,
+// type, amount, occurred_at (RFC 3339) and metadata (an object of strings).
+// A CSV log has a header row naming its columns, which are the same fields
+// with metadata encoded as a JSON object. Only user_id, type and amount are
+// required. A user's actions are served in the order of the files' names and
+// then of their lines.
+type FileService struct {
+	actions map[string][]domain.UserAction
+}
+
+// NewFileService reads the action logs in dir. Malformed lines are reported
+// with their file and line numbers, and fail the whole directory.
+func NewFileService(dir string) (*FileService, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read action logs: %w", err)
+	}
+
+	s := &FileService{actions: make(map[string][]domain.UserAction)}
+	var malformed []error
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		var read func(io.Reader, func(line int, rec logRecord, err error)) error
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".jsonl":
+			read = readJSONLines
+		case ".csv":
+			read = readCSV
+		default:
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read action logs: %w", err)
+		}
+		err = read(f, func(line int, rec logRecord, err error) {
+			if err == nil {
+				err = rec.validate()
+			}
+			if err != nil {
+				malformed = append(malformed, fmt.Errorf("%s:%d: %w: %v", path, line, ErrMalformedAction, err))
+				return
+			}
+			s.actions[rec.UserID] = append(s.actions[rec.UserID], rec.action())
+		})
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	if len(malformed) > maxReportedLines {
+		more := len(malformed) - maxReportedLines
+		malformed = append(malformed[:maxReportedLines], fmt.Errorf("%d more malformed lines", more))
+	}
+	if err := errors.Join(malformed...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetActions returns the user's actions, or usecase.ErrUserNotFound when no
+// action log mentions the user.
+func (s *FileService) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	actions, ok := s.actions[userID]
+	if !ok {
+		return nil, usecase.ErrUserNotFound
+	}
+	return slices.Clone(actions), nil
+}
+
+// readJSONLines reads a JSONL action log, passing every non-blank line to
+// fn. It fails only when r cannot be read.
+func readJSONLines(r io.Reader, fn func(line int, rec logRecord, err error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	line := 1
+	for ; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var rec logRecord
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&rec)
+		if err == nil && dec.More() {
+			err = errors.New("more than one value on the line")
+		}
+		fn(line, rec, err)
+	}
+
+	// The rest of the file cannot be split into lines once one is too long
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		fn(line, logRecord{}, fmt.Errorf("line longer than %d bytes", maxLineSize))
+		return nil
+	}
+	return scanner.Err()
+}
+
+// readCSV reads a CSV action log, passing every record to fn. It fails only
+// when r cannot be read; an invalid header is passed to fn as line 1.
+func readCSV(r io.Reader, fn func(line int, rec logRecord, err error)) error {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		fn(parseErr.StartLine, logRecord{}, parseErr.Err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	columns, err := csvHeader(header)
+	if err != nil {
+		fn(1, logRecord{}, err)
+		return nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if errors.As(err, &parseErr) {
+			fn(parseErr.StartLine, logRecord{}, parseErr.Err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+		rec, err := parseCSVRecord(record, columns)
+		fn(line, rec, err)
+	}
+}
+
+func csvHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(csvColumns, name) {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"user_id", "type", "amount"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+func parseCSVRecord(record []string, columns map[string]int) (logRecord, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rec := logRecord{UserID: field("user_id"), ID: field("id"), Type: field("type")}
+	if value := field("amount"); value != "" {
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return logRecord{}, fmt.Errorf("invalid amount %q", value)
+		}
+		rec.Amount = &amount
+	}
+	if value := field("occurred_at"); value != "" {
+		occurredAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return logRecord{}, fmt.Errorf("invalid occurred_at %q", value)
+		}
+		rec.OccurredAt = &occurredAt
+	}
+	if value := field("metadata"); value != "" {
+		if err := json.Unmarshal([]byte(value), &rec.Metadata); err != nil {
+			return logRecord{}, fmt.Errorf("invalid metadata: %v", err)
+		}
+	}
+	return rec, nil
+}
+
+func (rec logRecord) validate() error {
+	switch {
+	case rec.UserID == "":
+		return errors.New("missing user_id")
+	case rec.Type == "":
+		return errors.New("missing type")
+	case rec.Amount == nil:
+		return errors.New("missing amount")
+	}
+	return nil
+}
+
+func (rec logRecord) action() domain.UserAction {
+	action := domain.UserAction{
+		ID:       rec.ID,
+		Type:     rec.Type,
+		Amount:   *rec.Amount,
+		Metadata: rec.Metadata,
+	}
+	if rec.OccurredAt != nil {
+		action.OccurredAt = *rec.OccurredAt
+	}
+	return action
+}
@@ -0,0 +1,158 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+// writeLogs writes the named action logs to a new directory.
+func writeLogs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestFileService_GetActions(t *testing.T) {
+	dir := writeLogs(t, map[string]string{
+		"1-export.jsonl": `{"user_id":"alice","id":"a1","type":"login","amount":1,"occurred_at":"2025-06-02T08:00:00Z","metadata":{"device":"ios"}}
+
+{"user_id":"bob","type":"quiz_answer","amount":2}
+`,
+		"2-export.csv": `user_id,id,type,amount,occurred_at,metadata
+alice,a2,quiz_answer,3,2025-06-02T09:00:00Z,"{""quiz"":""daily""}"
+bob,,login,0,,
+`,
+		"README.txt": "not an action log",
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "old.jsonl"), 0o755))
+
+	s, err := NewFileService(dir)
+	require.NoError(t, err)
+
+	actions, err := s.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{
+		{ID: "a1", Type: "login", Amount: 1, OccurredAt: time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC), Metadata: map[string]string{"device": "ios"}},
+		{ID: "a2", Type: "quiz_answer", Amount: 3, OccurredAt: time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC), Metadata: map[string]string{"quiz": "daily"}},
+	}, actions)
+
+	actions, err = s.GetActions(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{
+		{Type: "quiz_answer", Amount: 2},
+		{Type: "login", Amount: 0},
+	}, actions)
+
+	_, err = s.GetActions(context.Background(), "nobody")
+	assert.ErrorIs(t, err, usecase.ErrUserNotFound)
+}
+
+func TestFileService_CSVColumnsInAnyOrder(t *testing.T) {
+	dir := writeLogs(t, map[string]string{
+		"export.csv": "amount,type,user_id\n5,login,alice\n",
+	})
+
+	s, err := NewFileService(dir)
+	require.NoError(t, err)
+
+	actions, err := s.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{{Type: "login", Amount: 5}}, actions)
+}
+
+func TestFileService_MalformedLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"invalid JSON", "a.jsonl", "{\"user_id\":\"alice\",\"type\":\"login\",\"amount\":1}\n{\"user_id\":", "a.jsonl:2: malformed action: unexpected EOF"},
+		{"unknown field", "a.jsonl", `{"user_id":"alice","type":"login","amount":1,"points":3}`, `a.jsonl:1: malformed action: json: unknown field "points"`},
+		{"two values", "a.jsonl", `{"user_id":"alice","type":"login","amount":1} {}`, "a.jsonl:1: malformed action: more than one value on the line"},
+		{"missing user", "a.jsonl", "\n\n" + `{"type":"login","amount":1}`, "a.jsonl:3: malformed action: missing user_id"},
+		{"missing type", "a.jsonl", `{"user_id":"alice","amount":1}`, "a.jsonl:1: malformed action: missing type"},
+		{"missing amount", "a.jsonl", `{"user_id":"alice","type":"login"}`, "a.jsonl:1: malformed action: missing amount"},
+		{"string amount", "a.jsonl", `{"user_id":"alice","type":"login","amount":"1"}`, "a.jsonl:1: malformed action: json: cannot unmarshal string"},
+		{"line too long", "a.jsonl", "{}\n" + strings.Repeat(" ", maxLineSize+1), fmt.Sprintf("a.jsonl:2: malformed action: line longer than %d bytes", maxLineSize)},
+		{"unknown column", "a.csv", "user_id,type,amount,points\n", `a.csv:1: malformed action: unknown column "points"`},
+		{"duplicate column", "a.csv", "user_id,type,amount,type\n", `a.csv:1: malformed action: duplicate column "type"`},
+		{"missing column", "a.csv", "user_id,type\n", `a.csv:1: malformed action: missing column "amount"`},
+		{"wrong field count", "a.csv", "user_id,type,amount\nalice,login\n", "a.csv:2: malformed action: wrong number of fields"},
+		{"bare quote", "a.csv", "user_id,type,amount\nalice,lo\"gin,1\n", "a.csv:2: malformed action: bare \" in non-quoted-field"},
+		{"empty amount", "a.csv", "user_id,type,amount\nalice,login,\n", "a.csv:2: malformed action: missing amount"},
+		{"invalid amount", "a.csv", "user_id,type,amount\nalice,login,1.5\n", `a.csv:2: malformed action: invalid amount "1.5"`},
+		{"invalid time", "a.csv", "user_id,type,amount,occurred_at\nalice,login,1,yesterday\n", `a.csv:2: malformed action: invalid occurred_at "yesterday"`},
+		{"invalid metadata", "a.csv", "user_id,type,amount,metadata\nalice,login,1,device=ios\n", "a.csv:2: malformed action: invalid metadata"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeLogs(t, map[string]string{tt.file: tt.content})
+
+			s, err := NewFileService(dir)
+
+			assert.ErrorIs(t, err, ErrMalformedAction)
+			assert.ErrorContains(t, err, filepath.Join(dir, tt.want))
+			assert.Nil(t, s)
+		})
+	}
+}
+
+func TestFileService_ReportsEveryMalformedLine(t *testing.T) {
+	dir := writeLogs(t, map[string]string{
+		"a.jsonl": "{\"user_id\":\"alice\"}\n{\"user_id\":\"alice\",\"type\":\"login\",\"amount\":1}\n{}\n",
+		"b.csv":   "user_id,type,amount\n,login,1\n",
+	})
+
+	_, err := NewFileService(dir)
+
+	require.Error(t, err)
+	assert.Equal(t, strings.Join([]string{
+		filepath.Join(dir, "a.jsonl") + ":1: malformed action: missing type",
+		filepath.Join(dir, "a.jsonl") + ":3: malformed action: missing user_id",
+		filepath.Join(dir, "b.csv") + ":2: malformed action: missing user_id",
+	}, "\n"), err.Error())
+}
+
+func TestFileService_LimitsReportedLines(t *testing.T) {
+	dir := writeLogs(t, map[string]string{
+		"a.jsonl": strings.Repeat("{}\n", maxReportedLines+5),
+	})
+
+	_, err := NewFileService(dir)
+
+	require.Error(t, err)
+	lines := strings.Split(err.Error(), "\n")
+	assert.Len(t, lines, maxReportedLines+1)
+	assert.Equal(t, "5 more malformed lines", lines[maxReportedLines])
+}
+
+func TestFileService_MissingDir(t *testing.T) {
+	_, err := NewFileService(filepath.Join(t.TempDir(), "missing"))
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFileService_Fixtures(t *testing.T) {
+	s, err := NewFileService("../../fixtures/actions")
+	require.NoError(t, err)
+
+	actions, err := s.GetActions(context.Background(), "user_active")
+	require.NoError(t, err)
+	assert.NotEmpty(t, actions)
+}
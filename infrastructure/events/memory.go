@@ -0,0 +1,61 @@
+// Package events provides usecase.ScoreEventPublisher implementations that
+// keep score events in memory or deliver them to a webhook.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"scoreapp/domain"
+)
+
+// DefaultMemoryCapacity is how many events a Memory publisher keeps when no
+// capacity is configured.
+const DefaultMemoryCapacity = 1000
+
+// Memory is a usecase.ScoreEventPublisher that keeps the most recent events
+// it was given, e.g. for tests or when no downstream system is configured.
+type Memory struct {
+	mu       sync.Mutex
+	events   []domain.ScoreChanged
+	capacity int
+}
+
+// NewMemory creates a Memory publisher keeping up to capacity events; older
+// events are dropped first. A capacity that is not positive defaults to
+// DefaultMemoryCapacity.
+func NewMemory(capacity int) *Memory {
+	if capacity <= 0 {
+		capacity = DefaultMemoryCapacity
+	}
+	return &Memory{capacity: capacity}
+}
+
+// Publish keeps the event, unless it is a redelivery of one it keeps.
+func (m *Memory) Publish(ctx context.Context, event domain.ScoreChanged) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.events {
+		if e.ID == event.ID {
+			return nil
+		}
+	}
+	if len(m.events) == m.capacity {
+		m.events = append(m.events[:0], m.events[1:]...)
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+// Events returns the kept events, oldest first.
+func (m *Memory) Events() []domain.ScoreChanged {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]domain.ScoreChanged(nil), m.events...)
+}
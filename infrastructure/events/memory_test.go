@@ -0,0 +1,43 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+func event(id int64) domain.ScoreChanged {
+	return domain.ScoreChanged{ID: id, UserID: "alice", Window: "all_time", OldScore: id - 1, NewScore: id, Delta: 1, RuleVersion: 1}
+}
+
+func TestMemory_KeepsRecentEvents(t *testing.T) {
+	m := NewMemory(2)
+
+	for id := int64(1); id <= 3; id++ {
+		require.NoError(t, m.Publish(context.Background(), event(id)))
+	}
+
+	assert.Equal(t, []domain.ScoreChanged{event(2), event(3)}, m.Events())
+}
+
+func TestMemory_DropsRedeliveries(t *testing.T) {
+	m := NewMemory(0)
+
+	require.NoError(t, m.Publish(context.Background(), event(1)))
+	require.NoError(t, m.Publish(context.Background(), event(1)))
+
+	assert.Equal(t, []domain.ScoreChanged{event(1)}, m.Events())
+}
+
+func TestMemory_Canceled(t *testing.T) {
+	m := NewMemory(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, m.Publish(ctx, event(1)), context.Canceled)
+	assert.Empty(t, m.Events())
+}
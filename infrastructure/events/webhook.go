@@ -0,0 +1,144 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"scoreapp/domain"
+)
+
+// DefaultWebhookTimeout bounds a single delivery when no timeout is
+// configured.
+const DefaultWebhookTimeout = 5 * time.Second
+
+// EventIDHeader carries the ID of the delivered event, which stays the same
+// when the event is delivered again, so receivers can drop redeliveries.
+const EventIDHeader = "X-Score-Event-ID"
+
+// maxDrainSize bounds how much of a response body is read to reuse the
+// connection.
+const maxDrainSize = 64 << 10
+
+// scoreChangedPayload is the wire form of a domain.ScoreChanged.
+type scoreChangedPayload struct {
+	ID          int64     `json:"id"`
+	Type        string    `json:"type"`
+	UserID      string    `json:"user_id"`
+	Window      string    `json:"window"`
+	OldScore    int64     `json:"old_score"`
+	NewScore    int64     `json:"new_score"`
+	Delta       int64     `json:"delta"`
+	RuleVersion int       `json:"rule_version"`
+	ChangedAt   time.Time `json:"changed_at"`
+	RequestID   string    `json:"request_id,omitempty"`
+}
+
+// Webhook is a usecase.ScoreEventPublisher that POSTs every event as JSON to
+// a URL:
+//
+//	{"id":42,"type":"score.changed","user_id":"alice","window":"all_time",
+//	 "old_score":10,"new_score":15,"delta":5,"rule_version":2,
+//	 "changed_at":"2025-06-02T08:00:00Z","request_id":"..."}
+//
+// Any 2xx answer delivers the event. Failed deliveries are not retried
+// here; the usecase.ScoreEventRelay publishes them again.
+type Webhook struct {
+	url        string
+	httpClient *http.Client
+	timeout    time.Duration
+}
+
+// WebhookOption configures optional Webhook behavior.
+type WebhookOption func(*Webhook)
+
+// WithWebhookHTTPClient sets the HTTP client events are sent with. Defaults
+// to http.DefaultClient.
+func WithWebhookHTTPClient(c *http.Client) WebhookOption {
+	return func(w *Webhook) {
+		w.httpClient = c
+	}
+}
+
+// WithWebhookTimeout bounds each delivery. Zero disables the timeout.
+// Defaults to DefaultWebhookTimeout.
+func WithWebhookTimeout(d time.Duration) WebhookOption {
+	return func(w *Webhook) {
+		w.timeout = d
+	}
+}
+
+// NewWebhook creates a Webhook delivering events to rawURL.
+func NewWebhook(rawURL string, opts ...WebhookOption) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q: must be an absolute http or https URL", rawURL)
+	}
+
+	w := &Webhook{
+		url:        u.String(),
+		httpClient: http.DefaultClient,
+		timeout:    DefaultWebhookTimeout,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
+}
+
+// Publish POSTs the event to the webhook and fails unless it answers with a
+// 2xx status.
+func (w *Webhook) Publish(ctx context.Context, event domain.ScoreChanged) error {
+	body, err := json.Marshal(scoreChangedPayload{
+		ID:          event.ID,
+		Type:        "score.changed",
+		UserID:      event.UserID,
+		Window:      event.Window,
+		OldScore:    event.OldScore,
+		NewScore:    event.NewScore,
+		Delta:       event.Delta,
+		RuleVersion: event.RuleVersion,
+		ChangedAt:   event.ChangedAt,
+		RequestID:   event.RequestID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if w.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, strconv.FormatInt(event.ID, 10))
+	if event.RequestID != "" {
+		req.Header.Set("X-Request-ID", event.RequestID)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
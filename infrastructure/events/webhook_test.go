@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+func TestWebhook_Publish(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	webhook, err := NewWebhook(server.URL + "/hooks/scores")
+	require.NoError(t, err)
+
+	err = webhook.Publish(context.Background(), domain.ScoreChanged{
+		ID:          42,
+		UserID:      "alice",
+		Window:      "all_time",
+		OldScore:    10,
+		NewScore:    15,
+		Delta:       5,
+		RuleVersion: 2,
+		ChangedAt:   time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC),
+		RequestID:   "req-1",
+	})
+
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "/hooks/scores", got.URL.Path)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, "42", got.Header.Get(EventIDHeader))
+	assert.Equal(t, "req-1", got.Header.Get("X-Request-ID"))
+	assert.JSONEq(t, `{
+		"id": 42,
+		"type": "score.changed",
+		"user_id": "alice",
+		"window": "all_time",
+		"old_score": 10,
+		"new_score": 15,
+		"delta": 5,
+		"rule_version": 2,
+		"changed_at": "2025-06-02T08:00:00Z",
+		"request_id": "req-1"
+	}`, string(body))
+}
+
+func TestWebhook_Rejected(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusServiceUnavailable, http.StatusFound} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Location", "/elsewhere")
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			webhook, err := NewWebhook(server.URL, WithWebhookHTTPClient(&http.Client{
+				CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+			}))
+			require.NoError(t, err)
+
+			err = webhook.Publish(context.Background(), event(1))
+			assert.ErrorContains(t, err, "webhook answered")
+		})
+	}
+}
+
+func TestWebhook_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	webhook, err := NewWebhook(server.URL, WithWebhookTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+
+	err = webhook.Publish(context.Background(), event(1))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNewWebhook_InvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"", "hooks/scores", "ftp://example.com", "http://", ":"} {
+		_, err := NewWebhook(rawURL)
+		assert.Error(t, err, rawURL)
+	}
+}
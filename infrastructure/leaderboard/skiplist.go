@@ -0,0 +1,267 @@
+// Package leaderboard provides ordered indexes for ranking users by score.
+package leaderboard
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"scoreapp/domain"
+)
+
+const (
+	// maxLevel bounds the height of the list; with p = 1/4 it comfortably
+	// covers 2^64 entries.
+	maxLevel = 32
+	// branching is the inverse of the chance that a node is promoted to the
+	// next level.
+	branching = 4
+)
+
+// link points from a node to its successor on one level. Span is the number
+// of level-0 steps the link skips, which lets ranks be counted on the way
+// down instead of walking the bottom level.
+type link struct {
+	next *node
+	span int
+}
+
+type node struct {
+	userID    string
+	score     int64
+	reachedAt time.Time
+	levels    []link
+}
+
+// before reports whether n ranks ahead of the given position: higher scores
+// first, then the earliest to reach the score, then by user ID so that the
+// order is total.
+func (n *node) before(score int64, reachedAt time.Time, userID string) bool {
+	if n.score != score {
+		return n.score > score
+	}
+	if !n.reachedAt.Equal(reachedAt) {
+		return n.reachedAt.Before(reachedAt)
+	}
+	return n.userID < userID
+}
+
+// SkipList is an in-memory leaderboard index. It is an indexable skip list,
+// so updates, rank lookups and offset queries all take O(log n).
+type SkipList struct {
+	mu     sync.RWMutex
+	head   *node
+	level  int
+	length int
+	users  map[string]*node
+}
+
+// NewSkipList creates an empty SkipList.
+func NewSkipList() *SkipList {
+	return &SkipList{
+		head:  &node{levels: make([]link, maxLevel)},
+		level: 1,
+		users: make(map[string]*node),
+	}
+}
+
+// Upsert sets the user's score. A user whose score is unchanged keeps the
+// time it was first reached, and with it the user's place among ties.
+func (s *SkipList) Upsert(userID string, score int64, reachedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, exists := s.users[userID]; exists {
+		if n.score == score {
+			return
+		}
+		s.remove(n)
+	}
+	s.users[userID] = s.insert(userID, score, reachedAt)
+}
+
+// Remove drops the user from the index.
+func (s *SkipList) Remove(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, exists := s.users[userID]; exists {
+		s.remove(n)
+		delete(s.users, userID)
+	}
+}
+
+// Len returns the number of ranked users.
+func (s *SkipList) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.length
+}
+
+// Rank returns the user's entry, or false if the user is not ranked.
+func (s *SkipList) Rank(userID string) (domain.LeaderboardEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n, exists := s.users[userID]
+	if !exists {
+		return domain.LeaderboardEntry{}, false
+	}
+	return n.entry(s.rank(n)), true
+}
+
+// Range returns up to limit entries starting after the first offset ranks.
+func (s *SkipList) Range(offset, limit int) []domain.LeaderboardEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.entries(offset+1, limit)
+}
+
+// Around returns the user's entry with up to radius entries on each side,
+// or false if the user is not ranked.
+func (s *SkipList) Around(userID string, radius int) ([]domain.LeaderboardEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n, exists := s.users[userID]
+	if !exists {
+		return nil, false
+	}
+	rank := s.rank(n)
+	first := max(1, rank-radius)
+	return s.entries(first, rank+radius-first+1), true
+}
+
+// entries returns up to limit entries starting at the 1-based rank first.
+func (s *SkipList) entries(first, limit int) []domain.LeaderboardEntry {
+	if limit <= 0 || first < 1 || first > s.length {
+		return nil
+	}
+
+	entries := make([]domain.LeaderboardEntry, 0, min(limit, s.length-first+1))
+	for n, rank := s.byRank(first), first; n != nil && len(entries) < limit; n, rank = n.levels[0].next, rank+1 {
+		entries = append(entries, n.entry(rank))
+	}
+	return entries
+}
+
+// rank returns the 1-based rank of a node in the list.
+func (s *SkipList) rank(target *node) int {
+	rank := 0
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for next := x.levels[i].next; next != nil && (next == target || next.before(target.score, target.reachedAt, target.userID)); next = x.levels[i].next {
+			rank += x.levels[i].span
+			x = next
+		}
+		if x == target {
+			return rank
+		}
+	}
+	return rank
+}
+
+// byRank returns the node at the 1-based rank, or nil if there is none.
+func (s *SkipList) byRank(rank int) *node {
+	traversed := 0
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].next != nil && traversed+x.levels[i].span <= rank {
+			traversed += x.levels[i].span
+			x = x.levels[i].next
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+func (s *SkipList) insert(userID string, score int64, reachedAt time.Time) *node {
+	var update [maxLevel]*node
+	var rank [maxLevel]int
+
+	// Find the last node ahead of the new one on every level, counting the
+	// rank of each
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		if i < s.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.levels[i].next != nil && x.levels[i].next.before(score, reachedAt, userID) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].next
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > s.level {
+		for i := s.level; i < level; i++ {
+			update[i] = s.head
+			update[i].levels[i].span = s.length
+		}
+		s.level = level
+	}
+
+	n := &node{userID: userID, score: score, reachedAt: reachedAt, levels: make([]link, level)}
+	for i := range level {
+		n.levels[i].next = update[i].levels[i].next
+		update[i].levels[i].next = n
+		n.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+	// Links above the new node now skip one more node
+	for i := level; i < s.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	s.length++
+	return n
+}
+
+func (s *SkipList) remove(target *node) {
+	var update [maxLevel]*node
+
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].next != nil && x.levels[i].next != target && x.levels[i].next.before(target.score, target.reachedAt, target.userID) {
+			x = x.levels[i].next
+		}
+		update[i] = x
+	}
+
+	for i := range s.level {
+		if update[i].levels[i].next == target {
+			update[i].levels[i].span += target.levels[i].span - 1
+			update[i].levels[i].next = target.levels[i].next
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+	for s.level > 1 && s.head.levels[s.level-1].next == nil {
+		s.level--
+	}
+
+	s.length--
+}
+
+func (n *node) entry(rank int) domain.LeaderboardEntry {
+	return domain.LeaderboardEntry{
+		Rank:      rank,
+		UserID:    n.userID,
+		Score:     n.score,
+		ReachedAt: n.reachedAt,
+	}
+}
+
+// randomLevel picks the height of a new node.
+func randomLevel() int {
+	level := 1
+	for level < maxLevel && rand.IntN(branching) == 0 {
+		level++
+	}
+	return level
+}
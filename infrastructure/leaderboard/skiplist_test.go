@@ -0,0 +1,285 @@
+package leaderboard
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+var start = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSkipList_Order(t *testing.T) {
+	s := NewSkipList()
+	s.Upsert("carol", 50, start.Add(2*time.Hour))
+	s.Upsert("alice", 100, start.Add(time.Hour))
+	s.Upsert("bob", 50, start.Add(time.Hour))
+	s.Upsert("dave", 75, start)
+
+	assert.Equal(t, []domain.LeaderboardEntry{
+		{Rank: 1, UserID: "alice", Score: 100, ReachedAt: start.Add(time.Hour)},
+		{Rank: 2, UserID: "dave", Score: 75, ReachedAt: start},
+		{Rank: 3, UserID: "bob", Score: 50, ReachedAt: start.Add(time.Hour)},
+		{Rank: 4, UserID: "carol", Score: 50, ReachedAt: start.Add(2 * time.Hour)},
+	}, s.Range(0, 10))
+	assert.Equal(t, 4, s.Len())
+}
+
+func TestSkipList_TiesAtTheSameInstant(t *testing.T) {
+	s := NewSkipList()
+	for _, userID := range []string{"d", "b", "a", "c"} {
+		s.Upsert(userID, 10, start)
+	}
+
+	var order []string
+	for _, e := range s.Range(0, 4) {
+		order = append(order, e.UserID)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, order)
+}
+
+func TestSkipList_UnchangedScoreKeepsPlace(t *testing.T) {
+	s := NewSkipList()
+	s.Upsert("early", 10, start)
+	s.Upsert("late", 10, start.Add(time.Hour))
+
+	// Saving the same score again does not move the user behind later ties
+	s.Upsert("early", 10, start.Add(2*time.Hour))
+
+	entry, ok := s.Rank("early")
+	assert.True(t, ok)
+	assert.Equal(t, domain.LeaderboardEntry{Rank: 1, UserID: "early", Score: 10, ReachedAt: start}, entry)
+
+	// A new score resets the time it was reached
+	s.Upsert("early", 11, start.Add(3*time.Hour))
+	s.Upsert("early", 10, start.Add(4*time.Hour))
+
+	entry, ok = s.Rank("early")
+	assert.True(t, ok)
+	assert.Equal(t, 2, entry.Rank)
+	assert.Equal(t, start.Add(4*time.Hour), entry.ReachedAt)
+}
+
+func TestSkipList_RankMissing(t *testing.T) {
+	s := NewSkipList()
+	s.Upsert("user", 1, start)
+
+	_, ok := s.Rank("nobody")
+	assert.False(t, ok)
+
+	_, ok = s.Around("nobody", 3)
+	assert.False(t, ok)
+}
+
+func TestSkipList_Range(t *testing.T) {
+	s := NewSkipList()
+	for i := range 10 {
+		s.Upsert(fmt.Sprintf("user%d", i), int64(i), start)
+	}
+
+	page := s.Range(3, 4)
+	require.Len(t, page, 4)
+	assert.Equal(t, 4, page[0].Rank)
+	assert.Equal(t, int64(6), page[0].Score)
+	assert.Equal(t, 7, page[3].Rank)
+
+	assert.Len(t, s.Range(8, 5), 2)
+	assert.Empty(t, s.Range(10, 5))
+	assert.Empty(t, s.Range(0, 0))
+}
+
+func TestSkipList_Around(t *testing.T) {
+	s := NewSkipList()
+	for i := range 10 {
+		s.Upsert(fmt.Sprintf("user%d", i), int64(100-i), start)
+	}
+
+	entries, ok := s.Around("user5", 2)
+	assert.True(t, ok)
+	assert.Equal(t, []int{4, 5, 6, 7, 8}, ranksOf(entries))
+
+	// The window is cut at both ends of the leaderboard
+	entries, ok = s.Around("user0", 2)
+	assert.True(t, ok)
+	assert.Equal(t, []int{1, 2, 3}, ranksOf(entries))
+
+	entries, ok = s.Around("user9", 2)
+	assert.True(t, ok)
+	assert.Equal(t, []int{8, 9, 10}, ranksOf(entries))
+
+	entries, ok = s.Around("user3", 0)
+	assert.True(t, ok)
+	assert.Equal(t, []int{4}, ranksOf(entries))
+}
+
+func TestSkipList_Remove(t *testing.T) {
+	s := NewSkipList()
+	s.Upsert("a", 3, start)
+	s.Upsert("b", 2, start)
+	s.Upsert("c", 1, start)
+
+	s.Remove("b")
+	s.Remove("nobody")
+
+	assert.Equal(t, 2, s.Len())
+	entry, ok := s.Rank("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, entry.Rank)
+}
+
+// TestSkipList_MatchesSortedModel checks random updates against a sorted
+// slice, which is obviously right but slow.
+func TestSkipList_MatchesSortedModel(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	s := NewSkipList()
+	model := map[string]domain.LeaderboardEntry{}
+
+	for i := range 5000 {
+		userID := fmt.Sprintf("user%d", rng.IntN(300))
+		switch rng.IntN(10) {
+		case 0:
+			s.Remove(userID)
+			delete(model, userID)
+		default:
+			score := int64(rng.IntN(50))
+			at := start.Add(time.Duration(i) * time.Second)
+			s.Upsert(userID, score, at)
+			if current, exists := model[userID]; !exists || current.Score != score {
+				model[userID] = domain.LeaderboardEntry{UserID: userID, Score: score, ReachedAt: at}
+			}
+		}
+
+		if i%250 == 0 {
+			expected := sortedModel(model)
+			require.Equal(t, expected, nilIfEmpty(s.Range(0, len(model)+1)), "step %d", i)
+			for _, e := range expected {
+				entry, ok := s.Rank(e.UserID)
+				require.True(t, ok)
+				require.Equal(t, e, entry, "step %d", i)
+			}
+		}
+	}
+}
+
+func TestSkipList_ConcurrentAccess(t *testing.T) {
+	s := NewSkipList()
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				userID := fmt.Sprintf("user%d", (w*200+i)%500)
+				s.Upsert(userID, int64(i), start)
+				_, _ = s.Rank(userID)
+				_ = s.Range(0, 10)
+				_, _ = s.Around(userID, 3)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries := s.Range(0, s.Len())
+	assert.Len(t, entries, s.Len())
+	for i := 1; i < len(entries); i++ {
+		assert.GreaterOrEqual(t, entries[i-1].Score, entries[i].Score)
+	}
+}
+
+func sortedModel(model map[string]domain.LeaderboardEntry) []domain.LeaderboardEntry {
+	var entries []domain.LeaderboardEntry
+	for _, e := range model {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.ReachedAt.Equal(b.ReachedAt) {
+			return a.ReachedAt.Before(b.ReachedAt)
+		}
+		return a.UserID < b.UserID
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+func nilIfEmpty(entries []domain.LeaderboardEntry) []domain.LeaderboardEntry {
+	if len(entries) == 0 {
+		return nil
+	}
+	return entries
+}
+
+func ranksOf(entries []domain.LeaderboardEntry) []int {
+	ranks := make([]int, len(entries))
+	for i, e := range entries {
+		ranks[i] = e.Rank
+	}
+	return ranks
+}
+
+const benchmarkUsers = 1_000_000
+
+// newBenchmarkList returns a list of a million users with scores drawn from
+// a narrow range, so there are many ties.
+func newBenchmarkList(b *testing.B) *SkipList {
+	b.Helper()
+	rng := rand.New(rand.NewPCG(1, 2))
+	s := NewSkipList()
+	for i := range benchmarkUsers {
+		s.Upsert(fmt.Sprintf("user%d", i), int64(rng.IntN(100_000)), start.Add(time.Duration(i)*time.Millisecond))
+	}
+	return s
+}
+
+func BenchmarkSkipList_Upsert(b *testing.B) {
+	s := newBenchmarkList(b)
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	b.ResetTimer()
+	for i := range b.N {
+		s.Upsert(fmt.Sprintf("user%d", rng.IntN(benchmarkUsers)), int64(rng.IntN(100_000)), start.Add(time.Duration(i)))
+	}
+}
+
+func BenchmarkSkipList_Rank(b *testing.B) {
+	s := newBenchmarkList(b)
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	b.ResetTimer()
+	for range b.N {
+		_, _ = s.Rank(fmt.Sprintf("user%d", rng.IntN(benchmarkUsers)))
+	}
+}
+
+func BenchmarkSkipList_Range(b *testing.B) {
+	s := newBenchmarkList(b)
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	b.ResetTimer()
+	for range b.N {
+		_ = s.Range(rng.IntN(benchmarkUsers), 100)
+	}
+}
+
+func BenchmarkSkipList_Around(b *testing.B) {
+	s := newBenchmarkList(b)
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	b.ResetTimer()
+	for range b.N {
+		_, _ = s.Around(fmt.Sprintf("user%d", rng.IntN(benchmarkUsers)), 10)
+	}
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"expvar"
+
+	"scoreapp/domain"
+)
+
+// capHits counts scoring caps that removed points, keyed by
+// "<cap kind>:<action type>", or by cap kind alone for caps on the whole
+// score. It is served with the other expvars at /debug/vars.
+var capHits = expvar.NewMap("score_cap_hits")
+
+// ExpvarMetrics publishes scoring metrics through the standard expvar package.
+type ExpvarMetrics struct{}
+
+// NewExpvarMetrics creates a new ExpvarMetrics.
+func NewExpvarMetrics() *ExpvarMetrics {
+	return &ExpvarMetrics{}
+}
+
+// CapHit increments the counter for the cap and action type.
+func (m *ExpvarMetrics) CapHit(kind domain.CapKind, actionType string) {
+	key := string(kind)
+	if actionType != "" {
+		key += ":" + actionType
+	}
+	capHits.Add(key, 1)
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"expvar"
+	"testing"
+
+	"scoreapp/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func capHitCount(key string) int64 {
+	if v, ok := capHits.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestNewExpvarMetrics(t *testing.T) {
+	m := NewExpvarMetrics()
+
+	assert.NotNil(t, m)
+	assert.Same(t, capHits, expvar.Get("score_cap_hits"))
+}
+
+func TestExpvarMetrics_CapHit(t *testing.T) {
+	m := NewExpvarMetrics()
+
+	perTypeBefore := capHitCount("max_points_per_day:quiz_answer")
+	totalBefore := capHitCount("max_total_points")
+
+	m.CapHit(domain.CapMaxPointsPerDay, "quiz_answer")
+	m.CapHit(domain.CapMaxPointsPerDay, "quiz_answer")
+	m.CapHit(domain.CapMaxTotalPoints, "")
+
+	assert.Equal(t, perTypeBefore+2, capHitCount("max_points_per_day:quiz_answer"))
+	assert.Equal(t, totalBefore+1, capHitCount("max_total_points"))
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"maps"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+// actionRecord is the persisted form of a domain.ActionEvent.
+type actionRecord struct {
+	UserID     string            `json:"user_id"`
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Amount     int64             `json:"amount"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+func newActionRecord(event domain.ActionEvent) actionRecord {
+	return actionRecord{
+		UserID:     event.UserID,
+		ID:         event.ID,
+		Type:       event.Type,
+		Amount:     event.Amount,
+		OccurredAt: event.OccurredAt,
+		Metadata:   cloneMetadata(event.Metadata),
+	}
+}
+
+func (rec actionRecord) event() domain.ActionEvent {
+	return domain.ActionEvent{
+		UserID: rec.UserID,
+		UserAction: domain.UserAction{
+			ID:         rec.ID,
+			Type:       rec.Type,
+			Amount:     rec.Amount,
+			OccurredAt: rec.OccurredAt,
+			Metadata:   cloneMetadata(rec.Metadata),
+		},
+	}
+}
+
+// actionKey identifies an ingested action by user and action ID.
+type actionKey struct {
+	userID string
+	id     string
+}
+
+// actionIndex holds ingested actions per user, in the order they arrived.
+type actionIndex struct {
+	actions map[string][]actionRecord
+	ids     map[actionKey]struct{}
+}
+
+func newActionIndex() actionIndex {
+	return actionIndex{
+		actions: make(map[string][]actionRecord),
+		ids:     make(map[actionKey]struct{}),
+	}
+}
+
+// fresh returns the events whose action ID the user does not have yet,
+// keeping only the first of events repeating an ID.
+func (ix actionIndex) fresh(events []domain.ActionEvent) []domain.ActionEvent {
+	var fresh []domain.ActionEvent
+	seen := make(map[actionKey]struct{}, len(events))
+	for _, event := range events {
+		key := actionKey{userID: event.UserID, id: event.ID}
+		if _, ok := ix.ids[key]; ok {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		fresh = append(fresh, event)
+	}
+	return fresh
+}
+
+func (ix actionIndex) add(rec actionRecord) {
+	ix.ids[actionKey{userID: rec.UserID, id: rec.ID}] = struct{}{}
+	ix.actions[rec.UserID] = append(ix.actions[rec.UserID], rec)
+}
+
+// get returns a copy of the user's actions, or usecase.ErrUserNotFound if
+// the user has none.
+func (ix actionIndex) get(userID string) ([]domain.UserAction, error) {
+	records, ok := ix.actions[userID]
+	if !ok {
+		return nil, usecase.ErrUserNotFound
+	}
+	actions := make([]domain.UserAction, len(records))
+	for i, rec := range records {
+		actions[i] = rec.event().UserAction
+	}
+	return actions, nil
+}
+
+// after returns copies of the user's actions after the position, which is
+// the number of the user's actions before them, and the position of the
+// last one.
+func (ix actionIndex) after(userID string, position int64) ([]domain.UserAction, int64) {
+	records := ix.actions[userID]
+	if position >= int64(len(records)) {
+		return nil, position
+	}
+	actions := make([]domain.UserAction, 0, int64(len(records))-position)
+	for _, rec := range records[position:] {
+		actions = append(actions, rec.event().UserAction)
+	}
+	return actions, int64(len(records))
+}
+
+// cloneMetadata copies action metadata. Empty metadata is stored as nil, as
+// it reads back from JSON.
+func cloneMetadata(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return maps.Clone(m)
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"scoreapp/domain"
+)
+
+// cursorRecord is the persisted form of a domain.ScoreCursor.
+type cursorRecord struct {
+	UserID      string    `json:"user_id"`
+	Position    int64     `json:"position"`
+	Score       int64     `json:"score"`
+	RuleVersion int       `json:"rule_version"`
+	UpdatedAt   time.Time `json:"updated_at,omitzero"`
+	// Processed and Unprocessed are the IDs added to and removed from the
+	// user's processed actions with the cursor.
+	Processed   []string `json:"processed,omitempty"`
+	Unprocessed []string `json:"unprocessed,omitempty"`
+}
+
+func newCursorRecord(c domain.ScoreCursor) cursorRecord {
+	return cursorRecord{
+		UserID:      c.UserID,
+		Position:    c.Position,
+		Score:       c.Score,
+		RuleVersion: c.RuleVersion,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
+
+func (rec cursorRecord) cursor() domain.ScoreCursor {
+	return domain.ScoreCursor{
+		UserID:      rec.UserID,
+		Position:    rec.Position,
+		Score:       rec.Score,
+		RuleVersion: rec.RuleVersion,
+		UpdatedAt:   rec.UpdatedAt,
+	}
+}
+
+// listScoreCursors returns every cursor, ordered by user ID.
+func listScoreCursors(cursors map[string]domain.ScoreCursor) []domain.ScoreCursor {
+	list := make([]domain.ScoreCursor, 0, len(cursors))
+	for _, c := range cursors {
+		list = append(list, c)
+	}
+	slices.SortFunc(list, func(a, b domain.ScoreCursor) int { return strings.Compare(a.UserID, b.UserID) })
+	return list
+}
+
+// processedActions holds the IDs of each user's processed actions.
+type processedActions map[string]map[string]struct{}
+
+func (p processedActions) add(userID string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	if p[userID] == nil {
+		p[userID] = make(map[string]struct{}, len(ids))
+	}
+	for _, id := range ids {
+		p[userID][id] = struct{}{}
+	}
+}
+
+func (p processedActions) remove(userID string, ids []string) {
+	for _, id := range ids {
+		delete(p[userID], id)
+	}
+	if len(p[userID]) == 0 {
+		delete(p, userID)
+	}
+}
+
+// find returns the IDs that are among the user's processed actions.
+func (p processedActions) find(userID string, ids []string) []string {
+	var found []string
+	for _, id := range ids {
+		if _, ok := p[userID][id]; ok {
+			found = append(found, id)
+		}
+	}
+	return found
+}
+
+// diff returns the IDs to add to and remove from the user's processed
+// actions to replace them with ids.
+func (p processedActions) diff(userID string, ids []string) (added, removed []string) {
+	keep := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		keep[id] = struct{}{}
+		if _, ok := p[userID][id]; !ok {
+			added = append(added, id)
+		}
+	}
+	for id := range p[userID] {
+		if _, ok := keep[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	slices.Sort(removed)
+	return added, removed
+}
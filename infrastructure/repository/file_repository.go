@@ -0,0 +1,1167 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+const (
+	walFileName      = "scores.wal"
+	snapshotFileName = "scores.snapshot"
+	// actionLogFileName holds the ingested actions. It is never compacted.
+	actionLogFileName = "actions.log"
+	// cursorLogFileName holds every version of the score cursors, the last
+	// one of each user winning, and the changes to the users' processed
+	// action IDs that came with them. It grows with the actions the scores
+	// count and is never compacted either.
+	cursorLogFileName = "cursors.log"
+	// historyLogFileName holds the score history entries that were moved out
+	// of the score log when it was compacted. It is never compacted itself.
+	historyLogFileName = "history.log"
+	// jobsDirName holds one JSON file per recalculation job.
+	jobsDirName = "jobs"
+	// rulesDirName holds one JSON file per rule set version.
+	rulesDirName = "rules"
+	// outboxFileName holds the score events that were pending when the log
+	// was last compacted, as the log no longer holds them afterwards.
+	outboxFileName = "events.outbox"
+	// publishedFileName holds the ID of the last acknowledged score event.
+	publishedFileName = "events.published"
+
+	// walHeaderSize is the length and CRC-32 prefix of every log record.
+	walHeaderSize = 8
+	// maxRecordSize guards replay against reading a garbage length.
+	maxRecordSize = 1 << 20
+)
+
+// SyncPolicy controls when the write-ahead log is flushed to stable storage.
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs after every save. No acknowledged save is lost.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsyncs in the background every FileOptions.SyncInterval.
+	// A crash loses at most the saves of the last interval.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the operating system.
+	SyncNever SyncPolicy = "never"
+)
+
+// FileOptions configures a FileRepository.
+type FileOptions struct {
+	Sync SyncPolicy
+	// SyncInterval is how often the log is flushed under SyncInterval.
+	SyncInterval time.Duration
+	// SnapshotEvery compacts the log into a snapshot after that many saves.
+	// Zero disables automatic snapshots.
+	SnapshotEvery int
+	// OnSnapshotError is called when an automatic snapshot fails. The save
+	// that triggered it is already in the log, so it still succeeds, and the
+	// snapshot is tried again on the next save. Defaults to ignoring the
+	// error.
+	OnSnapshotError func(error)
+}
+
+// scoreRecord is the persisted form of a domain.UserScore.
+type scoreRecord struct {
+	UserID      string `json:"user_id"`
+	Score       int64  `json:"score"`
+	RuleVersion int    `json:"rule_version"`
+	Window      string `json:"window"`
+	Streak      int    `json:"streak"`
+
+	CalculatedAt time.Time `json:"calculated_at,omitzero"`
+	RequestID    string    `json:"request_id,omitempty"`
+
+	// Event is the score event the save recorded. Log records only.
+	Event *scoreEventRecord `json:"event,omitempty"`
+	// History is the history entry the save recorded. Log records only.
+	History *historyRecord `json:"history,omitempty"`
+}
+
+func newScoreRecord(s domain.UserScore) scoreRecord {
+	return scoreRecord{
+		UserID:      s.UserID,
+		Score:       s.Score,
+		RuleVersion: s.RuleVersion,
+		Window:      s.Window,
+		Streak:      s.Streak,
+
+		CalculatedAt: s.CalculatedAt,
+		RequestID:    s.RequestID,
+	}
+}
+
+func (rec scoreRecord) userScore() domain.UserScore {
+	return domain.UserScore{
+		UserID:      rec.UserID,
+		Score:       rec.Score,
+		RuleVersion: rec.RuleVersion,
+		Window:      rec.Window,
+		Streak:      rec.Streak,
+
+		CalculatedAt: rec.CalculatedAt,
+		RequestID:    rec.RequestID,
+	}
+}
+
+// FileRepository is a durable ScoreRepository. Saves are appended to a
+// write-ahead log that is periodically compacted into a snapshot, and both
+// are replayed into memory when the repository is opened.
+//
+// Each log record is a big-endian uint32 payload length, a CRC-32 of the
+// payload and the JSON-encoded score. A record torn by a crash fails its
+// length or checksum and is cut off on replay.
+//
+// Ingested actions and score cursors are appended to logs of their own in
+// the same format, which are replayed into memory as well.
+//
+// A save that changes a score records its score event in the same log
+// record, so the event is exactly as durable as the score. Events still
+// pending when the log is compacted are moved to an outbox file, and the
+// ID of the last acknowledged event is kept in a file of its own.
+//
+// Every save also records its score history entry in the same log record.
+// Compacting the log moves the entries to a history log that is never
+// compacted, and which ScoreHistoryRepository reads together with the
+// entries still in the log.
+type FileRepository struct {
+	mu      sync.Mutex
+	dir     string
+	opts    FileOptions
+	store   map[scoreKey]domain.UserScore
+	wal     *os.File
+	size    int64 // bytes of intact records in the log
+	pending int   // records in the log since the last snapshot
+	dirty   bool
+	closed  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	actions   actionIndex
+	actionLog *appendLog
+	cursors   map[string]domain.ScoreCursor
+	processed processedActions
+	cursorLog *appendLog
+	events    scoreOutbox
+
+	history    map[string][]domain.ScoreSnapshot
+	seq        int64
+	historyLog *appendLog
+	// unmoved holds the history entries of the records in the score log,
+	// which compaction moves to the history log.
+	unmoved []domain.ScoreSnapshot
+}
+
+// NewFileRepository opens or creates a FileRepository in dir, replaying the
+// snapshot and write-ahead log found there.
+func NewFileRepository(dir string, opts FileOptions) (*FileRepository, error) {
+	switch opts.Sync {
+	case SyncAlways, SyncNever:
+	case SyncInterval:
+		if opts.SyncInterval <= 0 {
+			return nil, errors.New("sync interval must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("unknown sync policy %q", opts.Sync)
+	}
+	if opts.SnapshotEvery < 0 {
+		return nil, errors.New("snapshot interval must not be negative")
+	}
+	if opts.OnSnapshotError == nil {
+		opts.OnSnapshotError = func(error) {}
+	}
+
+	for _, sub := range []string{jobsDirName, rulesDirName} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create repository directory: %w", err)
+		}
+	}
+
+	r := &FileRepository{
+		dir:       dir,
+		opts:      opts,
+		store:     make(map[scoreKey]domain.UserScore),
+		done:      make(chan struct{}),
+		actions:   newActionIndex(),
+		cursors:   make(map[string]domain.ScoreCursor),
+		processed: make(processedActions),
+		history:   make(map[string][]domain.ScoreSnapshot),
+	}
+
+	// The history log goes first, so replaying the score log can skip the
+	// entries a crashed compaction already moved
+	if err := r.replayHistoryLog(); err != nil {
+		return nil, err
+	}
+	if err := r.loadScoreEvents(); err != nil {
+		_ = r.historyLog.f.Close()
+		return nil, err
+	}
+	if err := r.loadSnapshot(); err != nil {
+		_ = r.historyLog.f.Close()
+		return nil, err
+	}
+	if err := r.replayLog(); err != nil {
+		_ = r.historyLog.f.Close()
+		return nil, err
+	}
+	if err := r.replayActionLog(); err != nil {
+		_ = r.wal.Close()
+		_ = r.historyLog.f.Close()
+		return nil, err
+	}
+	if err := r.replayCursorLog(); err != nil {
+		_ = r.wal.Close()
+		_ = r.historyLog.f.Close()
+		_ = r.actionLog.f.Close()
+		return nil, err
+	}
+
+	if opts.Sync == SyncInterval {
+		r.wg.Add(1)
+		go r.syncLoop()
+	}
+
+	return r, nil
+}
+
+// Save appends the score to the write-ahead log, together with its history
+// entry and a score event if the score changed, and then stores them in
+// memory.
+func (r *FileRepository) Save(ctx context.Context, score domain.UserScore) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return errors.New("repository is closed")
+	}
+
+	score.Window = score.WindowKey()
+	key := scoreKey{userID: score.UserID, window: score.Window}
+	rec := newScoreRecord(score)
+	event, changed := domain.NewScoreChanged(r.store[key], score, time.Now())
+	if changed {
+		event.ID = r.events.nextID()
+		eventRec := newScoreEventRecord(event)
+		rec.Event = &eventRec
+	}
+	entry := newSnapshot(r.seq+1, score)
+	historyRec := newHistoryRecord(entry)
+	rec.History = &historyRec
+	if err := r.append(rec); err != nil {
+		return fmt.Errorf("failed to write score log: %w", err)
+	}
+	r.store[key] = score
+	if changed {
+		r.events.add(event)
+	}
+	r.addHistory(entry)
+	r.unmoved = append(r.unmoved, entry)
+
+	if r.opts.SnapshotEvery > 0 && r.pending >= r.opts.SnapshotEvery {
+		if err := r.snapshot(); err != nil {
+			r.opts.OnSnapshotError(fmt.Errorf("failed to snapshot scores: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// Get retrieves the all-time score for a given user.
+func (r *FileRepository) Get(ctx context.Context, userID string) (domain.UserScore, bool, error) {
+	return r.GetWindow(ctx, userID, domain.AllTime().Key())
+}
+
+// GetWindow retrieves the score for a given user and window key.
+func (r *FileRepository) GetWindow(ctx context.Context, userID, window string) (domain.UserScore, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.UserScore{}, false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	score, exists := r.store[scoreKey{userID: userID, window: window}]
+	return score, exists, nil
+}
+
+// ListWindow returns every score saved for the window key, ordered by user ID.
+func (r *FileRepository) ListWindow(ctx context.Context, window string) ([]domain.UserScore, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return listWindow(r.store, window), nil
+}
+
+// ListUserIDs returns every user with a stored score, sorted.
+func (r *FileRepository) ListUserIDs(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return listUserIDs(r.store), nil
+}
+
+// History returns the user's score history entries that match the filter.
+func (r *FileRepository) History(ctx context.Context, userID string, filter usecase.HistoryFilter) ([]domain.ScoreSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return filterHistory(r.history[userID], filter), nil
+}
+
+// SaveJob atomically writes the job to its file in the jobs directory.
+func (r *FileRepository) SaveJob(ctx context.Context, job domain.Job) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(newJobRecord(job))
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return errors.New("repository is closed")
+	}
+
+	dir := filepath.Join(r.dir, jobsDirName)
+	path := filepath.Join(dir, job.ID+".json")
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// GetJob reads the job with the ID.
+func (r *FileRepository) GetJob(ctx context.Context, id string) (domain.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Job{}, err
+	}
+	// IDs come from clients, so keep them from naming other files
+	if id == "" || id != filepath.Base(id) {
+		return domain.Job{}, usecase.ErrJobNotFound
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, err := readJob(filepath.Join(r.dir, jobsDirName, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.Job{}, usecase.ErrJobNotFound
+	}
+	return job, err
+}
+
+// ListUnfinishedJobs reads the queued and running jobs, oldest first.
+func (r *FileRepository) ListUnfinishedJobs(ctx context.Context) ([]domain.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(r.dir, jobsDirName, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]domain.Job, 0, len(paths))
+	for _, path := range paths {
+		job, err := readJob(path)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return unfinishedJobs(jobs), nil
+}
+
+// SaveRuleSet writes the rule set to a file of its own, replacing the rule
+// set of the same version.
+func (r *FileRepository) SaveRuleSet(ctx context.Context, ruleSet *usecase.RuleSet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(newRuleSetRecord(ruleSet))
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return errors.New("repository is closed")
+	}
+
+	if err := writeFileAtomic(filepath.Join(r.dir, rulesDirName), strconv.Itoa(ruleSet.Version)+".json", data); err != nil {
+		return fmt.Errorf("failed to write rule set: %w", err)
+	}
+	return nil
+}
+
+// GetRuleSet reads the rule set of the version.
+func (r *FileRepository) GetRuleSet(ctx context.Context, version int) (*usecase.RuleSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(r.dir, rulesDirName, strconv.Itoa(version)+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, usecase.ErrRuleSetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRuleSet(data)
+}
+
+// LatestRuleSet reads the rule set of the highest version.
+func (r *FileRepository) LatestRuleSet(ctx context.Context) (*usecase.RuleSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(r.dir, rulesDirName, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	latest := 0
+	for _, path := range paths {
+		version, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			continue
+		}
+		latest = max(latest, version)
+	}
+	if latest == 0 {
+		return nil, usecase.ErrRuleSetNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.dir, rulesDirName, strconv.Itoa(latest)+".json"))
+	if err != nil {
+		return nil, err
+	}
+	return decodeRuleSet(data)
+}
+
+// AppendActions appends the events whose action ID the user does not have
+// yet to the action log, and returns them. A crash while appending may keep
+// only some of the events, which is harmless as resending them is
+// idempotent.
+func (r *FileRepository) AppendActions(ctx context.Context, events []domain.ActionEvent) ([]domain.ActionEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return nil, errors.New("repository is closed")
+	}
+
+	fresh := r.actions.fresh(events)
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+	records := make([]actionRecord, len(fresh))
+	var buf []byte
+	for i, event := range fresh {
+		records[i] = newActionRecord(event)
+		payload, err := json.Marshal(records[i])
+		if err != nil {
+			return nil, err
+		}
+		buf = appendFrame(buf, payload)
+	}
+
+	if err := r.actionLog.write(buf, r.opts.Sync); err != nil {
+		return nil, fmt.Errorf("failed to write action log: %w", err)
+	}
+
+	for _, rec := range records {
+		r.actions.add(rec)
+	}
+	return fresh, nil
+}
+
+// GetActions returns the user's ingested actions in the order they arrived.
+func (r *FileRepository) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.actions.get(userID)
+}
+
+// ActionsAfter returns the user's ingested actions after the position, which
+// counts the user's actions before them.
+func (r *FileRepository) ActionsAfter(ctx context.Context, userID string, position int64) ([]domain.UserAction, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	actions, last := r.actions.after(userID, position)
+	return actions, last, nil
+}
+
+// GetScoreCursor returns the user's score cursor.
+func (r *FileRepository) GetScoreCursor(ctx context.Context, userID string) (domain.ScoreCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ScoreCursor{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cursor, exists := r.cursors[userID]
+	if !exists {
+		return domain.ScoreCursor{}, usecase.ErrScoreCursorNotFound
+	}
+	return cursor, nil
+}
+
+// SaveScoreCursor appends the cursor and the applied action IDs to the
+// cursor log and then stores them in memory.
+func (r *FileRepository) SaveScoreCursor(ctx context.Context, cursor domain.ScoreCursor, applied []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := newCursorRecord(cursor)
+	rec.Processed = applied
+	return r.writeCursor(rec)
+}
+
+// ResetScoreCursor appends the cursor and the changes replacing the user's
+// processed actions to the cursor log and then stores them in memory.
+func (r *FileRepository) ResetScoreCursor(ctx context.Context, cursor domain.ScoreCursor, processed []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := newCursorRecord(cursor)
+	rec.Processed, rec.Unprocessed = r.processed.diff(cursor.UserID, processed)
+	return r.writeCursor(rec)
+}
+
+// writeCursor appends the record to the cursor log and applies it. The
+// caller must hold r.mu.
+func (r *FileRepository) writeCursor(rec cursorRecord) error {
+	if r.wal == nil {
+		return errors.New("repository is closed")
+	}
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := r.cursorLog.write(appendFrame(nil, payload), r.opts.Sync); err != nil {
+		return fmt.Errorf("failed to write cursor log: %w", err)
+	}
+	r.applyCursor(rec)
+	return nil
+}
+
+func (r *FileRepository) applyCursor(rec cursorRecord) {
+	r.cursors[rec.UserID] = rec.cursor()
+	r.processed.add(rec.UserID, rec.Processed)
+	r.processed.remove(rec.UserID, rec.Unprocessed)
+}
+
+// ProcessedActions returns which of the IDs are among the user's processed
+// actions.
+func (r *FileRepository) ProcessedActions(ctx context.Context, userID string, ids []string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.processed.find(userID, ids), nil
+}
+
+// ListScoreCursors returns every score cursor, ordered by user ID.
+func (r *FileRepository) ListScoreCursors(ctx context.Context) ([]domain.ScoreCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return listScoreCursors(r.cursors), nil
+}
+
+// PendingScoreEvents returns up to limit unacknowledged score events, oldest
+// first.
+func (r *FileRepository) PendingScoreEvents(ctx context.Context, limit int) ([]domain.ScoreChanged, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.events.list(limit), nil
+}
+
+// AckScoreEvents atomically records throughID as the last acknowledged score
+// event and then drops the events up to it from memory.
+func (r *FileRepository) AckScoreEvents(ctx context.Context, throughID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return errors.New("repository is closed")
+	}
+	if err := writeFileAtomic(r.dir, publishedFileName, strconv.AppendInt(nil, throughID, 10)); err != nil {
+		return fmt.Errorf("failed to acknowledge score events: %w", err)
+	}
+	r.events.ack(throughID)
+	return nil
+}
+
+// Snapshot compacts the write-ahead log into a snapshot of every score.
+func (r *FileRepository) Snapshot() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return errors.New("repository is closed")
+	}
+	return r.snapshot()
+}
+
+// Close flushes and closes the write-ahead log. Closing a closed or closing
+// repository does nothing.
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	r.mu.Unlock()
+
+	// The sync loop takes the lock, so wait for it outside
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.wal.Sync()
+	if cerr := r.wal.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := r.actionLog.close(); err == nil {
+		err = cerr
+	}
+	if cerr := r.cursorLog.close(); err == nil {
+		err = cerr
+	}
+	if cerr := r.historyLog.close(); err == nil {
+		err = cerr
+	}
+	r.wal = nil
+	r.actionLog = nil
+	r.cursorLog = nil
+	r.historyLog = nil
+	return err
+}
+
+func (r *FileRepository) append(rec scoreRecord) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	buf := appendFrame(nil, payload)
+	if _, err := r.wal.Write(buf); err != nil {
+		// Drop a partial record so later records are not appended after it
+		if terr := r.wal.Truncate(r.size); terr == nil {
+			_, _ = r.wal.Seek(r.size, io.SeekStart)
+		}
+		return err
+	}
+	r.size += int64(len(buf))
+	r.pending++
+
+	if r.opts.Sync == SyncAlways {
+		return r.wal.Sync()
+	}
+	r.dirty = true
+	return nil
+}
+
+// snapshot writes the pending score events to a new outbox file, moves the
+// history entries of the log to the history log, writes every score to a
+// new snapshot file, atomically replaces the old files and starts an empty
+// log. A crash before the log is reset replays records the snapshot, outbox
+// and history log already hold, which is harmless.
+func (r *FileRepository) snapshot() error {
+	events := make([]scoreEventRecord, 0, len(r.events.pending))
+	for _, e := range r.events.pending {
+		events = append(events, newScoreEventRecord(e))
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(r.dir, outboxFileName, data); err != nil {
+		return err
+	}
+
+	var buf []byte
+	for _, e := range r.unmoved {
+		payload, err := json.Marshal(newHistoryRecord(e))
+		if err != nil {
+			return err
+		}
+		buf = appendFrame(buf, payload)
+	}
+	// The entries must be durable before the log holding them is reset,
+	// whatever the sync policy
+	if err := r.historyLog.write(buf, SyncAlways); err != nil {
+		return err
+	}
+	r.unmoved = nil
+
+	records := make([]scoreRecord, 0, len(r.store))
+	for _, s := range r.store {
+		records = append(records, newScoreRecord(s))
+	}
+	data, err = json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(r.dir, snapshotFileName, data); err != nil {
+		return err
+	}
+
+	if err := r.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := r.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := r.wal.Sync(); err != nil {
+		return err
+	}
+	r.size = 0
+	r.pending = 0
+	r.dirty = false
+	return nil
+}
+
+// loadScoreEvents reads the ID of the last acknowledged score event and the
+// events of the outbox file after it.
+func (r *FileRepository) loadScoreEvents() error {
+	data, err := os.ReadFile(filepath.Join(r.dir, publishedFileName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read published score events: %w", err)
+	default:
+		published, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to decode published score events: %w", err)
+		}
+		r.events.ack(published)
+	}
+
+	data, err = os.ReadFile(filepath.Join(r.dir, outboxFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read score event outbox: %w", err)
+	}
+
+	var records []scoreEventRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to decode score event outbox: %w", err)
+	}
+	for _, rec := range records {
+		r.events.add(rec.event())
+	}
+	return nil
+}
+
+func (r *FileRepository) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(r.dir, snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var records []scoreRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	for _, rec := range records {
+		r.apply(rec)
+	}
+	return nil
+}
+
+// replayLog applies every intact record of the log and cuts off a torn or
+// corrupt tail, so new records are appended after the last good one.
+func (r *FileRepository) replayLog() error {
+	wal, good, err := replayFile(filepath.Join(r.dir, walFileName), func(payload []byte) error {
+		var rec scoreRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return err
+		}
+		r.apply(rec)
+		r.applyHistory(rec)
+		r.pending++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open score log: %w", err)
+	}
+
+	r.wal = wal
+	r.size = good
+	return nil
+}
+
+// replayActionLog indexes every intact record of the action log and cuts off
+// a torn or corrupt tail.
+func (r *FileRepository) replayActionLog() error {
+	log, err := openAppendLog(filepath.Join(r.dir, actionLogFileName), func(payload []byte) error {
+		var rec actionRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return err
+		}
+		r.actions.add(rec)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open action log: %w", err)
+	}
+
+	r.actionLog = log
+	return nil
+}
+
+// replayCursorLog applies every intact record of the cursor log and cuts off
+// a torn or corrupt tail.
+func (r *FileRepository) replayCursorLog() error {
+	log, err := openAppendLog(filepath.Join(r.dir, cursorLogFileName), func(payload []byte) error {
+		var rec cursorRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return err
+		}
+		r.applyCursor(rec)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open cursor log: %w", err)
+	}
+
+	r.cursorLog = log
+	return nil
+}
+
+// replayHistoryLog adds every intact entry of the history log and cuts off a
+// torn or corrupt tail.
+func (r *FileRepository) replayHistoryLog() error {
+	log, err := openAppendLog(filepath.Join(r.dir, historyLogFileName), func(payload []byte) error {
+		var rec historyRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return err
+		}
+		r.addHistory(rec.snapshot())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open history log: %w", err)
+	}
+
+	r.historyLog = log
+	return nil
+}
+
+// replayFile opens or creates the log at path and passes the payload of
+// every record to apply, up to the first incomplete or corrupt record or
+// one that apply rejects. The log is truncated after the last good record
+// and returned positioned there, together with its size.
+func replayFile(path string, apply func(payload []byte) error) (*os.File, int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reader := bufio.NewReader(f)
+	var good int64
+	for {
+		payload, n, err := readFrame(reader)
+		if err != nil || apply(payload) != nil {
+			break
+		}
+		good += n
+	}
+
+	if err := f.Truncate(good); err != nil {
+		_ = f.Close()
+		return nil, 0, err
+	}
+	if _, err := f.Seek(good, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, 0, err
+	}
+	return f, good, nil
+}
+
+// appendFrame appends a log record holding payload to buf.
+func appendFrame(buf, payload []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(payload)))
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(payload))
+	return append(buf, payload...)
+}
+
+// readFrame reads one log record and returns its payload with the size of
+// the record in bytes. Any incomplete or corrupt record is reported as an
+// error.
+func readFrame(reader io.Reader) ([]byte, int64, error) {
+	var header [walHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxRecordSize {
+		return nil, 0, errors.New("record too large")
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+	return payload, int64(walHeaderSize + size), nil
+}
+
+func (r *FileRepository) apply(rec scoreRecord) {
+	r.store[scoreKey{userID: rec.UserID, window: rec.Window}] = rec.userScore()
+	if rec.Event != nil {
+		r.events.add(rec.Event.event())
+	}
+}
+
+// applyHistory adds the history entry of a log record, unless a compaction
+// that crashed before resetting the log already moved it.
+func (r *FileRepository) applyHistory(rec scoreRecord) {
+	if rec.History == nil {
+		return
+	}
+	entry := rec.History.snapshot()
+	if entry.Seq <= r.seq {
+		return
+	}
+	r.addHistory(entry)
+	r.unmoved = append(r.unmoved, entry)
+}
+
+func (r *FileRepository) addHistory(entry domain.ScoreSnapshot) {
+	r.history[entry.UserID] = append(r.history[entry.UserID], entry)
+	r.seq = entry.Seq
+}
+
+// syncLoop flushes the log every SyncInterval until the repository closes.
+func (r *FileRepository) syncLoop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.opts.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+
+		r.mu.Lock()
+		if r.dirty {
+			if err := r.wal.Sync(); err == nil {
+				r.dirty = false
+			}
+		}
+		r.actionLog.flush()
+		r.cursorLog.flush()
+		r.mu.Unlock()
+	}
+}
+
+// appendLog is a log of records that are only ever appended, never
+// compacted.
+type appendLog struct {
+	f     *os.File
+	size  int64 // bytes of intact records
+	dirty bool
+}
+
+// openAppendLog opens or creates the log at path, replaying its intact
+// records into apply.
+func openAppendLog(path string, apply func(payload []byte) error) (*appendLog, error) {
+	f, good, err := replayFile(path, apply)
+	if err != nil {
+		return nil, err
+	}
+	return &appendLog{f: f, size: good}, nil
+}
+
+// write appends buf, which holds whole records, and flushes it under
+// SyncAlways.
+func (l *appendLog) write(buf []byte, sync SyncPolicy) error {
+	if _, err := l.f.Write(buf); err != nil {
+		// Drop partial records so later records are not appended after them
+		if terr := l.f.Truncate(l.size); terr == nil {
+			_, _ = l.f.Seek(l.size, io.SeekStart)
+		}
+		return err
+	}
+	l.size += int64(len(buf))
+
+	if sync == SyncAlways {
+		return l.f.Sync()
+	}
+	l.dirty = true
+	return nil
+}
+
+// flush syncs records written since the last flush.
+func (l *appendLog) flush() {
+	if l.dirty {
+		if err := l.f.Sync(); err == nil {
+			l.dirty = false
+		}
+	}
+}
+
+func (l *appendLog) close() error {
+	err := l.f.Sync()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func readJob(path string) (domain.Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return domain.Job{}, err
+	}
+	var rec jobRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return domain.Job{}, fmt.Errorf("failed to decode job %s: %w", filepath.Base(path), err)
+	}
+	return rec.job(), nil
+}
+
+// writeFileAtomic replaces the file name in dir with one holding data, so a
+// crash leaves either the old or the new file behind.
+func writeFileAtomic(dir, name string, data []byte) error {
+	tmp := filepath.Join(dir, name+".tmp")
+	if err := writeFileSync(tmp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,470 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+	"scoreapp/infrastructure/repository/repotest"
+	"scoreapp/usecase"
+)
+
+func openFileRepository(t *testing.T, dir string, opts FileOptions) *FileRepository {
+	t.Helper()
+	repo, err := NewFileRepository(dir, opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+// getScore returns the user's all-time score, failing the test on an error.
+func getScore(t *testing.T, repo repotest.Repository, userID string) (domain.UserScore, bool) {
+	t.Helper()
+	score, exists, err := repo.Get(context.Background(), userID)
+	require.NoError(t, err)
+	return score, exists
+}
+
+func saveScores(t *testing.T, repo *FileRepository, scores ...domain.UserScore) {
+	t.Helper()
+	for _, s := range scores {
+		require.NoError(t, repo.Save(context.Background(), s))
+	}
+}
+
+func TestFileRepository(t *testing.T) {
+	for _, policy := range []SyncPolicy{SyncAlways, SyncInterval, SyncNever} {
+		t.Run(string(policy), func(t *testing.T) {
+			repotest.Run(t, func(t *testing.T) repotest.Repository {
+				return openFileRepository(t, t.TempDir(), FileOptions{Sync: policy, SyncInterval: time.Millisecond, SnapshotEvery: 10})
+			})
+		})
+	}
+}
+
+func TestFileRepository_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	saveScores(t, repo,
+		domain.UserScore{UserID: "user1", Score: 100, RuleVersion: 1, Window: "all_time"},
+		domain.UserScore{UserID: "user1", Score: 10, RuleVersion: 1, Window: "rolling_7d", Streak: 3},
+		domain.UserScore{UserID: "user2", Score: 50, RuleVersion: 2},
+		domain.UserScore{UserID: "user1", Score: 150, RuleVersion: 2, Window: "all_time"},
+	)
+	require.NoError(t, repo.Close())
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+
+	score, exists := getScore(t, reopened, "user1")
+	assert.True(t, exists)
+	assert.Equal(t, domain.UserScore{UserID: "user1", Score: 150, RuleVersion: 2, Window: "all_time"}, score)
+
+	score, exists, err := reopened.GetWindow(context.Background(), "user1", "rolling_7d")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 3, score.Streak)
+
+	// Scores saved without a window are stored as all-time
+	score, exists = getScore(t, reopened, "user2")
+	assert.True(t, exists)
+	assert.Equal(t, int64(50), score.Score)
+}
+
+func TestFileRepository_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways, SnapshotEvery: 2})
+	saveScores(t, repo,
+		domain.UserScore{UserID: "user1", Score: 1, Window: "all_time"},
+		domain.UserScore{UserID: "user2", Score: 2, Window: "all_time"},
+		domain.UserScore{UserID: "user3", Score: 3, Window: "all_time"},
+	)
+	require.NoError(t, repo.Close())
+
+	// Two saves were compacted, the third is still in the log
+	_, err := os.Stat(filepath.Join(dir, snapshotFileName))
+	assert.NoError(t, err)
+	info, err := os.Stat(filepath.Join(dir, walFileName))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Size())
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	for userID, expected := range map[string]int64{"user1": 1, "user2": 2, "user3": 3} {
+		score, exists := getScore(t, reopened, userID)
+		assert.True(t, exists)
+		assert.Equal(t, expected, score.Score)
+	}
+
+	require.NoError(t, reopened.Snapshot())
+	info, err = os.Stat(filepath.Join(dir, walFileName))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
+
+func TestFileRepository_TornRecord(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, walFileName)
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	saveScores(t, repo,
+		domain.UserScore{UserID: "user1", Score: 1, Window: "all_time"},
+		domain.UserScore{UserID: "user2", Score: 2, Window: "all_time"},
+	)
+	info, err := os.Stat(walPath)
+	require.NoError(t, err)
+	intact := info.Size()
+	saveScores(t, repo, domain.UserScore{UserID: "user3", Score: 3, Window: "all_time"})
+	require.NoError(t, repo.Close())
+
+	info, err = os.Stat(walPath)
+	require.NoError(t, err)
+	full := info.Size()
+
+	// Simulate a crash at every byte of the last record
+	for size := intact; size < full; size++ {
+		data, err := os.ReadFile(walPath)
+		require.NoError(t, err)
+		crashDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(crashDir, walFileName), data[:size], 0o644))
+
+		recovered := openFileRepository(t, crashDir, FileOptions{Sync: SyncAlways})
+
+		_, exists := getScore(t, recovered, "user2")
+		assert.True(t, exists, "size %d", size)
+		_, exists = getScore(t, recovered, "user3")
+		assert.False(t, exists, "size %d", size)
+
+		// The torn tail is cut off, so new records stay readable
+		saveScores(t, recovered, domain.UserScore{UserID: "user4", Score: 4, Window: "all_time"})
+		require.NoError(t, recovered.Close())
+
+		reopened := openFileRepository(t, crashDir, FileOptions{Sync: SyncAlways})
+		score, exists := getScore(t, reopened, "user4")
+		assert.True(t, exists, "size %d", size)
+		assert.Equal(t, int64(4), score.Score)
+	}
+}
+
+func TestFileRepository_CorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, walFileName)
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	saveScores(t, repo,
+		domain.UserScore{UserID: "user1", Score: 1, Window: "all_time"},
+		domain.UserScore{UserID: "user2", Score: 2, Window: "all_time"},
+	)
+	require.NoError(t, repo.Close())
+
+	// Flip a byte in the payload of the last record
+	data, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+	data[len(data)-2] ^= 0xff
+	require.NoError(t, os.WriteFile(walPath, data, 0o644))
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+
+	_, exists := getScore(t, reopened, "user1")
+	assert.True(t, exists)
+	_, exists = getScore(t, reopened, "user2")
+	assert.False(t, exists)
+}
+
+func TestFileRepository_SyncInterval(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncInterval, SyncInterval: time.Millisecond})
+	saveScores(t, repo, domain.UserScore{UserID: "user1", Score: 1, Window: "all_time"})
+
+	assert.Eventually(t, func() bool {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+		return !repo.dirty
+	}, time.Second, time.Millisecond)
+	require.NoError(t, repo.Close())
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncNever})
+	_, exists := getScore(t, reopened, "user1")
+	assert.True(t, exists)
+}
+
+func TestFileRepository_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts FileOptions
+	}{
+		{"unknown sync policy", FileOptions{Sync: "sometimes"}},
+		{"missing sync interval", FileOptions{Sync: SyncInterval}},
+		{"negative snapshot interval", FileOptions{Sync: SyncAlways, SnapshotEvery: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := NewFileRepository(t.TempDir(), tt.opts)
+
+			assert.Error(t, err)
+			assert.Nil(t, repo)
+		})
+	}
+}
+
+func TestFileRepository_SaveAfterClose(t *testing.T) {
+	repo, err := NewFileRepository(t.TempDir(), FileOptions{Sync: SyncAlways})
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	err = repo.Save(context.Background(), domain.UserScore{UserID: "user", Score: 10})
+	assert.Error(t, err)
+	assert.NoError(t, repo.Close())
+}
+
+func TestFileRepository_SnapshotFails(t *testing.T) {
+	dir := t.TempDir()
+	// A directory in place of the temporary snapshot file fails every
+	// snapshot
+	require.NoError(t, os.Mkdir(filepath.Join(dir, snapshotFileName+".tmp"), 0o755))
+
+	var snapshotErrs []error
+	repo := openFileRepository(t, dir, FileOptions{
+		Sync:            SyncAlways,
+		SnapshotEvery:   1,
+		OnSnapshotError: func(err error) { snapshotErrs = append(snapshotErrs, err) },
+	})
+
+	// The saves are in the log, so they succeed anyway
+	saveScores(t, repo,
+		domain.UserScore{UserID: "user1", Score: 1, Window: "all_time"},
+		domain.UserScore{UserID: "user2", Score: 2, Window: "all_time"},
+	)
+	require.Len(t, snapshotErrs, 2)
+	assert.ErrorContains(t, snapshotErrs[0], "failed to snapshot scores")
+	require.NoError(t, repo.Close())
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	for userID, expected := range map[string]int64{"user1": 1, "user2": 2} {
+		score, exists := getScore(t, reopened, userID)
+		assert.True(t, exists)
+		assert.Equal(t, expected, score.Score)
+	}
+}
+
+func TestFileRepository_ConcurrentClose(t *testing.T) {
+	repo, err := NewFileRepository(t.TempDir(), FileOptions{Sync: SyncInterval, SyncInterval: time.Millisecond})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestFileRepository_JobsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	createdAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	job := domain.Job{ID: "job1", Status: domain.JobRunning, Window: "all_time", UserIDs: []string{"alice", "bob"}, Processed: 1, Succeeded: 1, CreatedAt: createdAt}
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	require.NoError(t, repo.SaveJob(context.Background(), job))
+	require.NoError(t, repo.Close())
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+
+	unfinished, err := reopened.ListUnfinishedJobs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Job{job}, unfinished)
+
+	// No temporary file is left behind
+	entries, err := os.ReadDir(filepath.Join(dir, jobsDirName))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "job1.json", entries[0].Name())
+}
+
+func TestFileRepository_ActionsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	occurredAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	events := []domain.ActionEvent{
+		{UserID: "alice", UserAction: domain.UserAction{ID: "a1", Type: "login", Amount: 1, OccurredAt: occurredAt}},
+		{UserID: "alice", UserAction: domain.UserAction{ID: "a2", Type: "quiz_answer", Amount: 3, OccurredAt: occurredAt, Metadata: map[string]string{"quiz": "daily"}}},
+	}
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways, SnapshotEvery: 1})
+	_, err := repo.AppendActions(context.Background(), events)
+	require.NoError(t, err)
+	// Snapshots compact the score log only
+	saveScores(t, repo, domain.UserScore{UserID: "alice", Score: 7, Window: "all_time"})
+	require.NoError(t, repo.Close())
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+
+	actions, err := reopened.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{events[0].UserAction, events[1].UserAction}, actions)
+
+	// Resending after a restart is still idempotent
+	appended, err := reopened.AppendActions(context.Background(), events)
+	require.NoError(t, err)
+	assert.Empty(t, appended)
+}
+
+func TestFileRepository_TornActionRecord(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, actionLogFileName)
+	occurredAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	event := func(id string) domain.ActionEvent {
+		return domain.ActionEvent{UserID: "alice", UserAction: domain.UserAction{ID: id, Type: "login", Amount: 1, OccurredAt: occurredAt}}
+	}
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	_, err := repo.AppendActions(context.Background(), []domain.ActionEvent{event("a1")})
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+	info, err := os.Stat(logPath)
+	require.NoError(t, err)
+
+	// Cut the last record in half, as a crash while appending would
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write(appendFrame(nil, []byte(`{"user_id":"alice","id":"a2"}`))[:20])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	recovered := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	recoveredInfo, err := os.Stat(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), recoveredInfo.Size())
+
+	// The torn tail is cut off, so new records stay readable
+	_, err = recovered.AppendActions(context.Background(), []domain.ActionEvent{event("a2")})
+	require.NoError(t, err)
+	require.NoError(t, recovered.Close())
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	actions, err := reopened.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{event("a1").UserAction, event("a2").UserAction}, actions)
+}
+
+func TestFileRepository_ScoreCursorsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	updatedAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	first := domain.ScoreCursor{UserID: "alice", Position: 1, Score: 1, RuleVersion: 1, UpdatedAt: updatedAt}
+	latest := domain.ScoreCursor{UserID: "alice", Position: 2, Score: 4, RuleVersion: 1, UpdatedAt: updatedAt.Add(time.Minute)}
+	require.NoError(t, repo.ResetScoreCursor(context.Background(), first, []string{"a0", "a1"}))
+	require.NoError(t, repo.SaveScoreCursor(context.Background(), latest, []string{"a2"}))
+	require.NoError(t, repo.ResetScoreCursor(context.Background(), latest, []string{"a1", "a2"}))
+	require.NoError(t, repo.Close())
+
+	// The last cursor saved for a user wins
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	cursor, err := reopened.GetScoreCursor(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, latest, cursor)
+
+	// and the processed actions are replayed along with the cursors
+	processed, err := reopened.ProcessedActions(context.Background(), "alice", []string{"a0", "a1", "a2"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a1", "a2"}, processed)
+}
+
+func TestFileRepository_ScoreEventsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	for _, score := range []int64{1, 2, 3} {
+		require.NoError(t, repo.Save(ctx, domain.UserScore{UserID: "alice", Score: score}))
+	}
+	events, err := repo.PendingScoreEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.NoError(t, repo.AckScoreEvents(ctx, events[0].ID))
+
+	// Compacting moves the pending events out of the log
+	require.NoError(t, repo.Snapshot())
+	require.NoError(t, repo.Save(ctx, domain.UserScore{UserID: "alice", Score: 5}))
+	require.NoError(t, repo.Close())
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	pending, err := reopened.PendingScoreEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+	assert.Equal(t, events[1:], pending[:2])
+	assert.Equal(t, int64(3), pending[2].OldScore)
+	assert.Equal(t, int64(5), pending[2].NewScore)
+
+	// Acknowledged events stay acknowledged, and IDs keep growing
+	require.NoError(t, reopened.AckScoreEvents(ctx, pending[2].ID))
+	require.NoError(t, reopened.Close())
+
+	reopened = openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	pending, err = reopened.PendingScoreEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	require.NoError(t, reopened.Save(ctx, domain.UserScore{UserID: "alice", Score: 6}))
+	pending, err = reopened.PendingScoreEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, events[2].ID+2, pending[0].ID)
+}
+
+func TestFileRepository_HistorySurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	saveScores(t, repo,
+		domain.UserScore{UserID: "alice", Score: 1},
+		domain.UserScore{UserID: "alice", Score: 2},
+	)
+	wal, err := os.ReadFile(filepath.Join(dir, walFileName))
+	require.NoError(t, err)
+
+	// Compacting moves the entries out of the log
+	require.NoError(t, repo.Snapshot())
+	saveScores(t, repo, domain.UserScore{UserID: "alice", Score: 3})
+	require.NoError(t, repo.Close())
+
+	reopened := openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	entries, err := reopened.History(ctx, "alice", usecase.HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, e := range entries {
+		assert.Equal(t, int64(i+1), e.Score)
+		assert.Equal(t, int64(i+1), e.Seq)
+	}
+	require.NoError(t, reopened.Snapshot())
+	require.NoError(t, reopened.Close())
+
+	// A compaction that crashed before resetting the log moved its entries
+	// already, so replaying them again must not duplicate them
+	require.NoError(t, os.WriteFile(filepath.Join(dir, walFileName), wal, 0o644))
+
+	reopened = openFileRepository(t, dir, FileOptions{Sync: SyncAlways})
+	saveScores(t, reopened, domain.UserScore{UserID: "alice", Score: 4})
+	entries, err = reopened.History(ctx, "alice", usecase.HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, int64(4), entries[3].Seq)
+}
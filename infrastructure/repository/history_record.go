@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"time"
+
+	"scoreapp/domain"
+)
+
+// historyRecord is the persisted form of a domain.ScoreSnapshot.
+type historyRecord struct {
+	Seq         int64     `json:"seq"`
+	UserID      string    `json:"user_id"`
+	Window      string    `json:"window"`
+	Score       int64     `json:"score"`
+	RuleVersion int       `json:"rule_version"`
+	Streak      int       `json:"streak"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	RequestID   string    `json:"request_id,omitempty"`
+}
+
+func newHistoryRecord(s domain.ScoreSnapshot) historyRecord {
+	return historyRecord{
+		Seq:         s.Seq,
+		UserID:      s.UserID,
+		Window:      s.Window,
+		Score:       s.Score,
+		RuleVersion: s.RuleVersion,
+		Streak:      s.Streak,
+		RecordedAt:  s.RecordedAt,
+		RequestID:   s.RequestID,
+	}
+}
+
+func (rec historyRecord) snapshot() domain.ScoreSnapshot {
+	return domain.ScoreSnapshot{
+		Seq:         rec.Seq,
+		UserID:      rec.UserID,
+		Window:      rec.Window,
+		Score:       rec.Score,
+		RuleVersion: rec.RuleVersion,
+		Streak:      rec.Streak,
+		RecordedAt:  rec.RecordedAt,
+		RequestID:   rec.RequestID,
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"scoreapp/domain"
+)
+
+// jobRecord is the persisted form of a domain.Job.
+type jobRecord struct {
+	ID        string             `json:"id"`
+	Status    domain.JobStatus   `json:"status"`
+	Window    string             `json:"window"`
+	AllUsers  bool               `json:"all_users,omitempty"`
+	UserIDs   []string           `json:"user_ids"`
+	Processed int                `json:"processed"`
+	Succeeded int                `json:"succeeded"`
+	Failures  []jobFailureRecord `json:"failures,omitempty"`
+	Error     string             `json:"error,omitempty"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+}
+
+type jobFailureRecord struct {
+	UserID   string `json:"user_id"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+}
+
+func newJobRecord(job domain.Job) jobRecord {
+	rec := jobRecord{
+		ID:        job.ID,
+		Status:    job.Status,
+		Window:    job.Window,
+		AllUsers:  job.AllUsers,
+		UserIDs:   slices.Clone(job.UserIDs),
+		Processed: job.Processed,
+		Succeeded: job.Succeeded,
+		Error:     job.Error,
+
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	for _, f := range job.Failures {
+		rec.Failures = append(rec.Failures, jobFailureRecord{UserID: f.UserID, Error: f.Error, Attempts: f.Attempts})
+	}
+	return rec
+}
+
+func (rec jobRecord) job() domain.Job {
+	job := domain.Job{
+		ID:        rec.ID,
+		Status:    rec.Status,
+		Window:    rec.Window,
+		AllUsers:  rec.AllUsers,
+		UserIDs:   slices.Clone(rec.UserIDs),
+		Processed: rec.Processed,
+		Succeeded: rec.Succeeded,
+		Error:     rec.Error,
+
+		CreatedAt:  rec.CreatedAt,
+		StartedAt:  rec.StartedAt,
+		FinishedAt: rec.FinishedAt,
+	}
+	for _, f := range rec.Failures {
+		job.Failures = append(job.Failures, domain.JobFailure{UserID: f.UserID, Error: f.Error, Attempts: f.Attempts})
+	}
+	return job
+}
+
+// unfinishedJobs returns the jobs that are not finished, oldest first.
+func unfinishedJobs(jobs []domain.Job) []domain.Job {
+	var unfinished []domain.Job
+	for _, job := range jobs {
+		if !job.Status.Finished() {
+			unfinished = append(unfinished, job)
+		}
+	}
+	slices.SortStableFunc(unfinished, func(a, b domain.Job) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return unfinished
+}
+
+// listUserIDs returns the distinct users with a stored score, sorted.
+func listUserIDs(store map[scoreKey]domain.UserScore) []string {
+	var userIDs []string
+	for key := range store {
+		userIDs = append(userIDs, key.userID)
+	}
+	slices.Sort(userIDs)
+	return slices.Compact(userIDs)
+}
@@ -1,38 +1,418 @@
 package repository
 
 import (
+	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"scoreapp/domain"
+	"scoreapp/usecase"
 )
 
+// scoreKey identifies a stored score by user and scoring window.
+type scoreKey struct {
+	userID string
+	window string
+}
+
 // MemoryRepository is a simple in-memory example implementation of ScoreRepository.
 type MemoryRepository struct {
-	mu    sync.Mutex
-	store map[string]domain.UserScore
+	mu        sync.Mutex
+	store     map[scoreKey]domain.UserScore
+	history   map[string][]domain.ScoreSnapshot
+	seq       int64
+	jobs      map[string]domain.Job
+	actions   actionIndex
+	cursors   map[string]domain.ScoreCursor
+	processed processedActions
+	events    scoreOutbox
+	rules     map[int]*usecase.RuleSet
 }
 
 // NewMemoryRepository creates a new MemoryRepository.
 func NewMemoryRepository() *MemoryRepository {
 	return &MemoryRepository{
-		store: make(map[string]domain.UserScore),
+		store:     make(map[scoreKey]domain.UserScore),
+		history:   make(map[string][]domain.ScoreSnapshot),
+		jobs:      make(map[string]domain.Job),
+		actions:   newActionIndex(),
+		cursors:   make(map[string]domain.ScoreCursor),
+		processed: make(processedActions),
+		rules:     make(map[int]*usecase.RuleSet),
+	}
+}
+
+// Save stores or updates the score for a given user and window, appends it
+// to the user's history and records a score event if the score changed.
+func (r *MemoryRepository) Save(ctx context.Context, score domain.UserScore) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	score.Window = score.WindowKey()
+	key := scoreKey{userID: score.UserID, window: score.Window}
+	if event, changed := domain.NewScoreChanged(r.store[key], score, time.Now()); changed {
+		event.ID = r.events.nextID()
+		r.events.add(event)
+	}
+	r.store[key] = score
+
+	r.seq++
+	r.history[score.UserID] = append(r.history[score.UserID], newSnapshot(r.seq, score))
+	return nil
+}
+
+// Get retrieves the all-time score for a given user.
+func (r *MemoryRepository) Get(ctx context.Context, userID string) (domain.UserScore, bool, error) {
+	return r.GetWindow(ctx, userID, domain.AllTime().Key())
+}
+
+// GetWindow retrieves the score for a given user and window key.
+func (r *MemoryRepository) GetWindow(ctx context.Context, userID, window string) (domain.UserScore, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.UserScore{}, false, err
 	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	score, exists := r.store[scoreKey{userID: userID, window: window}]
+	return score, exists, nil
 }
 
-// Save stores or updates the score for a given user.
-func (r *MemoryRepository) Save(score domain.UserScore) error {
+// ListWindow returns every score saved for the window key, ordered by user ID.
+func (r *MemoryRepository) ListWindow(ctx context.Context, window string) ([]domain.UserScore, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return listWindow(r.store, window), nil
+}
+
+// ListUserIDs returns every user with a stored score, sorted.
+func (r *MemoryRepository) ListUserIDs(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return listUserIDs(r.store), nil
+}
+
+// History returns the user's score history entries that match the filter.
+func (r *MemoryRepository) History(ctx context.Context, userID string, filter usecase.HistoryFilter) ([]domain.ScoreSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return filterHistory(r.history[userID], filter), nil
+}
+
+// SaveJob creates or replaces the job with the same ID.
+func (r *MemoryRepository) SaveJob(ctx context.Context, job domain.Job) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.store[score.UserID] = score
+	r.jobs[job.ID] = newJobRecord(job).job()
 	return nil
 }
 
-// Get retrieves a score for a given user.
-func (r *MemoryRepository) Get(userID string) (domain.UserScore, bool) {
+// GetJob returns the job with the ID.
+func (r *MemoryRepository) GetJob(ctx context.Context, id string) (domain.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Job{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, exists := r.jobs[id]
+	if !exists {
+		return domain.Job{}, usecase.ErrJobNotFound
+	}
+	return newJobRecord(job).job(), nil
+}
+
+// ListUnfinishedJobs returns the queued and running jobs, oldest first.
+func (r *MemoryRepository) ListUnfinishedJobs(ctx context.Context) ([]domain.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobs := make([]domain.Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		jobs = append(jobs, newJobRecord(job).job())
+	}
+	return unfinishedJobs(jobs), nil
+}
+
+// AppendActions stores the events whose action ID the user does not have
+// yet and returns them.
+func (r *MemoryRepository) AppendActions(ctx context.Context, events []domain.ActionEvent) ([]domain.ActionEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	score, exists := r.store[userID]
-	return score, exists
+	fresh := r.actions.fresh(events)
+	for _, event := range fresh {
+		r.actions.add(newActionRecord(event))
+	}
+	return fresh, nil
+}
+
+// GetActions returns the user's ingested actions in the order they arrived.
+func (r *MemoryRepository) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.actions.get(userID)
+}
+
+// ActionsAfter returns the user's ingested actions after the position, which
+// counts the user's actions before them.
+func (r *MemoryRepository) ActionsAfter(ctx context.Context, userID string, position int64) ([]domain.UserAction, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	actions, last := r.actions.after(userID, position)
+	return actions, last, nil
+}
+
+// GetScoreCursor returns the user's score cursor.
+func (r *MemoryRepository) GetScoreCursor(ctx context.Context, userID string) (domain.ScoreCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ScoreCursor{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cursor, exists := r.cursors[userID]
+	if !exists {
+		return domain.ScoreCursor{}, usecase.ErrScoreCursorNotFound
+	}
+	return cursor, nil
+}
+
+// SaveScoreCursor creates or replaces the cursor of the same user and adds
+// the applied action IDs to the user's processed actions.
+func (r *MemoryRepository) SaveScoreCursor(ctx context.Context, cursor domain.ScoreCursor, applied []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cursors[cursor.UserID] = cursor
+	r.processed.add(cursor.UserID, applied)
+	return nil
+}
+
+// ResetScoreCursor creates or replaces the cursor of the same user and
+// replaces the user's processed actions.
+func (r *MemoryRepository) ResetScoreCursor(ctx context.Context, cursor domain.ScoreCursor, processed []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cursors[cursor.UserID] = cursor
+	delete(r.processed, cursor.UserID)
+	r.processed.add(cursor.UserID, processed)
+	return nil
+}
+
+// ProcessedActions returns which of the IDs are among the user's processed
+// actions.
+func (r *MemoryRepository) ProcessedActions(ctx context.Context, userID string, ids []string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.processed.find(userID, ids), nil
+}
+
+// ListScoreCursors returns every score cursor, ordered by user ID.
+func (r *MemoryRepository) ListScoreCursors(ctx context.Context) ([]domain.ScoreCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return listScoreCursors(r.cursors), nil
+}
+
+// PendingScoreEvents returns up to limit unacknowledged score events, oldest
+// first.
+func (r *MemoryRepository) PendingScoreEvents(ctx context.Context, limit int) ([]domain.ScoreChanged, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.events.list(limit), nil
+}
+
+// AckScoreEvents drops the score events with IDs up to throughID.
+func (r *MemoryRepository) AckScoreEvents(ctx context.Context, throughID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events.ack(throughID)
+	return nil
+}
+
+// newSnapshot builds the history entry recorded for a saved score. Scores
+// without a calculation time are recorded at the time of the save.
+func newSnapshot(seq int64, score domain.UserScore) domain.ScoreSnapshot {
+	recordedAt := score.CalculatedAt
+	if recordedAt.IsZero() {
+		recordedAt = time.Now()
+	}
+	return domain.ScoreSnapshot{
+		Seq:         seq,
+		UserID:      score.UserID,
+		Window:      score.WindowKey(),
+		Score:       score.Score,
+		RuleVersion: score.RuleVersion,
+		Streak:      score.Streak,
+		RecordedAt:  recordedAt.UTC(),
+		RequestID:   score.RequestID,
+	}
+}
+
+// listWindow returns the scores of one window, ordered by user ID.
+func listWindow(store map[scoreKey]domain.UserScore, window string) []domain.UserScore {
+	var scores []domain.UserScore
+	for key, score := range store {
+		if key.window == window {
+			scores = append(scores, score)
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].UserID < scores[j].UserID })
+	return scores
+}
+
+// filterHistory returns the entries, which are in Seq order, that match the
+// filter.
+func filterHistory(entries []domain.ScoreSnapshot, filter usecase.HistoryFilter) []domain.ScoreSnapshot {
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].Seq > filter.AfterSeq })
+
+	var matched []domain.ScoreSnapshot
+	for _, e := range entries[start:] {
+		if filter.Limit > 0 && len(matched) == filter.Limit {
+			break
+		}
+		if matchesHistoryFilter(e, filter) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+func matchesHistoryFilter(e domain.ScoreSnapshot, filter usecase.HistoryFilter) bool {
+	if filter.Window != "" && e.Window != filter.Window {
+		return false
+	}
+	if !filter.From.IsZero() && e.RecordedAt.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !e.RecordedAt.Before(filter.To) {
+		return false
+	}
+	return true
+}
+
+// SaveRuleSet creates or replaces the rule set of the same version.
+func (r *MemoryRepository) SaveRuleSet(ctx context.Context, ruleSet *usecase.RuleSet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rules[ruleSet.Version] = ruleSet
+	return nil
+}
+
+// GetRuleSet returns the rule set of the version.
+func (r *MemoryRepository) GetRuleSet(ctx context.Context, version int) (*usecase.RuleSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ruleSet, ok := r.rules[version]
+	if !ok {
+		return nil, usecase.ErrRuleSetNotFound
+	}
+	return ruleSet, nil
+}
+
+// LatestRuleSet returns the rule set of the highest version.
+func (r *MemoryRepository) LatestRuleSet(ctx context.Context) (*usecase.RuleSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest *usecase.RuleSet
+	for _, ruleSet := range r.rules {
+		if latest == nil || ruleSet.Version > latest.Version {
+			latest = ruleSet
+		}
+	}
+	if latest == nil {
+		return nil, usecase.ErrRuleSetNotFound
+	}
+	return latest, nil
 }
@@ -0,0 +1,27 @@
+CREATE TABLE scores (
+    user_id      TEXT    NOT NULL,
+    window_key   TEXT    NOT NULL,
+    score        INTEGER NOT NULL,
+    rule_version INTEGER NOT NULL,
+    streak       INTEGER NOT NULL,
+    updated_at   TEXT    NOT NULL,
+    PRIMARY KEY (user_id, window_key)
+);
+
+CREATE TABLE score_history (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      TEXT    NOT NULL,
+    window_key   TEXT    NOT NULL,
+    score        INTEGER NOT NULL,
+    rule_version INTEGER NOT NULL,
+    streak       INTEGER NOT NULL,
+    recorded_at  TEXT    NOT NULL
+);
+
+CREATE INDEX score_history_user_window ON score_history (user_id, window_key, id);
+
+CREATE TABLE processed_actions (
+    action_id    TEXT NOT NULL PRIMARY KEY,
+    user_id      TEXT NOT NULL,
+    processed_at TEXT NOT NULL
+);
@@ -0,0 +1,9 @@
+ALTER TABLE scores ADD COLUMN calculated_at TEXT NOT NULL DEFAULT '';
+ALTER TABLE scores ADD COLUMN request_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE score_history ADD COLUMN request_id TEXT NOT NULL DEFAULT '';
+
+-- Timestamps are compared as text, so store them with a fixed width
+UPDATE score_history SET recorded_at = strftime('%Y-%m-%dT%H:%M:%f', recorded_at) || '000000Z';
+
+CREATE INDEX score_history_user_recorded ON score_history (user_id, recorded_at);
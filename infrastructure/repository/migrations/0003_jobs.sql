@@ -0,0 +1,10 @@
+-- A job is stored whole as JSON; status and created_at are kept as columns
+-- to find the jobs left to resume
+CREATE TABLE jobs (
+    id         TEXT NOT NULL PRIMARY KEY,
+    status     TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    data       TEXT NOT NULL
+);
+
+CREATE INDEX jobs_status_created ON jobs (status, created_at);
@@ -0,0 +1,12 @@
+-- Ingested actions in the order they arrived; an action ID is stored once
+-- per user
+CREATE TABLE actions (
+    seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     TEXT    NOT NULL,
+    action_id   TEXT    NOT NULL,
+    type        TEXT    NOT NULL,
+    amount      INTEGER NOT NULL,
+    occurred_at TEXT    NOT NULL,
+    metadata    TEXT    NOT NULL DEFAULT '',
+    UNIQUE (user_id, action_id)
+);
@@ -0,0 +1,11 @@
+-- How far the incrementally updated score of each user has applied the
+-- user's ingested actions; position is the seq of the last one applied
+CREATE TABLE score_cursors (
+    user_id      TEXT    NOT NULL PRIMARY KEY,
+    position     INTEGER NOT NULL,
+    score        INTEGER NOT NULL,
+    rule_version INTEGER NOT NULL,
+    updated_at   TEXT    NOT NULL
+);
+
+CREATE INDEX actions_user_seq ON actions (user_id, seq);
@@ -0,0 +1,13 @@
+-- Outbox of score changes, written in the same transaction as the score and
+-- deleted once published; AUTOINCREMENT keeps published IDs from coming back
+CREATE TABLE score_events (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      TEXT    NOT NULL,
+    window_key   TEXT    NOT NULL,
+    old_score    INTEGER NOT NULL,
+    new_score    INTEGER NOT NULL,
+    delta        INTEGER NOT NULL,
+    rule_version INTEGER NOT NULL,
+    changed_at   TEXT    NOT NULL,
+    request_id   TEXT    NOT NULL DEFAULT ''
+);
@@ -0,0 +1,3 @@
+-- Ingested actions are deduplicated by the actions table; processed_actions
+-- was never written to
+DROP TABLE IF EXISTS processed_actions;
@@ -0,0 +1,8 @@
+-- Brings back the processed action IDs 0007 dropped. Action IDs are only
+-- unique per user, so they are keyed by user this time.
+CREATE TABLE processed_actions (
+    user_id      TEXT NOT NULL,
+    action_id    TEXT NOT NULL,
+    processed_at TEXT NOT NULL,
+    PRIMARY KEY (user_id, action_id)
+);
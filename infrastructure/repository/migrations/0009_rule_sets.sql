@@ -0,0 +1,6 @@
+-- Every rule set the registry activated, so versions keep increasing across
+-- restarts; data is the JSON-encoded rule set
+CREATE TABLE rule_sets (
+    version INTEGER NOT NULL PRIMARY KEY,
+    data    TEXT    NOT NULL
+);
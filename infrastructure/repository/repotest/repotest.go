@@ -0,0 +1,951 @@
+// Package repotest provides a conformance test suite for score repositories.
+// Repositories that also implement usecase.ScoreLister,
+// usecase.ScoreHistoryRepository, usecase.UserLister, usecase.JobRepository,
+// ActionRepository, usecase.ScoreCursorRepository, usecase.ScoreEventOutbox
+// or usecase.RuleSetStore are checked against those contracts as well.
+//
+// Every ScoreRepository implementation should pass it from its own tests:
+//
+//	func TestMyRepository(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repotest.Repository {
+//			return NewMyRepository(t.TempDir())
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+// Repository is the behavior the suite checks.
+type Repository interface {
+	Save(ctx context.Context, score domain.UserScore) error
+	Get(ctx context.Context, userID string) (domain.UserScore, bool, error)
+	GetWindow(ctx context.Context, userID, window string) (domain.UserScore, bool, error)
+}
+
+// ActionRepository is an action store that serves the actions it stores.
+type ActionRepository interface {
+	usecase.ActionStore
+	usecase.ActionService
+	usecase.ActionLog
+}
+
+// Factory returns a new, empty repository. It is called once per test and
+// should register any cleanup with t.Cleanup.
+type Factory func(t *testing.T) Repository
+
+// Run runs the conformance suite against repositories built by newRepo.
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo Repository)
+	}{
+		{"SaveNewScore", testSaveNewScore},
+		{"SaveOverwrites", testSaveOverwrites},
+		{"GetMissing", testGetMissing},
+		{"SaveMultipleUsers", testSaveMultipleUsers},
+		{"SaveEdgeValues", testSaveEdgeValues},
+		{"SaveSeparateWindows", testSaveSeparateWindows},
+		{"SaveWithoutWindowIsAllTime", testSaveWithoutWindowIsAllTime},
+		{"SaveCanceled", testSaveCanceled},
+		{"GetCanceled", testGetCanceled},
+		{"ConcurrentWrites", testConcurrentWrites},
+		{"SaveAuditFields", testSaveAuditFields},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepo(t))
+		})
+	}
+
+	t.Run("ListWindow", func(t *testing.T) {
+		repo := newRepo(t)
+		lister, ok := repo.(usecase.ScoreLister)
+		if !ok {
+			t.Skip("repository does not list scores")
+		}
+		testListWindow(t, repo, lister)
+	})
+
+	t.Run("ListUserIDs", func(t *testing.T) {
+		repo := newRepo(t)
+		users, ok := repo.(usecase.UserLister)
+		if !ok {
+			t.Skip("repository does not list users")
+		}
+		testListUserIDs(t, repo, users)
+	})
+
+	historyTests := []struct {
+		name string
+		test func(t *testing.T, repo Repository, history usecase.ScoreHistoryRepository)
+	}{
+		{"HistoryOrder", testHistoryOrder},
+		{"HistoryWindowFilter", testHistoryWindowFilter},
+		{"HistoryTimeRange", testHistoryTimeRange},
+		{"HistoryAfterSeq", testHistoryAfterSeq},
+		{"HistoryPerUser", testHistoryPerUser},
+		{"HistoryCanceledSave", testHistoryCanceledSave},
+	}
+
+	for _, tt := range historyTests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			history, ok := repo.(usecase.ScoreHistoryRepository)
+			if !ok {
+				t.Skip("repository does not record score history")
+			}
+			tt.test(t, repo, history)
+		})
+	}
+
+	jobTests := []struct {
+		name string
+		test func(t *testing.T, jobs usecase.JobRepository)
+	}{
+		{"SaveAndGetJob", testSaveAndGetJob},
+		{"GetMissingJob", testGetMissingJob},
+		{"ListUnfinishedJobs", testListUnfinishedJobs},
+	}
+
+	for _, tt := range jobTests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, ok := newRepo(t).(usecase.JobRepository)
+			if !ok {
+				t.Skip("repository does not store jobs")
+			}
+			tt.test(t, jobs)
+		})
+	}
+
+	actionTests := []struct {
+		name string
+		test func(t *testing.T, actions ActionRepository)
+	}{
+		{"AppendAndGetActions", testAppendAndGetActions},
+		{"AppendDuplicateActions", testAppendDuplicateActions},
+		{"GetActionsUnknownUser", testGetActionsUnknownUser},
+		{"ActionsAfter", testActionsAfter},
+	}
+
+	for _, tt := range actionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, ok := newRepo(t).(ActionRepository)
+			if !ok {
+				t.Skip("repository does not store actions")
+			}
+			tt.test(t, actions)
+		})
+	}
+
+	cursorTests := []struct {
+		name string
+		test func(t *testing.T, cursors usecase.ScoreCursorRepository)
+	}{
+		{"SaveAndGetScoreCursor", testSaveAndGetScoreCursor},
+		{"GetMissingScoreCursor", testGetMissingScoreCursor},
+		{"ListScoreCursors", testListScoreCursors},
+		{"ProcessedActions", testProcessedActions},
+	}
+
+	for _, tt := range cursorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursors, ok := newRepo(t).(usecase.ScoreCursorRepository)
+			if !ok {
+				t.Skip("repository does not store score cursors")
+			}
+			tt.test(t, cursors)
+		})
+	}
+
+	ruleSetTests := []struct {
+		name string
+		test func(t *testing.T, rules usecase.RuleSetStore)
+	}{
+		{"SaveAndGetRuleSet", testSaveAndGetRuleSet},
+		{"GetMissingRuleSet", testGetMissingRuleSet},
+		{"LatestRuleSet", testLatestRuleSet},
+	}
+
+	for _, tt := range ruleSetTests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, ok := newRepo(t).(usecase.RuleSetStore)
+			if !ok {
+				t.Skip("repository does not store rule sets")
+			}
+			tt.test(t, rules)
+		})
+	}
+
+	outboxTests := []struct {
+		name string
+		test func(t *testing.T, repo Repository, outbox usecase.ScoreEventOutbox)
+	}{
+		{"RecordsScoreEvents", testRecordsScoreEvents},
+		{"PendingScoreEventsLimit", testPendingScoreEventsLimit},
+		{"AckScoreEvents", testAckScoreEvents},
+	}
+
+	for _, tt := range outboxTests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			outbox, ok := repo.(usecase.ScoreEventOutbox)
+			if !ok {
+				t.Skip("repository does not record score events")
+			}
+			tt.test(t, repo, outbox)
+		})
+	}
+}
+
+func testSaveNewScore(t *testing.T, repo Repository) {
+	score := domain.UserScore{UserID: "user", Score: 100, RuleVersion: 2, Window: "all_time", Streak: 3}
+
+	require.NoError(t, repo.Save(context.Background(), score))
+
+	saved, exists := get(t, repo, "user")
+	assert.True(t, exists)
+	assert.Equal(t, score, saved)
+}
+
+func testSaveOverwrites(t *testing.T, repo Repository) {
+	require.NoError(t, repo.Save(context.Background(), domain.UserScore{UserID: "user", Score: 100, RuleVersion: 1, Window: "all_time", Streak: 5}))
+
+	updated := domain.UserScore{UserID: "user", Score: 250, RuleVersion: 2, Window: "all_time"}
+	require.NoError(t, repo.Save(context.Background(), updated))
+
+	saved, exists := get(t, repo, "user")
+	assert.True(t, exists)
+	assert.Equal(t, updated, saved)
+}
+
+func testGetMissing(t *testing.T, repo Repository) {
+	require.NoError(t, repo.Save(context.Background(), domain.UserScore{UserID: "other", Score: 1, Window: "all_time"}))
+
+	score, exists := get(t, repo, "nonexistent")
+	assert.False(t, exists)
+	assert.Equal(t, domain.UserScore{}, score)
+
+	score, exists, err := repo.GetWindow(context.Background(), "other", "month@UTC")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, domain.UserScore{}, score)
+}
+
+func testSaveMultipleUsers(t *testing.T, repo Repository) {
+	users := []domain.UserScore{
+		{UserID: "user1", Score: 10, Window: "all_time"},
+		{UserID: "user2", Score: 20, Window: "all_time"},
+		{UserID: "user3", Score: 30, Window: "all_time"},
+	}
+	for _, u := range users {
+		require.NoError(t, repo.Save(context.Background(), u))
+	}
+
+	for _, expected := range users {
+		actual, exists := get(t, repo, expected.UserID)
+		assert.True(t, exists)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func testSaveEdgeValues(t *testing.T, repo Repository) {
+	scores := []domain.UserScore{
+		{UserID: "", Score: 100, Window: "all_time"},
+		{UserID: "zero", Score: 0, Window: "all_time"},
+		{UserID: "negative", Score: -50, Window: "all_time"},
+		{UserID: "max", Score: math.MaxInt64, Window: "all_time"},
+		{UserID: "min", Score: math.MinInt64, Window: "all_time"},
+		{UserID: "ünïcode ✓", Score: 7, Window: "week@Europe/Istanbul"},
+	}
+	for _, s := range scores {
+		require.NoError(t, repo.Save(context.Background(), s))
+	}
+
+	for _, expected := range scores {
+		actual, exists, err := repo.GetWindow(context.Background(), expected.UserID, expected.Window)
+		require.NoError(t, err)
+		assert.True(t, exists, expected.UserID)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func testSaveSeparateWindows(t *testing.T, repo Repository) {
+	weekly := domain.UserScore{UserID: "user", Score: 20, Window: "week@UTC"}
+	lifetime := domain.UserScore{UserID: "user", Score: 300, Window: "all_time"}
+
+	require.NoError(t, repo.Save(context.Background(), weekly))
+	require.NoError(t, repo.Save(context.Background(), lifetime))
+
+	saved, exists, err := repo.GetWindow(context.Background(), "user", "week@UTC")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, weekly, saved)
+
+	saved, exists = get(t, repo, "user")
+	assert.True(t, exists)
+	assert.Equal(t, lifetime, saved)
+}
+
+func testSaveWithoutWindowIsAllTime(t *testing.T, repo Repository) {
+	require.NoError(t, repo.Save(context.Background(), domain.UserScore{UserID: "user", Score: 10}))
+
+	saved, exists, err := repo.GetWindow(context.Background(), "user", "all_time")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, domain.UserScore{UserID: "user", Score: 10, Window: "all_time"}, saved)
+}
+
+func testGetCanceled(t *testing.T, repo Repository) {
+	require.NoError(t, repo.Save(context.Background(), domain.UserScore{UserID: "user", Score: 10, Window: "all_time"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := repo.Get(ctx, "user")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func testSaveCanceled(t *testing.T, repo Repository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := repo.Save(ctx, domain.UserScore{UserID: "user", Score: 10, Window: "all_time"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, exists := get(t, repo, "user")
+	assert.False(t, exists)
+}
+
+func testConcurrentWrites(t *testing.T, repo Repository) {
+	const writers, saves = 8, 25
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range saves {
+				// Each writer owns one user and shares the "shared" user
+				own := domain.UserScore{UserID: fmt.Sprintf("user%d", w), Score: int64(i), Window: "all_time"}
+				shared := domain.UserScore{UserID: "shared", Score: int64(w*saves + i), Window: "all_time"}
+				assert.NoError(t, repo.Save(context.Background(), own))
+				assert.NoError(t, repo.Save(context.Background(), shared))
+				_, _, _ = repo.Get(context.Background(), "shared")
+			}
+		}()
+	}
+	wg.Wait()
+
+	for w := range writers {
+		saved, exists := get(t, repo, fmt.Sprintf("user%d", w))
+		assert.True(t, exists)
+		assert.Equal(t, int64(saves-1), saved.Score)
+	}
+
+	// The shared score is the last save of one of the writers
+	saved, exists := get(t, repo, "shared")
+	assert.True(t, exists)
+	assert.Equal(t, int64(saves-1), saved.Score%saves)
+}
+
+func testSaveAuditFields(t *testing.T, repo Repository) {
+	score := domain.UserScore{
+		UserID:       "user",
+		Score:        10,
+		Window:       "all_time",
+		CalculatedAt: time.Date(2025, 6, 2, 8, 30, 0, 123456789, time.UTC),
+		RequestID:    "req-1",
+	}
+
+	require.NoError(t, repo.Save(context.Background(), score))
+
+	saved, exists := get(t, repo, "user")
+	assert.True(t, exists)
+	assert.Equal(t, score, saved)
+}
+
+// saveAt saves a score calculated at the given time.
+func saveAt(t *testing.T, repo Repository, userID, window string, score int64, at time.Time) {
+	t.Helper()
+	require.NoError(t, repo.Save(context.Background(), domain.UserScore{
+		UserID:       userID,
+		Score:        score,
+		RuleVersion:  1,
+		Window:       window,
+		CalculatedAt: at,
+		RequestID:    fmt.Sprintf("req-%d", score),
+	}))
+}
+
+func scoresOf(entries []domain.ScoreSnapshot) []int64 {
+	scores := make([]int64, len(entries))
+	for i, e := range entries {
+		scores[i] = e.Score
+	}
+	return scores
+}
+
+var historyStart = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func testHistoryOrder(t *testing.T, repo Repository, history usecase.ScoreHistoryRepository) {
+	for i := range 5 {
+		saveAt(t, repo, "user", "all_time", int64(i), historyStart.Add(time.Duration(i)*time.Hour))
+	}
+
+	entries, err := history.History(context.Background(), "user", usecase.HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	assert.Equal(t, []int64{0, 1, 2, 3, 4}, scoresOf(entries))
+	for i := 1; i < len(entries); i++ {
+		assert.Greater(t, entries[i].Seq, entries[i-1].Seq)
+	}
+
+	assert.Equal(t, domain.ScoreSnapshot{
+		Seq:         entries[2].Seq,
+		UserID:      "user",
+		Window:      "all_time",
+		Score:       2,
+		RuleVersion: 1,
+		RecordedAt:  historyStart.Add(2 * time.Hour),
+		RequestID:   "req-2",
+	}, entries[2])
+
+	// Overwriting the current score keeps the earlier entries
+	current, exists := get(t, repo, "user")
+	assert.True(t, exists)
+	assert.Equal(t, int64(4), current.Score)
+}
+
+func testHistoryWindowFilter(t *testing.T, repo Repository, history usecase.ScoreHistoryRepository) {
+	saveAt(t, repo, "user", "all_time", 1, historyStart)
+	saveAt(t, repo, "user", "week@UTC", 2, historyStart)
+	saveAt(t, repo, "user", "all_time", 3, historyStart)
+
+	entries, err := history.History(context.Background(), "user", usecase.HistoryFilter{Window: "all_time"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 3}, scoresOf(entries))
+
+	entries, err = history.History(context.Background(), "user", usecase.HistoryFilter{Window: "week@UTC"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, scoresOf(entries))
+}
+
+func testHistoryTimeRange(t *testing.T, repo Repository, history usecase.ScoreHistoryRepository) {
+	for i := range 5 {
+		saveAt(t, repo, "user", "all_time", int64(i), historyStart.Add(time.Duration(i)*time.Hour))
+	}
+
+	// From is inclusive and To is exclusive
+	entries, err := history.History(context.Background(), "user", usecase.HistoryFilter{
+		From: historyStart.Add(time.Hour),
+		To:   historyStart.Add(3 * time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, scoresOf(entries))
+
+	// Bounds in another time zone select the same instants
+	istanbul := time.FixedZone("+03", 3*60*60)
+	entries, err = history.History(context.Background(), "user", usecase.HistoryFilter{
+		From: historyStart.Add(3 * time.Hour).In(istanbul),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 4}, scoresOf(entries))
+
+	entries, err = history.History(context.Background(), "user", usecase.HistoryFilter{
+		To: historyStart.Add(time.Nanosecond),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0}, scoresOf(entries))
+}
+
+func testHistoryAfterSeq(t *testing.T, repo Repository, history usecase.ScoreHistoryRepository) {
+	for i := range 5 {
+		saveAt(t, repo, "user", "all_time", int64(i), historyStart)
+	}
+
+	first, err := history.History(context.Background(), "user", usecase.HistoryFilter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, []int64{0, 1}, scoresOf(first))
+
+	rest, err := history.History(context.Background(), "user", usecase.HistoryFilter{AfterSeq: first[1].Seq})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 4}, scoresOf(rest))
+
+	none, err := history.History(context.Background(), "user", usecase.HistoryFilter{AfterSeq: rest[2].Seq})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func testHistoryPerUser(t *testing.T, repo Repository, history usecase.ScoreHistoryRepository) {
+	saveAt(t, repo, "user1", "all_time", 1, historyStart)
+	saveAt(t, repo, "user2", "all_time", 2, historyStart)
+	saveAt(t, repo, "user1", "all_time", 3, historyStart)
+
+	entries, err := history.History(context.Background(), "user1", usecase.HistoryFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 3}, scoresOf(entries))
+
+	entries, err = history.History(context.Background(), "nonexistent", usecase.HistoryFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func testHistoryCanceledSave(t *testing.T, repo Repository, history usecase.ScoreHistoryRepository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := repo.Save(ctx, domain.UserScore{UserID: "user", Score: 10, Window: "all_time"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	entries, err := history.History(context.Background(), "user", usecase.HistoryFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func testListWindow(t *testing.T, repo Repository, lister usecase.ScoreLister) {
+	scores := []domain.UserScore{
+		{UserID: "carol", Score: 30, Window: "all_time", CalculatedAt: historyStart},
+		{UserID: "alice", Score: 10, Window: "all_time"},
+		{UserID: "bob", Score: 20, Window: "week@UTC"},
+		{UserID: "bob", Score: 25, Window: "all_time", RequestID: "req-1"},
+	}
+	for _, s := range scores {
+		require.NoError(t, repo.Save(context.Background(), s))
+	}
+	// Only the latest score of a user is listed
+	require.NoError(t, repo.Save(context.Background(), domain.UserScore{UserID: "alice", Score: 15, Window: "all_time"}))
+
+	listed, err := lister.ListWindow(context.Background(), "all_time")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserScore{
+		{UserID: "alice", Score: 15, Window: "all_time"},
+		{UserID: "bob", Score: 25, Window: "all_time", RequestID: "req-1"},
+		{UserID: "carol", Score: 30, Window: "all_time", CalculatedAt: historyStart},
+	}, listed)
+
+	listed, err = lister.ListWindow(context.Background(), "month@UTC")
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+}
+
+func testListUserIDs(t *testing.T, repo Repository, users usecase.UserLister) {
+	userIDs, err := users.ListUserIDs(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, userIDs)
+
+	for _, s := range []domain.UserScore{
+		{UserID: "carol", Score: 3, Window: "all_time"},
+		{UserID: "alice", Score: 1, Window: "all_time"},
+		{UserID: "alice", Score: 2, Window: "week@UTC"},
+		{UserID: "bob", Score: 5, Window: "month@UTC"},
+	} {
+		require.NoError(t, repo.Save(context.Background(), s))
+	}
+
+	// Each user is listed once, whatever windows they have scores in
+	userIDs, err = users.ListUserIDs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, userIDs)
+}
+
+// newJob returns a queued job created at the given time.
+func newJob(id string, createdAt time.Time) domain.Job {
+	return domain.Job{
+		ID:        id,
+		Status:    domain.JobQueued,
+		Window:    "all_time",
+		UserIDs:   []string{"alice", "bob"},
+		CreatedAt: createdAt,
+	}
+}
+
+func testSaveAndGetJob(t *testing.T, jobs usecase.JobRepository) {
+	job := newJob("job1", historyStart)
+	require.NoError(t, jobs.SaveJob(context.Background(), job))
+
+	saved, err := jobs.GetJob(context.Background(), "job1")
+	require.NoError(t, err)
+	assert.Equal(t, job, saved)
+
+	// Saving again replaces the job
+	job.Status = domain.JobCompleted
+	job.Processed = 2
+	job.Succeeded = 1
+	job.Failures = []domain.JobFailure{{UserID: "bob", Error: "user not found", Attempts: 1}}
+	job.StartedAt = historyStart.Add(time.Second)
+	job.FinishedAt = historyStart.Add(time.Minute)
+	require.NoError(t, jobs.SaveJob(context.Background(), job))
+
+	saved, err = jobs.GetJob(context.Background(), "job1")
+	require.NoError(t, err)
+	assert.Equal(t, job, saved)
+
+	// The saved job does not share memory with the caller's
+	saved.UserIDs[0] = "mallory"
+	again, err := jobs.GetJob(context.Background(), "job1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", again.UserIDs[0])
+
+	// A job for all users keeps users it resolved to none
+	all := domain.Job{ID: "job2", Status: domain.JobRunning, Window: "week@UTC", AllUsers: true, UserIDs: []string{}, CreatedAt: historyStart}
+	require.NoError(t, jobs.SaveJob(context.Background(), all))
+	saved, err = jobs.GetJob(context.Background(), "job2")
+	require.NoError(t, err)
+	assert.Equal(t, all, saved)
+	assert.NotNil(t, saved.UserIDs)
+}
+
+func testGetMissingJob(t *testing.T, jobs usecase.JobRepository) {
+	require.NoError(t, jobs.SaveJob(context.Background(), newJob("job1", historyStart)))
+
+	for _, id := range []string{"nonexistent", "", "../job1"} {
+		_, err := jobs.GetJob(context.Background(), id)
+		assert.ErrorIs(t, err, usecase.ErrJobNotFound, id)
+	}
+}
+
+func testListUnfinishedJobs(t *testing.T, jobs usecase.JobRepository) {
+	unfinished, err := jobs.ListUnfinishedJobs(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, unfinished)
+
+	running := newJob("running", historyStart.Add(2*time.Hour))
+	running.Status = domain.JobRunning
+	running.Processed = 1
+	completed := newJob("completed", historyStart)
+	completed.Status = domain.JobCompleted
+	failed := newJob("failed", historyStart)
+	failed.Status = domain.JobFailed
+	queued := newJob("queued", historyStart.Add(time.Hour))
+
+	for _, job := range []domain.Job{running, completed, failed, queued} {
+		require.NoError(t, jobs.SaveJob(context.Background(), job))
+	}
+
+	unfinished, err = jobs.ListUnfinishedJobs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Job{queued, running}, unfinished)
+}
+
+// newActionEvent returns an action of the user occurring at historyStart.
+func newActionEvent(userID, id, actionType string, amount int64) domain.ActionEvent {
+	return domain.ActionEvent{
+		UserID:     userID,
+		UserAction: domain.UserAction{ID: id, Type: actionType, Amount: amount, OccurredAt: historyStart},
+	}
+}
+
+func testAppendAndGetActions(t *testing.T, actions ActionRepository) {
+	withMetadata := newActionEvent("alice", "a2", "challenge_completed", 2)
+	withMetadata.Metadata = map[string]string{"challenge": "daily"}
+	events := []domain.ActionEvent{
+		newActionEvent("alice", "a1", "login", 1),
+		newActionEvent("bob", "b1", "quiz_answer", 3),
+		withMetadata,
+	}
+
+	appended, err := actions.AppendActions(context.Background(), events)
+	require.NoError(t, err)
+	assert.Equal(t, events, appended)
+
+	later := newActionEvent("alice", "a0", "login", 1)
+	later.OccurredAt = historyStart.Add(-time.Hour)
+	_, err = actions.AppendActions(context.Background(), []domain.ActionEvent{later})
+	require.NoError(t, err)
+
+	// Actions are served in the order they arrived, not the order they occurred
+	got, err := actions.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{events[0].UserAction, withMetadata.UserAction, later.UserAction}, got)
+
+	got, err = actions.GetActions(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{events[1].UserAction}, got)
+
+	// The stored actions do not share memory with the caller's
+	withMetadata.Metadata["challenge"] = "weekly"
+	got[0].Type = "login"
+	got, err = actions.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "daily", got[1].Metadata["challenge"])
+	got, err = actions.GetActions(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "quiz_answer", got[0].Type)
+}
+
+func testAppendDuplicateActions(t *testing.T, actions ActionRepository) {
+	first := newActionEvent("alice", "a1", "login", 1)
+	_, err := actions.AppendActions(context.Background(), []domain.ActionEvent{first})
+	require.NoError(t, err)
+
+	// Repeated IDs are ignored, whether stored before or earlier in the
+	// batch, while another user may reuse an ID
+	resent := newActionEvent("alice", "a1", "quiz_answer", 5)
+	second := newActionEvent("alice", "a2", "login", 1)
+	other := newActionEvent("bob", "a1", "login", 1)
+	appended, err := actions.AppendActions(context.Background(), []domain.ActionEvent{resent, second, second, other})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ActionEvent{second, other}, appended)
+
+	appended, err = actions.AppendActions(context.Background(), []domain.ActionEvent{first, second})
+	require.NoError(t, err)
+	assert.Empty(t, appended)
+
+	got, err := actions.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{first.UserAction, second.UserAction}, got)
+}
+
+func testGetActionsUnknownUser(t *testing.T, actions ActionRepository) {
+	_, err := actions.AppendActions(context.Background(), []domain.ActionEvent{newActionEvent("alice", "a1", "login", 1)})
+	require.NoError(t, err)
+
+	_, err = actions.GetActions(context.Background(), "nobody")
+	assert.ErrorIs(t, err, usecase.ErrUserNotFound)
+}
+
+func testActionsAfter(t *testing.T, actions ActionRepository) {
+	ctx := context.Background()
+	a1 := newActionEvent("alice", "a1", "login", 1)
+	b1 := newActionEvent("bob", "b1", "login", 1)
+	a2 := newActionEvent("alice", "a2", "quiz_answer", 3)
+	_, err := actions.AppendActions(ctx, []domain.ActionEvent{a1, b1, a2})
+	require.NoError(t, err)
+
+	got, position, err := actions.ActionsAfter(ctx, "alice", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{a1.UserAction, a2.UserAction}, got)
+
+	// Nothing is after the last action, and the position stays put
+	got, last, err := actions.ActionsAfter(ctx, "alice", position)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Equal(t, position, last)
+
+	// Only actions stored later are after the position, whatever other
+	// users stored in between
+	a3 := newActionEvent("alice", "a3", "login", 1)
+	_, err = actions.AppendActions(ctx, []domain.ActionEvent{newActionEvent("bob", "b2", "login", 1), a3})
+	require.NoError(t, err)
+	got, last, err = actions.ActionsAfter(ctx, "alice", position)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{a3.UserAction}, got)
+	assert.Greater(t, last, position)
+
+	got, last, err = actions.ActionsAfter(ctx, "nobody", 0)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Zero(t, last)
+}
+
+func testSaveAndGetScoreCursor(t *testing.T, cursors usecase.ScoreCursorRepository) {
+	ctx := context.Background()
+	cursor := domain.ScoreCursor{UserID: "alice", Position: 3, Score: 42, RuleVersion: 2, UpdatedAt: historyStart}
+	require.NoError(t, cursors.SaveScoreCursor(ctx, cursor, nil))
+
+	got, err := cursors.GetScoreCursor(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, cursor, got)
+
+	cursor.Position, cursor.Score = 5, 50
+	require.NoError(t, cursors.SaveScoreCursor(ctx, cursor, nil))
+	got, err = cursors.GetScoreCursor(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, cursor, got)
+}
+
+func testGetMissingScoreCursor(t *testing.T, cursors usecase.ScoreCursorRepository) {
+	_, err := cursors.GetScoreCursor(context.Background(), "nobody")
+	assert.ErrorIs(t, err, usecase.ErrScoreCursorNotFound)
+}
+
+func testListScoreCursors(t *testing.T, cursors usecase.ScoreCursorRepository) {
+	ctx := context.Background()
+	list, err := cursors.ListScoreCursors(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	bob := domain.ScoreCursor{UserID: "bob", Position: 1, Score: 1, RuleVersion: 1, UpdatedAt: historyStart}
+	alice := domain.ScoreCursor{UserID: "alice", Position: 2, Score: 3, RuleVersion: 1, UpdatedAt: historyStart}
+	require.NoError(t, cursors.SaveScoreCursor(ctx, bob, nil))
+	require.NoError(t, cursors.SaveScoreCursor(ctx, alice, nil))
+
+	list, err = cursors.ListScoreCursors(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ScoreCursor{alice, bob}, list)
+}
+
+func testProcessedActions(t *testing.T, cursors usecase.ScoreCursorRepository) {
+	ctx := context.Background()
+	processed, err := cursors.ProcessedActions(ctx, "alice", []string{"a1"})
+	require.NoError(t, err)
+	assert.Empty(t, processed)
+
+	cursor := domain.ScoreCursor{UserID: "alice", Position: 2, Score: 2, RuleVersion: 1, UpdatedAt: historyStart}
+	require.NoError(t, cursors.ResetScoreCursor(ctx, cursor, []string{"a1", "a2"}))
+	cursor.Position, cursor.Score = 3, 3
+	require.NoError(t, cursors.SaveScoreCursor(ctx, cursor, []string{"a3"}))
+	require.NoError(t, cursors.SaveScoreCursor(ctx, domain.ScoreCursor{UserID: "bob", Position: 1, Score: 1, RuleVersion: 1, UpdatedAt: historyStart}, []string{"a4"}))
+
+	processed, err = cursors.ProcessedActions(ctx, "alice", []string{"a1", "a3", "a4", "a5"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a1", "a3"}, processed)
+
+	// A reset replaces the processed actions instead of adding to them
+	cursor.Position, cursor.Score = 4, 2
+	require.NoError(t, cursors.ResetScoreCursor(ctx, cursor, []string{"a2", "a5"}))
+	processed, err = cursors.ProcessedActions(ctx, "alice", []string{"a1", "a2", "a3", "a5"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a2", "a5"}, processed)
+
+	got, err := cursors.GetScoreCursor(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, cursor, got)
+	processed, err = cursors.ProcessedActions(ctx, "bob", []string{"a4"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a4"}, processed)
+}
+
+// withoutIDs returns the events with their IDs cleared, after checking that
+// the IDs grow.
+func withoutIDs(t *testing.T, events []domain.ScoreChanged) []domain.ScoreChanged {
+	t.Helper()
+	cleared := make([]domain.ScoreChanged, len(events))
+	for i, e := range events {
+		if i > 0 {
+			assert.Greater(t, e.ID, events[i-1].ID)
+		}
+		e.ID = 0
+		cleared[i] = e
+	}
+	return cleared
+}
+
+func testRecordsScoreEvents(t *testing.T, repo Repository, outbox usecase.ScoreEventOutbox) {
+	ctx := context.Background()
+	saveAt(t, repo, "alice", "all_time", 10, historyStart)
+	// Saving the same score again changes nothing
+	saveAt(t, repo, "alice", "all_time", 10, historyStart.Add(time.Hour))
+	saveAt(t, repo, "alice", "all_time", 4, historyStart.Add(2*time.Hour))
+	saveAt(t, repo, "alice", "week:2025-W23", 3, historyStart.Add(3*time.Hour))
+	require.NoError(t, repo.Save(ctx, domain.UserScore{
+		UserID: "bob", Score: math.MinInt64, RuleVersion: 2, CalculatedAt: historyStart, RequestID: "req-1",
+	}))
+
+	events, err := outbox.PendingScoreEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ScoreChanged{
+		{UserID: "alice", Window: "all_time", OldScore: 0, NewScore: 10, Delta: 10, RuleVersion: 1, ChangedAt: historyStart, RequestID: "req-10"},
+		{UserID: "alice", Window: "all_time", OldScore: 10, NewScore: 4, Delta: -6, RuleVersion: 1, ChangedAt: historyStart.Add(2 * time.Hour), RequestID: "req-4"},
+		{UserID: "alice", Window: "week:2025-W23", OldScore: 0, NewScore: 3, Delta: 3, RuleVersion: 1, ChangedAt: historyStart.Add(3 * time.Hour), RequestID: "req-3"},
+		{UserID: "bob", Window: "all_time", OldScore: 0, NewScore: math.MinInt64, Delta: math.MinInt64, RuleVersion: 2, ChangedAt: historyStart, RequestID: "req-1"},
+	}, withoutIDs(t, events))
+}
+
+func testPendingScoreEventsLimit(t *testing.T, repo Repository, outbox usecase.ScoreEventOutbox) {
+	ctx := context.Background()
+	for i := int64(1); i <= 3; i++ {
+		saveAt(t, repo, "alice", "all_time", i, historyStart)
+	}
+
+	events, err := outbox.PendingScoreEvents(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(1), events[0].NewScore)
+	assert.Equal(t, int64(2), events[1].NewScore)
+}
+
+func testAckScoreEvents(t *testing.T, repo Repository, outbox usecase.ScoreEventOutbox) {
+	ctx := context.Background()
+	for i := int64(1); i <= 3; i++ {
+		saveAt(t, repo, "alice", "all_time", i, historyStart)
+	}
+	events, err := outbox.PendingScoreEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	require.NoError(t, outbox.AckScoreEvents(ctx, events[1].ID))
+	pending, err := outbox.PendingScoreEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events[2:], pending)
+
+	// IDs of acknowledged events are not handed out again
+	require.NoError(t, outbox.AckScoreEvents(ctx, events[2].ID))
+	saveAt(t, repo, "alice", "all_time", 4, historyStart)
+	pending, err = outbox.PendingScoreEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Greater(t, pending[0].ID, events[2].ID)
+	assert.Equal(t, int64(1), pending[0].Delta)
+}
+
+func ruleSet(version int) *usecase.RuleSet {
+	rules := usecase.DefaultScoringRules()
+	rules.Rules["login"] = usecase.Rule{Base: int64(version)}
+	return &usecase.RuleSet{Version: version, Rules: rules, LoadedAt: historyStart.Add(time.Duration(version) * time.Hour)}
+}
+
+func testSaveAndGetRuleSet(t *testing.T, rules usecase.RuleSetStore) {
+	ctx := context.Background()
+	saved := ruleSet(1)
+	saved.Rules.Decay = &usecase.DecayPolicy{Kind: usecase.DecayExponential, HalfLifeDays: 14, Floor: 0.1}
+	saved.Rules.Streak = &usecase.StreakPolicy{ActionType: "login", Milestones: []usecase.StreakMilestone{{Days: 7, Points: 50}}}
+	require.NoError(t, rules.SaveRuleSet(ctx, saved))
+
+	got, err := rules.GetRuleSet(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, saved.Version, got.Version)
+	assert.Equal(t, saved.Rules, got.Rules)
+	assert.True(t, saved.LoadedAt.Equal(got.LoadedAt))
+
+	// Saving the same version again replaces it
+	replaced := ruleSet(1)
+	replaced.Rules.Rules["login"] = usecase.Rule{Base: 9}
+	require.NoError(t, rules.SaveRuleSet(ctx, replaced))
+	got, err = rules.GetRuleSet(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, replaced.Rules, got.Rules)
+}
+
+func testGetMissingRuleSet(t *testing.T, rules usecase.RuleSetStore) {
+	_, err := rules.GetRuleSet(context.Background(), 1)
+	assert.ErrorIs(t, err, usecase.ErrRuleSetNotFound)
+}
+
+func testLatestRuleSet(t *testing.T, rules usecase.RuleSetStore) {
+	ctx := context.Background()
+	_, err := rules.LatestRuleSet(ctx)
+	assert.ErrorIs(t, err, usecase.ErrRuleSetNotFound)
+
+	for _, version := range []int{1, 10, 2} {
+		require.NoError(t, rules.SaveRuleSet(ctx, ruleSet(version)))
+	}
+
+	latest, err := rules.LatestRuleSet(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 10, latest.Version)
+	assert.Equal(t, usecase.Rule{Base: 10}, latest.Rules.Rules["login"])
+}
+
+// get returns the user's all-time score, failing the test on an error.
+func get(t *testing.T, repo Repository, userID string) (domain.UserScore, bool) {
+	t.Helper()
+	score, exists, err := repo.Get(context.Background(), userID)
+	require.NoError(t, err)
+	return score, exists
+}
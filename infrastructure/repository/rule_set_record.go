@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"scoreapp/usecase"
+)
+
+// ruleSetRecord is the persisted form of a usecase.RuleSet. The rules are
+// not validated when read back, so rule sets stay readable after the
+// validation got stricter.
+type ruleSetRecord struct {
+	Version  int                   `json:"version"`
+	Rules    *usecase.ScoringRules `json:"rules"`
+	LoadedAt time.Time             `json:"loaded_at"`
+}
+
+func newRuleSetRecord(rs *usecase.RuleSet) ruleSetRecord {
+	return ruleSetRecord{
+		Version:  rs.Version,
+		Rules:    rs.Rules,
+		LoadedAt: rs.LoadedAt,
+	}
+}
+
+func (rec ruleSetRecord) ruleSet() *usecase.RuleSet {
+	return &usecase.RuleSet{
+		Version:  rec.Version,
+		Rules:    rec.Rules,
+		LoadedAt: rec.LoadedAt,
+	}
+}
+
+// decodeRuleSet reads a rule set record.
+func decodeRuleSet(data []byte) (*usecase.RuleSet, error) {
+	var rec ruleSetRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	return rec.ruleSet(), nil
+}
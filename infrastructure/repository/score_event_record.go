@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"scoreapp/domain"
+)
+
+// scoreEventRecord is the persisted form of a domain.ScoreChanged.
+type scoreEventRecord struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"user_id"`
+	Window      string    `json:"window"`
+	OldScore    int64     `json:"old_score"`
+	NewScore    int64     `json:"new_score"`
+	Delta       int64     `json:"delta"`
+	RuleVersion int       `json:"rule_version"`
+	ChangedAt   time.Time `json:"changed_at"`
+	RequestID   string    `json:"request_id,omitempty"`
+}
+
+func newScoreEventRecord(e domain.ScoreChanged) scoreEventRecord {
+	return scoreEventRecord{
+		ID:          e.ID,
+		UserID:      e.UserID,
+		Window:      e.Window,
+		OldScore:    e.OldScore,
+		NewScore:    e.NewScore,
+		Delta:       e.Delta,
+		RuleVersion: e.RuleVersion,
+		ChangedAt:   e.ChangedAt,
+		RequestID:   e.RequestID,
+	}
+}
+
+func (rec scoreEventRecord) event() domain.ScoreChanged {
+	return domain.ScoreChanged{
+		ID:          rec.ID,
+		UserID:      rec.UserID,
+		Window:      rec.Window,
+		OldScore:    rec.OldScore,
+		NewScore:    rec.NewScore,
+		Delta:       rec.Delta,
+		RuleVersion: rec.RuleVersion,
+		ChangedAt:   rec.ChangedAt,
+		RequestID:   rec.RequestID,
+	}
+}
+
+// scoreOutbox keeps the unacknowledged score events of a repository in
+// memory, in the order of their IDs.
+type scoreOutbox struct {
+	pending []domain.ScoreChanged
+	// lastID is the highest ID recorded or acknowledged.
+	lastID int64
+}
+
+// nextID returns the ID of the next event.
+func (o *scoreOutbox) nextID() int64 {
+	return o.lastID + 1
+}
+
+// add adds the event unless its ID is not newer than every event added or
+// acknowledged so far, which makes replaying events recorded before
+// harmless.
+func (o *scoreOutbox) add(event domain.ScoreChanged) {
+	if event.ID <= o.lastID {
+		return
+	}
+	o.lastID = event.ID
+	o.pending = append(o.pending, event)
+}
+
+// list returns up to limit pending events; a limit of zero returns all.
+func (o *scoreOutbox) list(limit int) []domain.ScoreChanged {
+	events := o.pending
+	if limit > 0 && limit < len(events) {
+		events = events[:limit]
+	}
+	return append([]domain.ScoreChanged(nil), events...)
+}
+
+// ack drops the events with IDs up to throughID.
+func (o *scoreOutbox) ack(throughID int64) {
+	n := sort.Search(len(o.pending), func(i int) bool { return o.pending[i].ID > throughID })
+	o.pending = append([]domain.ScoreChanged(nil), o.pending[n:]...)
+	o.lastID = max(o.lastID, throughID)
+}
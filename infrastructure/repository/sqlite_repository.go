@@ -0,0 +1,751 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// Pure-Go SQLite driver, so the binary still builds with CGO_ENABLED=0
+	_ "modernc.org/sqlite"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// sqliteTimeLayout is a fixed-width UTC timestamp, so timestamps stored as
+// text sort and compare chronologically.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// SQLiteRepository is a ScoreRepository backed by an embedded SQLite
+// database. It keeps the current score per user and window, and appends
+// every save to a history table that ScoreHistoryRepository reads. It also
+// stores recalculation jobs, ingested actions, score cursors and the outbox
+// of score events.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository opens or creates the database at dbPath and applies any
+// pending schema migrations.
+func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
+	if dir := filepath.Dir(dbPath); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	dsn := "file:" + dbPath + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows a single writer; one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(context.Background(), db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &SQLiteRepository{db: db}, nil
+}
+
+// Save stores or updates the score for a given user and window, records it
+// in the score history and, if the score changed, adds a score event to the
+// outbox, all in one transaction.
+func (r *SQLiteRepository) Save(ctx context.Context, score domain.UserScore) error {
+	window := score.WindowKey()
+	recordedAt := score.CalculatedAt
+	if recordedAt.IsZero() {
+		recordedAt = time.Now()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	previous := domain.UserScore{UserID: score.UserID, Window: window}
+	err = tx.QueryRowContext(ctx, `SELECT score FROM scores WHERE user_id = ? AND window_key = ?`,
+		score.UserID, window).Scan(&previous.Score)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read score: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO scores (user_id, window_key, score, rule_version, streak, updated_at, calculated_at, request_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, window_key) DO UPDATE SET
+			score = excluded.score,
+			rule_version = excluded.rule_version,
+			streak = excluded.streak,
+			updated_at = excluded.updated_at,
+			calculated_at = excluded.calculated_at,
+			request_id = excluded.request_id`,
+		score.UserID, window, score.Score, score.RuleVersion, score.Streak,
+		formatTime(time.Now()), formatTime(score.CalculatedAt), score.RequestID)
+	if err != nil {
+		return fmt.Errorf("failed to save score: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO score_history (user_id, window_key, score, rule_version, streak, recorded_at, request_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		score.UserID, window, score.Score, score.RuleVersion, score.Streak, formatTime(recordedAt), score.RequestID)
+	if err != nil {
+		return fmt.Errorf("failed to record score history: %w", err)
+	}
+
+	if event, changed := domain.NewScoreChanged(previous, score, recordedAt); changed {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO score_events (user_id, window_key, old_score, new_score, delta, rule_version, changed_at, request_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			event.UserID, event.Window, event.OldScore, event.NewScore, event.Delta, event.RuleVersion,
+			formatTime(event.ChangedAt), event.RequestID)
+		if err != nil {
+			return fmt.Errorf("failed to record score event: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get retrieves the all-time score for a given user.
+func (r *SQLiteRepository) Get(ctx context.Context, userID string) (domain.UserScore, bool, error) {
+	return r.GetWindow(ctx, userID, domain.AllTime().Key())
+}
+
+// GetWindow retrieves the score for a given user and window key.
+func (r *SQLiteRepository) GetWindow(ctx context.Context, userID, window string) (domain.UserScore, bool, error) {
+	score := domain.UserScore{UserID: userID, Window: window}
+	var calculatedAt string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT score, rule_version, streak, calculated_at, request_id FROM scores
+		WHERE user_id = ? AND window_key = ?`,
+		userID, window).Scan(&score.Score, &score.RuleVersion, &score.Streak, &calculatedAt, &score.RequestID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.UserScore{}, false, nil
+	}
+	if err != nil {
+		return domain.UserScore{}, false, err
+	}
+	if score.CalculatedAt, err = parseTime(calculatedAt); err != nil {
+		return domain.UserScore{}, false, err
+	}
+	return score, true, nil
+}
+
+// ListWindow returns every score saved for the window key, ordered by user ID.
+func (r *SQLiteRepository) ListWindow(ctx context.Context, window string) ([]domain.UserScore, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, score, rule_version, streak, calculated_at, request_id FROM scores
+		WHERE window_key = ?
+		ORDER BY user_id`, window)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []domain.UserScore
+	for rows.Next() {
+		score := domain.UserScore{Window: window}
+		var calculatedAt string
+		if err := rows.Scan(&score.UserID, &score.Score, &score.RuleVersion, &score.Streak, &calculatedAt, &score.RequestID); err != nil {
+			return nil, err
+		}
+		if score.CalculatedAt, err = parseTime(calculatedAt); err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+	return scores, rows.Err()
+}
+
+// History returns the user's score history entries that match the filter.
+func (r *SQLiteRepository) History(ctx context.Context, userID string, filter usecase.HistoryFilter) ([]domain.ScoreSnapshot, error) {
+	query := `
+		SELECT id, window_key, score, rule_version, streak, recorded_at, request_id
+		FROM score_history
+		WHERE user_id = ? AND id > ?`
+	args := []any{userID, filter.AfterSeq}
+	if filter.Window != "" {
+		query += ` AND window_key = ?`
+		args = append(args, filter.Window)
+	}
+	if !filter.From.IsZero() {
+		query += ` AND recorded_at >= ?`
+		args = append(args, formatTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		query += ` AND recorded_at < ?`
+		args = append(args, formatTime(filter.To))
+	}
+	query += ` ORDER BY id`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []domain.ScoreSnapshot
+	for rows.Next() {
+		e := domain.ScoreSnapshot{UserID: userID}
+		var recordedAt string
+		if err := rows.Scan(&e.Seq, &e.Window, &e.Score, &e.RuleVersion, &e.Streak, &recordedAt, &e.RequestID); err != nil {
+			return nil, err
+		}
+		if e.RecordedAt, err = parseTime(recordedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ListUserIDs returns every user with a stored score, sorted.
+func (r *SQLiteRepository) ListUserIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT user_id FROM scores ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// SaveJob creates or replaces the job with the same ID.
+func (r *SQLiteRepository) SaveJob(ctx context.Context, job domain.Job) error {
+	data, err := json.Marshal(newJobRecord(job))
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO jobs (id, status, created_at, data)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			data = excluded.data`,
+		job.ID, string(job.Status), formatTime(job.CreatedAt), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// GetJob returns the job with the ID.
+func (r *SQLiteRepository) GetJob(ctx context.Context, id string) (domain.Job, error) {
+	var data string
+	err := r.db.QueryRowContext(ctx, `SELECT data FROM jobs WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Job{}, usecase.ErrJobNotFound
+	}
+	if err != nil {
+		return domain.Job{}, err
+	}
+	return decodeJob(data)
+}
+
+// ListUnfinishedJobs returns the queued and running jobs, oldest first.
+func (r *SQLiteRepository) ListUnfinishedJobs(ctx context.Context) ([]domain.Job, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT data FROM jobs
+		WHERE status IN (?, ?)
+		ORDER BY created_at, id`,
+		string(domain.JobQueued), string(domain.JobRunning))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []domain.Job
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		job, err := decodeJob(data)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// SaveRuleSet creates or replaces the rule set of the same version.
+func (r *SQLiteRepository) SaveRuleSet(ctx context.Context, ruleSet *usecase.RuleSet) error {
+	data, err := json.Marshal(newRuleSetRecord(ruleSet))
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO rule_sets (version, data) VALUES (?, ?)
+		ON CONFLICT (version) DO UPDATE SET data = excluded.data`,
+		ruleSet.Version, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save rule set: %w", err)
+	}
+	return nil
+}
+
+// GetRuleSet returns the rule set of the version.
+func (r *SQLiteRepository) GetRuleSet(ctx context.Context, version int) (*usecase.RuleSet, error) {
+	var data string
+	err := r.db.QueryRowContext(ctx, `SELECT data FROM rule_sets WHERE version = ?`, version).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecase.ErrRuleSetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRuleSet([]byte(data))
+}
+
+// LatestRuleSet returns the rule set of the highest version.
+func (r *SQLiteRepository) LatestRuleSet(ctx context.Context) (*usecase.RuleSet, error) {
+	var data string
+	err := r.db.QueryRowContext(ctx, `SELECT data FROM rule_sets ORDER BY version DESC LIMIT 1`).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecase.ErrRuleSetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRuleSet([]byte(data))
+}
+
+// AppendActions stores the events whose action ID the user does not have
+// yet and returns them.
+func (r *SQLiteRepository) AppendActions(ctx context.Context, events []domain.ActionEvent) ([]domain.ActionEvent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var fresh []domain.ActionEvent
+	for _, event := range events {
+		metadata, err := encodeMetadata(event.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO actions (user_id, action_id, type, amount, occurred_at, metadata)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (user_id, action_id) DO NOTHING`,
+			event.UserID, event.ID, event.Type, event.Amount, formatTime(event.OccurredAt), metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to append action: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n > 0 {
+			fresh = append(fresh, event)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return fresh, nil
+}
+
+// GetActions returns the user's ingested actions in the order they arrived.
+func (r *SQLiteRepository) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	actions, _, err := r.queryActions(ctx, `
+		SELECT seq, action_id, type, amount, occurred_at, metadata FROM actions
+		WHERE user_id = ?
+		ORDER BY seq`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if actions == nil {
+		return nil, usecase.ErrUserNotFound
+	}
+	return actions, nil
+}
+
+// ActionsAfter returns the user's ingested actions after the position, which
+// is the seq of the action before them.
+func (r *SQLiteRepository) ActionsAfter(ctx context.Context, userID string, position int64) ([]domain.UserAction, int64, error) {
+	actions, last, err := r.queryActions(ctx, `
+		SELECT seq, action_id, type, amount, occurred_at, metadata FROM actions
+		WHERE user_id = ? AND seq > ?
+		ORDER BY seq`, userID, position)
+	if err != nil {
+		return nil, 0, err
+	}
+	if actions == nil {
+		return nil, position, nil
+	}
+	return actions, last, nil
+}
+
+// queryActions runs a query for the seq, action_id, type, amount,
+// occurred_at and metadata of actions, and returns them with the last seq.
+func (r *SQLiteRepository) queryActions(ctx context.Context, query string, args ...any) ([]domain.UserAction, int64, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var actions []domain.UserAction
+	var seq int64
+	for rows.Next() {
+		var action domain.UserAction
+		var occurredAt, metadata string
+		if err := rows.Scan(&seq, &action.ID, &action.Type, &action.Amount, &occurredAt, &metadata); err != nil {
+			return nil, 0, err
+		}
+		if action.OccurredAt, err = parseTime(occurredAt); err != nil {
+			return nil, 0, err
+		}
+		if metadata != "" {
+			if err := json.Unmarshal([]byte(metadata), &action.Metadata); err != nil {
+				return nil, 0, fmt.Errorf("failed to decode action metadata: %w", err)
+			}
+		}
+		actions = append(actions, action)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return actions, seq, nil
+}
+
+// GetScoreCursor returns the user's score cursor.
+func (r *SQLiteRepository) GetScoreCursor(ctx context.Context, userID string) (domain.ScoreCursor, error) {
+	cursor := domain.ScoreCursor{UserID: userID}
+	var updatedAt string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT position, score, rule_version, updated_at FROM score_cursors
+		WHERE user_id = ?`, userID).
+		Scan(&cursor.Position, &cursor.Score, &cursor.RuleVersion, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ScoreCursor{}, usecase.ErrScoreCursorNotFound
+	}
+	if err != nil {
+		return domain.ScoreCursor{}, err
+	}
+	if cursor.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return domain.ScoreCursor{}, err
+	}
+	return cursor, nil
+}
+
+// SaveScoreCursor creates or replaces the cursor of the same user and adds
+// the applied action IDs to the user's processed actions.
+func (r *SQLiteRepository) SaveScoreCursor(ctx context.Context, cursor domain.ScoreCursor, applied []string) error {
+	return r.saveScoreCursor(ctx, cursor, applied, false)
+}
+
+// ResetScoreCursor creates or replaces the cursor of the same user and
+// replaces the user's processed actions.
+func (r *SQLiteRepository) ResetScoreCursor(ctx context.Context, cursor domain.ScoreCursor, processed []string) error {
+	return r.saveScoreCursor(ctx, cursor, processed, true)
+}
+
+func (r *SQLiteRepository) saveScoreCursor(ctx context.Context, cursor domain.ScoreCursor, ids []string, reset bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO score_cursors (user_id, position, score, rule_version, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			position = excluded.position,
+			score = excluded.score,
+			rule_version = excluded.rule_version,
+			updated_at = excluded.updated_at`,
+		cursor.UserID, cursor.Position, cursor.Score, cursor.RuleVersion, formatTime(cursor.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save score cursor: %w", err)
+	}
+
+	// Replacing only what changed keeps when each action was processed
+	added, removed := ids, []string(nil)
+	if reset {
+		current, err := processedActionsOf(ctx, tx, cursor.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to read processed actions: %w", err)
+		}
+		added, removed = current.diff(cursor.UserID, ids)
+	}
+	for _, id := range removed {
+		_, err := tx.ExecContext(ctx, `DELETE FROM processed_actions WHERE user_id = ? AND action_id = ?`, cursor.UserID, id)
+		if err != nil {
+			return fmt.Errorf("failed to remove processed action: %w", err)
+		}
+	}
+	for _, id := range added {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO processed_actions (user_id, action_id, processed_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT (user_id, action_id) DO NOTHING`,
+			cursor.UserID, id, formatTime(cursor.UpdatedAt))
+		if err != nil {
+			return fmt.Errorf("failed to save processed action: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// processedActionsOf reads every processed action ID of the user.
+func processedActionsOf(ctx context.Context, tx *sql.Tx, userID string) (processedActions, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT action_id FROM processed_actions WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	processed := make(processedActions)
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	processed.add(userID, ids)
+	return processed, rows.Err()
+}
+
+// processedActionsBatch bounds the IDs looked up by one query, well below
+// SQLite's limit on query parameters.
+const processedActionsBatch = 500
+
+// ProcessedActions returns which of the IDs are among the user's processed
+// actions.
+func (r *SQLiteRepository) ProcessedActions(ctx context.Context, userID string, ids []string) ([]string, error) {
+	var found []string
+	for batch := range slices.Chunk(ids, processedActionsBatch) {
+		args := make([]any, 0, len(batch)+1)
+		args = append(args, userID)
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		rows, err := r.db.QueryContext(ctx, `
+			SELECT action_id FROM processed_actions
+			WHERE user_id = ? AND action_id IN (?`+strings.Repeat(", ?", len(batch)-1)+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			found = append(found, id)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// ListScoreCursors returns every score cursor, ordered by user ID.
+func (r *SQLiteRepository) ListScoreCursors(ctx context.Context) ([]domain.ScoreCursor, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, position, score, rule_version, updated_at FROM score_cursors
+		ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cursors []domain.ScoreCursor
+	for rows.Next() {
+		var cursor domain.ScoreCursor
+		var updatedAt string
+		if err := rows.Scan(&cursor.UserID, &cursor.Position, &cursor.Score, &cursor.RuleVersion, &updatedAt); err != nil {
+			return nil, err
+		}
+		if cursor.UpdatedAt, err = parseTime(updatedAt); err != nil {
+			return nil, err
+		}
+		cursors = append(cursors, cursor)
+	}
+	return cursors, rows.Err()
+}
+
+// PendingScoreEvents returns up to limit unacknowledged score events, oldest
+// first. A limit of zero returns all of them.
+func (r *SQLiteRepository) PendingScoreEvents(ctx context.Context, limit int) ([]domain.ScoreChanged, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, window_key, old_score, new_score, delta, rule_version, changed_at, request_id
+		FROM score_events
+		ORDER BY id
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.ScoreChanged
+	for rows.Next() {
+		var e domain.ScoreChanged
+		var changedAt string
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Window, &e.OldScore, &e.NewScore, &e.Delta, &e.RuleVersion, &changedAt, &e.RequestID); err != nil {
+			return nil, err
+		}
+		if e.ChangedAt, err = parseTime(changedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// AckScoreEvents deletes the score events with IDs up to throughID.
+func (r *SQLiteRepository) AckScoreEvents(ctx context.Context, throughID int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM score_events WHERE id <= ?`, throughID); err != nil {
+		return fmt.Errorf("failed to acknowledge score events: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// formatTime encodes t for storage. The zero time is stored as "".
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func decodeJob(data string) (domain.Job, error) {
+	var rec jobRecord
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return domain.Job{}, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return rec.job(), nil
+}
+
+// encodeMetadata encodes action metadata for storage. Empty metadata is
+// stored as "".
+func encodeMetadata(m map[string]string) (string, error) {
+	if len(m) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(sqliteTimeLayout, s)
+}
+
+// migrate applies the embedded migrations that are newer than the schema
+// version recorded in the database, each in its own transaction. Migration
+// files are named <version>_<description>.sql.
+func migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			applied_at TEXT NOT NULL
+		)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		base := path.Base(name)
+		prefix, _, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("invalid migration name %q", base)
+		}
+		if version <= current {
+			continue
+		}
+
+		script, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := applyMigration(ctx, db, version, string(script)); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", base, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, version int, script string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+		version, time.Now().UTC().Format(time.RFC3339Nano)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
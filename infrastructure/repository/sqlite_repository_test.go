@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+	"scoreapp/infrastructure/repository/repotest"
+)
+
+func openSQLiteRepository(t *testing.T, dbPath string) *SQLiteRepository {
+	t.Helper()
+	repo, err := NewSQLiteRepository(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+func newSQLiteRepository(t *testing.T) *SQLiteRepository {
+	t.Helper()
+	return openSQLiteRepository(t, filepath.Join(t.TempDir(), "scores.db"))
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return newSQLiteRepository(t)
+	})
+}
+
+func TestSQLiteRepository_SurvivesRestart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data", "scores.db")
+
+	repo, err := NewSQLiteRepository(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(context.Background(), domain.UserScore{UserID: "user", Score: 42, RuleVersion: 3, Window: "all_time"}))
+	require.NoError(t, repo.Close())
+
+	// Reopening runs the migrations again, which must be a no-op
+	reopened := openSQLiteRepository(t, dbPath)
+
+	score, exists := getScore(t, reopened, "user")
+	assert.True(t, exists)
+	assert.Equal(t, int64(42), score.Score)
+	assert.Equal(t, 3, score.RuleVersion)
+
+	var versions int
+	require.NoError(t, reopened.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&versions))
+	assert.Equal(t, 9, versions)
+}
+
+func TestSQLiteRepository_RecordsHistory(t *testing.T) {
+	repo := newSQLiteRepository(t)
+
+	for _, s := range []int64{10, 20, 30} {
+		require.NoError(t, repo.Save(context.Background(), domain.UserScore{UserID: "user", Score: s}))
+	}
+
+	rows, err := repo.db.Query(`SELECT score FROM score_history WHERE user_id = ? ORDER BY id`, "user")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var history []int64
+	for rows.Next() {
+		var s int64
+		require.NoError(t, rows.Scan(&s))
+		history = append(history, s)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []int64{10, 20, 30}, history)
+}
+
+func TestSQLiteRepository_GetWindowFails(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	require.NoError(t, repo.Save(context.Background(), domain.UserScore{UserID: "user", Score: 10, Window: "all_time"}))
+	_, err := repo.db.Exec(`UPDATE scores SET calculated_at = 'yesterday' WHERE user_id = ?`, "user")
+	require.NoError(t, err)
+
+	// An unreadable score is an error, not a missing score
+	_, exists, err := repo.GetWindow(context.Background(), "user", "all_time")
+	assert.Error(t, err)
+	assert.False(t, exists)
+	_, exists, err = repo.Get(context.Background(), "user")
+	assert.Error(t, err)
+	assert.False(t, exists)
+
+	_, exists, err = repo.GetWindow(context.Background(), "nobody", "all_time")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestSQLiteRepository_Schema(t *testing.T) {
+	repo := newSQLiteRepository(t)
+
+	for _, table := range []string{"scores", "score_history", "processed_actions", "jobs", "actions", "score_cursors", "score_events", "rule_sets"} {
+		var name string
+		err := repo.db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&name)
+		assert.NoError(t, err, table)
+	}
+}
+
+func TestSQLiteRepository_JobsSurviveRestart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "scores.db")
+	createdAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	job := domain.Job{ID: "job1", Status: domain.JobRunning, Window: "all_time", UserIDs: []string{"alice", "bob"}, Processed: 1, Succeeded: 1, CreatedAt: createdAt}
+
+	repo, err := NewSQLiteRepository(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveJob(context.Background(), job))
+	require.NoError(t, repo.Close())
+
+	reopened := openSQLiteRepository(t, dbPath)
+
+	unfinished, err := reopened.ListUnfinishedJobs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Job{job}, unfinished)
+}
+
+func TestSQLiteRepository_ActionsSurviveRestart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "scores.db")
+	occurredAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	events := []domain.ActionEvent{
+		{UserID: "alice", UserAction: domain.UserAction{ID: "a1", Type: "login", Amount: 1, OccurredAt: occurredAt}},
+		{UserID: "alice", UserAction: domain.UserAction{ID: "a2", Type: "quiz_answer", Amount: 3, OccurredAt: occurredAt, Metadata: map[string]string{"quiz": "daily"}}},
+	}
+
+	repo, err := NewSQLiteRepository(dbPath)
+	require.NoError(t, err)
+	_, err = repo.AppendActions(context.Background(), events)
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	reopened := openSQLiteRepository(t, dbPath)
+
+	actions, err := reopened.GetActions(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAction{events[0].UserAction, events[1].UserAction}, actions)
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"scoreapp/domain"
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+)
+
+// ActionIngester defines the interface for storing ingested actions.
+type ActionIngester interface {
+	Ingest(ctx context.Context, events []domain.ActionEvent) (usecase.IngestResult, error)
+}
+
+// ActionsHandler exposes HTTP endpoints for ingesting actions.
+type ActionsHandler struct {
+	ingester ActionIngester
+}
+
+// NewActionsHandler creates a new ActionsHandler.
+func NewActionsHandler(i ActionIngester) *ActionsHandler {
+	return &ActionsHandler{
+		ingester: i,
+	}
+}
+
+// Handle handles POST /actions with a single action or an array of actions
+// as the body. A batch is stored whole or not at all, and actions whose ID
+// the user already has are ignored, so a batch can safely be sent again.
+//
+// swagger:route POST /actions actions ingestActions
+//
+// Ingest user actions
+//
+//	Responses:
+//	  200: ingestResponse
+//	  400: errorResponse
+//	  405: errorResponse
+//	  413: errorResponse
+//	  499: errorResponse
+//	  500: errorResponse
+func (h *ActionsHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "request too large"})
+			return
+		}
+		writeBadRequest(w, errors.New("failed to read body"))
+		return
+	}
+	requests, err := decodeActions(body)
+	if err != nil {
+		writeBadRequest(w, errors.New("body must be an action or an array of actions"))
+		return
+	}
+
+	events := make([]domain.ActionEvent, len(requests))
+	for i, req := range requests {
+		events[i] = domain.ActionEvent{
+			UserID: req.UserID,
+			UserAction: domain.UserAction{
+				ID:         req.ID,
+				Type:       req.Type,
+				Amount:     req.Amount,
+				OccurredAt: req.OccurredAt,
+				Metadata:   req.Metadata,
+			},
+		}
+	}
+
+	result, err := h.ingester.Ingest(r.Context(), events)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidAction) {
+			writeBadRequest(w, err)
+			return
+		}
+		writeScoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(models.IngestResponse{Accepted: result.Accepted, Duplicates: result.Duplicates})
+}
+
+// decodeActions decodes a single action or an array of actions.
+func decodeActions(body []byte) ([]models.ActionRequest, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+
+	var requests []models.ActionRequest
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := decoder.Decode(&requests); err != nil {
+			return nil, err
+		}
+	} else {
+		var req models.ActionRequest
+		if err := decoder.Decode(&req); err != nil {
+			return nil, err
+		}
+		requests = []models.ActionRequest{req}
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the actions")
+	}
+	return requests, nil
+}
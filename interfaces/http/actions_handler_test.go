@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockActionIngester is a mock for ActionIngester.
+type MockActionIngester struct {
+	mock.Mock
+}
+
+func (m *MockActionIngester) Ingest(ctx context.Context, events []domain.ActionEvent) (usecase.IngestResult, error) {
+	args := m.Called(ctx, events)
+	return args.Get(0).(usecase.IngestResult), args.Error(1)
+}
+
+var actionOccurredAt = time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+func TestIngestActions_Single(t *testing.T) {
+	mockIngester := new(MockActionIngester)
+	handler := NewActionsHandler(mockIngester)
+
+	mockIngester.On("Ingest", mock.Anything, []domain.ActionEvent{{
+		UserID: "alice",
+		UserAction: domain.UserAction{
+			ID:         "a1",
+			Type:       "quiz_answer",
+			Amount:     3,
+			OccurredAt: actionOccurredAt,
+			Metadata:   map[string]string{"quiz": "daily"},
+		},
+	}}).Return(usecase.IngestResult{Accepted: 1}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/actions", strings.NewReader(`{
+		"user_id": "alice",
+		"id": "a1",
+		"type": "quiz_answer",
+		"amount": 3,
+		"occurred_at": "2025-06-02T08:00:00Z",
+		"metadata": {"quiz": "daily"}
+	}`))
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"accepted":1,"duplicates":0}`, w.Body.String())
+	mockIngester.AssertExpectations(t)
+}
+
+func TestIngestActions_Batch(t *testing.T) {
+	mockIngester := new(MockActionIngester)
+	handler := NewActionsHandler(mockIngester)
+
+	mockIngester.On("Ingest", mock.Anything, []domain.ActionEvent{
+		{UserID: "alice", UserAction: domain.UserAction{ID: "a1", Type: "login", Amount: 1, OccurredAt: actionOccurredAt}},
+		{UserID: "bob", UserAction: domain.UserAction{ID: "b1", Type: "login", Amount: 1, OccurredAt: actionOccurredAt}},
+	}).Return(usecase.IngestResult{Accepted: 1, Duplicates: 1}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/actions", strings.NewReader(` [
+		{"user_id":"alice","id":"a1","type":"login","amount":1,"occurred_at":"2025-06-02T08:00:00Z"},
+		{"user_id":"bob","id":"b1","type":"login","amount":1,"occurred_at":"2025-06-02T08:00:00Z"}
+	]`))
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"accepted":1,"duplicates":1}`, w.Body.String())
+	mockIngester.AssertExpectations(t)
+}
+
+func TestIngestActions_InvalidBody(t *testing.T) {
+	for _, body := range []string{
+		``,
+		`{"user_id":"alice"`,
+		`{"user_id":"alice","points":3}`,
+		`[{"user_id":"alice","amount":"1"}]`,
+		`{"user_id":"alice"} {"user_id":"bob"}`,
+		`"alice"`,
+	} {
+		t.Run(body, func(t *testing.T) {
+			mockIngester := new(MockActionIngester)
+			handler := NewActionsHandler(mockIngester)
+
+			req := httptest.NewRequest(http.MethodPost, "/actions", strings.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.JSONEq(t, `{"error":"body must be an action or an array of actions"}`, w.Body.String())
+			mockIngester.AssertNotCalled(t, "Ingest", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestIngestActions_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantError  string
+	}{
+		{"invalid action", fmt.Errorf(`%w: actions[0]: unknown type "referral"`, usecase.ErrInvalidAction), http.StatusBadRequest, `invalid action: actions[0]: unknown type \"referral\"`},
+		{"client gone", context.Canceled, 499, "request canceled"},
+		{"store failure", errors.New("disk full"), http.StatusInternalServerError, "disk full"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockIngester := new(MockActionIngester)
+			handler := NewActionsHandler(mockIngester)
+			mockIngester.On("Ingest", mock.Anything, mock.Anything).Return(usecase.IngestResult{}, tt.err)
+
+			req := httptest.NewRequest(http.MethodPost, "/actions", strings.NewReader(`{"user_id":"alice","id":"a1","type":"referral","amount":1}`))
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.JSONEq(t, `{"error":"`+tt.wantError+`"}`, w.Body.String())
+		})
+	}
+}
+
+func TestIngestActions_TooLarge(t *testing.T) {
+	mockIngester := new(MockActionIngester)
+	handler := NewActionsHandler(mockIngester)
+
+	body := `[` + strings.Repeat(`{"user_id":"alice","id":"a1","type":"login","amount":1},`, maxBatchBodySize/50) + `{}]`
+	req := httptest.NewRequest(http.MethodPost, "/actions", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"error":"request too large"}`, w.Body.String())
+	mockIngester.AssertNotCalled(t, "Ingest", mock.Anything, mock.Anything)
+}
+
+func TestIngestActions_MethodNotAllowed(t *testing.T) {
+	handler := NewActionsHandler(new(MockActionIngester))
+
+	req := httptest.NewRequest(http.MethodGet, "/actions", nil)
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"scoreapp/domain"
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+)
+
+// maxBatchBodySize bounds the size of a batch request body.
+const maxBatchBodySize = 1 << 20
+
+// BatchCalculator defines the interface for calculating scores in batches.
+type BatchCalculator interface {
+	CalculateBatch(ctx context.Context, userIDs []string, window domain.Window) ([]usecase.BatchResult, error)
+}
+
+// BatchHandler exposes the HTTP endpoint for batch score calculation.
+type BatchHandler struct {
+	calculator BatchCalculator
+}
+
+// NewBatchHandler creates a new BatchHandler.
+func NewBatchHandler(c BatchCalculator) *BatchHandler {
+	return &BatchHandler{
+		calculator: c,
+	}
+}
+
+// Handle handles POST /scores/calculate:batch[?window=<kind>&days=<n>&tz=<zone>]
+// with a JSON array of user IDs as the body. Each user is calculated and
+// saved independently. The response is 200 when every user succeeded and 207
+// when some failed; each result carries the status its user would have had
+// from /scores/calculate.
+//
+// swagger:route POST /scores/calculate:batch scores calculateScoreBatch
+//
+// Calculate the scores of several users at once
+//
+//	Parameters:
+//	  + name: window
+//	    in: query
+//	    description: Scoring window
+//	    required: false
+//	    type: string
+//	    enum: all_time, rolling, day, week, month, season
+//	    default: all_time
+//	  + name: days
+//	    in: query
+//	    description: Length of a rolling window in days
+//	    required: false
+//	    type: integer
+//	  + name: tz
+//	    in: query
+//	    description: IANA timezone calendar windows are aligned to, defaults to the server's configured timezone
+//	    required: false
+//	    type: string
+//
+//	Responses:
+//	  200: batchResponse
+//	  207: batchResponse
+//	  400: errorResponse
+//	  405: errorResponse
+//	  413: errorResponse
+//	  499: errorResponse
+func (h *BatchHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	window, err := parseWindow(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	var userIDs []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&userIDs); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "batch too large"})
+			return
+		}
+		writeBadRequest(w, errors.New("body must be a JSON array of user IDs"))
+		return
+	}
+
+	results, err := h.calculator.CalculateBatch(r.Context(), userIDs, window)
+	if err != nil {
+		writeBatchError(w, err)
+		return
+	}
+
+	response := toBatchResponse(results)
+	status := http.StatusOK
+	if response.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// writeBatchError maps an error rejecting a whole batch to an HTTP response.
+func writeBatchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidBatch):
+		writeBadRequest(w, err)
+	case errors.Is(err, usecase.ErrBatchTooLarge):
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+	default:
+		writeScoreError(w, err)
+	}
+}
+
+func toBatchResponse(results []usecase.BatchResult) models.BatchResponse {
+	response := models.BatchResponse{
+		Results: make([]models.BatchResult, 0, len(results)),
+	}
+	for _, result := range results {
+		item := models.BatchResult{UserID: result.UserID}
+		if result.Err != nil {
+			item.Status, item.Error = scoreError(result.Err)
+			response.Failed++
+		} else {
+			score := toScoreResponse(result.Score)
+			item.Status = http.StatusOK
+			item.Score = &score
+			response.Succeeded++
+		}
+		response.Results = append(response.Results, item)
+	}
+	return response
+}
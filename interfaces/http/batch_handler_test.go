@@ -0,0 +1,183 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"scoreapp/domain"
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockBatchCalculator is a mock for BatchCalculator.
+type MockBatchCalculator struct {
+	mock.Mock
+}
+
+func (m *MockBatchCalculator) CalculateBatch(ctx context.Context, userIDs []string, window domain.Window) ([]usecase.BatchResult, error) {
+	args := m.Called(ctx, userIDs, window)
+	return args.Get(0).([]usecase.BatchResult), args.Error(1)
+}
+
+func TestBatch_AllSucceeded(t *testing.T) {
+	mockCalculator := new(MockBatchCalculator)
+	handler := NewBatchHandler(mockCalculator)
+
+	mockCalculator.On("CalculateBatch", mock.Anything, []string{"alice", "bob"}, domain.AllTime()).Return([]usecase.BatchResult{
+		{UserID: "alice", Score: domain.UserScore{UserID: "alice", Score: 10, Window: "all_time"}},
+		{UserID: "bob", Score: domain.UserScore{UserID: "bob", Score: 20, Window: "all_time"}},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/calculate:batch", strings.NewReader(`["alice","bob"]`))
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"results": [
+			{"user_id": "alice", "status": 200, "score": {"user_id": "alice", "score": 10, "window": "all_time", "rule_version": 0, "streak": 0}},
+			{"user_id": "bob", "status": 200, "score": {"user_id": "bob", "score": 20, "window": "all_time", "rule_version": 0, "streak": 0}}
+		],
+		"succeeded": 2,
+		"failed": 0
+	}`, w.Body.String())
+	mockCalculator.AssertExpectations(t)
+}
+
+func TestBatch_PartialSuccess(t *testing.T) {
+	mockCalculator := new(MockBatchCalculator)
+	handler := NewBatchHandler(mockCalculator)
+
+	mockCalculator.On("CalculateBatch", mock.Anything, []string{"alice", "nobody", "late"}, mock.MatchedBy(func(w domain.Window) bool {
+		return w.Key() == "week@UTC"
+	})).Return([]usecase.BatchResult{
+		{UserID: "alice", Score: domain.UserScore{UserID: "alice", Score: 10, Window: "week@UTC"}},
+		{UserID: "nobody", Err: fmt.Errorf("failed to get actions: %w", usecase.ErrUserNotFound)},
+		{UserID: "late", Err: context.DeadlineExceeded},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/calculate:batch?window=week&tz=UTC", strings.NewReader(`["alice","nobody","late"]`))
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+	var response models.BatchResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, 1, response.Succeeded)
+	assert.Equal(t, 2, response.Failed)
+	require.Len(t, response.Results, 3)
+	assert.Equal(t, http.StatusOK, response.Results[0].Status)
+	assert.Equal(t, int64(10), response.Results[0].Score.Score)
+	assert.Equal(t, models.BatchResult{UserID: "nobody", Status: http.StatusNotFound, Error: "user not found"}, response.Results[1])
+	assert.Equal(t, http.StatusGatewayTimeout, response.Results[2].Status)
+	assert.Nil(t, response.Results[2].Score)
+}
+
+func TestBatch_RejectedBatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"invalid batch", fmt.Errorf("%w: duplicate user ID %q", usecase.ErrInvalidBatch, "alice"), http.StatusBadRequest},
+		{"too large", fmt.Errorf("%w: 3 user IDs, at most 2 allowed", usecase.ErrBatchTooLarge), http.StatusRequestEntityTooLarge},
+		{"client gone", context.Canceled, StatusClientClosedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCalculator := new(MockBatchCalculator)
+			handler := NewBatchHandler(mockCalculator)
+
+			mockCalculator.On("CalculateBatch", mock.Anything, mock.Anything, mock.Anything).Return([]usecase.BatchResult(nil), tt.err)
+
+			req := httptest.NewRequest(http.MethodPost, "/scores/calculate:batch", strings.NewReader(`["alice","alice"]`))
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestBatch_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"not an array", "/scores/calculate:batch", `{"user_ids":["alice"]}`, http.StatusBadRequest, "body must be a JSON array of user IDs"},
+		{"malformed JSON", "/scores/calculate:batch", `["alice"`, http.StatusBadRequest, "body must be a JSON array of user IDs"},
+		{"invalid window", "/scores/calculate:batch?window=rolling", `["alice"]`, http.StatusBadRequest, "invalid window: rolling window needs a positive number of days"},
+		{"oversized body", "/scores/calculate:batch", `["` + strings.Repeat("a", maxBatchBodySize) + `"]`, http.StatusRequestEntityTooLarge, "batch too large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCalculator := new(MockBatchCalculator)
+			handler := NewBatchHandler(mockCalculator)
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response models.ErrorResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			assert.Equal(t, tt.expectedError, response.Error)
+			assert.Empty(t, mockCalculator.Calls)
+		})
+	}
+}
+
+func TestBatch_MethodNotAllowed(t *testing.T) {
+	mockCalculator := new(MockBatchCalculator)
+	handler := NewBatchHandler(mockCalculator)
+
+	req := httptest.NewRequest(http.MethodGet, "/scores/calculate:batch", nil)
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Empty(t, mockCalculator.Calls)
+}
+
+func TestBatch_Routing(t *testing.T) {
+	mockCalculator := new(MockBatchCalculator)
+	mockQuery := new(MockScoreQuery)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scores/calculate:batch", NewBatchHandler(mockCalculator).Handle)
+	mux.HandleFunc("/scores/{user_id}", NewScoreQueryHandler(mockQuery).Handle)
+
+	mockCalculator.On("CalculateBatch", mock.Anything, []string{"alice"}, mock.Anything).
+		Return([]usecase.BatchResult{{UserID: "alice", Err: errors.New("boom")}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/calculate:batch", strings.NewReader(`["alice"]`))
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	// The batch route wins over the user ID pattern
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	mockQuery.AssertNotCalled(t, "Get", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+)
+
+// ScoreHistory defines the interface for reading a user's score history.
+type ScoreHistory interface {
+	List(ctx context.Context, userID string, q usecase.HistoryQuery) (usecase.HistoryPage, error)
+}
+
+// HistoryHandler exposes HTTP endpoints for score history.
+type HistoryHandler struct {
+	history ScoreHistory
+}
+
+// NewHistoryHandler creates a new HistoryHandler.
+func NewHistoryHandler(h ScoreHistory) *HistoryHandler {
+	return &HistoryHandler{
+		history: h,
+	}
+}
+
+// Handle handles GET /scores/{user_id}/history[?window=<key>&from=<time>&to=<time>&cursor=<cursor>&limit=<n>].
+//
+// swagger:route GET /scores/{user_id}/history scores getScoreHistory
+//
+// List the scores saved for a user, oldest first
+//
+//	Parameters:
+//	  + name: user_id
+//	    in: path
+//	    description: The ID of the user
+//	    required: true
+//	    type: string
+//	  + name: window
+//	    in: query
+//	    description: Only list scores of this window key, as reported in score responses
+//	    required: false
+//	    type: string
+//	  + name: from
+//	    in: query
+//	    description: Only list scores recorded at or after this RFC 3339 time
+//	    required: false
+//	    type: string
+//	    format: date-time
+//	  + name: to
+//	    in: query
+//	    description: Only list scores recorded before this RFC 3339 time
+//	    required: false
+//	    type: string
+//	    format: date-time
+//	  + name: cursor
+//	    in: query
+//	    description: The next_cursor of the previous page
+//	    required: false
+//	    type: string
+//	  + name: limit
+//	    in: query
+//	    description: Maximum number of entries per page, between 1 and 500
+//	    required: false
+//	    type: integer
+//	    default: 50
+//
+//	Responses:
+//	  200: historyResponse
+//	  400: errorResponse
+//	  405: errorResponse
+//	  499: errorResponse
+//	  500: errorResponse
+//	  504: errorResponse
+func (h *HistoryHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	userID := r.PathValue("user_id")
+	query, err := historyQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	page, err := h.history.List(r.Context(), userID, query)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidHistoryQuery) {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeScoreError(w, err)
+		return
+	}
+
+	entries := make([]models.HistoryEntry, 0, len(page.Entries))
+	for _, e := range page.Entries {
+		entries = append(entries, models.HistoryEntry{
+			Window:      e.Window,
+			Score:       e.Score,
+			RuleVersion: e.RuleVersion,
+			Streak:      e.Streak,
+			RecordedAt:  e.RecordedAt,
+			RequestID:   e.RequestID,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(models.HistoryResponse{
+		UserID:     userID,
+		Entries:    entries,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// historyQuery builds a history query from the query parameters.
+func historyQuery(r *http.Request) (usecase.HistoryQuery, error) {
+	q := r.URL.Query()
+	query := usecase.HistoryQuery{
+		Window: q.Get("window"),
+		Cursor: q.Get("cursor"),
+	}
+
+	var err error
+	if query.From, err = parseTimeParam(q.Get("from"), "from"); err != nil {
+		return usecase.HistoryQuery{}, err
+	}
+	if query.To, err = parseTimeParam(q.Get("to"), "to"); err != nil {
+		return usecase.HistoryQuery{}, err
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return usecase.HistoryQuery{}, errors.New("limit must be an integer")
+		}
+		query.Limit = n
+	}
+
+	return query, nil
+}
+
+// parseTimeParam parses an optional RFC 3339 query parameter.
+func parseTimeParam(v, name string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, errors.New(name + " must be an RFC 3339 time")
+	}
+	return t, nil
+}
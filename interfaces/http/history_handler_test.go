@@ -0,0 +1,170 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockScoreHistory is a mock for ScoreHistory.
+type MockScoreHistory struct {
+	mock.Mock
+}
+
+func (m *MockScoreHistory) List(ctx context.Context, userID string, q usecase.HistoryQuery) (usecase.HistoryPage, error) {
+	args := m.Called(ctx, userID, q)
+	return args.Get(0).(usecase.HistoryPage), args.Error(1)
+}
+
+func newHistoryRequest(method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.SetPathValue("user_id", "user")
+	return req
+}
+
+func TestHistory_Success(t *testing.T) {
+	mockHistory := new(MockScoreHistory)
+	handler := NewHistoryHandler(mockHistory)
+
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	to, err := time.Parse(time.RFC3339, "2025-06-08T00:00:00+03:00")
+	assert.NoError(t, err)
+	recordedAt := from.Add(time.Hour)
+
+	mockHistory.On("List", mock.Anything, "user", usecase.HistoryQuery{
+		Window: "week@UTC",
+		From:   from,
+		To:     to,
+		Cursor: "MTI",
+		Limit:  2,
+	}).Return(usecase.HistoryPage{
+		Entries: []domain.ScoreSnapshot{
+			{Seq: 13, UserID: "user", Window: "week@UTC", Score: 10, RuleVersion: 1, RecordedAt: recordedAt, RequestID: "req-1"},
+			{Seq: 14, UserID: "user", Window: "week@UTC", Score: 25, RuleVersion: 2, Streak: 3, RecordedAt: recordedAt.Add(time.Hour)},
+		},
+		NextCursor: "MTQ",
+	}, nil)
+
+	req := newHistoryRequest(http.MethodGet,
+		"/scores/user/history?window=week%40UTC&from=2025-06-01T00:00:00Z&to=2025-06-08T00:00:00%2B03:00&cursor=MTI&limit=2")
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var response models.HistoryResponse
+	err = json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", response.UserID)
+	assert.Equal(t, "MTQ", response.NextCursor)
+	assert.Equal(t, []models.HistoryEntry{
+		{Window: "week@UTC", Score: 10, RuleVersion: 1, RecordedAt: recordedAt, RequestID: "req-1"},
+		{Window: "week@UTC", Score: 25, RuleVersion: 2, Streak: 3, RecordedAt: recordedAt.Add(time.Hour)},
+	}, response.Entries)
+
+	mockHistory.AssertExpectations(t)
+}
+
+func TestHistory_Empty(t *testing.T) {
+	mockHistory := new(MockScoreHistory)
+	handler := NewHistoryHandler(mockHistory)
+
+	mockHistory.On("List", mock.Anything, "user", usecase.HistoryQuery{}).Return(usecase.HistoryPage{}, nil)
+
+	req := newHistoryRequest(http.MethodGet, "/scores/user/history")
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":"user","entries":[]}`, w.Body.String())
+}
+
+func TestHistory_MethodNotAllowed(t *testing.T) {
+	mockHistory := new(MockScoreHistory)
+	handler := NewHistoryHandler(mockHistory)
+
+	req := newHistoryRequest(http.MethodPost, "/scores/user/history")
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	mockHistory.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHistory_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedError string
+	}{
+		{"malformed from", "from=yesterday", "from must be an RFC 3339 time"},
+		{"malformed to", "to=2025-06-01", "to must be an RFC 3339 time"},
+		{"non-numeric limit", "limit=ten", "limit must be an integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHistory := new(MockScoreHistory)
+			handler := NewHistoryHandler(mockHistory)
+
+			req := newHistoryRequest(http.MethodGet, "/scores/user/history?"+tt.query)
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response models.ErrorResponse
+			err := json.NewDecoder(w.Body).Decode(&response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedError, response.Error)
+
+			mockHistory.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHistory_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{"invalid query", fmt.Errorf("%w: malformed cursor", usecase.ErrInvalidHistoryQuery), http.StatusBadRequest},
+		{"canceled", context.Canceled, StatusClientClosedRequest},
+		{"timeout", context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{"repository error", errors.New("database unavailable"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHistory := new(MockScoreHistory)
+			handler := NewHistoryHandler(mockHistory)
+
+			mockHistory.On("List", mock.Anything, "user", mock.Anything).Return(usecase.HistoryPage{}, tt.err)
+
+			req := newHistoryRequest(http.MethodGet, "/scores/user/history")
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
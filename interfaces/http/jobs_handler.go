@@ -0,0 +1,185 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+)
+
+// Jobs defines the interface for submitting and inspecting recalculation jobs.
+type Jobs interface {
+	Submit(ctx context.Context, req usecase.RecalculationRequest) (domain.Job, error)
+	Get(ctx context.Context, id string) (domain.Job, error)
+}
+
+// JobsHandler exposes HTTP endpoints for recalculation jobs.
+type JobsHandler struct {
+	jobs Jobs
+}
+
+// NewJobsHandler creates a new JobsHandler.
+func NewJobsHandler(j Jobs) *JobsHandler {
+	return &JobsHandler{
+		jobs: j,
+	}
+}
+
+// Recalculate handles POST /jobs/recalculate[?window=<kind>&days=<n>&tz=<zone>]
+// with {"user_ids": [...]} or {"all_users": true} as the body. The job runs
+// in the background; poll the URL in the Location header for its progress.
+//
+// swagger:route POST /jobs/recalculate jobs recalculateScores
+//
+// Recalculate the scores of several users in the background
+//
+//	Parameters:
+//	  + name: window
+//	    in: query
+//	    description: Scoring window
+//	    required: false
+//	    type: string
+//	    enum: all_time, rolling, day, week, month, season
+//	    default: all_time
+//	  + name: days
+//	    in: query
+//	    description: Length of a rolling window in days
+//	    required: false
+//	    type: integer
+//	  + name: tz
+//	    in: query
+//	    description: IANA timezone calendar windows are aligned to, defaults to the server's configured timezone
+//	    required: false
+//	    type: string
+//
+//	Responses:
+//	  202: jobResponse
+//	  400: errorResponse
+//	  405: errorResponse
+//	  413: errorResponse
+//	  500: errorResponse
+func (h *JobsHandler) Recalculate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	window, err := parseWindow(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	var body models.RecalculateRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "request too large"})
+			return
+		}
+		writeBadRequest(w, errors.New(`body must be {"user_ids": [...]} or {"all_users": true}`))
+		return
+	}
+
+	job, err := h.jobs.Submit(r.Context(), usecase.RecalculationRequest{
+		UserIDs:  body.UserIDs,
+		AllUsers: body.AllUsers,
+		Window:   window,
+	})
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidJob) {
+			writeBadRequest(w, err)
+			return
+		}
+		writeScoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(toJobResponse(job))
+}
+
+// Get handles GET /jobs/{id}.
+//
+// swagger:route GET /jobs/{id} jobs getJob
+//
+// Get the progress of a recalculation job
+//
+//	Parameters:
+//	  + name: id
+//	    in: path
+//	    description: The ID of the job
+//	    required: true
+//	    type: string
+//
+//	Responses:
+//	  200: jobResponse
+//	  404: errorResponse
+//	  405: errorResponse
+//	  499: errorResponse
+//	  500: errorResponse
+func (h *JobsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	job, err := h.jobs.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrJobNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "job not found"})
+			return
+		}
+		writeScoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toJobResponse(job))
+}
+
+func toJobResponse(job domain.Job) models.JobResponse {
+	response := models.JobResponse{
+		ID:         job.ID,
+		Status:     string(job.Status),
+		Window:     job.Window,
+		AllUsers:   job.AllUsers,
+		Total:      len(job.UserIDs),
+		Processed:  job.Processed,
+		Succeeded:  job.Succeeded,
+		Failed:     len(job.Failures),
+		Failures:   make([]models.JobFailure, 0, len(job.Failures)),
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  optionalTime(job.StartedAt),
+		FinishedAt: optionalTime(job.FinishedAt),
+	}
+	for _, f := range job.Failures {
+		response.Failures = append(response.Failures, models.JobFailure{UserID: f.UserID, Error: f.Error, Attempts: f.Attempts})
+	}
+	return response
+}
+
+// optionalTime returns nil for the zero time, so it is left out of responses.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,223 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockJobs is a mock for Jobs.
+type MockJobs struct {
+	mock.Mock
+}
+
+func (m *MockJobs) Submit(ctx context.Context, req usecase.RecalculationRequest) (domain.Job, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(domain.Job), args.Error(1)
+}
+
+func (m *MockJobs) Get(ctx context.Context, id string) (domain.Job, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(domain.Job), args.Error(1)
+}
+
+var jobCreatedAt = time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+func TestRecalculate_UserIDs(t *testing.T) {
+	mockJobs := new(MockJobs)
+	handler := NewJobsHandler(mockJobs)
+
+	mockJobs.On("Submit", mock.Anything, mock.MatchedBy(func(req usecase.RecalculationRequest) bool {
+		return !req.AllUsers && len(req.UserIDs) == 2 && req.Window.Key() == "week@UTC"
+	})).Return(domain.Job{
+		ID:        "job1",
+		Status:    domain.JobQueued,
+		Window:    "week@UTC",
+		UserIDs:   []string{"alice", "bob"},
+		CreatedAt: jobCreatedAt,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs/recalculate?window=week&tz=UTC", strings.NewReader(`{"user_ids":["alice","bob"]}`))
+	w := httptest.NewRecorder()
+
+	handler.Recalculate(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/jobs/job1", w.Header().Get("Location"))
+	assert.JSONEq(t, `{
+		"id": "job1",
+		"status": "queued",
+		"window": "week@UTC",
+		"all_users": false,
+		"total": 2,
+		"processed": 0,
+		"succeeded": 0,
+		"failed": 0,
+		"failures": [],
+		"created_at": "2025-06-02T08:00:00Z"
+	}`, w.Body.String())
+	mockJobs.AssertExpectations(t)
+}
+
+func TestRecalculate_AllUsers(t *testing.T) {
+	mockJobs := new(MockJobs)
+	handler := NewJobsHandler(mockJobs)
+
+	mockJobs.On("Submit", mock.Anything, usecase.RecalculationRequest{AllUsers: true, Window: domain.AllTime()}).
+		Return(domain.Job{ID: "job1", Status: domain.JobQueued, Window: "all_time", AllUsers: true, CreatedAt: jobCreatedAt}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs/recalculate", strings.NewReader(`{"all_users":true}`))
+	w := httptest.NewRecorder()
+
+	handler.Recalculate(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	mockJobs.AssertExpectations(t)
+}
+
+func TestRecalculate_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"malformed JSON", "/jobs/recalculate", `{"user_ids":`, http.StatusBadRequest, `{"error":"body must be {\"user_ids\": [...]} or {\"all_users\": true}"}`},
+		{"unknown field", "/jobs/recalculate", `{"users":["alice"]}`, http.StatusBadRequest, `{"error":"body must be {\"user_ids\": [...]} or {\"all_users\": true}"}`},
+		{"invalid window", "/jobs/recalculate?window=fortnight", `{"all_users":true}`, http.StatusBadRequest, `{"error":"invalid window: unknown kind \"fortnight\""}`},
+		{"oversized body", "/jobs/recalculate", `{"user_ids":["` + strings.Repeat("a", maxBatchBodySize) + `"]}`, http.StatusRequestEntityTooLarge, `{"error":"request too large"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockJobs := new(MockJobs)
+			handler := NewJobsHandler(mockJobs)
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.Recalculate(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedError, w.Body.String())
+			assert.Empty(t, mockJobs.Calls)
+		})
+	}
+}
+
+func TestRecalculate_Rejected(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"invalid job", fmt.Errorf("%w: no user IDs", usecase.ErrInvalidJob), http.StatusBadRequest},
+		{"save failed", errors.New("failed to save job: disk full"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockJobs := new(MockJobs)
+			handler := NewJobsHandler(mockJobs)
+
+			mockJobs.On("Submit", mock.Anything, mock.Anything).Return(domain.Job{}, tt.err)
+
+			req := httptest.NewRequest(http.MethodPost, "/jobs/recalculate", strings.NewReader(`{}`))
+			w := httptest.NewRecorder()
+
+			handler.Recalculate(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Empty(t, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestGetJob_Completed(t *testing.T) {
+	mockJobs := new(MockJobs)
+	mux := http.NewServeMux()
+	handler := NewJobsHandler(mockJobs)
+	mux.HandleFunc("/jobs/recalculate", handler.Recalculate)
+	mux.HandleFunc("/jobs/{id}", handler.Get)
+
+	mockJobs.On("Get", mock.Anything, "job1").Return(domain.Job{
+		ID:         "job1",
+		Status:     domain.JobCompleted,
+		Window:     "all_time",
+		UserIDs:    []string{"alice", "nobody"},
+		Processed:  2,
+		Succeeded:  1,
+		Failures:   []domain.JobFailure{{UserID: "nobody", Error: "user not found", Attempts: 1}},
+		CreatedAt:  jobCreatedAt,
+		StartedAt:  jobCreatedAt.Add(time.Second),
+		FinishedAt: jobCreatedAt.Add(time.Minute),
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/job1", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"id": "job1",
+		"status": "completed",
+		"window": "all_time",
+		"all_users": false,
+		"total": 2,
+		"processed": 2,
+		"succeeded": 1,
+		"failed": 1,
+		"failures": [{"user_id": "nobody", "error": "user not found", "attempts": 1}],
+		"created_at": "2025-06-02T08:00:00Z",
+		"started_at": "2025-06-02T08:00:01Z",
+		"finished_at": "2025-06-02T08:01:00Z"
+	}`, w.Body.String())
+}
+
+func TestGetJob_NotFound(t *testing.T) {
+	mockJobs := new(MockJobs)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs/{id}", NewJobsHandler(mockJobs).Get)
+
+	mockJobs.On("Get", mock.Anything, "nope").Return(domain.Job{}, usecase.ErrJobNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/nope", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"job not found"}`, w.Body.String())
+}
+
+func TestJobs_MethodNotAllowed(t *testing.T) {
+	mockJobs := new(MockJobs)
+	mux := http.NewServeMux()
+	handler := NewJobsHandler(mockJobs)
+	mux.HandleFunc("/jobs/recalculate", handler.Recalculate)
+	mux.HandleFunc("/jobs/{id}", handler.Get)
+
+	for _, req := range []*http.Request{
+		// The fixed path wins over the job ID pattern
+		httptest.NewRequest(http.MethodGet, "/jobs/recalculate", nil),
+		httptest.NewRequest(http.MethodDelete, "/jobs/job1", nil),
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code, req.URL.Path)
+	}
+	assert.Empty(t, mockJobs.Calls)
+}
@@ -0,0 +1,345 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"scoreapp/domain"
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+)
+
+// Leaderboard defines the interface for reading the leaderboard.
+type Leaderboard interface {
+	Top(ctx context.Context, q usecase.LeaderboardQuery, offset, limit int) (usecase.LeaderboardPage, error)
+	Rank(ctx context.Context, q usecase.LeaderboardQuery, userID string) (domain.LeaderboardEntry, error)
+	Around(ctx context.Context, q usecase.LeaderboardQuery, userID string, radius int) (usecase.LeaderboardPage, error)
+}
+
+// LeaderboardHandler exposes HTTP endpoints for the leaderboard.
+type LeaderboardHandler struct {
+	leaderboard Leaderboard
+}
+
+// NewLeaderboardHandler creates a new LeaderboardHandler.
+func NewLeaderboardHandler(l Leaderboard) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		leaderboard: l,
+	}
+}
+
+// Top handles GET /leaderboard[?limit=<n>&offset=<n>&segment=<dimension>:<value>&window=<kind>&tz=<zone>&at=<time>].
+//
+// swagger:route GET /leaderboard leaderboard getLeaderboard
+//
+// List users by score, highest first
+//
+//	Parameters:
+//	  + name: limit
+//	    in: query
+//	    description: Maximum number of entries, between 1 and 100
+//	    required: false
+//	    type: integer
+//	    default: 10
+//	  + name: offset
+//	    in: query
+//	    description: Number of ranks to skip
+//	    required: false
+//	    type: integer
+//	    default: 0
+//	  + name: segment
+//	    in: query
+//	    description: Segment to rank as <dimension>:<value>, e.g. country:TR, defaults to every user
+//	    required: false
+//	    type: string
+//	  + name: window
+//	    in: query
+//	    description: Scoring window to rank; rolling windows are not ranked
+//	    required: false
+//	    type: string
+//	    enum: all_time, day, week, month, season
+//	    default: all_time
+//	  + name: tz
+//	    in: query
+//	    description: IANA timezone calendar windows are aligned to, defaults to the server's configured timezone
+//	    required: false
+//	    type: string
+//	  + name: at
+//	    in: query
+//	    description: RFC 3339 time within the window period to rank, defaults to the current period
+//	    required: false
+//	    type: string
+//	    format: date-time
+//
+//	Responses:
+//	  200: leaderboardResponse
+//	  400: errorResponse
+//	  405: errorResponse
+//	  499: errorResponse
+func (h *LeaderboardHandler) Top(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !leaderboardMethod(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := intParam(q, "limit")
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	offset, err := intParam(q, "offset")
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	query, err := leaderboardQuery(q)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	page, err := h.leaderboard.Top(r.Context(), query, offset, limit)
+	if err != nil {
+		writeLeaderboardError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toLeaderboardResponse(page))
+}
+
+// Rank handles GET /leaderboard/rank/{user_id}[?segment=<dimension>:<value>&window=<kind>&tz=<zone>&at=<time>].
+//
+// swagger:route GET /leaderboard/rank/{user_id} leaderboard getLeaderboardRank
+//
+// Get a user's rank
+//
+//	Parameters:
+//	  + name: user_id
+//	    in: path
+//	    description: The ID of the user
+//	    required: true
+//	    type: string
+//	  + name: segment
+//	    in: query
+//	    description: Segment to rank as <dimension>:<value>, e.g. country:TR, defaults to every user
+//	    required: false
+//	    type: string
+//	  + name: window
+//	    in: query
+//	    description: Scoring window to rank; rolling windows are not ranked
+//	    required: false
+//	    type: string
+//	    enum: all_time, day, week, month, season
+//	    default: all_time
+//	  + name: tz
+//	    in: query
+//	    description: IANA timezone calendar windows are aligned to, defaults to the server's configured timezone
+//	    required: false
+//	    type: string
+//	  + name: at
+//	    in: query
+//	    description: RFC 3339 time within the window period to rank, defaults to the current period
+//	    required: false
+//	    type: string
+//	    format: date-time
+//
+//	Responses:
+//	  200: leaderboardEntryResponse
+//	  400: errorResponse
+//	  404: errorResponse
+//	  405: errorResponse
+//	  499: errorResponse
+func (h *LeaderboardHandler) Rank(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !leaderboardMethod(w, r) {
+		return
+	}
+
+	query, err := leaderboardQuery(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	entry, err := h.leaderboard.Rank(r.Context(), query, r.PathValue("user_id"))
+	if err != nil {
+		writeLeaderboardError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toLeaderboardEntry(entry))
+}
+
+// Around handles GET /leaderboard/around/{user_id}[?radius=<n>&segment=<dimension>:<value>&window=<kind>&tz=<zone>&at=<time>].
+//
+// swagger:route GET /leaderboard/around/{user_id} leaderboard getLeaderboardAround
+//
+// List the users ranked directly above and below a user
+//
+//	Parameters:
+//	  + name: user_id
+//	    in: path
+//	    description: The ID of the user
+//	    required: true
+//	    type: string
+//	  + name: radius
+//	    in: query
+//	    description: Number of entries on each side of the user, between 1 and 50
+//	    required: false
+//	    type: integer
+//	    default: 5
+//	  + name: segment
+//	    in: query
+//	    description: Segment to rank as <dimension>:<value>, e.g. country:TR, defaults to every user
+//	    required: false
+//	    type: string
+//	  + name: window
+//	    in: query
+//	    description: Scoring window to rank; rolling windows are not ranked
+//	    required: false
+//	    type: string
+//	    enum: all_time, day, week, month, season
+//	    default: all_time
+//	  + name: tz
+//	    in: query
+//	    description: IANA timezone calendar windows are aligned to, defaults to the server's configured timezone
+//	    required: false
+//	    type: string
+//	  + name: at
+//	    in: query
+//	    description: RFC 3339 time within the window period to rank, defaults to the current period
+//	    required: false
+//	    type: string
+//	    format: date-time
+//
+//	Responses:
+//	  200: leaderboardResponse
+//	  400: errorResponse
+//	  404: errorResponse
+//	  405: errorResponse
+//	  499: errorResponse
+func (h *LeaderboardHandler) Around(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !leaderboardMethod(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	radius, err := intParam(q, "radius")
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	query, err := leaderboardQuery(q)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	page, err := h.leaderboard.Around(r.Context(), query, r.PathValue("user_id"), radius)
+	if err != nil {
+		writeLeaderboardError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toLeaderboardResponse(page))
+}
+
+// leaderboardMethod writes a 405 response and returns false unless the
+// request is a GET.
+func leaderboardMethod(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "method not allowed"})
+		return false
+	}
+	return true
+}
+
+// intParam parses an optional integer query parameter; a missing one is 0.
+func intParam(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errors.New(name + " must be an integer")
+	}
+	return n, nil
+}
+
+// leaderboardQuery builds the query selecting a leaderboard from the request
+// parameters.
+func leaderboardQuery(q url.Values) (usecase.LeaderboardQuery, error) {
+	window, err := parseWindow(q)
+	if err != nil {
+		return usecase.LeaderboardQuery{}, err
+	}
+	at, err := parseTimeParam(q.Get("at"), "at")
+	if err != nil {
+		return usecase.LeaderboardQuery{}, err
+	}
+	return usecase.LeaderboardQuery{
+		Segment: q.Get("segment"),
+		Window:  window,
+		At:      at,
+	}, nil
+}
+
+func writeBadRequest(w http.ResponseWriter, err error) {
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+}
+
+// writeLeaderboardError maps a leaderboard error to an HTTP response.
+func writeLeaderboardError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidLeaderboardQuery):
+		writeBadRequest(w, err)
+	case errors.Is(err, usecase.ErrNotRanked):
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+	default:
+		writeScoreError(w, err)
+	}
+}
+
+func toLeaderboardResponse(page usecase.LeaderboardPage) models.LeaderboardResponse {
+	entries := make([]models.LeaderboardEntry, 0, len(page.Entries))
+	for _, e := range page.Entries {
+		entries = append(entries, toLeaderboardEntry(e))
+	}
+	response := models.LeaderboardResponse{
+		Segment: page.Key.Segment,
+		Window:  page.Key.Window,
+		Entries: entries,
+		Total:   page.Total,
+	}
+	if !page.Key.Period.IsZero() {
+		periodStart, resetsAt := page.Key.Period, page.ResetsAt
+		response.PeriodStart = &periodStart
+		response.ResetsAt = &resetsAt
+	}
+	return response
+}
+
+func toLeaderboardEntry(e domain.LeaderboardEntry) models.LeaderboardEntry {
+	return models.LeaderboardEntry{
+		Rank:      e.Rank,
+		UserID:    e.UserID,
+		Score:     e.Score,
+		ReachedAt: e.ReachedAt,
+	}
+}
@@ -0,0 +1,270 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLeaderboard is a mock for Leaderboard.
+type MockLeaderboard struct {
+	mock.Mock
+}
+
+func (m *MockLeaderboard) Top(ctx context.Context, q usecase.LeaderboardQuery, offset, limit int) (usecase.LeaderboardPage, error) {
+	args := m.Called(ctx, q, offset, limit)
+	return args.Get(0).(usecase.LeaderboardPage), args.Error(1)
+}
+
+func (m *MockLeaderboard) Rank(ctx context.Context, q usecase.LeaderboardQuery, userID string) (domain.LeaderboardEntry, error) {
+	args := m.Called(ctx, q, userID)
+	return args.Get(0).(domain.LeaderboardEntry), args.Error(1)
+}
+
+func (m *MockLeaderboard) Around(ctx context.Context, q usecase.LeaderboardQuery, userID string, radius int) (usecase.LeaderboardPage, error) {
+	args := m.Called(ctx, q, userID, radius)
+	return args.Get(0).(usecase.LeaderboardPage), args.Error(1)
+}
+
+var reachedAt = time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+// allTimeQuery is the query of requests that select no leaderboard.
+var allTimeQuery = usecase.LeaderboardQuery{Window: domain.AllTime()}
+
+var allTimeKey = domain.LeaderboardKey{Window: "all_time"}
+
+func TestLeaderboard_Top(t *testing.T) {
+	mockLeaderboard := new(MockLeaderboard)
+	handler := NewLeaderboardHandler(mockLeaderboard)
+
+	mockLeaderboard.On("Top", mock.Anything, allTimeQuery, 20, 2).Return(usecase.LeaderboardPage{
+		Key: allTimeKey,
+		Entries: []domain.LeaderboardEntry{
+			{Rank: 21, UserID: "alice", Score: 50, ReachedAt: reachedAt},
+			{Rank: 22, UserID: "bob", Score: 50, ReachedAt: reachedAt.Add(time.Minute)},
+		},
+		Total: 40,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/leaderboard?limit=2&offset=20", nil)
+	w := httptest.NewRecorder()
+
+	handler.Top(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var response models.LeaderboardResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.LeaderboardResponse{
+		Window: "all_time",
+		Entries: []models.LeaderboardEntry{
+			{Rank: 21, UserID: "alice", Score: 50, ReachedAt: reachedAt},
+			{Rank: 22, UserID: "bob", Score: 50, ReachedAt: reachedAt.Add(time.Minute)},
+		},
+		Total: 40,
+	}, response)
+
+	mockLeaderboard.AssertExpectations(t)
+}
+
+func TestLeaderboard_TopEmpty(t *testing.T) {
+	mockLeaderboard := new(MockLeaderboard)
+	handler := NewLeaderboardHandler(mockLeaderboard)
+
+	mockLeaderboard.On("Top", mock.Anything, allTimeQuery, 0, 0).Return(usecase.LeaderboardPage{Key: allTimeKey}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/leaderboard", nil)
+	w := httptest.NewRecorder()
+
+	handler.Top(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"window":"all_time","entries":[],"total":0}`, w.Body.String())
+}
+
+func TestLeaderboard_TopOfSegmentWindow(t *testing.T) {
+	mockLeaderboard := new(MockLeaderboard)
+	handler := NewLeaderboardHandler(mockLeaderboard)
+
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	assert.NoError(t, err)
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	periodStart := time.Date(2025, 5, 26, 0, 0, 0, 0, istanbul)
+	resetsAt := time.Date(2025, 6, 2, 0, 0, 0, 0, istanbul)
+
+	mockLeaderboard.On("Top", mock.Anything, mock.MatchedBy(func(q usecase.LeaderboardQuery) bool {
+		return q.Segment == "country:TR" && q.Window.Key() == "week@Europe/Istanbul" && q.At.Equal(at)
+	}), 0, 0).Return(usecase.LeaderboardPage{
+		Key:      domain.LeaderboardKey{Segment: "country:TR", Window: "week@Europe/Istanbul", Period: periodStart},
+		Entries:  []domain.LeaderboardEntry{{Rank: 1, UserID: "alice", Score: 50, ReachedAt: reachedAt}},
+		Total:    1,
+		ResetsAt: resetsAt,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/leaderboard?segment=country:TR&window=week&tz=Europe/Istanbul&at=2025-06-01T12:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	handler.Top(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"segment": "country:TR",
+		"window": "week@Europe/Istanbul",
+		"period_start": "2025-05-26T00:00:00+03:00",
+		"resets_at": "2025-06-02T00:00:00+03:00",
+		"entries": [{"rank": 1, "user_id": "alice", "score": 50, "reached_at": "2025-06-02T08:00:00Z"}],
+		"total": 1
+	}`, w.Body.String())
+	mockLeaderboard.AssertExpectations(t)
+}
+
+func TestLeaderboard_Rank(t *testing.T) {
+	mockLeaderboard := new(MockLeaderboard)
+	handler := NewLeaderboardHandler(mockLeaderboard)
+
+	mockLeaderboard.On("Rank", mock.Anything, allTimeQuery, "alice").
+		Return(domain.LeaderboardEntry{Rank: 3, UserID: "alice", Score: 50, ReachedAt: reachedAt}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/leaderboard/rank/alice", nil)
+	req.SetPathValue("user_id", "alice")
+	w := httptest.NewRecorder()
+
+	handler.Rank(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.LeaderboardEntry
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.LeaderboardEntry{Rank: 3, UserID: "alice", Score: 50, ReachedAt: reachedAt}, response)
+}
+
+func TestLeaderboard_Around(t *testing.T) {
+	mockLeaderboard := new(MockLeaderboard)
+	handler := NewLeaderboardHandler(mockLeaderboard)
+
+	mockLeaderboard.On("Around", mock.Anything, allTimeQuery, "bob", 1).Return(usecase.LeaderboardPage{
+		Key: allTimeKey,
+		Entries: []domain.LeaderboardEntry{
+			{Rank: 1, UserID: "alice", Score: 60, ReachedAt: reachedAt},
+			{Rank: 2, UserID: "bob", Score: 50, ReachedAt: reachedAt},
+			{Rank: 3, UserID: "carol", Score: 40, ReachedAt: reachedAt},
+		},
+		Total: 3,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/leaderboard/around/bob?radius=1", nil)
+	req.SetPathValue("user_id", "bob")
+	w := httptest.NewRecorder()
+
+	handler.Around(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.LeaderboardResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Entries, 3)
+	assert.Equal(t, "bob", response.Entries[1].UserID)
+	assert.Equal(t, 3, response.Total)
+}
+
+func TestLeaderboard_NotRanked(t *testing.T) {
+	mockLeaderboard := new(MockLeaderboard)
+	handler := NewLeaderboardHandler(mockLeaderboard)
+
+	mockLeaderboard.On("Rank", mock.Anything, allTimeQuery, "nobody").Return(domain.LeaderboardEntry{}, usecase.ErrNotRanked)
+	mockLeaderboard.On("Around", mock.Anything, allTimeQuery, "nobody", 0).Return(usecase.LeaderboardPage{}, usecase.ErrNotRanked)
+
+	req := httptest.NewRequest(http.MethodGet, "/leaderboard/rank/nobody", nil)
+	req.SetPathValue("user_id", "nobody")
+	w := httptest.NewRecorder()
+	handler.Rank(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/leaderboard/around/nobody", nil)
+	req.SetPathValue("user_id", "nobody")
+	w = httptest.NewRecorder()
+	handler.Around(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLeaderboard_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name          string
+		target        string
+		serve         func(h *LeaderboardHandler) http.HandlerFunc
+		expectedError string
+	}{
+		{"non-numeric limit", "/leaderboard?limit=ten", func(h *LeaderboardHandler) http.HandlerFunc { return h.Top }, "limit must be an integer"},
+		{"non-numeric offset", "/leaderboard?offset=first", func(h *LeaderboardHandler) http.HandlerFunc { return h.Top }, "offset must be an integer"},
+		{"non-numeric radius", "/leaderboard/around/user?radius=wide", func(h *LeaderboardHandler) http.HandlerFunc { return h.Around }, "radius must be an integer"},
+		{"unknown window", "/leaderboard?window=fortnight", func(h *LeaderboardHandler) http.HandlerFunc { return h.Top }, `invalid window: unknown kind "fortnight"`},
+		{"unknown timezone", "/leaderboard/rank/user?window=week&tz=Mars/Olympus", func(h *LeaderboardHandler) http.HandlerFunc { return h.Rank }, `invalid window: unknown timezone "Mars/Olympus"`},
+		{"malformed at", "/leaderboard/around/user?window=week&at=yesterday", func(h *LeaderboardHandler) http.HandlerFunc { return h.Around }, "at must be an RFC 3339 time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLeaderboard := new(MockLeaderboard)
+			handler := NewLeaderboardHandler(mockLeaderboard)
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.SetPathValue("user_id", "user")
+			w := httptest.NewRecorder()
+
+			tt.serve(handler)(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response models.ErrorResponse
+			err := json.NewDecoder(w.Body).Decode(&response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedError, response.Error)
+			assert.Empty(t, mockLeaderboard.Calls)
+		})
+	}
+}
+
+func TestLeaderboard_InvalidQuery(t *testing.T) {
+	mockLeaderboard := new(MockLeaderboard)
+	handler := NewLeaderboardHandler(mockLeaderboard)
+
+	mockLeaderboard.On("Top", mock.Anything, allTimeQuery, 0, 1000).
+		Return(usecase.LeaderboardPage{}, fmt.Errorf("%w: limit must be between 1 and 100", usecase.ErrInvalidLeaderboardQuery))
+
+	req := httptest.NewRequest(http.MethodGet, "/leaderboard?limit=1000", nil)
+	w := httptest.NewRecorder()
+
+	handler.Top(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLeaderboard_MethodNotAllowed(t *testing.T) {
+	mockLeaderboard := new(MockLeaderboard)
+	handler := NewLeaderboardHandler(mockLeaderboard)
+
+	for _, serve := range []http.HandlerFunc{handler.Top, handler.Rank, handler.Around} {
+		req := httptest.NewRequest(http.MethodPost, "/leaderboard", nil)
+		w := httptest.NewRecorder()
+
+		serve(w, req)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	}
+	assert.Empty(t, mockLeaderboard.Calls)
+}
@@ -1,9 +1,15 @@
 package models
 
+import "time"
+
 // ScoreResponse represents the response for score calculation endpoints.
 type ScoreResponse struct {
-	UserID string `json:"user_id"`
-	Score  int    `json:"score"`
+	UserID       string     `json:"user_id"`
+	Score        int64      `json:"score"`
+	RuleVersion  int        `json:"rule_version"`
+	Window       string     `json:"window"`
+	Streak       int        `json:"streak"`
+	CalculatedAt *time.Time `json:"calculated_at,omitempty"`
 }
 
 // ErrorResponse represents the response for error cases.
@@ -15,3 +21,219 @@ type ErrorResponse struct {
 type HealthResponse struct {
 	Status string `json:"status"`
 }
+
+// Rule represents how a single action type is converted into points.
+type Rule struct {
+	Base            int64 `json:"base"`
+	Multiplier      int64 `json:"multiplier"`
+	MaxAmount       int64 `json:"max_amount,omitempty"`
+	MaxPoints       int64 `json:"max_points,omitempty"`
+	MaxPointsPerDay int64 `json:"max_points_per_day,omitempty"`
+}
+
+// DecayPolicy represents how action points decay with age.
+type DecayPolicy struct {
+	Kind         string  `json:"kind"`
+	HalfLifeDays float64 `json:"half_life_days,omitempty"`
+	DurationDays float64 `json:"duration_days,omitempty"`
+	Floor        float64 `json:"floor,omitempty"`
+}
+
+// StreakMilestone represents points awarded when a streak reaches a length.
+type StreakMilestone struct {
+	Days   int   `json:"days"`
+	Points int64 `json:"points"`
+}
+
+// StreakPolicy represents how consecutive active days are rewarded.
+type StreakPolicy struct {
+	ActionType string            `json:"action_type"`
+	Milestones []StreakMilestone `json:"milestones"`
+}
+
+// ComboRule represents how several actions within a short time are rewarded.
+type ComboRule struct {
+	Name          string  `json:"name"`
+	ActionType    string  `json:"action_type"`
+	Count         int     `json:"count"`
+	WithinMinutes int     `json:"within_minutes"`
+	Multiplier    float64 `json:"multiplier"`
+}
+
+// RuleSetResponse represents the response for scoring rule endpoints.
+type RuleSetResponse struct {
+	Version        int             `json:"version"`
+	LoadedAt       time.Time       `json:"loaded_at"`
+	UnknownAction  string          `json:"unknown_action"`
+	Rules          map[string]Rule `json:"rules"`
+	Decay          *DecayPolicy    `json:"decay,omitempty"`
+	Streak         *StreakPolicy   `json:"streak,omitempty"`
+	Combos         []ComboRule     `json:"combos,omitempty"`
+	MaxTotalPoints int64           `json:"max_total_points,omitempty"`
+}
+
+// Action represents a single user action.
+type Action struct {
+	ID         string            `json:"id,omitempty"`
+	Type       string            `json:"type"`
+	Amount     int64             `json:"amount"`
+	OccurredAt *time.Time        `json:"occurred_at,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// CapHit represents the points a scoring cap removed.
+type CapHit struct {
+	Kind    string `json:"kind"`
+	Removed int64  `json:"removed"`
+}
+
+// ActionContribution represents the points a single action added to a score.
+type ActionContribution struct {
+	Action      Action   `json:"action"`
+	Rule        string   `json:"rule"`
+	RawPoints   int64    `json:"raw_points"`
+	Caps        []CapHit `json:"caps,omitempty"`
+	DecayFactor float64  `json:"decay_factor"`
+	Points      int64    `json:"points"`
+}
+
+// SkippedAction represents an action that did not contribute to a score.
+type SkippedAction struct {
+	Action Action `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// Bonus represents points awarded for a sequence of actions.
+type Bonus struct {
+	Kind   string    `json:"kind"`
+	Rule   string    `json:"rule"`
+	Points int64     `json:"points"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+}
+
+// BreakdownResponse represents the response for score explanation endpoints.
+type BreakdownResponse struct {
+	UserID        string               `json:"user_id"`
+	Score         int64                `json:"score"`
+	RuleVersion   int                  `json:"rule_version"`
+	Window        string               `json:"window"`
+	Streak        int                  `json:"streak"`
+	Contributions []ActionContribution `json:"contributions"`
+	Bonuses       []Bonus              `json:"bonuses"`
+	Skipped       []SkippedAction      `json:"skipped"`
+	Caps          []CapHit             `json:"caps,omitempty"`
+}
+
+// HistoryEntry represents a score saved at some point in time.
+type HistoryEntry struct {
+	Window      string    `json:"window"`
+	Score       int64     `json:"score"`
+	RuleVersion int       `json:"rule_version"`
+	Streak      int       `json:"streak"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	RequestID   string    `json:"request_id,omitempty"`
+}
+
+// HistoryResponse represents one page of a user's score history.
+type HistoryResponse struct {
+	UserID     string         `json:"user_id"`
+	Entries    []HistoryEntry `json:"entries"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// LeaderboardEntry represents a user's position on the leaderboard.
+type LeaderboardEntry struct {
+	Rank      int       `json:"rank"`
+	UserID    string    `json:"user_id"`
+	Score     int64     `json:"score"`
+	ReachedAt time.Time `json:"reached_at"`
+}
+
+// LeaderboardResponse represents a list of leaderboard entries.
+type LeaderboardResponse struct {
+	// Segment is the ranked segment, empty for every user.
+	Segment string `json:"segment,omitempty"`
+	// Window is the key of the ranked scoring window.
+	Window string `json:"window"`
+	// PeriodStart and ResetsAt bound the window period that is ranked.
+	// They are omitted for all-time leaderboards, which never reset.
+	PeriodStart *time.Time         `json:"period_start,omitempty"`
+	ResetsAt    *time.Time         `json:"resets_at,omitempty"`
+	Entries     []LeaderboardEntry `json:"entries"`
+	// Total is the number of ranked users.
+	Total int `json:"total"`
+}
+
+// BatchResult represents the outcome for one user of a batch calculation.
+type BatchResult struct {
+	UserID string `json:"user_id"`
+	// Status is the HTTP status the user would have had on its own.
+	Status int            `json:"status"`
+	Score  *ScoreResponse `json:"score,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// BatchResponse represents the outcome of a batch calculation.
+type BatchResponse struct {
+	Results   []BatchResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+// RecalculateRequest represents a request to recalculate users in the
+// background. Exactly one of UserIDs and AllUsers must be set.
+type RecalculateRequest struct {
+	UserIDs  []string `json:"user_ids,omitempty"`
+	AllUsers bool     `json:"all_users,omitempty"`
+}
+
+// JobFailure represents a user whose score a job could not recalculate.
+type JobFailure struct {
+	UserID   string `json:"user_id"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+}
+
+// JobResponse represents the state of a recalculation job.
+type JobResponse struct {
+	ID string `json:"id"`
+	// Status is one of queued, running, completed or failed.
+	Status string `json:"status"`
+	// Window is the key of the recalculated scoring window.
+	Window   string `json:"window"`
+	AllUsers bool   `json:"all_users"`
+	// Total is the number of users to recalculate. It is 0 for a job for
+	// all users until the job starts.
+	Total     int          `json:"total"`
+	Processed int          `json:"processed"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Failures  []JobFailure `json:"failures"`
+	// Error is why a failed job stopped.
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ActionRequest represents an action ingested through POST /actions.
+type ActionRequest struct {
+	UserID string `json:"user_id"`
+	// ID identifies the action; an action whose ID the user already has is
+	// ignored.
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Amount     int64             `json:"amount"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// IngestResponse represents the outcome of ingesting actions.
+type IngestResponse struct {
+	// Accepted is the number of actions stored.
+	Accepted int `json:"accepted"`
+	// Duplicates is the number of actions ignored because their ID was
+	// already known.
+	Duplicates int `json:"duplicates"`
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"scoreapp/usecase"
+)
+
+// RequestIDHeader carries the ID of a request in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the client-supplied request IDs that are kept.
+const maxRequestIDLength = 128
+
+// RequestID tags every request with an ID, taken from the X-Request-ID header
+// or generated when the header is missing or too long. The ID is echoed in
+// the response and recorded with the scores the request saves.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(usecase.ContextWithRequestID(r.Context(), id)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"scoreapp/usecase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveWithRequestID(header string) (seen string, w *httptest.ResponseRecorder) {
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = usecase.RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	if header != "" {
+		req.Header.Set(RequestIDHeader, header)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return seen, w
+}
+
+func TestRequestID_FromHeader(t *testing.T) {
+	seen, w := serveWithRequestID("req-42")
+
+	assert.Equal(t, "req-42", seen)
+	assert.Equal(t, "req-42", w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestID_Generated(t *testing.T) {
+	first, w := serveWithRequestID("")
+	second, _ := serveWithRequestID("")
+
+	assert.Len(t, first, 32)
+	assert.Equal(t, first, w.Header().Get(RequestIDHeader))
+	assert.NotEqual(t, first, second)
+}
+
+func TestRequestID_TooLong(t *testing.T) {
+	long := strings.Repeat("x", maxRequestIDLength+1)
+
+	seen, w := serveWithRequestID(long)
+
+	assert.NotEqual(t, long, seen)
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+}
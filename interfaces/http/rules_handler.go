@@ -0,0 +1,233 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+)
+
+// maxRulesBodySize bounds the size of an uploaded rule set.
+const maxRulesBodySize = 1 << 20
+
+// RuleRegistry defines the interface for reading and replacing scoring rules.
+type RuleRegistry interface {
+	Current() *usecase.RuleSet
+	Version(ctx context.Context, version int) (*usecase.RuleSet, error)
+	Upload(ctx context.Context, data []byte, format string) (*usecase.RuleSet, error)
+}
+
+// RulesHandler exposes admin HTTP endpoints for scoring rules.
+type RulesHandler struct {
+	registry RuleRegistry
+	token    string
+}
+
+// NewRulesHandler creates a new RulesHandler. Requests must carry the token
+// as a bearer token in the Authorization header.
+func NewRulesHandler(r RuleRegistry, token string) *RulesHandler {
+	return &RulesHandler{
+		registry: r,
+		token:    token,
+	}
+}
+
+// Handle handles GET and PUT /admin/rules.
+func (h *RulesHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !h.authorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, r)
+	case http.MethodPut:
+		h.put(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "method not allowed"})
+	}
+}
+
+// get handles GET /admin/rules.
+//
+// swagger:route GET /admin/rules admin getRules
+//
+// Get the active scoring rule set, or with ?version= the rule set of that
+// version, which may have been replaced since
+//
+//	Parameters:
+//	  + name: version
+//	    in: query
+//	    description: Version of the rule set, defaults to the active one
+//	    required: false
+//	    type: integer
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  200: ruleSetResponse
+//	  400: errorResponse
+//	  401: errorResponse
+//	  404: errorResponse
+//	  405: errorResponse
+//	  500: errorResponse
+func (h *RulesHandler) get(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query().Get("version")
+	if v == "" {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(toRuleSetResponse(h.registry.Current()))
+		return
+	}
+
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "version must be a positive integer"})
+		return
+	}
+
+	ruleSet, err := h.registry.Version(r.Context(), version)
+	if err != nil {
+		if errors.Is(err, usecase.ErrRuleSetNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "rule set not found"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toRuleSetResponse(ruleSet))
+}
+
+// put handles PUT /admin/rules.
+//
+// swagger:route PUT /admin/rules admin putRules
+//
+// Replace the active scoring rule set with a JSON or YAML document
+//
+//	Consumes:
+//	  - application/json
+//	  - application/yaml
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  200: ruleSetResponse
+//	  400: errorResponse
+//	  401: errorResponse
+//	  405: errorResponse
+//	  413: errorResponse
+//	  500: errorResponse
+func (h *RulesHandler) put(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRulesBodySize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "rule set too large"})
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ruleSet, err := h.registry.Upload(r.Context(), data, rulesFormat(r.Header.Get("Content-Type")))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, usecase.ErrInvalidRules) {
+			status = http.StatusBadRequest
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toRuleSetResponse(ruleSet))
+}
+
+func (h *RulesHandler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return false
+	}
+	expected := "Bearer " + h.token
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) == 1
+}
+
+// rulesFormat maps a request content type to a rule set format.
+// JSON is assumed when the content type is missing or unrecognized.
+func rulesFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return "yaml"
+	default:
+		return "json"
+	}
+}
+
+func toRuleSetResponse(rs *usecase.RuleSet) models.RuleSetResponse {
+	rules := make(map[string]models.Rule, len(rs.Rules.Rules))
+	for actionType, rule := range rs.Rules.Rules {
+		rules[actionType] = models.Rule{
+			Base:            rule.Base,
+			Multiplier:      rule.Multiplier,
+			MaxAmount:       rule.MaxAmount,
+			MaxPoints:       rule.MaxPoints,
+			MaxPointsPerDay: rule.MaxPointsPerDay,
+		}
+	}
+
+	response := models.RuleSetResponse{
+		Version:        rs.Version,
+		LoadedAt:       rs.LoadedAt,
+		UnknownAction:  string(rs.Rules.UnknownAction),
+		Rules:          rules,
+		MaxTotalPoints: rs.Rules.MaxTotalPoints,
+	}
+	if d := rs.Rules.Decay; d != nil {
+		response.Decay = &models.DecayPolicy{
+			Kind:         string(d.Kind),
+			HalfLifeDays: d.HalfLifeDays,
+			DurationDays: d.DurationDays,
+			Floor:        d.Floor,
+		}
+	}
+
+	if st := rs.Rules.Streak; st != nil {
+		milestones := make([]models.StreakMilestone, 0, len(st.Milestones))
+		for _, m := range st.Milestones {
+			milestones = append(milestones, models.StreakMilestone{Days: m.Days, Points: m.Points})
+		}
+		response.Streak = &models.StreakPolicy{ActionType: st.ActionType, Milestones: milestones}
+	}
+	for _, c := range rs.Rules.Combos {
+		response.Combos = append(response.Combos, models.ComboRule{
+			Name:          c.Name,
+			ActionType:    c.ActionType,
+			Count:         c.Count,
+			WithinMinutes: c.WithinMinutes,
+			Multiplier:    c.Multiplier,
+		})
+	}
+
+	return response
+}
@@ -0,0 +1,268 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRuleRegistry is a mock for RuleRegistry.
+type MockRuleRegistry struct {
+	mock.Mock
+}
+
+func (m *MockRuleRegistry) Current() *usecase.RuleSet {
+	args := m.Called()
+	return args.Get(0).(*usecase.RuleSet)
+}
+
+func (m *MockRuleRegistry) Version(ctx context.Context, version int) (*usecase.RuleSet, error) {
+	args := m.Called(ctx, version)
+	ruleSet, _ := args.Get(0).(*usecase.RuleSet)
+	return ruleSet, args.Error(1)
+}
+
+func (m *MockRuleRegistry) Upload(ctx context.Context, data []byte, format string) (*usecase.RuleSet, error) {
+	args := m.Called(ctx, data, format)
+	ruleSet, _ := args.Get(0).(*usecase.RuleSet)
+	return ruleSet, args.Error(1)
+}
+
+const testAdminToken = "secret"
+
+func newRulesRequest(method, body, contentType string) *http.Request {
+	req := httptest.NewRequest(method, "/admin/rules", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func TestRulesHandle_Get(t *testing.T) {
+	mockRegistry := new(MockRuleRegistry)
+	handler := NewRulesHandler(mockRegistry, testAdminToken)
+
+	loadedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockRegistry.On("Current").Return(&usecase.RuleSet{
+		Version:  3,
+		Rules:    usecase.DefaultScoringRules(),
+		LoadedAt: loadedAt,
+	})
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRulesRequest(http.MethodGet, "", ""))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var response models.RuleSetResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, response.Version)
+	assert.True(t, loadedAt.Equal(response.LoadedAt))
+	assert.Equal(t, "ignore", response.UnknownAction)
+	assert.Equal(t, models.Rule{Multiplier: 10}, response.Rules["challenge_completed"])
+
+	mockRegistry.AssertExpectations(t)
+}
+
+func TestRulesHandle_GetWithDecay(t *testing.T) {
+	mockRegistry := new(MockRuleRegistry)
+	handler := NewRulesHandler(mockRegistry, testAdminToken)
+
+	rules := usecase.DefaultScoringRules()
+	rules.Decay = &usecase.DecayPolicy{Kind: usecase.DecayExponential, HalfLifeDays: 14, Floor: 0.1}
+	mockRegistry.On("Current").Return(&usecase.RuleSet{Version: 1, Rules: rules})
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRulesRequest(http.MethodGet, "", ""))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.RuleSetResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.DecayPolicy{Kind: "exponential", HalfLifeDays: 14, Floor: 0.1}, response.Decay)
+
+	mockRegistry.AssertExpectations(t)
+}
+
+func TestRulesHandle_PutJSON(t *testing.T) {
+	mockRegistry := new(MockRuleRegistry)
+	handler := NewRulesHandler(mockRegistry, testAdminToken)
+
+	body := `{"rules": {"login": {"base": 2}}}`
+	mockRegistry.On("Upload", mock.Anything, []byte(body), "json").Return(&usecase.RuleSet{
+		Version: 2,
+		Rules: &usecase.ScoringRules{
+			Rules:         map[string]usecase.Rule{"login": {Base: 2}},
+			UnknownAction: usecase.UnknownActionIgnore,
+		},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRulesRequest(http.MethodPut, body, "application/json"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.RuleSetResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, response.Version)
+	assert.Equal(t, models.Rule{Base: 2}, response.Rules["login"])
+
+	mockRegistry.AssertExpectations(t)
+}
+
+func TestRulesHandle_PutYAML(t *testing.T) {
+	mockRegistry := new(MockRuleRegistry)
+	handler := NewRulesHandler(mockRegistry, testAdminToken)
+
+	body := "rules:\n  login:\n    base: 2\n"
+	mockRegistry.On("Upload", mock.Anything, []byte(body), "yaml").Return(&usecase.RuleSet{
+		Version: 2,
+		Rules:   usecase.DefaultScoringRules(),
+	}, nil)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRulesRequest(http.MethodPut, body, "application/yaml; charset=utf-8"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRegistry.AssertExpectations(t)
+}
+
+func TestRulesHandle_PutInvalid(t *testing.T) {
+	mockRegistry := new(MockRuleRegistry)
+	handler := NewRulesHandler(mockRegistry, testAdminToken)
+
+	mockRegistry.On("Upload", mock.Anything, mock.Anything, "json").Return(nil, usecase.ErrInvalidRules)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRulesRequest(http.MethodPut, `{"rules": {}}`, ""))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response models.ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, usecase.ErrInvalidRules.Error(), response.Error)
+
+	mockRegistry.AssertExpectations(t)
+}
+
+func TestRulesHandle_PutNotStored(t *testing.T) {
+	mockRegistry := new(MockRuleRegistry)
+	handler := NewRulesHandler(mockRegistry, testAdminToken)
+
+	mockRegistry.On("Upload", mock.Anything, mock.Anything, "json").Return(nil, errors.New("failed to save rule set: disk full"))
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRulesRequest(http.MethodPut, `{"rules": {"login": {"base": 2}}}`, ""))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestRulesHandle_GetVersion(t *testing.T) {
+	mockRegistry := new(MockRuleRegistry)
+	handler := NewRulesHandler(mockRegistry, testAdminToken)
+
+	mockRegistry.On("Version", mock.Anything, 2).Return(&usecase.RuleSet{Version: 2, Rules: usecase.DefaultScoringRules()}, nil)
+	mockRegistry.On("Version", mock.Anything, 9).Return(nil, usecase.ErrRuleSetNotFound)
+	mockRegistry.On("Version", mock.Anything, 3).Return(nil, errors.New("database is locked"))
+
+	tests := []struct {
+		query string
+		code  int
+	}{
+		{"version=2", http.StatusOK},
+		{"version=9", http.StatusNotFound},
+		{"version=3", http.StatusInternalServerError},
+		{"version=0", http.StatusBadRequest},
+		{"version=two", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := newRulesRequest(http.MethodGet, "", "")
+			req.URL.RawQuery = tt.query
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusOK {
+				var response models.RuleSetResponse
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+				assert.Equal(t, 2, response.Version)
+			}
+		})
+	}
+	mockRegistry.AssertNotCalled(t, "Current")
+}
+
+func TestRulesHandle_PutTooLarge(t *testing.T) {
+	mockRegistry := new(MockRuleRegistry)
+	handler := NewRulesHandler(mockRegistry, testAdminToken)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRulesRequest(http.MethodPut, strings.Repeat(" ", maxRulesBodySize+1), ""))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	mockRegistry.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRulesHandle_Unauthorized(t *testing.T) {
+	tests := []struct {
+		name          string
+		handlerToken  string
+		authorization string
+	}{
+		{"missing header", testAdminToken, ""},
+		{"wrong token", testAdminToken, "Bearer wrong"},
+		{"disabled", "", "Bearer "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRegistry := new(MockRuleRegistry)
+			handler := NewRulesHandler(mockRegistry, tt.handlerToken)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/rules", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			mockRegistry.AssertNotCalled(t, "Current")
+		})
+	}
+}
+
+func TestRulesHandle_MethodNotAllowed(t *testing.T) {
+	mockRegistry := new(MockRuleRegistry)
+	handler := NewRulesHandler(mockRegistry, testAdminToken)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRulesRequest(http.MethodDelete, "", ""))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	var response models.ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "method not allowed", response.Error)
+}
@@ -1,17 +1,28 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"scoreapp/domain"
 	"scoreapp/interfaces/http/models"
 	"scoreapp/usecase"
 )
 
+// StatusClientClosedRequest is the non-standard status reported when the
+// client goes away before the response is ready.
+const StatusClientClosedRequest = 499
+
 // ScoreCalculator defines the interface for score calculation.
 type ScoreCalculator interface {
-	Calculate(userID string) (int, error)
+	Calculate(ctx context.Context, userID string, window domain.Window) (domain.UserScore, error)
+	Explain(ctx context.Context, userID string, window domain.Window) (domain.ScoreBreakdown, error)
 }
 
 // ScoreHandler exposes HTTP endpoints for score calculation.
@@ -26,7 +37,7 @@ func NewScoreHandler(c ScoreCalculator) *ScoreHandler {
 	}
 }
 
-// Handle handles POST /scores/calculate?user_id=<id>.
+// Handle handles POST /scores/calculate?user_id=<id>[&window=<kind>&days=<n>&tz=<zone>].
 //
 // swagger:route POST /scores/calculate scores calculateScore
 //
@@ -38,42 +49,278 @@ func NewScoreHandler(c ScoreCalculator) *ScoreHandler {
 //	    description: The ID of the user to calculate score for
 //	    required: true
 //	    type: string
+//	  + name: window
+//	    in: query
+//	    description: Scoring window
+//	    required: false
+//	    type: string
+//	    enum: all_time, rolling, day, week, month, season
+//	    default: all_time
+//	  + name: days
+//	    in: query
+//	    description: Length of a rolling window in days
+//	    required: false
+//	    type: integer
+//	  + name: tz
+//	    in: query
+//	    description: IANA timezone calendar windows are aligned to, defaults to the server's configured timezone
+//	    required: false
+//	    type: string
 //
 //	Responses:
 //	  200: scoreResponse
 //	  400: errorResponse
 //	  404: errorResponse
+//	  422: errorResponse
+//	  499: errorResponse
 //	  500: errorResponse
+//	  503: errorResponse
+//	  504: errorResponse
 func (h *ScoreHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	userID, window, ok := scoreRequest(w, r)
+	if !ok {
+		return
+	}
+
+	score, err := h.calculator.Calculate(r.Context(), userID, window)
+	if err != nil {
+		writeScoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toScoreResponse(score))
+}
+
+// Explain handles POST /scores/explain?user_id=<id>[&window=<kind>&days=<n>&tz=<zone>].
+//
+// swagger:route POST /scores/explain scores explainScore
+//
+// Explain how a user's score is calculated without saving it
+//
+//	Parameters:
+//	  + name: user_id
+//	    in: query
+//	    description: The ID of the user to explain the score for
+//	    required: true
+//	    type: string
+//	  + name: window
+//	    in: query
+//	    description: Scoring window
+//	    required: false
+//	    type: string
+//	    enum: all_time, rolling, day, week, month, season
+//	    default: all_time
+//	  + name: days
+//	    in: query
+//	    description: Length of a rolling window in days
+//	    required: false
+//	    type: integer
+//	  + name: tz
+//	    in: query
+//	    description: IANA timezone calendar windows are aligned to, defaults to the server's configured timezone
+//	    required: false
+//	    type: string
+//
+//	Responses:
+//	  200: breakdownResponse
+//	  400: errorResponse
+//	  404: errorResponse
+//	  422: errorResponse
+//	  499: errorResponse
+//	  500: errorResponse
+//	  503: errorResponse
+//	  504: errorResponse
+func (h *ScoreHandler) Explain(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, window, ok := scoreRequest(w, r)
+	if !ok {
+		return
+	}
+
+	breakdown, err := h.calculator.Explain(r.Context(), userID, window)
+	if err != nil {
+		writeScoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toBreakdownResponse(breakdown))
+}
+
+// scoreRequest validates the method, user_id and window of a score request.
+// It writes the error response and returns false when the request is invalid.
+func scoreRequest(w http.ResponseWriter, r *http.Request) (string, domain.Window, bool) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "method not allowed"})
-		return
+		return "", domain.Window{}, false
 	}
 
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "user_id is required"})
-		return
+		return "", domain.Window{}, false
 	}
 
-	score, err := h.calculator.Calculate(userID)
+	window, err := parseWindow(r.URL.Query())
 	if err != nil {
-		// Check if the error is user not found
-		if errors.Is(err, usecase.ErrUserNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "user not found"})
-			return
-		}
-		// Other errors are internal server errors
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
-		return
+		return "", domain.Window{}, false
 	}
 
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(models.ScoreResponse{UserID: userID, Score: score})
+	return userID, window, true
+}
+
+// parseWindow builds a scoring window from the window, days and tz query
+// parameters. The all-time window is used when window is omitted.
+func parseWindow(q url.Values) (domain.Window, error) {
+	kind := domain.WindowAllTime
+	if v := q.Get("window"); v != "" {
+		kind = domain.WindowKind(v)
+	}
+
+	days := 0
+	if v := q.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return domain.Window{}, fmt.Errorf("%w: days must be an integer", domain.ErrInvalidWindow)
+		}
+		days = n
+	}
+
+	var loc *time.Location
+	if v := q.Get("tz"); v != "" {
+		l, err := time.LoadLocation(v)
+		if err != nil {
+			return domain.Window{}, fmt.Errorf("%w: unknown timezone %q", domain.ErrInvalidWindow, v)
+		}
+		loc = l
+	}
+
+	return domain.NewWindow(kind, days, loc)
+}
+
+// writeScoreError maps a score calculation error to an HTTP response.
+func writeScoreError(w http.ResponseWriter, err error) {
+	status, message := scoreError(err)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: message})
+}
+
+// scoreError returns the HTTP status and message reporting a score
+// calculation error.
+func scoreError(err error) (int, string) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidWindow):
+		return http.StatusBadRequest, err.Error()
+	// Check if the error is user not found
+	case errors.Is(err, usecase.ErrUserNotFound):
+		return http.StatusNotFound, "user not found"
+	// Actions rejected by the scoring rules, or scoring to more than fits
+	// in 64 bits, cannot be processed
+	case errors.Is(err, usecase.ErrUnknownActionType) || errors.Is(err, usecase.ErrScoreOverflow):
+		return http.StatusUnprocessableEntity, err.Error()
+	// The client gave up, or a dependency did not answer in time
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest, "request canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, err.Error()
+	case errors.Is(err, usecase.ErrActionServiceUnavailable):
+		return http.StatusServiceUnavailable, err.Error()
+	// Other errors are internal server errors
+	default:
+		return http.StatusInternalServerError, err.Error()
+	}
+}
+
+func toScoreResponse(s domain.UserScore) models.ScoreResponse {
+	response := models.ScoreResponse{
+		UserID:      s.UserID,
+		Score:       s.Score,
+		RuleVersion: s.RuleVersion,
+		Window:      s.WindowKey(),
+		Streak:      s.Streak,
+	}
+	if !s.CalculatedAt.IsZero() {
+		calculatedAt := s.CalculatedAt
+		response.CalculatedAt = &calculatedAt
+	}
+	return response
+}
+
+func toBreakdownResponse(b domain.ScoreBreakdown) models.BreakdownResponse {
+	contributions := make([]models.ActionContribution, 0, len(b.Contributions))
+	for _, c := range b.Contributions {
+		contributions = append(contributions, models.ActionContribution{
+			Action:      toAction(c.Action),
+			Rule:        c.Rule,
+			RawPoints:   c.RawPoints,
+			Caps:        toCapHits(c.Caps),
+			DecayFactor: c.DecayFactor,
+			Points:      c.Points,
+		})
+	}
+
+	bonuses := make([]models.Bonus, 0, len(b.Bonuses))
+	for _, bonus := range b.Bonuses {
+		bonuses = append(bonuses, models.Bonus{
+			Kind:   string(bonus.Kind),
+			Rule:   bonus.Rule,
+			Points: bonus.Points,
+			From:   bonus.From,
+			To:     bonus.To,
+		})
+	}
+
+	skipped := make([]models.SkippedAction, 0, len(b.Skipped))
+	for _, s := range b.Skipped {
+		skipped = append(skipped, models.SkippedAction{
+			Action: toAction(s.Action),
+			Reason: string(s.Reason),
+		})
+	}
+
+	return models.BreakdownResponse{
+		UserID:        b.UserID,
+		Score:         b.Score,
+		RuleVersion:   b.RuleVersion,
+		Window:        b.Window,
+		Streak:        b.Streak,
+		Contributions: contributions,
+		Bonuses:       bonuses,
+		Skipped:       skipped,
+		Caps:          toCapHits(b.Caps),
+	}
+}
+
+func toCapHits(hits []domain.CapHit) []models.CapHit {
+	if len(hits) == 0 {
+		return nil
+	}
+
+	caps := make([]models.CapHit, 0, len(hits))
+	for _, hit := range hits {
+		caps = append(caps, models.CapHit{Kind: string(hit.Kind), Removed: hit.Removed})
+	}
+	return caps
+}
+
+func toAction(a domain.UserAction) models.Action {
+	action := models.Action{
+		ID:       a.ID,
+		Type:     a.Type,
+		Amount:   a.Amount,
+		Metadata: a.Metadata,
+	}
+	if !a.OccurredAt.IsZero() {
+		occurredAt := a.OccurredAt
+		action.OccurredAt = &occurredAt
+	}
+	return action
 }
@@ -1,12 +1,16 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"scoreapp/domain"
 	"scoreapp/interfaces/http/models"
 	"scoreapp/usecase"
 
@@ -19,9 +23,14 @@ type MockScoreCalculator struct {
 	mock.Mock
 }
 
-func (m *MockScoreCalculator) Calculate(userID string) (int, error) {
-	args := m.Called(userID)
-	return args.Int(0), args.Error(1)
+func (m *MockScoreCalculator) Calculate(ctx context.Context, userID string, window domain.Window) (domain.UserScore, error) {
+	args := m.Called(ctx, userID, window)
+	return args.Get(0).(domain.UserScore), args.Error(1)
+}
+
+func (m *MockScoreCalculator) Explain(ctx context.Context, userID string, window domain.Window) (domain.ScoreBreakdown, error) {
+	args := m.Called(ctx, userID, window)
+	return args.Get(0).(domain.ScoreBreakdown), args.Error(1)
 }
 
 func TestHandle_MethodNotAllowed(t *testing.T) {
@@ -41,7 +50,7 @@ func TestHandle_MethodNotAllowed(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "method not allowed", response.Error)
 
-	mockCalculator.AssertNotCalled(t, "Calculate", mock.Anything)
+	mockCalculator.AssertNotCalled(t, "Calculate", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestHandle_MissingUserID(t *testing.T) {
@@ -61,7 +70,7 @@ func TestHandle_MissingUserID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "user_id is required", response.Error)
 
-	mockCalculator.AssertNotCalled(t, "Calculate", mock.Anything)
+	mockCalculator.AssertNotCalled(t, "Calculate", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestHandle_SuccessfulCalculation(t *testing.T) {
@@ -69,9 +78,14 @@ func TestHandle_SuccessfulCalculation(t *testing.T) {
 	handler := NewScoreHandler(mockCalculator)
 
 	userID := "user"
-	expectedScore := 42
+	expectedScore := int64(42)
 
-	mockCalculator.On("Calculate", userID).Return(expectedScore, nil)
+	mockCalculator.On("Calculate", mock.Anything, userID, domain.AllTime()).Return(domain.UserScore{
+		UserID:      userID,
+		Score:       expectedScore,
+		RuleVersion: 1,
+		Window:      "all_time",
+	}, nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id="+userID, nil)
 	w := httptest.NewRecorder()
@@ -96,7 +110,7 @@ func TestHandle_UserNotFound(t *testing.T) {
 
 	userID := "user"
 
-	mockCalculator.On("Calculate", userID).Return(0, usecase.ErrUserNotFound)
+	mockCalculator.On("Calculate", mock.Anything, userID, domain.AllTime()).Return(domain.UserScore{}, usecase.ErrUserNotFound)
 
 	req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id="+userID, nil)
 	w := httptest.NewRecorder()
@@ -121,7 +135,7 @@ func TestHandle_InternalServerError(t *testing.T) {
 	userID := "user"
 	internalError := errors.New("database connection failed")
 
-	mockCalculator.On("Calculate", userID).Return(0, internalError)
+	mockCalculator.On("Calculate", mock.Anything, userID, domain.AllTime()).Return(domain.UserScore{}, internalError)
 
 	req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id="+userID, nil)
 	w := httptest.NewRecorder()
@@ -156,7 +170,7 @@ func TestHandle_EmptyUserID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "user_id is required", response.Error)
 
-	mockCalculator.AssertNotCalled(t, "Calculate", mock.Anything)
+	mockCalculator.AssertNotCalled(t, "Calculate", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestHandle_ScoreZero(t *testing.T) {
@@ -164,9 +178,14 @@ func TestHandle_ScoreZero(t *testing.T) {
 	handler := NewScoreHandler(mockCalculator)
 
 	userID := "user"
-	expectedScore := 0
+	expectedScore := int64(0)
 
-	mockCalculator.On("Calculate", userID).Return(expectedScore, nil)
+	mockCalculator.On("Calculate", mock.Anything, userID, domain.AllTime()).Return(domain.UserScore{
+		UserID:      userID,
+		Score:       expectedScore,
+		RuleVersion: 1,
+		Window:      "all_time",
+	}, nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id="+userID, nil)
 	w := httptest.NewRecorder()
@@ -184,3 +203,405 @@ func TestHandle_ScoreZero(t *testing.T) {
 
 	mockCalculator.AssertExpectations(t)
 }
+
+func TestHandle_UnknownActionType(t *testing.T) {
+	mockCalculator := new(MockScoreCalculator)
+	handler := NewScoreHandler(mockCalculator)
+
+	userID := "user"
+
+	mockCalculator.On("Calculate", mock.Anything, userID, domain.AllTime()).Return(domain.UserScore{}, fmt.Errorf("%w: %q", usecase.ErrUnknownActionType, "dance"))
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id="+userID, nil)
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var response models.ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Error, "unknown action type")
+
+	mockCalculator.AssertExpectations(t)
+}
+
+func TestHandle_ScoreOverflow(t *testing.T) {
+	mockCalculator := new(MockScoreCalculator)
+	handler := NewScoreHandler(mockCalculator)
+
+	userID := "user"
+
+	mockCalculator.On("Calculate", mock.Anything, userID, domain.AllTime()).Return(domain.UserScore{}, fmt.Errorf("%w: user %q", usecase.ErrScoreOverflow, userID))
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id="+userID, nil)
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var response models.ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Error, "score overflow")
+
+	mockCalculator.AssertExpectations(t)
+}
+
+func TestHandle_DependencyErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"client canceled", fmt.Errorf("failed to get actions: %w", context.Canceled), StatusClientClosedRequest},
+		{"dependency timed out", fmt.Errorf("failed to save score: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{"action service down", fmt.Errorf("failed to get actions: %w", usecase.ErrActionServiceUnavailable), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCalculator := new(MockScoreCalculator)
+			handler := NewScoreHandler(mockCalculator)
+
+			userID := "user"
+
+			mockCalculator.On("Calculate", mock.Anything, userID, domain.AllTime()).Return(domain.UserScore{}, tt.err)
+
+			req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id="+userID, nil)
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			mockCalculator.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandle_PassesRequestContext(t *testing.T) {
+	mockCalculator := new(MockScoreCalculator)
+	handler := NewScoreHandler(mockCalculator)
+
+	userID := "user"
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+
+	fromRequest := mock.MatchedBy(func(c context.Context) bool { return c.Value(ctxKey{}) == "request" })
+	mockCalculator.On("Calculate", fromRequest, userID, domain.AllTime()).Return(domain.UserScore{UserID: userID}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id="+userID, nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockCalculator.AssertExpectations(t)
+}
+
+func TestExplain_Success(t *testing.T) {
+	mockCalculator := new(MockScoreCalculator)
+	handler := NewScoreHandler(mockCalculator)
+
+	userID := "user"
+	comboStart := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	breakdown := domain.ScoreBreakdown{
+		UserID:      userID,
+		Score:       31,
+		RuleVersion: 2,
+		Window:      "week@UTC",
+		Streak:      3,
+		Contributions: []domain.ActionContribution{
+			{Action: domain.UserAction{Type: "login", Amount: 1}, Rule: "login", RawPoints: 1, DecayFactor: 1, Points: 1},
+			{Action: domain.UserAction{Type: "challenge_completed", Amount: 3}, Rule: "challenge_completed", RawPoints: 30, DecayFactor: 1, Points: 30},
+		},
+		Bonuses: []domain.Bonus{
+			{Kind: domain.BonusCombo, Rule: "rush", Points: 15, From: comboStart, To: comboStart.Add(time.Minute)},
+		},
+		Skipped: []domain.SkippedAction{
+			{Action: domain.UserAction{Type: "quiz_answer", Amount: 0}, Reason: domain.SkipNonPositiveAmount},
+		},
+	}
+
+	mockCalculator.On("Explain", mock.Anything, userID, domain.AllTime()).Return(breakdown, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/explain?user_id="+userID, nil)
+	w := httptest.NewRecorder()
+
+	handler.Explain(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var response models.BreakdownResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.BreakdownResponse{
+		UserID:      userID,
+		Score:       31,
+		RuleVersion: 2,
+		Window:      "week@UTC",
+		Streak:      3,
+		Contributions: []models.ActionContribution{
+			{Action: models.Action{Type: "login", Amount: 1}, Rule: "login", RawPoints: 1, DecayFactor: 1, Points: 1},
+			{Action: models.Action{Type: "challenge_completed", Amount: 3}, Rule: "challenge_completed", RawPoints: 30, DecayFactor: 1, Points: 30},
+		},
+		Bonuses: []models.Bonus{
+			{Kind: "combo", Rule: "rush", Points: 15, From: comboStart, To: comboStart.Add(time.Minute)},
+		},
+		Skipped: []models.SkippedAction{
+			{Action: models.Action{Type: "quiz_answer", Amount: 0}, Reason: "non_positive_amount"},
+		},
+	}, response)
+
+	mockCalculator.AssertExpectations(t)
+	mockCalculator.AssertNotCalled(t, "Calculate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExplain_EmptyBreakdown(t *testing.T) {
+	mockCalculator := new(MockScoreCalculator)
+	handler := NewScoreHandler(mockCalculator)
+
+	userID := "user"
+	mockCalculator.On("Explain", mock.Anything, userID, domain.AllTime()).Return(domain.ScoreBreakdown{UserID: userID, RuleVersion: 1, Window: "all_time"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/explain?user_id="+userID, nil)
+	w := httptest.NewRecorder()
+
+	handler.Explain(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":"user","score":0,"rule_version":1,"window":"all_time","streak":0,"contributions":[],"bonuses":[],"skipped":[]}`, w.Body.String())
+
+	mockCalculator.AssertExpectations(t)
+}
+
+func TestExplain_MissingUserID(t *testing.T) {
+	mockCalculator := new(MockScoreCalculator)
+	handler := NewScoreHandler(mockCalculator)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/explain", nil)
+	w := httptest.NewRecorder()
+
+	handler.Explain(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockCalculator.AssertNotCalled(t, "Explain", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExplain_UserNotFound(t *testing.T) {
+	mockCalculator := new(MockScoreCalculator)
+	handler := NewScoreHandler(mockCalculator)
+
+	userID := "user"
+	mockCalculator.On("Explain", mock.Anything, userID, domain.AllTime()).Return(domain.ScoreBreakdown{}, usecase.ErrUserNotFound)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/explain?user_id="+userID, nil)
+	w := httptest.NewRecorder()
+
+	handler.Explain(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response models.ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "user not found", response.Error)
+
+	mockCalculator.AssertExpectations(t)
+}
+
+func TestExplain_ActionFields(t *testing.T) {
+	mockCalculator := new(MockScoreCalculator)
+	handler := NewScoreHandler(mockCalculator)
+
+	userID := "user"
+	occurredAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	breakdown := domain.ScoreBreakdown{
+		UserID:      userID,
+		Score:       20,
+		RuleVersion: 1,
+		Window:      "all_time",
+		Contributions: []domain.ActionContribution{
+			{
+				Action: domain.UserAction{
+					ID:         "a1",
+					Type:       "challenge_completed",
+					Amount:     2,
+					OccurredAt: occurredAt,
+					Metadata:   map[string]string{"challenge": "daily"},
+				},
+				Rule:        "challenge_completed",
+				RawPoints:   40,
+				DecayFactor: 0.5,
+				Points:      20,
+			},
+		},
+		Skipped: []domain.SkippedAction{
+			{Action: domain.UserAction{Type: "login", Amount: 0}, Reason: domain.SkipNonPositiveAmount},
+		},
+	}
+
+	mockCalculator.On("Explain", mock.Anything, userID, domain.AllTime()).Return(breakdown, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/explain?user_id="+userID, nil)
+	w := httptest.NewRecorder()
+
+	handler.Explain(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"user_id": "user",
+		"score": 20,
+		"rule_version": 1,
+		"window": "all_time",
+		"streak": 0,
+		"contributions": [{
+			"action": {
+				"id": "a1",
+				"type": "challenge_completed",
+				"amount": 2,
+				"occurred_at": "2025-06-01T12:00:00Z",
+				"metadata": {"challenge": "daily"}
+			},
+			"rule": "challenge_completed",
+			"raw_points": 40,
+			"decay_factor": 0.5,
+			"points": 20
+		}],
+		"bonuses": [],
+		"skipped": [{"action": {"type": "login", "amount": 0}, "reason": "non_positive_amount"}]
+	}`, w.Body.String())
+
+	mockCalculator.AssertExpectations(t)
+}
+
+func TestHandle_WindowQuery(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected domain.Window
+	}{
+		{"default all time", "", domain.AllTime()},
+		{"explicit all time", "&window=all_time", domain.AllTime()},
+		{"rolling", "&window=rolling&days=7", domain.Window{Kind: domain.WindowRolling, Days: 7}},
+		{"week", "&window=week", domain.Window{Kind: domain.WindowWeek}},
+		{"month with timezone", "&window=month&tz=Europe/Istanbul", domain.Window{Kind: domain.WindowMonth, Location: istanbul}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCalculator := new(MockScoreCalculator)
+			handler := NewScoreHandler(mockCalculator)
+
+			userID := "user"
+			mockCalculator.On("Calculate", mock.Anything, userID, tt.expected).Return(domain.UserScore{
+				UserID:      userID,
+				Score:       5,
+				RuleVersion: 1,
+				Window:      tt.expected.Key(),
+			}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id="+userID+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response models.ScoreResponse
+			err := json.NewDecoder(w.Body).Decode(&response)
+			assert.NoError(t, err)
+			assert.Equal(t, models.ScoreResponse{
+				UserID:      userID,
+				Score:       5,
+				RuleVersion: 1,
+				Window:      tt.expected.Key(),
+			}, response)
+
+			mockCalculator.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandle_InvalidWindowQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unknown kind", "&window=fortnight"},
+		{"rolling without days", "&window=rolling"},
+		{"non-numeric days", "&window=rolling&days=seven"},
+		{"days on calendar window", "&window=day&days=2"},
+		{"unknown timezone", "&window=day&tz=Mars/Olympus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCalculator := new(MockScoreCalculator)
+			handler := NewScoreHandler(mockCalculator)
+
+			req := httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id=user"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response models.ErrorResponse
+			err := json.NewDecoder(w.Body).Decode(&response)
+			assert.NoError(t, err)
+			assert.Contains(t, response.Error, "invalid window")
+
+			mockCalculator.AssertNotCalled(t, "Calculate", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestExplain_Caps(t *testing.T) {
+	mockCalculator := new(MockScoreCalculator)
+	handler := NewScoreHandler(mockCalculator)
+
+	userID := "user"
+	breakdown := domain.ScoreBreakdown{
+		UserID:      userID,
+		Score:       100,
+		RuleVersion: 1,
+		Window:      "all_time",
+		Contributions: []domain.ActionContribution{
+			{
+				Action:      domain.UserAction{Type: "quiz_answer", Amount: 1000000},
+				Rule:        "quiz_answer",
+				RawPoints:   2000000,
+				Caps:        []domain.CapHit{{Kind: domain.CapMaxPoints, Removed: 1999900}},
+				DecayFactor: 1,
+				Points:      100,
+			},
+		},
+		Caps: []domain.CapHit{{Kind: domain.CapMaxTotalPoints, Removed: 0}},
+	}
+
+	mockCalculator.On("Explain", mock.Anything, userID, domain.AllTime()).Return(breakdown, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/scores/explain?user_id="+userID, nil)
+	w := httptest.NewRecorder()
+
+	handler.Explain(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.BreakdownResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CapHit{{Kind: "max_points", Removed: 1999900}}, response.Contributions[0].Caps)
+	assert.Equal(t, []models.CapHit{{Kind: "max_total_points", Removed: 0}}, response.Caps)
+
+	mockCalculator.AssertExpectations(t)
+}
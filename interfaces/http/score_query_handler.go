@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"scoreapp/domain"
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+)
+
+// ScoreQuery defines the interface for reading stored scores.
+type ScoreQuery interface {
+	Get(ctx context.Context, userID string, window domain.Window) (domain.UserScore, error)
+}
+
+// ScoreQueryHandler exposes HTTP endpoints for reading stored scores.
+type ScoreQueryHandler struct {
+	query ScoreQuery
+}
+
+// NewScoreQueryHandler creates a new ScoreQueryHandler.
+func NewScoreQueryHandler(q ScoreQuery) *ScoreQueryHandler {
+	return &ScoreQueryHandler{
+		query: q,
+	}
+}
+
+// Handle handles GET /scores/{user_id}[?window=<kind>&days=<n>&tz=<zone>].
+//
+// swagger:route GET /scores/{user_id} scores getScore
+//
+// Get the last score saved for a user, without recalculating it
+//
+//	Parameters:
+//	  + name: user_id
+//	    in: path
+//	    description: The ID of the user
+//	    required: true
+//	    type: string
+//	  + name: window
+//	    in: query
+//	    description: Scoring window
+//	    required: false
+//	    type: string
+//	    enum: all_time, rolling, day, week, month, season
+//	    default: all_time
+//	  + name: days
+//	    in: query
+//	    description: Length of a rolling window in days
+//	    required: false
+//	    type: integer
+//	  + name: tz
+//	    in: query
+//	    description: IANA timezone calendar windows are aligned to, defaults to the server's configured timezone
+//	    required: false
+//	    type: string
+//	  + name: If-None-Match
+//	    in: header
+//	    description: ETag of a previous response; answers 304 while the score is unchanged
+//	    required: false
+//	    type: string
+//
+//	Responses:
+//	  200: scoreResponse
+//	  304: notModifiedResponse
+//	  400: errorResponse
+//	  404: errorResponse
+//	  405: errorResponse
+//	  499: errorResponse
+//	  500: errorResponse
+func (h *ScoreQueryHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	window, err := parseWindow(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	score, err := h.query.Get(r.Context(), r.PathValue("user_id"), window)
+	if err != nil {
+		if errors.Is(err, usecase.ErrScoreNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: "score not found"})
+			return
+		}
+		writeScoreError(w, err)
+		return
+	}
+
+	body, err := json.Marshal(toScoreResponse(score))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Pollers revalidate with the ETag and skip the body while it matches
+	etag := bodyETag(body)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// bodyETag returns a strong entity tag for a response body.
+func bodyETag(body []byte) string {
+	h := fnv.New64a()
+	_, _ = h.Write(body)
+	return fmt.Sprintf(`"%016x"`, h.Sum64())
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"scoreapp/domain"
+	"scoreapp/interfaces/http/models"
+	"scoreapp/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockScoreQuery is a mock for ScoreQuery.
+type MockScoreQuery struct {
+	mock.Mock
+}
+
+func (m *MockScoreQuery) Get(ctx context.Context, userID string, window domain.Window) (domain.UserScore, error) {
+	args := m.Called(ctx, userID, window)
+	return args.Get(0).(domain.UserScore), args.Error(1)
+}
+
+func newScoreQueryRequest(method, target, ifNoneMatch string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.SetPathValue("user_id", "user")
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	return req
+}
+
+var storedScore = domain.UserScore{
+	UserID:       "user",
+	Score:        42,
+	RuleVersion:  2,
+	Window:       "all_time",
+	Streak:       3,
+	CalculatedAt: time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC),
+}
+
+func TestGetScore_Success(t *testing.T) {
+	mockQuery := new(MockScoreQuery)
+	handler := NewScoreQueryHandler(mockQuery)
+
+	mockQuery.On("Get", mock.Anything, "user", domain.AllTime()).Return(storedScore, nil)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newScoreQueryRequest(http.MethodGet, "/scores/user", ""))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+
+	var response models.ScoreResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", response.UserID)
+	assert.Equal(t, int64(42), response.Score)
+	assert.Equal(t, 2, response.RuleVersion)
+	assert.Equal(t, "all_time", response.Window)
+	assert.Equal(t, 3, response.Streak)
+	require.NotNil(t, response.CalculatedAt)
+	assert.Equal(t, storedScore.CalculatedAt, *response.CalculatedAt)
+
+	mockQuery.AssertExpectations(t)
+}
+
+func TestGetScore_Window(t *testing.T) {
+	mockQuery := new(MockScoreQuery)
+	handler := NewScoreQueryHandler(mockQuery)
+
+	rolling := domain.Window{Kind: domain.WindowRolling, Days: 7}
+	mockQuery.On("Get", mock.Anything, "user", rolling).
+		Return(domain.UserScore{UserID: "user", Score: 5, Window: "rolling_7d"}, nil)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newScoreQueryRequest(http.MethodGet, "/scores/user?window=rolling&days=7", ""))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.ScoreResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "rolling_7d", response.Window)
+	assert.Nil(t, response.CalculatedAt)
+}
+
+func TestGetScore_NotModified(t *testing.T) {
+	mockQuery := new(MockScoreQuery)
+	handler := NewScoreQueryHandler(mockQuery)
+
+	mockQuery.On("Get", mock.Anything, "user", domain.AllTime()).Return(storedScore, nil)
+
+	first := httptest.NewRecorder()
+	handler.Handle(first, newScoreQueryRequest(http.MethodGet, "/scores/user", ""))
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w := httptest.NewRecorder()
+		handler.Handle(w, newScoreQueryRequest(http.MethodGet, "/scores/user", ifNoneMatch))
+
+		assert.Equal(t, http.StatusNotModified, w.Code, ifNoneMatch)
+		assert.Equal(t, etag, w.Header().Get("ETag"), ifNoneMatch)
+		assert.Empty(t, w.Body.String(), ifNoneMatch)
+	}
+}
+
+func TestGetScore_ChangedScore(t *testing.T) {
+	mockQuery := new(MockScoreQuery)
+	handler := NewScoreQueryHandler(mockQuery)
+
+	mockQuery.On("Get", mock.Anything, "user", domain.AllTime()).Return(storedScore, nil).Once()
+
+	first := httptest.NewRecorder()
+	handler.Handle(first, newScoreQueryRequest(http.MethodGet, "/scores/user", ""))
+	etag := first.Header().Get("ETag")
+
+	// A recalculation with the same result still changes the ETag
+	recalculated := storedScore
+	recalculated.CalculatedAt = recalculated.CalculatedAt.Add(time.Minute)
+	mockQuery.On("Get", mock.Anything, "user", domain.AllTime()).Return(recalculated, nil).Once()
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newScoreQueryRequest(http.MethodGet, "/scores/user", etag))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	mockQuery.AssertExpectations(t)
+}
+
+func TestGetScore_NeverScored(t *testing.T) {
+	mockQuery := new(MockScoreQuery)
+	handler := NewScoreQueryHandler(mockQuery)
+
+	mockQuery.On("Get", mock.Anything, "user", domain.AllTime()).Return(domain.UserScore{}, usecase.ErrScoreNotFound)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newScoreQueryRequest(http.MethodGet, "/scores/user", ""))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	var response models.ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "score not found", response.Error)
+}
+
+func TestGetScore_MethodNotAllowed(t *testing.T) {
+	mockQuery := new(MockScoreQuery)
+	handler := NewScoreQueryHandler(mockQuery)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newScoreQueryRequest(http.MethodDelete, "/scores/user", ""))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	mockQuery.AssertNotCalled(t, "Get", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetScore_InvalidWindow(t *testing.T) {
+	mockQuery := new(MockScoreQuery)
+	handler := NewScoreQueryHandler(mockQuery)
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, newScoreQueryRequest(http.MethodGet, "/scores/user?window=fortnight", ""))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockQuery.AssertNotCalled(t, "Get", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetScore_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{"canceled", context.Canceled, StatusClientClosedRequest},
+		{"repository error", errors.New("database unavailable"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockQuery := new(MockScoreQuery)
+			handler := NewScoreQueryHandler(mockQuery)
+
+			mockQuery.On("Get", mock.Anything, "user", mock.Anything).Return(domain.UserScore{}, tt.err)
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, newScoreQueryRequest(http.MethodGet, "/scores/user", ""))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestGetScore_Routing(t *testing.T) {
+	mockQuery := new(MockScoreQuery)
+	mockCalculator := new(MockScoreCalculator)
+
+	// The read route must not shadow the calculation routes
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scores/calculate", NewScoreHandler(mockCalculator).Handle)
+	mux.HandleFunc("/scores/{user_id}", NewScoreQueryHandler(mockQuery).Handle)
+
+	mockQuery.On("Get", mock.Anything, "user", domain.AllTime()).Return(storedScore, nil)
+	mockCalculator.On("Calculate", mock.Anything, "user", domain.AllTime()).Return(storedScore, nil)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/scores/user", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/scores/calculate?user_id=user", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockQuery.AssertNumberOfCalls(t, "Get", 1)
+	mockCalculator.AssertNumberOfCalls(t, "Calculate", 1)
+}
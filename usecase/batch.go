@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"scoreapp/domain"
+)
+
+var (
+	// ErrInvalidBatch is returned when a batch is empty or lists a user ID
+	// that is empty or repeated.
+	ErrInvalidBatch = errors.New("invalid batch")
+	// ErrBatchTooLarge is returned when a batch lists more users than the
+	// calculator accepts at once.
+	ErrBatchTooLarge = errors.New("batch too large")
+)
+
+const (
+	// DefaultBatchWorkers is how many users of a batch are calculated at
+	// once when no worker count is configured.
+	DefaultBatchWorkers = 8
+	// DefaultMaxBatchSize is the largest batch accepted when no maximum is
+	// configured.
+	DefaultMaxBatchSize = 1000
+)
+
+// BatchResult is the outcome of calculating the score of one user of a batch.
+type BatchResult struct {
+	UserID string
+	// Score is the saved score, set when Err is nil.
+	Score domain.UserScore
+	Err   error
+}
+
+// CalculateBatch calculates and saves the scores of the users within the
+// window, several users at a time. A user whose score cannot be calculated
+// does not stop the others; its error is reported in its result instead.
+// Users not yet started when the batch deadline passes fail with the
+// context's error. Results are in the order of userIDs.
+func (c *ScoreCalculator) CalculateBatch(ctx context.Context, userIDs []string, window domain.Window) ([]BatchResult, error) {
+	if err := c.validateBatch(userIDs); err != nil {
+		return nil, err
+	}
+	if err := window.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, c.batchTimeout)
+	defer cancel()
+
+	results := make([]BatchResult, len(userIDs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(c.batchWorkers, 1), len(userIDs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = c.calculateBatchItem(ctx, userIDs[i], window)
+			}
+		}()
+	}
+	for i := range userIDs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results, nil
+}
+
+func (c *ScoreCalculator) validateBatch(userIDs []string) error {
+	if len(userIDs) == 0 {
+		return fmt.Errorf("%w: no user IDs", ErrInvalidBatch)
+	}
+	if c.maxBatchSize > 0 && len(userIDs) > c.maxBatchSize {
+		return fmt.Errorf("%w: %d user IDs, at most %d allowed", ErrBatchTooLarge, len(userIDs), c.maxBatchSize)
+	}
+
+	if err := checkUserIDs(userIDs); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+	}
+	return nil
+}
+
+// checkUserIDs reports an empty or repeated user ID.
+func checkUserIDs(userIDs []string) error {
+	seen := make(map[string]struct{}, len(userIDs))
+	for _, userID := range userIDs {
+		if userID == "" {
+			return errors.New("empty user ID")
+		}
+		if _, dup := seen[userID]; dup {
+			return fmt.Errorf("duplicate user ID %q", userID)
+		}
+		seen[userID] = struct{}{}
+	}
+	return nil
+}
+
+func (c *ScoreCalculator) calculateBatchItem(ctx context.Context, userID string, window domain.Window) BatchResult {
+	// Skip the remaining users once the batch is out of time
+	if err := ctx.Err(); err != nil {
+		return BatchResult{UserID: userID, Err: err}
+	}
+	score, err := c.Calculate(ctx, userID, window)
+	return BatchResult{UserID: userID, Score: score, Err: err}
+}
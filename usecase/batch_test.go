@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+// slowActionService answers after a delay and tracks how many calls it
+// serves at once.
+type slowActionService struct {
+	delay   time.Duration
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (s *slowActionService) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	running := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if running <= peak || s.peak.CompareAndSwap(peak, running) {
+			break
+		}
+	}
+
+	select {
+	case <-time.After(s.delay):
+		return []domain.UserAction{{ID: userID, Type: "login", Amount: 1, OccurredAt: calculatedAt}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestCalculateBatch_PartialSuccess(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+	serviceErr := errors.New("service unavailable")
+
+	mockActionService.On("GetActions", mock.Anything, "alice").
+		Return([]domain.UserAction{{ID: "a1", Type: "login", Amount: 1, OccurredAt: calculatedAt}}, nil)
+	mockActionService.On("GetActions", mock.Anything, "bob").Return([]domain.UserAction(nil), serviceErr)
+	mockActionService.On("GetActions", mock.Anything, "nobody").Return([]domain.UserAction(nil), ErrUserNotFound)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()),
+		WithClock(fixedClock(calculatedAt)), WithBatchWorkers(2))
+
+	results, err := calculator.CalculateBatch(context.Background(), []string{"alice", "bob", "nobody"}, domain.AllTime())
+
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "alice", results[0].UserID)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, int64(1), results[0].Score.Score)
+
+	assert.Equal(t, "bob", results[1].UserID)
+	assert.ErrorIs(t, results[1].Err, serviceErr)
+
+	assert.Equal(t, "nobody", results[2].UserID)
+	assert.ErrorIs(t, results[2].Err, ErrUserNotFound)
+
+	mockRepo.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestCalculateBatch_BoundedParallelism(t *testing.T) {
+	service := &slowActionService{delay: 10 * time.Millisecond}
+	mockRepo := new(MockScoreRepository)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+
+	calculator := NewScoreCalculator(service, mockRepo, NewRuleRegistry(DefaultScoringRules()),
+		WithClock(fixedClock(calculatedAt)), WithBatchWorkers(3))
+
+	userIDs := make([]string, 12)
+	for i := range userIDs {
+		userIDs[i] = fmt.Sprintf("user%d", i)
+	}
+
+	results, err := calculator.CalculateBatch(context.Background(), userIDs, domain.AllTime())
+
+	require.NoError(t, err)
+	for i, result := range results {
+		assert.Equal(t, userIDs[i], result.UserID)
+		assert.NoError(t, result.Err)
+	}
+	assert.Equal(t, int32(3), service.peak.Load())
+}
+
+func TestCalculateBatch_Deadline(t *testing.T) {
+	service := &slowActionService{delay: 50 * time.Millisecond}
+	mockRepo := new(MockScoreRepository)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+
+	calculator := NewScoreCalculator(service, mockRepo, NewRuleRegistry(DefaultScoringRules()),
+		WithClock(fixedClock(calculatedAt)), WithBatchWorkers(1), WithBatchTimeout(75*time.Millisecond))
+
+	results, err := calculator.CalculateBatch(context.Background(), []string{"a", "b", "c"}, domain.AllTime())
+
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	// The second user is cut short and the third never starts
+	assert.ErrorIs(t, results[1].Err, context.DeadlineExceeded)
+	assert.ErrorIs(t, results[2].Err, context.DeadlineExceeded)
+	mockRepo.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestCalculateBatch_InvalidBatch(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()),
+		WithMaxBatchSize(2))
+
+	tests := []struct {
+		name     string
+		userIDs  []string
+		window   domain.Window
+		expected error
+	}{
+		{"empty", nil, domain.AllTime(), ErrInvalidBatch},
+		{"empty user ID", []string{"alice", ""}, domain.AllTime(), ErrInvalidBatch},
+		{"duplicate user ID", []string{"alice", "alice"}, domain.AllTime(), ErrInvalidBatch},
+		{"too large", []string{"alice", "bob", "carol"}, domain.AllTime(), ErrBatchTooLarge},
+		{"invalid window", []string{"alice"}, domain.Window{Kind: domain.WindowRolling}, domain.ErrInvalidWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := calculator.CalculateBatch(context.Background(), tt.userIDs, tt.window)
+			assert.ErrorIs(t, err, tt.expected)
+			assert.Nil(t, results)
+		})
+	}
+
+	mockActionService.AssertNotCalled(t, "GetActions", mock.Anything, mock.Anything)
+}
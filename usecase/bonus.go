@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"scoreapp/domain"
+)
+
+// StreakPolicy rewards consecutive calendar days with at least one scored
+// action of ActionType. Each milestone is awarded once per run of days.
+type StreakPolicy struct {
+	ActionType string            `json:"action_type" yaml:"action_type"`
+	Milestones []StreakMilestone `json:"milestones" yaml:"milestones"`
+}
+
+// StreakMilestone awards Points when a streak reaches Days consecutive days.
+type StreakMilestone struct {
+	Days   int   `json:"days" yaml:"days"`
+	Points int64 `json:"points" yaml:"points"`
+}
+
+// ComboRule multiplies the points of Count scored actions of ActionType
+// that happen within WithinMinutes of each other. Combos do not overlap.
+type ComboRule struct {
+	Name          string  `json:"name" yaml:"name"`
+	ActionType    string  `json:"action_type" yaml:"action_type"`
+	Count         int     `json:"count" yaml:"count"`
+	WithinMinutes int     `json:"within_minutes" yaml:"within_minutes"`
+	Multiplier    float64 `json:"multiplier" yaml:"multiplier"`
+}
+
+// Validate checks that the streak policy is well formed.
+func (p *StreakPolicy) Validate() error {
+	if p.ActionType == "" {
+		return fmt.Errorf("%w: streak needs an action_type", ErrInvalidRules)
+	}
+	if len(p.Milestones) == 0 {
+		return fmt.Errorf("%w: streak needs at least one milestone", ErrInvalidRules)
+	}
+	for _, m := range p.Milestones {
+		if m.Days <= 0 || m.Points <= 0 {
+			return fmt.Errorf("%w: streak milestones need positive days and points", ErrInvalidRules)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the combo rule is well formed.
+func (r *ComboRule) Validate() error {
+	if r.Name == "" || r.ActionType == "" {
+		return fmt.Errorf("%w: combo needs a name and an action_type", ErrInvalidRules)
+	}
+	if r.Count < 2 {
+		return fmt.Errorf("%w: combo %q needs a count of at least 2", ErrInvalidRules, r.Name)
+	}
+	if r.WithinMinutes <= 0 {
+		return fmt.Errorf("%w: combo %q needs a positive within_minutes", ErrInvalidRules, r.Name)
+	}
+	if r.Multiplier < 1 {
+		return fmt.Errorf("%w: combo %q needs a multiplier of at least 1", ErrInvalidRules, r.Name)
+	}
+	return nil
+}
+
+// evaluate returns the current streak length at now and the milestone
+// bonuses earned by every run of consecutive days. The current streak stays
+// alive until a full day without a qualifying action has passed.
+func (p *StreakPolicy) evaluate(contributions []domain.ActionContribution, now time.Time, loc *time.Location) (int, []domain.Bonus) {
+	active := make(map[time.Time]struct{})
+	for _, c := range contributions {
+		if c.Action.Type != p.ActionType || c.Action.OccurredAt.IsZero() {
+			continue
+		}
+		active[startOfDay(c.Action.OccurredAt, loc)] = struct{}{}
+	}
+	if len(active) == 0 {
+		return 0, nil
+	}
+
+	days := make([]time.Time, 0, len(active))
+	for d := range active {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	var bonuses []domain.Bonus
+	runStart := 0
+	for i := 1; i <= len(days); i++ {
+		if i < len(days) && days[i].Equal(days[i-1].AddDate(0, 0, 1)) {
+			continue
+		}
+		bonuses = append(bonuses, p.runBonuses(days[runStart], i-runStart)...)
+		runStart = i
+	}
+
+	// Count back from today, or from yesterday when today has no action yet
+	current := 0
+	day := startOfDay(now, loc)
+	if _, ok := active[day]; !ok {
+		day = day.AddDate(0, 0, -1)
+	}
+	for {
+		if _, ok := active[day]; !ok {
+			break
+		}
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return current, bonuses
+}
+
+func (p *StreakPolicy) runBonuses(start time.Time, length int) []domain.Bonus {
+	var bonuses []domain.Bonus
+	for _, m := range p.Milestones {
+		if length < m.Days {
+			continue
+		}
+		bonuses = append(bonuses, domain.Bonus{
+			Kind:   domain.BonusStreak,
+			Rule:   fmt.Sprintf("streak_%dd", m.Days),
+			Points: m.Points,
+			From:   start,
+			To:     start.AddDate(0, 0, m.Days),
+		})
+	}
+	return bonuses
+}
+
+// evaluate finds non-overlapping combos among the contributions and returns
+// the extra points each one earns on top of the actions' own points.
+func (r *ComboRule) evaluate(contributions []domain.ActionContribution, c *checked) []domain.Bonus {
+	var matching []domain.ActionContribution
+	for _, c := range contributions {
+		if c.Action.Type == r.ActionType && !c.Action.OccurredAt.IsZero() {
+			matching = append(matching, c)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].Action.OccurredAt.Before(matching[j].Action.OccurredAt)
+	})
+
+	within := time.Duration(r.WithinMinutes) * time.Minute
+
+	var bonuses []domain.Bonus
+	for i := 0; i+r.Count <= len(matching); {
+		first := matching[i].Action.OccurredAt
+		last := matching[i+r.Count-1].Action.OccurredAt
+		if last.Sub(first) > within {
+			i++
+			continue
+		}
+
+		var points int64
+		for _, m := range matching[i : i+r.Count] {
+			points = c.add(points, m.Points)
+		}
+		bonuses = append(bonuses, domain.Bonus{
+			Kind:   domain.BonusCombo,
+			Rule:   r.Name,
+			Points: c.scale(points, r.Multiplier-1),
+			From:   first,
+			To:     last,
+		})
+		i += r.Count
+	}
+	return bonuses
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
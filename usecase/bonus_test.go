@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"scoreapp/domain"
+)
+
+// dailyLogins returns one login per day for the given day offsets from now.
+func dailyLogins(now time.Time, offsets ...int) []domain.UserAction {
+	actions := make([]domain.UserAction, 0, len(offsets))
+	for _, offset := range offsets {
+		actions = append(actions, domain.UserAction{
+			ID:         fmt.Sprintf("login-%d", offset),
+			Type:       "login",
+			Amount:     1,
+			OccurredAt: now.AddDate(0, 0, offset),
+		})
+	}
+	return actions
+}
+
+// challengesAt returns one challenge_completed per minute offset from start.
+func challengesAt(start time.Time, minutes ...int) []domain.UserAction {
+	actions := make([]domain.UserAction, 0, len(minutes))
+	for _, m := range minutes {
+		actions = append(actions, domain.UserAction{
+			ID:         fmt.Sprintf("challenge-%d", m),
+			Type:       "challenge_completed",
+			Amount:     1,
+			OccurredAt: start.Add(time.Duration(m) * time.Minute),
+		})
+	}
+	return actions
+}
+
+func TestScoreCalculation_StreakBonuses(t *testing.T) {
+	now := time.Date(2025, 6, 11, 12, 0, 0, 0, time.UTC)
+	streak := &StreakPolicy{
+		ActionType: "login",
+		Milestones: []StreakMilestone{{Days: 3, Points: 5}, {Days: 7, Points: 20}},
+	}
+
+	tests := []struct {
+		name           string
+		actions        []domain.UserAction
+		expectedScore  int64
+		expectedStreak int
+	}{
+		{
+			name:           "no logins",
+			actions:        nil,
+			expectedScore:  0,
+			expectedStreak: 0,
+		},
+		{
+			name:           "seven day streak ending today",
+			actions:        dailyLogins(now, 0, -1, -2, -3, -4, -5, -6),
+			expectedScore:  7 + 5 + 20,
+			expectedStreak: 7,
+		},
+		{
+			name:           "streak ending yesterday is still current",
+			actions:        dailyLogins(now, -1, -2, -3),
+			expectedScore:  3 + 5,
+			expectedStreak: 3,
+		},
+		{
+			name:           "broken streak keeps earned bonuses",
+			actions:        dailyLogins(now, -3, -4, -5),
+			expectedScore:  3 + 5,
+			expectedStreak: 0,
+		},
+		{
+			name:           "each run earns its milestones",
+			actions:        dailyLogins(now, 0, -1, -2, -4, -5, -6),
+			expectedScore:  6 + 5 + 5,
+			expectedStreak: 3,
+		},
+		{
+			name: "several logins on one day count once",
+			actions: append(dailyLogins(now, 0, -1),
+				domain.UserAction{ID: "extra", Type: "login", Amount: 1, OccurredAt: now.Add(-time.Hour)}),
+			expectedScore:  3,
+			expectedStreak: 2,
+		},
+		{
+			name:           "legacy actions without timestamps do not count",
+			actions:        []domain.UserAction{{Type: "login", Amount: 1}, {Type: "login", Amount: 1}},
+			expectedScore:  2,
+			expectedStreak: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockActionService := new(MockActionService)
+			mockRepo := new(MockScoreRepository)
+
+			userID := "user"
+			rules := DefaultScoringRules()
+			rules.Streak = streak
+
+			mockActionService.On("GetActions", mock.Anything, userID).Return(tt.actions, nil)
+			mockRepo.On("Save", mock.Anything, domain.UserScore{
+				UserID:       userID,
+				Score:        tt.expectedScore,
+				RuleVersion:  1,
+				Window:       "all_time",
+				Streak:       tt.expectedStreak,
+				CalculatedAt: now,
+			}).Return(nil)
+
+			calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(rules), WithClock(fixedClock(now)))
+
+			score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, score.Score)
+			assert.Equal(t, tt.expectedStreak, score.Streak)
+			mockActionService.AssertExpectations(t)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScoreCalculation_ComboBonuses(t *testing.T) {
+	start := time.Date(2025, 6, 11, 9, 0, 0, 0, time.UTC)
+	combo := ComboRule{
+		Name:          "challenge_rush",
+		ActionType:    "challenge_completed",
+		Count:         3,
+		WithinMinutes: 60,
+		Multiplier:    1.5,
+	}
+
+	tests := []struct {
+		name          string
+		actions       []domain.UserAction
+		expectedScore int64
+	}{
+		{
+			name:          "three within an hour",
+			actions:       challengesAt(start, 0, 20, 60),
+			expectedScore: 30 + 15,
+		},
+		{
+			name:          "three spread over more than an hour",
+			actions:       challengesAt(start, 0, 30, 61),
+			expectedScore: 30,
+		},
+		{
+			name:          "two is not enough",
+			actions:       challengesAt(start, 0, 1),
+			expectedScore: 20,
+		},
+		{
+			name:          "combos do not overlap",
+			actions:       challengesAt(start, 0, 1, 2, 3, 4, 5, 6),
+			expectedScore: 70 + 15 + 15,
+		},
+		{
+			name:          "order of actions does not matter",
+			actions:       challengesAt(start, 50, 0, 200, 10),
+			expectedScore: 40 + 15,
+		},
+		{
+			name: "other action types do not count",
+			actions: append(challengesAt(start, 0, 1),
+				domain.UserAction{ID: "quiz", Type: "quiz_answer", Amount: 1, OccurredAt: start.Add(2 * time.Minute)}),
+			expectedScore: 22,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockActionService := new(MockActionService)
+			mockRepo := new(MockScoreRepository)
+
+			userID := "user"
+			rules := DefaultScoringRules()
+			rules.Combos = []ComboRule{combo}
+
+			mockActionService.On("GetActions", mock.Anything, userID).Return(tt.actions, nil)
+			mockRepo.On("Save", mock.Anything, domain.UserScore{
+				UserID:       userID,
+				Score:        tt.expectedScore,
+				RuleVersion:  1,
+				Window:       "all_time",
+				CalculatedAt: start,
+			}).Return(nil)
+
+			calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(rules), WithClock(fixedClock(start)))
+
+			score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, score.Score)
+			mockActionService.AssertExpectations(t)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScoreExplanation_Bonuses(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	now := time.Date(2025, 6, 11, 12, 0, 0, 0, time.UTC)
+	userID := "user"
+	actions := append(dailyLogins(now, 0, -1, -2), challengesAt(now.Add(-time.Hour), 0, 10, 20)...)
+	rules := DefaultScoringRules()
+	rules.Streak = &StreakPolicy{ActionType: "login", Milestones: []StreakMilestone{{Days: 3, Points: 5}}}
+	rules.Combos = []ComboRule{{Name: "rush", ActionType: "challenge_completed", Count: 3, WithinMinutes: 60, Multiplier: 2}}
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(rules), WithClock(fixedClock(now)))
+
+	breakdown, err := calculator.Explain(context.Background(), userID, domain.AllTime())
+
+	streakStart := time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3+30+5+30), breakdown.Score)
+	assert.Equal(t, 3, breakdown.Streak)
+	assert.Equal(t, []domain.Bonus{
+		{Kind: domain.BonusStreak, Rule: "streak_3d", Points: 5, From: streakStart, To: streakStart.AddDate(0, 0, 3)},
+		{Kind: domain.BonusCombo, Rule: "rush", Points: 30, From: now.Add(-time.Hour), To: now.Add(-40 * time.Minute)},
+	}, breakdown.Bonuses)
+	mockActionService.AssertExpectations(t)
+}
+
+func TestScoringRules_ValidateSequenceRules(t *testing.T) {
+	valid := ComboRule{Name: "rush", ActionType: "challenge_completed", Count: 3, WithinMinutes: 60, Multiplier: 1.5}
+
+	tests := []struct {
+		name   string
+		streak *StreakPolicy
+		combos []ComboRule
+	}{
+		{"streak without action type", &StreakPolicy{Milestones: []StreakMilestone{{Days: 7, Points: 20}}}, nil},
+		{"streak without milestones", &StreakPolicy{ActionType: "login"}, nil},
+		{"streak with zero days", &StreakPolicy{ActionType: "login", Milestones: []StreakMilestone{{Days: 0, Points: 20}}}, nil},
+		{"combo without name", nil, []ComboRule{{ActionType: "login", Count: 2, WithinMinutes: 1, Multiplier: 2}}},
+		{"combo count below two", nil, []ComboRule{{Name: "x", ActionType: "login", Count: 1, WithinMinutes: 1, Multiplier: 2}}},
+		{"combo without duration", nil, []ComboRule{{Name: "x", ActionType: "login", Count: 2, Multiplier: 2}}},
+		{"combo multiplier below one", nil, []ComboRule{{Name: "x", ActionType: "login", Count: 2, WithinMinutes: 1, Multiplier: 0.5}}},
+		{"duplicate combo names", nil, []ComboRule{valid, valid}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultScoringRules()
+			rules.Streak = tt.streak
+			rules.Combos = tt.combos
+
+			assert.ErrorIs(t, rules.Validate(), ErrInvalidRules)
+		})
+	}
+}
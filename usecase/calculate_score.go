@@ -1,8 +1,10 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"scoreapp/domain"
 )
@@ -10,67 +12,350 @@ import (
 // ErrUserNotFound is returned when a user is not found.
 var ErrUserNotFound = errors.New("user not found")
 
+// ErrActionServiceUnavailable is returned when the action service cannot
+// answer for now, e.g. while it keeps failing or is known to be down.
+var ErrActionServiceUnavailable = errors.New("action service unavailable")
+
 // ActionService abstracts an external system that returns user actions.
 type ActionService interface {
-	GetActions(userID string) ([]domain.UserAction, error)
+	GetActions(ctx context.Context, userID string) ([]domain.UserAction, error)
 }
 
 // ScoreRepository abstracts where we persist the calculated score.
 type ScoreRepository interface {
-	Save(score domain.UserScore) error
+	Save(ctx context.Context, score domain.UserScore) error
 }
 
 // ScoreCalculator contains the business logic to calculate and persist scores.
 type ScoreCalculator struct {
 	actionService ActionService
 	repo          ScoreRepository
+	rules         RuleProvider
+	location      *time.Location
+	now           func() time.Time
+	metrics       Metrics
+	overflow      OverflowPolicy
+	actionTimeout time.Duration
+	repoTimeout   time.Duration
+	batchWorkers  int
+	maxBatchSize  int
+	batchTimeout  time.Duration
+	locks         *UserLocks
+}
+
+// Option configures optional ScoreCalculator behavior.
+type Option func(*ScoreCalculator)
+
+// WithLocation sets the timezone calendar windows are aligned to when the
+// window does not specify one. Defaults to UTC.
+func WithLocation(loc *time.Location) Option {
+	return func(c *ScoreCalculator) {
+		c.location = loc
+	}
+}
+
+// WithClock sets the source of the current time, used to resolve windows
+// and the age of decaying actions. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(c *ScoreCalculator) {
+		c.now = now
+	}
+}
+
+// WithMetrics sets the sink for scoring metrics. Defaults to discarding them.
+func WithMetrics(m Metrics) Option {
+	return func(c *ScoreCalculator) {
+		c.metrics = m
+	}
+}
+
+// WithOverflowPolicy sets how scores that do not fit in 64 bits are handled.
+// Defaults to OverflowError.
+func WithOverflowPolicy(p OverflowPolicy) Option {
+	return func(c *ScoreCalculator) {
+		c.overflow = p
+	}
+}
+
+// WithActionServiceTimeout bounds each call to the ActionService. Zero, the
+// default, leaves calls bounded only by the caller's context.
+func WithActionServiceTimeout(d time.Duration) Option {
+	return func(c *ScoreCalculator) {
+		c.actionTimeout = d
+	}
+}
+
+// WithRepositoryTimeout bounds each call to the ScoreRepository. Zero, the
+// default, leaves calls bounded only by the caller's context.
+func WithRepositoryTimeout(d time.Duration) Option {
+	return func(c *ScoreCalculator) {
+		c.repoTimeout = d
+	}
+}
+
+// WithBatchWorkers sets how many users of a batch are calculated at once.
+// Defaults to DefaultBatchWorkers.
+func WithBatchWorkers(n int) Option {
+	return func(c *ScoreCalculator) {
+		c.batchWorkers = n
+	}
+}
+
+// WithMaxBatchSize sets the largest batch CalculateBatch accepts. Defaults
+// to DefaultMaxBatchSize.
+func WithMaxBatchSize(n int) Option {
+	return func(c *ScoreCalculator) {
+		c.maxBatchSize = n
+	}
+}
+
+// WithBatchTimeout bounds each call to CalculateBatch. Zero, the default,
+// leaves batches bounded only by the caller's context.
+func WithBatchTimeout(d time.Duration) Option {
+	return func(c *ScoreCalculator) {
+		c.batchTimeout = d
+	}
+}
+
+// WithUserLocks locks the user while calculating and saving all-time
+// scores, so they do not overwrite a concurrent update of an
+// IncrementalScorer sharing the locks. Defaults to no locking.
+func WithUserLocks(locks *UserLocks) Option {
+	return func(c *ScoreCalculator) {
+		c.locks = locks
+	}
 }
 
 // NewScoreCalculator constructs a ScoreCalculator with its dependencies.
-func NewScoreCalculator(a ActionService, r ScoreRepository) *ScoreCalculator {
-	return &ScoreCalculator{
+func NewScoreCalculator(a ActionService, r ScoreRepository, rules RuleProvider, opts ...Option) *ScoreCalculator {
+	c := &ScoreCalculator{
 		actionService: a,
 		repo:          r,
+		rules:         rules,
+		location:      time.UTC,
+		now:           time.Now,
+		metrics:       noopMetrics{},
+		overflow:      OverflowError,
+		batchWorkers:  DefaultBatchWorkers,
+		maxBatchSize:  DefaultMaxBatchSize,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-// Calculate loads user actions and calculates a score
-func (c *ScoreCalculator) Calculate(userID string) (int, error) {
+// Calculate loads user actions, calculates the score within the window and
+// saves it under the user and window. Canceling ctx aborts the calculation
+// and the calls to its dependencies.
+func (c *ScoreCalculator) Calculate(ctx context.Context, userID string, window domain.Window) (domain.UserScore, error) {
+	if c.locks != nil && window.Kind == domain.WindowAllTime {
+		unlock := c.locks.Lock([]string{userID})
+		defer unlock()
+	}
+
+	now := c.now()
+	breakdown, err := c.breakdown(ctx, userID, window, now)
+	if err != nil {
+		return domain.UserScore{}, err
+	}
+	c.recordCaps(breakdown)
+
+	// Create UserScore domain object
+	userScore := domain.UserScore{
+		UserID:       userID,
+		Score:        breakdown.Score,
+		RuleVersion:  breakdown.RuleVersion,
+		Window:       breakdown.Window,
+		Streak:       breakdown.Streak,
+		CalculatedAt: now,
+		RequestID:    RequestIDFromContext(ctx),
+	}
+
+	// Save via repository
+	saveCtx, cancel := withTimeout(ctx, c.repoTimeout)
+	defer cancel()
+	if err := c.repo.Save(saveCtx, userScore); err != nil {
+		return domain.UserScore{}, fmt.Errorf("failed to save score: %w", err)
+	}
+
+	return userScore, nil
+}
+
+// Explain calculates a score like Calculate and returns how each action
+// contributed to it, without persisting anything.
+func (c *ScoreCalculator) Explain(ctx context.Context, userID string, window domain.Window) (domain.ScoreBreakdown, error) {
+	return c.breakdown(ctx, userID, window, c.now())
+}
+
+// recordCaps reports every cap hit in the breakdown to the metrics sink.
+func (c *ScoreCalculator) recordCaps(b domain.ScoreBreakdown) {
+	for _, contribution := range b.Contributions {
+		for _, hit := range contribution.Caps {
+			c.metrics.CapHit(hit.Kind, contribution.Rule)
+		}
+	}
+	for _, hit := range b.Caps {
+		c.metrics.CapHit(hit.Kind, "")
+	}
+}
+
+// breakdown loads user actions and scores each of them against the active
+// rules as of now.
+func (c *ScoreCalculator) breakdown(ctx context.Context, userID string, window domain.Window, now time.Time) (domain.ScoreBreakdown, error) {
+	if err := window.Validate(); err != nil {
+		return domain.ScoreBreakdown{}, err
+	}
+	if window.Location == nil {
+		window.Location = c.location
+	}
+	span := window.Span(now)
+
+	// Pin the rule set for the whole calculation so a concurrent reload
+	// cannot mix two versions into one score
+	ruleSet := c.rules.Current()
+
 	// Fetch actions from ActionService
-	actions, err := c.actionService.GetActions(userID)
+	actionCtx, cancel := withTimeout(ctx, c.actionTimeout)
+	defer cancel()
+	actions, err := c.actionService.GetActions(actionCtx, userID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get actions: %w", err)
+		return domain.ScoreBreakdown{}, fmt.Errorf("failed to get actions: %w", err)
 	}
 
-	// Calculate score based on rules
-	score := 0
+	breakdown := domain.ScoreBreakdown{
+		UserID:      userID,
+		RuleVersion: ruleSet.Version,
+		Window:      window.Key(),
+	}
+
+	// Calculate score based on rules, scoring each action ID once. Overflows
+	// saturate and are checked against the policy once the score is known.
+	var arith checked
+	seen := make(map[string]struct{}, len(actions))
+	daily := make(map[dailyKey]int64)
 	for _, action := range actions {
+		if action.ID != "" {
+			if _, dup := seen[action.ID]; dup {
+				breakdown.Skipped = append(breakdown.Skipped, domain.SkippedAction{
+					Action: action,
+					Reason: domain.SkipDuplicateActionID,
+				})
+				continue
+			}
+			seen[action.ID] = struct{}{}
+		}
+
+		if window.Bounded() {
+			if action.OccurredAt.IsZero() {
+				breakdown.Skipped = append(breakdown.Skipped, domain.SkippedAction{
+					Action: action,
+					Reason: domain.SkipMissingTimestamp,
+				})
+				continue
+			}
+			if !span.Contains(action.OccurredAt) {
+				breakdown.Skipped = append(breakdown.Skipped, domain.SkippedAction{
+					Action: action,
+					Reason: domain.SkipOutsideWindow,
+				})
+				continue
+			}
+		}
+
 		if action.Amount <= 0 {
+			breakdown.Skipped = append(breakdown.Skipped, domain.SkippedAction{
+				Action: action,
+				Reason: domain.SkipNonPositiveAmount,
+			})
 			continue
 		}
 
-		switch action.Type {
-		case "login":
-			score += 1
-		case "challenge_completed":
-			score += 10 * action.Amount
-		case "quiz_answer":
-			score += 2 * action.Amount
-		default:
+		rule, ok := ruleSet.Rules.Match(action.Type)
+		if !ok {
+			if ruleSet.Rules.UnknownAction == UnknownActionReject {
+				return domain.ScoreBreakdown{}, fmt.Errorf("%w: %q", ErrUnknownActionType, action.Type)
+			}
+			breakdown.Skipped = append(breakdown.Skipped, domain.SkippedAction{
+				Action: action,
+				Reason: domain.SkipUnknownActionType,
+			})
 			continue
 		}
+
+		points, caps, ok := rule.Points(action.Amount)
+		arith.overflowed = arith.overflowed || !ok
+		rawPoints := points
+		for _, hit := range caps {
+			rawPoints = arith.add(rawPoints, hit.Removed)
+		}
+
+		factor := 1.0
+		// Actions without a timestamp have no known age and do not decay
+		if ruleSet.Rules.Decay != nil && !action.OccurredAt.IsZero() {
+			points, factor = ruleSet.Rules.Decay.Apply(points, now.Sub(action.OccurredAt))
+		}
+
+		// Daily caps are filled in the order actions are returned. Actions
+		// without a timestamp share a single day.
+		if rule.MaxPointsPerDay > 0 {
+			key := dailyKey{actionType: action.Type}
+			if !action.OccurredAt.IsZero() {
+				key.day = startOfDay(action.OccurredAt, window.Location)
+			}
+			if remaining := arith.sub(rule.MaxPointsPerDay, daily[key]); points > remaining {
+				caps = append(caps, domain.CapHit{Kind: domain.CapMaxPointsPerDay, Removed: arith.sub(points, remaining)})
+				points = remaining
+			}
+			daily[key] = arith.add(daily[key], points)
+		}
+
+		breakdown.Score = arith.add(breakdown.Score, points)
+		breakdown.Contributions = append(breakdown.Contributions, domain.ActionContribution{
+			Action:      action,
+			Rule:        action.Type,
+			RawPoints:   rawPoints,
+			Caps:        caps,
+			DecayFactor: factor,
+			Points:      points,
+		})
 	}
 
-	// Create UserScore domain object
-	userScore := domain.UserScore{
-		UserID: userID,
-		Score:  score,
+	// Sequence rules look at every scored action together
+	if streak := ruleSet.Rules.Streak; streak != nil {
+		current, bonuses := streak.evaluate(breakdown.Contributions, now, window.Location)
+		breakdown.Streak = current
+		breakdown.Bonuses = append(breakdown.Bonuses, bonuses...)
+	}
+	for i := range ruleSet.Rules.Combos {
+		breakdown.Bonuses = append(breakdown.Bonuses, ruleSet.Rules.Combos[i].evaluate(breakdown.Contributions, &arith)...)
+	}
+	for _, b := range breakdown.Bonuses {
+		breakdown.Score = arith.add(breakdown.Score, b.Points)
 	}
 
-	// Save via repository
-	if err := c.repo.Save(userScore); err != nil {
-		return 0, fmt.Errorf("failed to save score: %w", err)
+	if arith.overflowed && c.overflow != OverflowSaturate {
+		return domain.ScoreBreakdown{}, fmt.Errorf("%w: user %q", ErrScoreOverflow, userID)
+	}
+
+	if limit := ruleSet.Rules.MaxTotalPoints; limit > 0 && breakdown.Score > limit {
+		breakdown.Caps = append(breakdown.Caps, domain.CapHit{Kind: domain.CapMaxTotalPoints, Removed: breakdown.Score - limit})
+		breakdown.Score = limit
 	}
 
-	return score, nil
+	return breakdown, nil
+}
+
+// withTimeout bounds ctx by d, unless d is zero.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// dailyKey groups the points of an action type on one calendar day.
+type dailyKey struct {
+	actionType string
+	day        time.Time
 }
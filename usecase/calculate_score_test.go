@@ -1,8 +1,11 @@
 package usecase
 
 import (
+	"context"
 	"errors"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -15,18 +18,26 @@ type MockActionService struct {
 	mock.Mock
 }
 
-func (m *MockActionService) GetActions(userID string) ([]domain.UserAction, error) {
-	args := m.Called(userID)
+func (m *MockActionService) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	args := m.Called(ctx, userID)
 	return args.Get(0).([]domain.UserAction), args.Error(1)
 }
 
+// calculatedAt is the clock reading of tests that do not care about time.
+var calculatedAt = time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+// fixedClock returns a clock that always reports t.
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
 // MockScoreRepository is a mock for ScoreRepository.
 type MockScoreRepository struct {
 	mock.Mock
 }
 
-func (m *MockScoreRepository) Save(score domain.UserScore) error {
-	args := m.Called(score)
+func (m *MockScoreRepository) Save(ctx context.Context, score domain.UserScore) error {
+	args := m.Called(ctx, score)
 	return args.Error(0)
 }
 
@@ -35,25 +46,29 @@ func TestScoreCalculation_HappyPath(t *testing.T) {
 	mockRepo := new(MockScoreRepository)
 
 	userID := "user"
+	occurredAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
 	actions := []domain.UserAction{
-		{Type: "login", Amount: 1},
-		{Type: "challenge_completed", Amount: 3},
-		{Type: "quiz_answer", Amount: 5},
+		{ID: "a1", Type: "login", Amount: 1, OccurredAt: occurredAt},
+		{ID: "a2", Type: "challenge_completed", Amount: 3, OccurredAt: occurredAt.Add(time.Minute), Metadata: map[string]string{"challenge": "daily"}},
+		{ID: "a3", Type: "quiz_answer", Amount: 5, OccurredAt: occurredAt.Add(2 * time.Minute)},
 	}
-	expectedScore := 41
+	expectedScore := int64(41)
 
-	mockActionService.On("GetActions", userID).Return(actions, nil)
-	mockRepo.On("Save", domain.UserScore{
-		UserID: userID,
-		Score:  expectedScore,
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        expectedScore,
+		RuleVersion:  1,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
 	}).Return(nil)
 
-	calculator := NewScoreCalculator(mockActionService, mockRepo)
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(calculatedAt)))
 
-	score, err := calculator.Calculate(userID)
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedScore, score)
+	assert.Equal(t, expectedScore, score.Score)
 	mockActionService.AssertExpectations(t)
 	mockRepo.AssertExpectations(t)
 }
@@ -64,20 +79,23 @@ func TestScoreCalculation_EmptyActions(t *testing.T) {
 
 	userID := "user"
 	var actions []domain.UserAction
-	expectedScore := 0
+	expectedScore := int64(0)
 
-	mockActionService.On("GetActions", userID).Return(actions, nil)
-	mockRepo.On("Save", domain.UserScore{
-		UserID: userID,
-		Score:  expectedScore,
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        expectedScore,
+		RuleVersion:  1,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
 	}).Return(nil)
 
-	calculator := NewScoreCalculator(mockActionService, mockRepo)
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(calculatedAt)))
 
-	score, err := calculator.Calculate(userID)
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedScore, score)
+	assert.Equal(t, expectedScore, score.Score)
 	mockActionService.AssertExpectations(t)
 	mockRepo.AssertExpectations(t)
 }
@@ -92,20 +110,23 @@ func TestScoreCalculation_ActionsWithZeroAmounts(t *testing.T) {
 		{Type: "challenge_completed", Amount: 0},
 		{Type: "quiz_answer", Amount: 0},
 	}
-	expectedScore := 0
+	expectedScore := int64(0)
 
-	mockActionService.On("GetActions", userID).Return(actions, nil)
-	mockRepo.On("Save", domain.UserScore{
-		UserID: userID,
-		Score:  expectedScore,
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        expectedScore,
+		RuleVersion:  1,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
 	}).Return(nil)
 
-	calculator := NewScoreCalculator(mockActionService, mockRepo)
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(calculatedAt)))
 
-	score, err := calculator.Calculate(userID)
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedScore, score)
+	assert.Equal(t, expectedScore, score.Score)
 	mockActionService.AssertExpectations(t)
 	mockRepo.AssertExpectations(t)
 }
@@ -120,20 +141,23 @@ func TestScoreCalculation_ActionsWithNegativeAmounts(t *testing.T) {
 		{Type: "challenge_completed", Amount: -5},
 		{Type: "quiz_answer", Amount: -7},
 	}
-	expectedScore := 0
+	expectedScore := int64(0)
 
-	mockActionService.On("GetActions", userID).Return(actions, nil)
-	mockRepo.On("Save", domain.UserScore{
-		UserID: userID,
-		Score:  expectedScore,
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        expectedScore,
+		RuleVersion:  1,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
 	}).Return(nil)
 
-	calculator := NewScoreCalculator(mockActionService, mockRepo)
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(calculatedAt)))
 
-	score, err := calculator.Calculate(userID)
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedScore, score)
+	assert.Equal(t, expectedScore, score.Score)
 	mockActionService.AssertExpectations(t)
 	mockRepo.AssertExpectations(t)
 }
@@ -148,20 +172,23 @@ func TestScoreCalculation_UnknownActionType(t *testing.T) {
 		{Type: "unknown_action", Amount: 100}, // Should be ignored
 		{Type: "challenge_completed", Amount: 2},
 	}
-	expectedScore := 21
+	expectedScore := int64(21)
 
-	mockActionService.On("GetActions", userID).Return(actions, nil)
-	mockRepo.On("Save", domain.UserScore{
-		UserID: userID,
-		Score:  expectedScore,
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        expectedScore,
+		RuleVersion:  1,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
 	}).Return(nil)
 
-	calculator := NewScoreCalculator(mockActionService, mockRepo)
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(calculatedAt)))
 
-	score, err := calculator.Calculate(userID)
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedScore, score)
+	assert.Equal(t, expectedScore, score.Score)
 	mockActionService.AssertExpectations(t)
 	mockRepo.AssertExpectations(t)
 }
@@ -173,17 +200,17 @@ func TestScoreCalculation_ActionServiceError(t *testing.T) {
 	userID := "user"
 	expectedError := errors.New("service unavailable")
 
-	mockActionService.On("GetActions", userID).Return([]domain.UserAction(nil), expectedError)
+	mockActionService.On("GetActions", mock.Anything, userID).Return([]domain.UserAction(nil), expectedError)
 
-	calculator := NewScoreCalculator(mockActionService, mockRepo)
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()))
 
-	score, err := calculator.Calculate(userID)
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
 
 	assert.Error(t, err)
-	assert.Equal(t, 0, score)
+	assert.Equal(t, domain.UserScore{}, score)
 	assert.Contains(t, err.Error(), "failed to get actions")
 	mockActionService.AssertExpectations(t)
-	mockRepo.AssertNotCalled(t, "Save", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
 }
 
 func TestScoreCalculation_RepositoryError(t *testing.T) {
@@ -196,16 +223,879 @@ func TestScoreCalculation_RepositoryError(t *testing.T) {
 	}
 	expectedError := errors.New("database error")
 
-	mockActionService.On("GetActions", userID).Return(actions, nil)
-	mockRepo.On("Save", mock.Anything).Return(expectedError)
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(expectedError)
 
-	calculator := NewScoreCalculator(mockActionService, mockRepo)
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()))
 
-	score, err := calculator.Calculate(userID)
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
 
 	assert.Error(t, err)
-	assert.Equal(t, 0, score)
+	assert.Equal(t, domain.UserScore{}, score)
+	assert.Contains(t, err.Error(), "failed to save score")
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestScoreCalculation_RejectUnknownActionType(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	actions := []domain.UserAction{
+		{Type: "login", Amount: 1},
+		{Type: "unknown_action", Amount: 100},
+	}
+
+	rules := DefaultScoringRules()
+	rules.UnknownAction = UnknownActionReject
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(rules))
+
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+	assert.ErrorIs(t, err, ErrUnknownActionType)
+	assert.Equal(t, domain.UserScore{}, score)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestScoreCalculation_CustomRules(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	actions := []domain.UserAction{
+		{Type: "login", Amount: 1},
+		{Type: "challenge_completed", Amount: 3},
+		{Type: "quiz_answer", Amount: 50},
+	}
+	rules := &ScoringRules{
+		Rules: map[string]Rule{
+			"login":               {Base: 5},
+			"challenge_completed": {Base: 2, Multiplier: 20},
+			"quiz_answer":         {Multiplier: 2, MaxPoints: 40},
+		},
+		UnknownAction: UnknownActionIgnore,
+	}
+	expectedScore := int64(5 + 62 + 40)
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        expectedScore,
+		RuleVersion:  1,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
+	}).Return(nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(rules), WithClock(fixedClock(calculatedAt)))
+
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedScore, score.Score)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestScoreCalculation_RuleReloadDuringCalculation(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	actions := []domain.UserAction{
+		{Type: "login", Amount: 1},
+		{Type: "quiz_answer", Amount: 5},
+	}
+	registry := NewRuleRegistry(DefaultScoringRules())
+	reloaded := &ScoringRules{
+		Rules:         map[string]Rule{"quiz_answer": {Multiplier: 100}},
+		UnknownAction: UnknownActionIgnore,
+	}
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil).Run(func(mock.Arguments) {
+		// A reload that lands mid-calculation must not affect the pinned rule set
+		_, err := registry.Update(context.Background(), reloaded)
+		assert.NoError(t, err)
+	}).Once()
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        11,
+		RuleVersion:  1,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
+	}).Return(nil).Once()
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, registry, WithClock(fixedClock(calculatedAt)))
+
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), score.Score)
+
+	// The next calculation picks up the new version
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil).Once()
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        500,
+		RuleVersion:  2,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
+	}).Return(nil).Once()
+
+	score, err = calculator.Calculate(context.Background(), userID, domain.AllTime())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), score.Score)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestScoreExplanation(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	actions := []domain.UserAction{
+		{Type: "login", Amount: 1},
+		{Type: "challenge_completed", Amount: 3},
+		{Type: "quiz_answer", Amount: 0},
+		{Type: "unknown_action", Amount: 100},
+		{Type: "quiz_answer", Amount: 5},
+	}
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()))
+
+	breakdown, err := calculator.Explain(context.Background(), userID, domain.AllTime())
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ScoreBreakdown{
+		UserID:      userID,
+		Score:       41,
+		RuleVersion: 1,
+		Window:      "all_time",
+		Contributions: []domain.ActionContribution{
+			{Action: actions[0], Rule: "login", RawPoints: 1, DecayFactor: 1, Points: 1},
+			{Action: actions[1], Rule: "challenge_completed", RawPoints: 30, DecayFactor: 1, Points: 30},
+			{Action: actions[4], Rule: "quiz_answer", RawPoints: 10, DecayFactor: 1, Points: 10},
+		},
+		Skipped: []domain.SkippedAction{
+			{Action: actions[2], Reason: domain.SkipNonPositiveAmount},
+			{Action: actions[3], Reason: domain.SkipUnknownActionType},
+		},
+	}, breakdown)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestScoreExplanation_ActionServiceError(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return([]domain.UserAction(nil), ErrUserNotFound)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()))
+
+	breakdown, err := calculator.Explain(context.Background(), userID, domain.AllTime())
+
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.Equal(t, domain.ScoreBreakdown{}, breakdown)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestScoreCalculation_DuplicateActionIDs(t *testing.T) {
+	tests := []struct {
+		name          string
+		actions       []domain.UserAction
+		expectedScore int64
+	}{
+		{
+			name: "replayed action scored once",
+			actions: []domain.UserAction{
+				{ID: "a1", Type: "challenge_completed", Amount: 2},
+				{ID: "a1", Type: "challenge_completed", Amount: 2},
+				{ID: "a2", Type: "quiz_answer", Amount: 1},
+			},
+			expectedScore: 22,
+		},
+		{
+			name: "first occurrence wins",
+			actions: []domain.UserAction{
+				{ID: "a1", Type: "quiz_answer", Amount: 0},
+				{ID: "a1", Type: "quiz_answer", Amount: 50},
+			},
+			expectedScore: 0,
+		},
+		{
+			name: "legacy actions without IDs are not deduplicated",
+			actions: []domain.UserAction{
+				{Type: "quiz_answer", Amount: 2},
+				{Type: "quiz_answer", Amount: 2},
+				{ID: "a1", Type: "login", Amount: 1},
+			},
+			expectedScore: 9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockActionService := new(MockActionService)
+			mockRepo := new(MockScoreRepository)
+
+			userID := "user"
+
+			mockActionService.On("GetActions", mock.Anything, userID).Return(tt.actions, nil)
+			mockRepo.On("Save", mock.Anything, domain.UserScore{
+				UserID:       userID,
+				Score:        tt.expectedScore,
+				RuleVersion:  1,
+				Window:       "all_time",
+				CalculatedAt: calculatedAt,
+			}).Return(nil)
+
+			calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(calculatedAt)))
+
+			score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, score.Score)
+			mockActionService.AssertExpectations(t)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScoreExplanation_DuplicateActionID(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	actions := []domain.UserAction{
+		{ID: "a1", Type: "login", Amount: 1},
+		{ID: "a1", Type: "login", Amount: 1},
+	}
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()))
+
+	breakdown, err := calculator.Explain(context.Background(), userID, domain.AllTime())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), breakdown.Score)
+	assert.Equal(t, []domain.SkippedAction{
+		{Action: actions[1], Reason: domain.SkipDuplicateActionID},
+	}, breakdown.Skipped)
+	mockActionService.AssertExpectations(t)
+}
+
+func TestScoreCalculation_Windows(t *testing.T) {
+	now := time.Date(2025, 6, 11, 12, 0, 0, 0, time.UTC)
+	actions := []domain.UserAction{
+		{ID: "a1", Type: "challenge_completed", Amount: 1, OccurredAt: now.Add(-time.Minute)},
+		{ID: "a2", Type: "challenge_completed", Amount: 2, OccurredAt: now.AddDate(0, 0, -3)},
+		{ID: "a3", Type: "challenge_completed", Amount: 4, OccurredAt: now.AddDate(0, 0, -20)},
+		{ID: "a4", Type: "challenge_completed", Amount: 8, OccurredAt: now.AddDate(-1, 0, 0)},
+		{Type: "quiz_answer", Amount: 1},
+	}
+
+	tests := []struct {
+		name          string
+		window        domain.Window
+		expectedKey   string
+		expectedScore int64
+	}{
+		{"all time", domain.AllTime(), "all_time", 152},
+		{"rolling 7 days", domain.Window{Kind: domain.WindowRolling, Days: 7}, "rolling_7d", 30},
+		{"rolling 30 days", domain.Window{Kind: domain.WindowRolling, Days: 30}, "rolling_30d", 70},
+		{"rolling 400 days", domain.Window{Kind: domain.WindowRolling, Days: 400}, "rolling_400d", 150},
+		{"calendar week", domain.Window{Kind: domain.WindowWeek}, "week@UTC", 10},
+		{"calendar day", domain.Window{Kind: domain.WindowDay}, "day@UTC", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockActionService := new(MockActionService)
+			mockRepo := new(MockScoreRepository)
+
+			userID := "user"
+			expected := domain.UserScore{
+				UserID:       userID,
+				Score:        tt.expectedScore,
+				RuleVersion:  1,
+				Window:       tt.expectedKey,
+				CalculatedAt: now,
+			}
+
+			mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+			mockRepo.On("Save", mock.Anything, expected).Return(nil)
+
+			calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(now)))
+
+			score, err := calculator.Calculate(context.Background(), userID, tt.window)
+
+			assert.NoError(t, err)
+			assert.Equal(t, expected, score)
+			mockActionService.AssertExpectations(t)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScoreCalculation_CalendarWindowUsesDefaultLocation(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	assert.NoError(t, err)
+
+	userID := "user"
+	now := time.Now()
+	actions := []domain.UserAction{
+		{ID: "a1", Type: "login", Amount: 1, OccurredAt: now},
+	}
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        1,
+		RuleVersion:  1,
+		Window:       "month@Europe/Istanbul",
+		CalculatedAt: now,
+	}).Return(nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithLocation(istanbul), WithClock(fixedClock(now)))
+
+	score, err := calculator.Calculate(context.Background(), userID, domain.Window{Kind: domain.WindowMonth})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), score.Score)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestScoreExplanation_WindowSkips(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	actions := []domain.UserAction{
+		{ID: "a1", Type: "login", Amount: 1, OccurredAt: time.Now()},
+		{ID: "a2", Type: "login", Amount: 1, OccurredAt: time.Now().AddDate(0, 0, -2)},
+		{Type: "login", Amount: 1},
+	}
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()))
+
+	breakdown, err := calculator.Explain(context.Background(), userID, domain.Window{Kind: domain.WindowRolling, Days: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), breakdown.Score)
+	assert.Equal(t, "rolling_1d", breakdown.Window)
+	assert.Equal(t, []domain.SkippedAction{
+		{Action: actions[1], Reason: domain.SkipOutsideWindow},
+		{Action: actions[2], Reason: domain.SkipMissingTimestamp},
+	}, breakdown.Skipped)
+	mockActionService.AssertExpectations(t)
+}
+
+func TestScoreExplanation_RollingWindowSkipsFutureActions(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	actions := []domain.UserAction{
+		{ID: "a1", Type: "login", Amount: 1, OccurredAt: calculatedAt.Add(-time.Hour)},
+		{ID: "a2", Type: "login", Amount: 1, OccurredAt: calculatedAt.Add(30 * time.Second)},
+		{ID: "a3", Type: "login", Amount: 1, OccurredAt: calculatedAt.AddDate(0, 0, 1)},
+	}
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(calculatedAt)))
+
+	breakdown, err := calculator.Explain(context.Background(), userID, domain.Window{Kind: domain.WindowRolling, Days: 7})
+
+	// An action a little ahead of the clock counts, one dated tomorrow does not
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), breakdown.Score)
+	assert.Equal(t, []domain.SkippedAction{
+		{Action: actions[2], Reason: domain.SkipOutsideWindow},
+	}, breakdown.Skipped)
+}
+
+func TestScoreCalculation_InvalidWindow(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()))
+
+	score, err := calculator.Calculate(context.Background(), "user", domain.Window{Kind: domain.WindowRolling})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidWindow)
+	assert.Equal(t, domain.UserScore{}, score)
+	mockActionService.AssertNotCalled(t, "GetActions", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestScoreCalculation_Decay(t *testing.T) {
+	now := time.Date(2025, 6, 11, 12, 0, 0, 0, time.UTC)
+	actions := []domain.UserAction{
+		{ID: "fresh", Type: "challenge_completed", Amount: 10, OccurredAt: now},
+		{ID: "week", Type: "challenge_completed", Amount: 10, OccurredAt: now.AddDate(0, 0, -7)},
+		{ID: "month", Type: "challenge_completed", Amount: 10, OccurredAt: now.AddDate(0, 0, -28)},
+		{Type: "challenge_completed", Amount: 10},
+	}
+
+	tests := []struct {
+		name          string
+		decay         *DecayPolicy
+		expectedScore int64
+	}{
+		{"no decay", nil, 400},
+		{"exponential half-life", &DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7}, 100 + 50 + 6 + 100},
+		{"exponential with floor", &DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7, Floor: 0.2}, 100 + 60 + 25 + 100},
+		{"linear to zero", &DecayPolicy{Kind: DecayLinear, DurationDays: 14}, 100 + 50 + 0 + 100},
+		{"linear to floor", &DecayPolicy{Kind: DecayLinear, DurationDays: 14, Floor: 0.5}, 100 + 75 + 50 + 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockActionService := new(MockActionService)
+			mockRepo := new(MockScoreRepository)
+
+			userID := "user"
+			rules := DefaultScoringRules()
+			rules.Decay = tt.decay
+
+			mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+			mockRepo.On("Save", mock.Anything, domain.UserScore{
+				UserID:       userID,
+				Score:        tt.expectedScore,
+				RuleVersion:  1,
+				Window:       "all_time",
+				CalculatedAt: now,
+			}).Return(nil)
+
+			calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(rules), WithClock(fixedClock(now)))
+
+			score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, score.Score)
+			mockActionService.AssertExpectations(t)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScoreExplanation_DecayedValues(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	now := time.Date(2025, 6, 11, 12, 0, 0, 0, time.UTC)
+	userID := "user"
+	actions := []domain.UserAction{
+		{ID: "a1", Type: "challenge_completed", Amount: 3, OccurredAt: now.AddDate(0, 0, -7)},
+		{Type: "quiz_answer", Amount: 5},
+	}
+	rules := DefaultScoringRules()
+	rules.Decay = &DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7}
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(rules), WithClock(fixedClock(now)))
+
+	breakdown, err := calculator.Explain(context.Background(), userID, domain.AllTime())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(25), breakdown.Score)
+	assert.Equal(t, []domain.ActionContribution{
+		{Action: actions[0], Rule: "challenge_completed", RawPoints: 30, DecayFactor: 0.5, Points: 15},
+		{Action: actions[1], Rule: "quiz_answer", RawPoints: 10, DecayFactor: 1, Points: 10},
+	}, breakdown.Contributions)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+// MockMetrics is a mock for Metrics.
+type MockMetrics struct {
+	mock.Mock
+}
+
+func (m *MockMetrics) CapHit(kind domain.CapKind, actionType string) {
+	m.Called(kind, actionType)
+}
+
+func TestScoreCalculation_Caps(t *testing.T) {
+	day1 := time.Date(2025, 6, 10, 9, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name          string
+		rules         *ScoringRules
+		actions       []domain.UserAction
+		expectedScore int64
+		expectedHits  []domain.CapKind
+	}{
+		{
+			name: "per action cap stops farming",
+			rules: &ScoringRules{
+				Rules: map[string]Rule{"quiz_answer": {Multiplier: 2, MaxPoints: 100}},
+			},
+			actions:       []domain.UserAction{{ID: "a1", Type: "quiz_answer", Amount: 1000000, OccurredAt: day1}},
+			expectedScore: 100,
+			expectedHits:  []domain.CapKind{domain.CapMaxPoints},
+		},
+		{
+			name: "per type per day cap",
+			rules: &ScoringRules{
+				Rules: map[string]Rule{
+					"quiz_answer": {Multiplier: 2, MaxPointsPerDay: 25},
+					"login":       {Base: 1},
+				},
+			},
+			actions: []domain.UserAction{
+				{ID: "a1", Type: "quiz_answer", Amount: 5, OccurredAt: day1},
+				{ID: "a2", Type: "quiz_answer", Amount: 5, OccurredAt: day1.Add(time.Hour)},
+				{ID: "a3", Type: "quiz_answer", Amount: 5, OccurredAt: day1.Add(2 * time.Hour)},
+				{ID: "a4", Type: "login", Amount: 1, OccurredAt: day1},
+				{ID: "a5", Type: "quiz_answer", Amount: 5, OccurredAt: day2},
+			},
+			expectedScore: 25 + 1 + 10,
+			expectedHits:  []domain.CapKind{domain.CapMaxPointsPerDay},
+		},
+		{
+			name: "actions without timestamps share a day",
+			rules: &ScoringRules{
+				Rules: map[string]Rule{"quiz_answer": {Multiplier: 2, MaxPointsPerDay: 5}},
+			},
+			actions: []domain.UserAction{
+				{Type: "quiz_answer", Amount: 2},
+				{Type: "quiz_answer", Amount: 2},
+			},
+			expectedScore: 5,
+			expectedHits:  []domain.CapKind{domain.CapMaxPointsPerDay},
+		},
+		{
+			name: "total cap",
+			rules: &ScoringRules{
+				Rules:          map[string]Rule{"challenge_completed": {Multiplier: 10}},
+				MaxTotalPoints: 50,
+			},
+			actions: []domain.UserAction{
+				{ID: "a1", Type: "challenge_completed", Amount: 3, OccurredAt: day1},
+				{ID: "a2", Type: "challenge_completed", Amount: 3, OccurredAt: day2},
+			},
+			expectedScore: 50,
+			expectedHits:  []domain.CapKind{domain.CapMaxTotalPoints},
+		},
+		{
+			name:          "no caps configured",
+			rules:         DefaultScoringRules(),
+			actions:       []domain.UserAction{{ID: "a1", Type: "quiz_answer", Amount: 1000000, OccurredAt: day1}},
+			expectedScore: 2000000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockActionService := new(MockActionService)
+			mockRepo := new(MockScoreRepository)
+			mockMetrics := new(MockMetrics)
+
+			userID := "user"
+			tt.rules.UnknownAction = UnknownActionIgnore
+
+			mockActionService.On("GetActions", mock.Anything, userID).Return(tt.actions, nil)
+			mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+			mockMetrics.On("CapHit", mock.Anything, mock.Anything).Return()
+
+			calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(tt.rules),
+				WithClock(fixedClock(day2)), WithMetrics(mockMetrics))
+
+			score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, score.Score)
+			mockMetrics.AssertNumberOfCalls(t, "CapHit", len(tt.expectedHits))
+			for _, kind := range tt.expectedHits {
+				mockMetrics.AssertCalled(t, "CapHit", kind, mock.Anything)
+			}
+			mockActionService.AssertExpectations(t)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScoreExplanation_Caps(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+	mockMetrics := new(MockMetrics)
+
+	day := time.Date(2025, 6, 10, 9, 0, 0, 0, time.UTC)
+	userID := "user"
+	actions := []domain.UserAction{
+		{ID: "a1", Type: "quiz_answer", Amount: 100, OccurredAt: day},
+		{ID: "a2", Type: "quiz_answer", Amount: 10, OccurredAt: day.Add(time.Minute)},
+	}
+	rules := &ScoringRules{
+		Rules:          map[string]Rule{"quiz_answer": {Multiplier: 2, MaxAmount: 20, MaxPointsPerDay: 50}},
+		UnknownAction:  UnknownActionIgnore,
+		MaxTotalPoints: 45,
+	}
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return(actions, nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(rules),
+		WithClock(fixedClock(day)), WithMetrics(mockMetrics))
+
+	breakdown, err := calculator.Explain(context.Background(), userID, domain.AllTime())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(45), breakdown.Score)
+	assert.Equal(t, []domain.ActionContribution{
+		{
+			Action:      actions[0],
+			Rule:        "quiz_answer",
+			RawPoints:   200,
+			Caps:        []domain.CapHit{{Kind: domain.CapMaxAmount, Removed: 160}},
+			DecayFactor: 1,
+			Points:      40,
+		},
+		{
+			Action:      actions[1],
+			Rule:        "quiz_answer",
+			RawPoints:   20,
+			Caps:        []domain.CapHit{{Kind: domain.CapMaxPointsPerDay, Removed: 10}},
+			DecayFactor: 1,
+			Points:      10,
+		},
+	}, breakdown.Contributions)
+	assert.Equal(t, []domain.CapHit{{Kind: domain.CapMaxTotalPoints, Removed: 5}}, breakdown.Caps)
+
+	// Explaining does not count towards abuse metrics
+	mockMetrics.AssertNotCalled(t, "CapHit", mock.Anything, mock.Anything)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestScoreCalculation_Overflow(t *testing.T) {
+	occurredAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		rules         *ScoringRules
+		actions       []domain.UserAction
+		policy        OverflowPolicy
+		expectedScore int64
+		expectedErr   error
+	}{
+		{
+			name:          "exactly max int",
+			rules:         &ScoringRules{Rules: map[string]Rule{"quiz_answer": {Base: math.MaxInt64 - 10, Multiplier: 1}}},
+			actions:       []domain.UserAction{{ID: "a1", Type: "quiz_answer", Amount: 10, OccurredAt: occurredAt}},
+			expectedScore: math.MaxInt64,
+		},
+		{
+			name:        "single action overflows",
+			rules:       &ScoringRules{Rules: map[string]Rule{"quiz_answer": {Multiplier: 2}}},
+			actions:     []domain.UserAction{{ID: "a1", Type: "quiz_answer", Amount: math.MaxInt64, OccurredAt: occurredAt}},
+			expectedErr: ErrScoreOverflow,
+		},
+		{
+			name:  "sum overflows",
+			rules: &ScoringRules{Rules: map[string]Rule{"quiz_answer": {Multiplier: 1}}},
+			actions: []domain.UserAction{
+				{ID: "a1", Type: "quiz_answer", Amount: math.MaxInt64, OccurredAt: occurredAt},
+				{ID: "a2", Type: "quiz_answer", Amount: 1, OccurredAt: occurredAt},
+			},
+			expectedErr: ErrScoreOverflow,
+		},
+		{
+			name:  "sum saturates",
+			rules: &ScoringRules{Rules: map[string]Rule{"quiz_answer": {Multiplier: 1}}},
+			actions: []domain.UserAction{
+				{ID: "a1", Type: "quiz_answer", Amount: math.MaxInt64, OccurredAt: occurredAt},
+				{ID: "a2", Type: "quiz_answer", Amount: 1, OccurredAt: occurredAt},
+			},
+			policy:        OverflowSaturate,
+			expectedScore: math.MaxInt64,
+		},
+		{
+			name:  "negative sum saturates",
+			rules: &ScoringRules{Rules: map[string]Rule{"penalty": {Multiplier: -1}}},
+			actions: []domain.UserAction{
+				{ID: "a1", Type: "penalty", Amount: math.MaxInt64, OccurredAt: occurredAt},
+				{ID: "a2", Type: "penalty", Amount: math.MaxInt64, OccurredAt: occurredAt},
+			},
+			policy:        OverflowSaturate,
+			expectedScore: math.MinInt64,
+		},
+		{
+			name: "combo bonus overflows",
+			rules: &ScoringRules{
+				Rules:  map[string]Rule{"quiz_answer": {Multiplier: 1}},
+				Combos: []ComboRule{{Name: "double", ActionType: "quiz_answer", Count: 2, WithinMinutes: 5, Multiplier: 3}},
+			},
+			actions: []domain.UserAction{
+				{ID: "a1", Type: "quiz_answer", Amount: math.MaxInt64 / 4, OccurredAt: occurredAt},
+				{ID: "a2", Type: "quiz_answer", Amount: math.MaxInt64 / 4, OccurredAt: occurredAt.Add(time.Minute)},
+			},
+			expectedErr: ErrScoreOverflow,
+		},
+		{
+			name: "total cap applies to saturated score",
+			rules: &ScoringRules{
+				Rules:          map[string]Rule{"quiz_answer": {Multiplier: 3}},
+				MaxTotalPoints: 1000,
+			},
+			actions:       []domain.UserAction{{ID: "a1", Type: "quiz_answer", Amount: math.MaxInt64, OccurredAt: occurredAt}},
+			policy:        OverflowSaturate,
+			expectedScore: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockActionService := new(MockActionService)
+			mockRepo := new(MockScoreRepository)
+
+			userID := "user"
+			tt.rules.UnknownAction = UnknownActionIgnore
+
+			mockActionService.On("GetActions", mock.Anything, userID).Return(tt.actions, nil)
+			mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+
+			opts := []Option{WithClock(fixedClock(occurredAt.Add(time.Hour)))}
+			if tt.policy != "" {
+				opts = append(opts, WithOverflowPolicy(tt.policy))
+			}
+			calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(tt.rules), opts...)
+
+			score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Equal(t, domain.UserScore{}, score)
+				mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, score.Score)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// blockUntilDone makes a mocked call wait for its context to be done.
+func blockUntilDone(args mock.Arguments) {
+	<-args.Get(0).(context.Context).Done()
+}
+
+func TestScoreCalculation_ActionServiceTimeout(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+
+	mockActionService.On("GetActions", mock.Anything, userID).
+		Run(blockUntilDone).
+		Return([]domain.UserAction(nil), context.DeadlineExceeded)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()),
+		WithActionServiceTimeout(10*time.Millisecond))
+
+	score, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, domain.UserScore{}, score)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestScoreCalculation_RepositoryTimeout(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return([]domain.UserAction{{Type: "login", Amount: 1}}, nil)
+	mockRepo.On("Save", hasDeadline, mock.Anything).
+		Run(blockUntilDone).
+		Return(context.DeadlineExceeded)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()),
+		WithRepositoryTimeout(10*time.Millisecond))
+
+	_, err := calculator.Calculate(context.Background(), userID, domain.AllTime())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
 	assert.Contains(t, err.Error(), "failed to save score")
 	mockActionService.AssertExpectations(t)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestScoreCalculation_Canceled(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The client goes away while actions are loading
+	mockActionService.On("GetActions", mock.Anything, userID).
+		Run(func(args mock.Arguments) {
+			cancel()
+			blockUntilDone(args)
+		}).
+		Return([]domain.UserAction(nil), context.Canceled)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()),
+		WithActionServiceTimeout(time.Minute))
+
+	_, err := calculator.Calculate(ctx, userID, domain.AllTime())
+
+	assert.ErrorIs(t, err, context.Canceled)
+	mockActionService.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestScoreCalculation_RecordsRequestID(t *testing.T) {
+	mockActionService := new(MockActionService)
+	mockRepo := new(MockScoreRepository)
+
+	userID := "user"
+	ctx := ContextWithRequestID(context.Background(), "req-42")
+
+	mockActionService.On("GetActions", mock.Anything, userID).Return([]domain.UserAction{{Type: "login", Amount: 1}}, nil)
+	mockRepo.On("Save", mock.Anything, domain.UserScore{
+		UserID:       userID,
+		Score:        1,
+		RuleVersion:  1,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
+		RequestID:    "req-42",
+	}).Return(nil)
+
+	calculator := NewScoreCalculator(mockActionService, mockRepo, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(calculatedAt)))
+
+	score, err := calculator.Calculate(ctx, userID, domain.AllTime())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "req-42", score.RequestID)
+	assert.Equal(t, calculatedAt, score.CalculatedAt)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DecayKind identifies how action points decay with age.
+type DecayKind string
+
+const (
+	// DecayExponential halves points every HalfLifeDays.
+	DecayExponential DecayKind = "exponential"
+	// DecayLinear reduces points linearly until Floor is reached after DurationDays.
+	DecayLinear DecayKind = "linear"
+)
+
+const day = 24 * time.Hour
+
+// DecayPolicy reduces the points of an action based on its age at
+// calculation time. Floor is the fraction of points an action keeps no
+// matter how old it is, between 0 and 1.
+type DecayPolicy struct {
+	Kind         DecayKind `json:"kind" yaml:"kind"`
+	HalfLifeDays float64   `json:"half_life_days,omitempty" yaml:"half_life_days,omitempty"`
+	DurationDays float64   `json:"duration_days,omitempty" yaml:"duration_days,omitempty"`
+	Floor        float64   `json:"floor,omitempty" yaml:"floor,omitempty"`
+}
+
+// Validate checks that the decay policy is well formed.
+func (d *DecayPolicy) Validate() error {
+	switch d.Kind {
+	case DecayExponential:
+		if d.HalfLifeDays <= 0 {
+			return fmt.Errorf("%w: exponential decay needs a positive half_life_days", ErrInvalidRules)
+		}
+	case DecayLinear:
+		if d.DurationDays <= 0 {
+			return fmt.Errorf("%w: linear decay needs a positive duration_days", ErrInvalidRules)
+		}
+	default:
+		return fmt.Errorf("%w: decay kind must be %q or %q", ErrInvalidRules, DecayExponential, DecayLinear)
+	}
+
+	if d.Floor < 0 || d.Floor > 1 {
+		return fmt.Errorf("%w: decay floor must be between 0 and 1", ErrInvalidRules)
+	}
+
+	return nil
+}
+
+// Factor returns the fraction of points kept by an action of the given age.
+// Actions from the future keep all their points.
+func (d *DecayPolicy) Factor(age time.Duration) float64 {
+	if age <= 0 {
+		return 1
+	}
+	days := float64(age) / float64(day)
+
+	var factor float64
+	switch d.Kind {
+	case DecayExponential:
+		factor = math.Pow(0.5, days/d.HalfLifeDays)
+	case DecayLinear:
+		factor = 1 - math.Min(days/d.DurationDays, 1)
+	default:
+		return 1
+	}
+
+	// Scale into [Floor, 1] so an action never drops below the floor
+	return d.Floor + (1-d.Floor)*factor
+}
+
+// Apply decays points by the factor for the given age, rounding to the
+// nearest point. Decay never increases points, so the result always fits.
+func (d *DecayPolicy) Apply(points int64, age time.Duration) (int64, float64) {
+	factor := d.Factor(age)
+	decayed, _ := scaleInt64(points, factor)
+	return decayed, factor
+}
@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecayPolicy_Factor(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   DecayPolicy
+		age      time.Duration
+		expected float64
+	}{
+		{"exponential fresh", DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7}, 0, 1},
+		{"exponential one half-life", DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7}, 7 * day, 0.5},
+		{"exponential two half-lives", DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7}, 14 * day, 0.25},
+		{"exponential with floor", DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7, Floor: 0.5}, 7 * day, 0.75},
+		{"linear halfway", DecayPolicy{Kind: DecayLinear, DurationDays: 10}, 5 * day, 0.5},
+		{"linear past duration", DecayPolicy{Kind: DecayLinear, DurationDays: 10}, 30 * day, 0},
+		{"linear to floor", DecayPolicy{Kind: DecayLinear, DurationDays: 10, Floor: 0.2}, 30 * day, 0.2},
+		{"future action", DecayPolicy{Kind: DecayLinear, DurationDays: 10}, -day, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, tt.policy.Factor(tt.age), 1e-9)
+		})
+	}
+}
+
+func TestDecayPolicy_Apply(t *testing.T) {
+	policy := DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7}
+
+	points, factor := policy.Apply(15, 7*day)
+
+	assert.Equal(t, int64(8), points)
+	assert.InDelta(t, 0.5, factor, 1e-9)
+}
+
+func TestDecayPolicy_Apply_MaxInt(t *testing.T) {
+	policy := DecayPolicy{Kind: DecayLinear, DurationDays: 10, Floor: 0.5}
+
+	fresh, _ := policy.Apply(math.MaxInt64, 0)
+	old, _ := policy.Apply(math.MaxInt64, 10*day)
+
+	assert.Equal(t, int64(math.MaxInt64), fresh)
+	assert.Equal(t, int64(1<<62), old)
+}
+
+func TestDecayPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  DecayPolicy
+		wantErr bool
+	}{
+		{"exponential", DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7}, false},
+		{"linear", DecayPolicy{Kind: DecayLinear, DurationDays: 30, Floor: 0.1}, false},
+		{"exponential without half-life", DecayPolicy{Kind: DecayExponential}, true},
+		{"linear without duration", DecayPolicy{Kind: DecayLinear, Floor: 0.1}, true},
+		{"floor above one", DecayPolicy{Kind: DecayLinear, DurationDays: 30, Floor: 1.5}, true},
+		{"negative floor", DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7, Floor: -0.1}, true},
+		{"unknown kind", DecayPolicy{Kind: "step"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRules)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseScoringRules_Decay(t *testing.T) {
+	data := []byte(`
+rules:
+  login:
+    base: 1
+decay:
+  kind: linear
+  duration_days: 30
+  floor: 0.25
+`)
+
+	rules, err := ParseScoringRules(data, "yaml")
+
+	require.NoError(t, err)
+	assert.Equal(t, &DecayPolicy{Kind: DecayLinear, DurationDays: 30, Floor: 0.25}, rules.Decay)
+
+	_, err = ParseScoringRules([]byte(`{"rules": {"login": {"base": 1}}, "decay": {"kind": "exponential"}}`), "json")
+	assert.ErrorIs(t, err, ErrInvalidRules)
+}
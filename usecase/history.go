@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"scoreapp/domain"
+)
+
+// ErrInvalidHistoryQuery is returned when a history query is malformed.
+var ErrInvalidHistoryQuery = errors.New("invalid history query")
+
+const (
+	// DefaultHistoryLimit is the page size used when a query sets none.
+	DefaultHistoryLimit = 50
+	// MaxHistoryLimit is the largest page size a query may ask for.
+	MaxHistoryLimit = 500
+)
+
+// HistoryFilter selects entries of a user's score history.
+type HistoryFilter struct {
+	// Window restricts the history to one window key. Empty means all windows.
+	Window string
+	// From and To bound RecordedAt to [From, To). A zero value leaves that
+	// side unbounded.
+	From time.Time
+	To   time.Time
+	// AfterSeq skips entries up to and including that Seq.
+	AfterSeq int64
+	// Limit is the maximum number of entries to return.
+	Limit int
+}
+
+// ScoreHistoryRepository reads the history a ScoreRepository records on
+// every Save.
+type ScoreHistoryRepository interface {
+	// History returns the matching entries of the user's history in Seq order.
+	History(ctx context.Context, userID string, filter HistoryFilter) ([]domain.ScoreSnapshot, error)
+}
+
+// HistoryQuery asks for one page of a user's score history.
+type HistoryQuery struct {
+	Window string
+	From   time.Time
+	To     time.Time
+	// Cursor is the NextCursor of the previous page, or empty for the first page.
+	Cursor string
+	// Limit is the page size; zero means DefaultHistoryLimit.
+	Limit int
+}
+
+// HistoryPage is one page of a user's score history, oldest entry first.
+type HistoryPage struct {
+	Entries []domain.ScoreSnapshot
+	// NextCursor fetches the following page. It is empty on the last page.
+	NextCursor string
+}
+
+// ScoreHistory pages through the score history of users.
+type ScoreHistory struct {
+	repo ScoreHistoryRepository
+}
+
+// NewScoreHistory constructs a ScoreHistory over the given repository.
+func NewScoreHistory(repo ScoreHistoryRepository) *ScoreHistory {
+	return &ScoreHistory{repo: repo}
+}
+
+// List returns the page of the user's score history selected by the query.
+func (h *ScoreHistory) List(ctx context.Context, userID string, q HistoryQuery) (HistoryPage, error) {
+	limit := q.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultHistoryLimit
+	case limit < 0 || limit > MaxHistoryLimit:
+		return HistoryPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidHistoryQuery, MaxHistoryLimit)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return HistoryPage{}, fmt.Errorf("%w: from must be before to", ErrInvalidHistoryQuery)
+	}
+
+	var after int64
+	if q.Cursor != "" {
+		var err error
+		if after, err = decodeCursor(q.Cursor); err != nil {
+			return HistoryPage{}, err
+		}
+	}
+
+	// Ask for one more entry than needed to learn whether another page exists
+	entries, err := h.repo.History(ctx, userID, HistoryFilter{
+		Window:   q.Window,
+		From:     q.From,
+		To:       q.To,
+		AfterSeq: after,
+		Limit:    limit + 1,
+	})
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("failed to get score history: %w", err)
+	}
+
+	page := HistoryPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = encodeCursor(page.Entries[limit-1].Seq)
+	}
+	return page, nil
+}
+
+func encodeCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: malformed cursor", ErrInvalidHistoryQuery)
+	}
+	seq, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, fmt.Errorf("%w: malformed cursor", ErrInvalidHistoryQuery)
+	}
+	return seq, nil
+}
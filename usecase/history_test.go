@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+// MockScoreHistoryRepository is a mock for ScoreHistoryRepository.
+type MockScoreHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockScoreHistoryRepository) History(ctx context.Context, userID string, filter HistoryFilter) ([]domain.ScoreSnapshot, error) {
+	args := m.Called(ctx, userID, filter)
+	return args.Get(0).([]domain.ScoreSnapshot), args.Error(1)
+}
+
+func snapshots(seqs ...int64) []domain.ScoreSnapshot {
+	entries := make([]domain.ScoreSnapshot, len(seqs))
+	for i, seq := range seqs {
+		entries[i] = domain.ScoreSnapshot{Seq: seq, UserID: "user", Score: seq * 10}
+	}
+	return entries
+}
+
+func encodeCursorString(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func TestScoreHistory_FirstPage(t *testing.T) {
+	mockRepo := new(MockScoreHistoryRepository)
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	mockRepo.On("History", mock.Anything, "user", HistoryFilter{Window: "all_time", From: from, To: to, Limit: 3}).
+		Return(snapshots(1, 4, 7), nil)
+
+	page, err := NewScoreHistory(mockRepo).List(context.Background(), "user", HistoryQuery{Window: "all_time", From: from, To: to, Limit: 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, snapshots(1, 4), page.Entries)
+	assert.NotEmpty(t, page.NextCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestScoreHistory_NextPage(t *testing.T) {
+	mockRepo := new(MockScoreHistoryRepository)
+
+	mockRepo.On("History", mock.Anything, "user", HistoryFilter{Limit: 3}).Return(snapshots(1, 4, 7), nil)
+	mockRepo.On("History", mock.Anything, "user", HistoryFilter{AfterSeq: 4, Limit: 3}).Return(snapshots(7), nil)
+
+	history := NewScoreHistory(mockRepo)
+	first, err := history.List(context.Background(), "user", HistoryQuery{Limit: 2})
+	require.NoError(t, err)
+
+	last, err := history.List(context.Background(), "user", HistoryQuery{Cursor: first.NextCursor, Limit: 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, snapshots(7), last.Entries)
+	assert.Empty(t, last.NextCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestScoreHistory_DefaultLimit(t *testing.T) {
+	mockRepo := new(MockScoreHistoryRepository)
+
+	mockRepo.On("History", mock.Anything, "user", HistoryFilter{Limit: DefaultHistoryLimit + 1}).
+		Return([]domain.ScoreSnapshot(nil), nil)
+
+	page, err := NewScoreHistory(mockRepo).List(context.Background(), "user", HistoryQuery{})
+
+	require.NoError(t, err)
+	assert.Empty(t, page.Entries)
+	assert.Empty(t, page.NextCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestScoreHistory_InvalidQuery(t *testing.T) {
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query HistoryQuery
+	}{
+		{"negative limit", HistoryQuery{Limit: -1}},
+		{"limit too large", HistoryQuery{Limit: MaxHistoryLimit + 1}},
+		{"from after to", HistoryQuery{From: from, To: from.Add(-time.Hour)}},
+		{"empty range", HistoryQuery{From: from, To: from}},
+		{"malformed cursor", HistoryQuery{Cursor: "not base64!"}},
+		{"non-numeric cursor", HistoryQuery{Cursor: encodeCursorString("abc")}},
+		{"negative cursor", HistoryQuery{Cursor: encodeCursorString("-1")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockScoreHistoryRepository)
+
+			_, err := NewScoreHistory(mockRepo).List(context.Background(), "user", tt.query)
+
+			assert.ErrorIs(t, err, ErrInvalidHistoryQuery)
+			mockRepo.AssertNotCalled(t, "History", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestScoreHistory_RepositoryError(t *testing.T) {
+	mockRepo := new(MockScoreHistoryRepository)
+	repoErr := errors.New("database unavailable")
+
+	mockRepo.On("History", mock.Anything, "user", mock.Anything).Return([]domain.ScoreSnapshot(nil), repoErr)
+
+	_, err := NewScoreHistory(mockRepo).List(context.Background(), "user", HistoryQuery{})
+
+	assert.ErrorIs(t, err, repoErr)
+	assert.NotErrorIs(t, err, ErrInvalidHistoryQuery)
+}
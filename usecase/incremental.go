@@ -0,0 +1,409 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"scoreapp/domain"
+)
+
+// ErrScoreCursorNotFound is returned when a user's score has never been
+// updated incrementally.
+var ErrScoreCursorNotFound = errors.New("score cursor not found")
+
+// ActionLog reads a user's ingested actions by their position.
+type ActionLog interface {
+	// ActionsAfter returns the user's ingested actions stored after the
+	// position, in the order they arrived, and the position of the last
+	// one, or position itself if there are none. Positions of a user's
+	// actions only grow, and zero comes before the first one.
+	ActionsAfter(ctx context.Context, userID string, position int64) ([]domain.UserAction, int64, error)
+}
+
+// ScoreCursorRepository persists how far incrementally updated scores have
+// applied their users' ingested actions.
+type ScoreCursorRepository interface {
+	// GetScoreCursor returns the user's cursor, or ErrScoreCursorNotFound.
+	GetScoreCursor(ctx context.Context, userID string) (domain.ScoreCursor, error)
+	// SaveScoreCursor creates or replaces the cursor of the same user and
+	// adds the IDs of the actions applied since the previous one to the
+	// user's processed actions, all at once.
+	SaveScoreCursor(ctx context.Context, cursor domain.ScoreCursor, applied []string) error
+	// ResetScoreCursor creates or replaces the cursor of the same user and
+	// replaces the user's processed actions with the IDs, all at once.
+	ResetScoreCursor(ctx context.Context, cursor domain.ScoreCursor, processed []string) error
+	// ProcessedActions returns which of the IDs are among the user's
+	// processed actions, in no particular order.
+	ProcessedActions(ctx context.Context, userID string, ids []string) ([]string, error)
+	// ListScoreCursors returns every cursor, ordered by user ID.
+	ListScoreCursors(ctx context.Context) ([]domain.ScoreCursor, error)
+}
+
+// FullScorer calculates scores from the whole action history of a user
+// without saving them.
+type FullScorer interface {
+	Explain(ctx context.Context, userID string, window domain.Window) (domain.ScoreBreakdown, error)
+}
+
+// ScoreDrift is a user whose incrementally updated score differed from a
+// full recalculation.
+type ScoreDrift struct {
+	UserID      string
+	Incremental int64
+	Full        int64
+}
+
+// ConsistencyReport is the outcome of comparing incrementally updated scores
+// with full recalculations.
+type ConsistencyReport struct {
+	// Checked is the number of users compared.
+	Checked int
+	// Drifts lists the users whose scores differed. Their scores have been
+	// replaced by the full recalculation.
+	Drifts []ScoreDrift
+}
+
+// IncrementalScorer keeps the all-time scores of users current as their
+// actions are ingested. While the scoring rules add up the points of each
+// action on its own, it adds the points of the actions ingested since the
+// user's cursor to the score at the cursor; otherwise, and for users it has
+// not seen before, it recalculates the score from the whole history. It
+// recalculates as well when the stored score is no longer the one at the
+// cursor, e.g. because a full calculation saved a score counting actions
+// from other sources, and when an ingested action has the ID of an action
+// the score already counts, which a full calculation scores only once. The
+// IDs of the actions a score counts are kept with its cursor.
+//
+// Updates of a user must not overlap with the ingestion of the user's
+// actions, see Lock.
+type IncrementalScorer struct {
+	log     ActionLog
+	cursors ScoreCursorRepository
+	repo    ScoreRepository
+	reader  ScoreReader
+	full    FullScorer
+	rules   RuleProvider
+	now     func() time.Time
+	locks   *UserLocks
+}
+
+// IncrementalOption configures optional IncrementalScorer behavior.
+type IncrementalOption func(*IncrementalScorer)
+
+// WithIncrementalClock sets the source of the current time, recorded with
+// updated scores. Defaults to time.Now.
+func WithIncrementalClock(now func() time.Time) IncrementalOption {
+	return func(s *IncrementalScorer) {
+		s.now = now
+	}
+}
+
+// WithIncrementalLocks sets the locks users are locked with, see Lock. Share
+// them with the ScoreCalculator saving all-time scores to the same
+// repository, see WithUserLocks. Defaults to locks of its own.
+func WithIncrementalLocks(locks *UserLocks) IncrementalOption {
+	return func(s *IncrementalScorer) {
+		s.locks = locks
+	}
+}
+
+// NewIncrementalScorer creates an IncrementalScorer. Updated scores are
+// saved to repo and read back through reader, and full is used to
+// recalculate scores from scratch.
+func NewIncrementalScorer(log ActionLog, cursors ScoreCursorRepository, repo ScoreRepository, reader ScoreReader, full FullScorer, rules RuleProvider, opts ...IncrementalOption) *IncrementalScorer {
+	s := &IncrementalScorer{
+		log:     log,
+		cursors: cursors,
+		repo:    repo,
+		reader:  reader,
+		full:    full,
+		rules:   rules,
+		now:     time.Now,
+		locks:   NewUserLocks(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Lock keeps the users from being updated by anyone else until the returned
+// function is called. Actions of the users must be ingested while they are
+// locked, or a recalculation may count an action its cursor does not.
+func (s *IncrementalScorer) Lock(userIDs []string) (unlock func()) {
+	return s.locks.Lock(userIDs)
+}
+
+// Update brings the user's stored all-time score up to date with the user's
+// ingested actions. The user must be locked.
+func (s *IncrementalScorer) Update(ctx context.Context, userID string) error {
+	ruleSet := s.rules.Current()
+
+	cursor, err := s.cursors.GetScoreCursor(ctx, userID)
+	if errors.Is(err, ErrScoreCursorNotFound) {
+		return s.recalculate(ctx, userID, 0)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get score cursor: %w", err)
+	}
+	if cursor.RuleVersion != ruleSet.Version || !additive(ruleSet.Rules) {
+		return s.recalculate(ctx, userID, cursor.Position)
+	}
+
+	actions, position, err := s.log.ActionsAfter(ctx, userID, cursor.Position)
+	if err != nil {
+		return fmt.Errorf("failed to get actions: %w", err)
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+
+	// Only add to the score at the cursor while it is still the stored one
+	stored, exists, err := s.reader.GetWindow(ctx, userID, domain.AllTime().Key())
+	if err != nil {
+		return fmt.Errorf("failed to get score: %w", err)
+	}
+	if !exists || stored.Score != cursor.Score || stored.RuleVersion != cursor.RuleVersion {
+		return s.recalculate(ctx, userID, cursor.Position)
+	}
+	applied := actionIDs(actions)
+	processed, err := s.cursors.ProcessedActions(ctx, userID, applied)
+	if err != nil {
+		return fmt.Errorf("failed to get processed actions: %w", err)
+	}
+	if len(processed) > 0 {
+		return s.recalculate(ctx, userID, cursor.Position)
+	}
+
+	score, ok := addPoints(cursor.Score, actions, ruleSet.Rules)
+	if !ok {
+		// Let the full calculation apply the overflow and unknown action
+		// policies
+		return s.recalculate(ctx, userID, cursor.Position)
+	}
+
+	now := s.now()
+	userScore := domain.UserScore{
+		UserID:       userID,
+		Score:        score,
+		RuleVersion:  ruleSet.Version,
+		Window:       domain.AllTime().Key(),
+		CalculatedAt: now,
+		RequestID:    RequestIDFromContext(ctx),
+	}
+	if err := s.repo.Save(ctx, userScore); err != nil {
+		return fmt.Errorf("failed to save score: %w", err)
+	}
+
+	// A cursor left behind is harmless: the next update finds the stored
+	// score moved past it and recalculates
+	if err := s.cursors.SaveScoreCursor(ctx, domain.ScoreCursor{
+		UserID:      userID,
+		Position:    position,
+		Score:       score,
+		RuleVersion: ruleSet.Version,
+		UpdatedAt:   now,
+	}, applied); err != nil {
+		return fmt.Errorf("failed to save score cursor: %w", err)
+	}
+	return nil
+}
+
+// recalculate calculates the user's all-time score from the whole history,
+// saves it and moves the cursor from position past every ingested action.
+// The user must be locked, so no action is ingested in between.
+func (s *IncrementalScorer) recalculate(ctx context.Context, userID string, position int64) error {
+	_, position, err := s.log.ActionsAfter(ctx, userID, position)
+	if err != nil {
+		return fmt.Errorf("failed to get actions: %w", err)
+	}
+
+	breakdown, err := s.full.Explain(ctx, userID, domain.AllTime())
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	if err := s.repo.Save(ctx, domain.UserScore{
+		UserID:       userID,
+		Score:        breakdown.Score,
+		RuleVersion:  breakdown.RuleVersion,
+		Window:       breakdown.Window,
+		Streak:       breakdown.Streak,
+		CalculatedAt: now,
+		RequestID:    RequestIDFromContext(ctx),
+	}); err != nil {
+		return fmt.Errorf("failed to save score: %w", err)
+	}
+
+	var processed []string
+	for _, c := range breakdown.Contributions {
+		processed = append(processed, c.Action.ID)
+	}
+	for _, skipped := range breakdown.Skipped {
+		processed = append(processed, skipped.Action.ID)
+	}
+	if err := s.cursors.ResetScoreCursor(ctx, domain.ScoreCursor{
+		UserID:      userID,
+		Position:    position,
+		Score:       breakdown.Score,
+		RuleVersion: breakdown.RuleVersion,
+		UpdatedAt:   now,
+	}, nonEmptyIDs(processed)); err != nil {
+		return fmt.Errorf("failed to save score cursor: %w", err)
+	}
+	return nil
+}
+
+// actionIDs returns the IDs of the actions that have one.
+func actionIDs(actions []domain.UserAction) []string {
+	ids := make([]string, 0, len(actions))
+	for _, action := range actions {
+		ids = append(ids, action.ID)
+	}
+	return nonEmptyIDs(ids)
+}
+
+// nonEmptyIDs returns the IDs without empty and repeated ones, sorted.
+func nonEmptyIDs(ids []string) []string {
+	ids = slices.DeleteFunc(ids, func(id string) bool { return id == "" })
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// Check compares the incrementally updated scores of up to sample randomly
+// chosen users with a full recalculation, or of every user if sample is not
+// positive. Users whose scores differ are recalculated and reported. Users
+// that cannot be checked are skipped and their errors returned together.
+func (s *IncrementalScorer) Check(ctx context.Context, sample int) (ConsistencyReport, error) {
+	cursors, err := s.cursors.ListScoreCursors(ctx)
+	if err != nil {
+		return ConsistencyReport{}, fmt.Errorf("failed to list score cursors: %w", err)
+	}
+	rand.Shuffle(len(cursors), func(i, j int) { cursors[i], cursors[j] = cursors[j], cursors[i] })
+	if sample > 0 && sample < len(cursors) {
+		cursors = cursors[:sample]
+	}
+
+	var report ConsistencyReport
+	var errs []error
+	for _, c := range cursors {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		drift, checked, err := s.check(ctx, c.UserID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %q: %w", c.UserID, err))
+			continue
+		}
+		if !checked {
+			continue
+		}
+		report.Checked++
+		if drift != nil {
+			report.Drifts = append(report.Drifts, *drift)
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// check compares the user's incrementally updated score with a full
+// recalculation. Users are not checked while their cursor is from an older
+// rule set, as their next update recalculates them anyway.
+func (s *IncrementalScorer) check(ctx context.Context, userID string) (*ScoreDrift, bool, error) {
+	unlock := s.Lock([]string{userID})
+	defer unlock()
+
+	// Apply actions an earlier update failed to, so they do not count as drift
+	if err := s.Update(ctx, userID); err != nil {
+		return nil, false, err
+	}
+	cursor, err := s.cursors.GetScoreCursor(ctx, userID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get score cursor: %w", err)
+	}
+
+	breakdown, err := s.full.Explain(ctx, userID, domain.AllTime())
+	if err != nil {
+		return nil, false, err
+	}
+	if breakdown.RuleVersion != cursor.RuleVersion {
+		return nil, false, nil
+	}
+	if breakdown.Score == cursor.Score {
+		return nil, true, nil
+	}
+
+	drift := &ScoreDrift{UserID: userID, Incremental: cursor.Score, Full: breakdown.Score}
+	if err := s.recalculate(ctx, userID, cursor.Position); err != nil {
+		return nil, false, err
+	}
+	return drift, true, nil
+}
+
+// WatchConsistency checks a sample of users every interval until ctx is
+// done, passing every drift found to onDrift and failures to onError.
+func (s *IncrementalScorer) WatchConsistency(ctx context.Context, interval time.Duration, sample int, onDrift func(ScoreDrift), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := s.Check(ctx, sample)
+		for _, drift := range report.Drifts {
+			onDrift(drift)
+		}
+		if err != nil && ctx.Err() == nil {
+			onError(err)
+		}
+	}
+}
+
+// additive reports whether a score under the rules is the sum of the points
+// each action earns on its own, so new actions can be scored without the
+// ones before them.
+func additive(rules *ScoringRules) bool {
+	if rules.Decay != nil || rules.Streak != nil || len(rules.Combos) > 0 || rules.MaxTotalPoints > 0 {
+		return false
+	}
+	for _, rule := range rules.Rules {
+		if rule.MaxPointsPerDay > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// addPoints adds the points the actions earn under additive rules to score,
+// skipping actions a full calculation skips too. It reports false if an
+// action the rules reject or an overflow makes the full calculation fail.
+func addPoints(score int64, actions []domain.UserAction, rules *ScoringRules) (int64, bool) {
+	var arith checked
+	for _, action := range actions {
+		if action.Amount <= 0 {
+			continue
+		}
+		rule, ok := rules.Match(action.Type)
+		if !ok {
+			if rules.UnknownAction == UnknownActionIgnore {
+				continue
+			}
+			return 0, false
+		}
+		points, _, ok := rule.Points(action.Amount)
+		if !ok {
+			return 0, false
+		}
+		score = arith.add(score, points)
+	}
+	return score, !arith.overflowed
+}
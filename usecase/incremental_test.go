@@ -0,0 +1,438 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+// memoryActions is an in-memory ActionLog that also serves its actions as
+// an ActionService, counting how often it does. The service serves the
+// actions in external as well, as if they came from another source.
+type memoryActions struct {
+	mu       sync.Mutex
+	actions  map[string][]domain.UserAction
+	external map[string][]domain.UserAction
+	reads    int
+}
+
+func newMemoryActions() *memoryActions {
+	return &memoryActions{
+		actions:  make(map[string][]domain.UserAction),
+		external: make(map[string][]domain.UserAction),
+	}
+}
+
+func (m *memoryActions) add(userID string, actions ...domain.UserAction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.actions[userID] = append(m.actions[userID], actions...)
+}
+
+func (m *memoryActions) GetActions(ctx context.Context, userID string) ([]domain.UserAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	actions := append(append([]domain.UserAction(nil), m.external[userID]...), m.actions[userID]...)
+	if len(actions) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return actions, nil
+}
+
+func (m *memoryActions) ActionsAfter(ctx context.Context, userID string, position int64) ([]domain.UserAction, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	actions := m.actions[userID]
+	if position >= int64(len(actions)) {
+		return nil, position, nil
+	}
+	return append([]domain.UserAction(nil), actions[position:]...), int64(len(actions)), nil
+}
+
+// memoryCursors is an in-memory ScoreCursorRepository.
+type memoryCursors struct {
+	mu        sync.Mutex
+	cursors   map[string]domain.ScoreCursor
+	processed map[string]map[string]bool
+}
+
+func newMemoryCursors() *memoryCursors {
+	return &memoryCursors{cursors: make(map[string]domain.ScoreCursor), processed: make(map[string]map[string]bool)}
+}
+
+func (m *memoryCursors) GetScoreCursor(ctx context.Context, userID string) (domain.ScoreCursor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cursor, ok := m.cursors[userID]
+	if !ok {
+		return domain.ScoreCursor{}, ErrScoreCursorNotFound
+	}
+	return cursor, nil
+}
+
+func (m *memoryCursors) SaveScoreCursor(ctx context.Context, cursor domain.ScoreCursor, applied []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.save(cursor, applied, false)
+	return nil
+}
+
+func (m *memoryCursors) ResetScoreCursor(ctx context.Context, cursor domain.ScoreCursor, processed []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.save(cursor, processed, true)
+	return nil
+}
+
+func (m *memoryCursors) save(cursor domain.ScoreCursor, ids []string, reset bool) {
+	m.cursors[cursor.UserID] = cursor
+	if reset || m.processed[cursor.UserID] == nil {
+		m.processed[cursor.UserID] = make(map[string]bool)
+	}
+	for _, id := range ids {
+		m.processed[cursor.UserID][id] = true
+	}
+}
+
+func (m *memoryCursors) ProcessedActions(ctx context.Context, userID string, ids []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var processed []string
+	for _, id := range ids {
+		if m.processed[userID][id] {
+			processed = append(processed, id)
+		}
+	}
+	return processed, nil
+}
+
+func (m *memoryCursors) ListScoreCursors(ctx context.Context) ([]domain.ScoreCursor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []domain.ScoreCursor
+	for _, c := range m.cursors {
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// memoryScores is a ScoreRepository and ScoreReader keeping the last score
+// saved per user and window.
+type memoryScores struct {
+	mu     sync.Mutex
+	scores map[[2]string]domain.UserScore
+}
+
+func (m *memoryScores) Save(ctx context.Context, score domain.UserScore) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.scores == nil {
+		m.scores = make(map[[2]string]domain.UserScore)
+	}
+	m.scores[[2]string{score.UserID, score.Window}] = score
+	return nil
+}
+
+func (m *memoryScores) GetWindow(ctx context.Context, userID, window string) (domain.UserScore, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	score, ok := m.scores[[2]string{userID, window}]
+	return score, ok, nil
+}
+
+// get returns the user's all-time score.
+func (m *memoryScores) get(userID string) domain.UserScore {
+	score, _, _ := m.GetWindow(context.Background(), userID, domain.AllTime().Key())
+	return score
+}
+
+type incrementalFixture struct {
+	actions    *memoryActions
+	cursors    *memoryCursors
+	scores     *memoryScores
+	registry   *RuleRegistry
+	calculator *ScoreCalculator
+	scorer     *IncrementalScorer
+}
+
+func newIncrementalFixture(rules *ScoringRules) *incrementalFixture {
+	f := &incrementalFixture{
+		actions:  newMemoryActions(),
+		cursors:  newMemoryCursors(),
+		scores:   &memoryScores{},
+		registry: NewRuleRegistry(rules),
+	}
+	f.calculator = NewScoreCalculator(f.actions, f.scores, f.registry, WithClock(fixedClock(calculatedAt)))
+	f.scorer = NewIncrementalScorer(f.actions, f.cursors, f.scores, f.scores, f.calculator, f.registry, WithIncrementalClock(fixedClock(calculatedAt)))
+	return f
+}
+
+func (f *incrementalFixture) update(t *testing.T, userID string) {
+	t.Helper()
+	unlock := f.scorer.Lock([]string{userID})
+	defer unlock()
+	require.NoError(t, f.scorer.Update(context.Background(), userID))
+}
+
+func action(id, actionType string, amount int64) domain.UserAction {
+	return domain.UserAction{ID: id, Type: actionType, Amount: amount, OccurredAt: calculatedAt.Add(-time.Hour)}
+}
+
+func TestIncremental_FirstUpdateRecalculates(t *testing.T) {
+	f := newIncrementalFixture(DefaultScoringRules())
+	f.actions.add("alice", action("a1", "login", 1), action("a2", "quiz_answer", 3))
+
+	f.update(t, "alice")
+
+	assert.Equal(t, 1, f.actions.reads)
+	assert.Equal(t, int64(7), f.scores.get("alice").Score)
+	cursor, err := f.cursors.GetScoreCursor(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ScoreCursor{UserID: "alice", Position: 2, Score: 7, RuleVersion: 1, UpdatedAt: calculatedAt}, cursor)
+}
+
+func TestIncremental_AddsNewActions(t *testing.T) {
+	f := newIncrementalFixture(DefaultScoringRules())
+	f.actions.add("alice", action("a1", "login", 1))
+	f.update(t, "alice")
+
+	f.actions.add("alice", action("a2", "challenge_completed", 2), action("a3", "quiz_answer", 0))
+	f.update(t, "alice")
+
+	// Only the new actions were read, and the empty quiz answer scores nothing
+	assert.Equal(t, 1, f.actions.reads)
+	assert.Equal(t, domain.UserScore{
+		UserID:       "alice",
+		Score:        21,
+		RuleVersion:  1,
+		Window:       "all_time",
+		CalculatedAt: calculatedAt,
+	}, f.scores.get("alice"))
+	cursor, err := f.cursors.GetScoreCursor(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), cursor.Position)
+	assert.Equal(t, int64(21), cursor.Score)
+
+	// Nothing new leaves the score alone
+	f.update(t, "alice")
+	assert.Equal(t, 1, f.actions.reads)
+}
+
+func TestIncremental_RecalculatesAfterFullCalculation(t *testing.T) {
+	f := newIncrementalFixture(DefaultScoringRules())
+	f.actions.add("alice", action("a1", "login", 1))
+	f.update(t, "alice")
+
+	// A full calculation saves a score counting an action the action
+	// service started serving, which the cursor does not know about
+	f.actions.mu.Lock()
+	f.actions.external["alice"] = []domain.UserAction{action("a0", "challenge_completed", 1)}
+	f.actions.mu.Unlock()
+	score, err := f.calculator.Calculate(context.Background(), "alice", domain.AllTime())
+	require.NoError(t, err)
+	require.Equal(t, int64(11), score.Score)
+
+	f.actions.add("alice", action("a2", "login", 1))
+	f.update(t, "alice")
+
+	// The new action is not added to the stale score at the cursor
+	assert.Equal(t, 3, f.actions.reads)
+	assert.Equal(t, int64(12), f.scores.get("alice").Score)
+	cursor, err := f.cursors.GetScoreCursor(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cursor.Position)
+	assert.Equal(t, int64(12), cursor.Score)
+
+	// Once the cursor caught up, new actions are added again
+	f.actions.add("alice", action("a3", "login", 1))
+	f.update(t, "alice")
+	assert.Equal(t, 3, f.actions.reads)
+	assert.Equal(t, int64(13), f.scores.get("alice").Score)
+}
+
+func TestIncremental_RecalculatesOnProcessedActionID(t *testing.T) {
+	f := newIncrementalFixture(DefaultScoringRules())
+	f.actions.mu.Lock()
+	f.actions.external["alice"] = []domain.UserAction{action("a0", "challenge_completed", 1)}
+	f.actions.mu.Unlock()
+	f.actions.add("alice", action("a1", "login", 1))
+	f.update(t, "alice")
+	require.Equal(t, int64(11), f.scores.get("alice").Score)
+
+	// The action service already serves an action under the ingested ID,
+	// which a full calculation scores only once
+	f.actions.add("alice", action("a0", "challenge_completed", 1))
+	f.update(t, "alice")
+
+	assert.Equal(t, 2, f.actions.reads)
+	assert.Equal(t, int64(11), f.scores.get("alice").Score)
+	cursor, err := f.cursors.GetScoreCursor(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cursor.Position)
+	assert.Equal(t, int64(11), cursor.Score)
+
+	// New IDs are added again
+	f.actions.add("alice", action("a2", "login", 1))
+	f.update(t, "alice")
+	assert.Equal(t, 2, f.actions.reads)
+	assert.Equal(t, int64(12), f.scores.get("alice").Score)
+
+	// The processed IDs are kept with the cursors, so a new scorer adds the
+	// points of new actions without recalculating first
+	restarted := NewIncrementalScorer(f.actions, f.cursors, f.scores, f.scores, f.calculator, f.registry, WithIncrementalClock(fixedClock(calculatedAt)))
+	f.actions.add("alice", action("a3", "login", 1))
+	unlock := restarted.Lock([]string{"alice"})
+	require.NoError(t, restarted.Update(context.Background(), "alice"))
+	unlock()
+	assert.Equal(t, 2, f.actions.reads)
+	assert.Equal(t, int64(13), f.scores.get("alice").Score)
+
+	processed, err := f.cursors.ProcessedActions(context.Background(), "alice", []string{"a0", "a1", "a2", "a3", "a4"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a0", "a1", "a2", "a3"}, processed)
+}
+
+func TestIncremental_RecalculatesOnNewRules(t *testing.T) {
+	f := newIncrementalFixture(DefaultScoringRules())
+	f.actions.add("alice", action("a1", "login", 1))
+	f.update(t, "alice")
+
+	rules := DefaultScoringRules()
+	rules.Rules["login"] = Rule{Base: 5}
+	_, err := f.registry.Update(context.Background(), rules)
+	require.NoError(t, err)
+
+	f.actions.add("alice", action("a2", "login", 1))
+	f.update(t, "alice")
+
+	assert.Equal(t, 2, f.actions.reads)
+	assert.Equal(t, int64(10), f.scores.get("alice").Score)
+	assert.Equal(t, 2, f.scores.get("alice").RuleVersion)
+}
+
+func TestIncremental_RecalculatesUnderNonAdditiveRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(r *ScoringRules)
+	}{
+		{"decay", func(r *ScoringRules) { r.Decay = &DecayPolicy{Kind: DecayExponential, HalfLifeDays: 7} }},
+		{"streak", func(r *ScoringRules) {
+			r.Streak = &StreakPolicy{ActionType: "login", Milestones: []StreakMilestone{{Days: 2, Points: 5}}}
+		}},
+		{"combo", func(r *ScoringRules) {
+			r.Combos = []ComboRule{{Name: "quick", ActionType: "login", Count: 2, WithinMinutes: 10, Multiplier: 2}}
+		}},
+		{"total cap", func(r *ScoringRules) { r.MaxTotalPoints = 100 }},
+		{"daily cap", func(r *ScoringRules) { r.Rules["login"] = Rule{Base: 1, MaxPointsPerDay: 1} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultScoringRules()
+			tt.modify(rules)
+			f := newIncrementalFixture(rules)
+			f.actions.add("alice", action("a1", "login", 1))
+			f.update(t, "alice")
+
+			f.actions.add("alice", action("a2", "login", 1))
+			f.update(t, "alice")
+
+			assert.Equal(t, 2, f.actions.reads)
+		})
+	}
+}
+
+func TestIncremental_Check(t *testing.T) {
+	f := newIncrementalFixture(DefaultScoringRules())
+	for _, user := range []string{"alice", "bob", "carol"} {
+		f.actions.add(user, action(user+"-1", "login", 1))
+		f.update(t, user)
+	}
+
+	report, err := f.scorer.Check(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, ConsistencyReport{Checked: 3}, report)
+
+	// An action the incremental update cannot see, e.g. one the action
+	// service started serving, makes the score drift
+	f.actions.mu.Lock()
+	f.actions.external["bob"] = []domain.UserAction{action("bob-0", "challenge_completed", 1)}
+	f.actions.mu.Unlock()
+
+	report, err = f.scorer.Check(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, ConsistencyReport{
+		Checked: 3,
+		Drifts:  []ScoreDrift{{UserID: "bob", Incremental: 1, Full: 11}},
+	}, report)
+
+	// The drifted score was replaced by the full one
+	assert.Equal(t, int64(11), f.scores.get("bob").Score)
+	cursor, err := f.cursors.GetScoreCursor(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), cursor.Score)
+}
+
+func TestIncremental_CheckSample(t *testing.T) {
+	f := newIncrementalFixture(DefaultScoringRules())
+	for _, user := range []string{"alice", "bob", "carol"} {
+		f.actions.add(user, action(user+"-1", "login", 1))
+		f.update(t, user)
+	}
+
+	report, err := f.scorer.Check(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+}
+
+func TestIncremental_CheckAppliesPendingActions(t *testing.T) {
+	f := newIncrementalFixture(DefaultScoringRules())
+	f.actions.add("alice", action("a1", "login", 1))
+	f.update(t, "alice")
+
+	// An action whose update failed is not drift
+	f.actions.add("alice", action("a2", "login", 1))
+
+	report, err := f.scorer.Check(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, ConsistencyReport{Checked: 1}, report)
+	assert.Equal(t, int64(2), f.scores.get("alice").Score)
+}
+
+func TestIncremental_CalculatorSharesLocks(t *testing.T) {
+	actions := newMemoryActions()
+	actions.add("alice", action("a1", "login", 1))
+	locks := NewUserLocks()
+	calculator := NewScoreCalculator(actions, &memoryScores{}, NewRuleRegistry(DefaultScoringRules()), WithClock(fixedClock(calculatedAt)), WithUserLocks(locks))
+	scores := &memoryScores{}
+	scorer := NewIncrementalScorer(actions, newMemoryCursors(), scores, scores, calculator, NewRuleRegistry(DefaultScoringRules()), WithIncrementalLocks(locks))
+
+	unlock := scorer.Lock([]string{"alice"})
+
+	calculated := make(chan struct{})
+	go func() {
+		defer close(calculated)
+		_, err := calculator.Calculate(context.Background(), "alice", domain.AllTime())
+		assert.NoError(t, err)
+	}()
+
+	select {
+	case <-calculated:
+		t.Fatal("an all-time score was calculated while the user was locked")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Other windows are not saved incrementally, so they are not held up
+	_, err := calculator.Calculate(context.Background(), "alice", domain.Window{Kind: domain.WindowDay})
+	require.NoError(t, err)
+
+	unlock()
+	<-calculated
+}
@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"scoreapp/domain"
+)
+
+// ErrInvalidAction is returned when an ingested action is malformed.
+var ErrInvalidAction = errors.New("invalid action")
+
+// ActionStore is an append-only store of ingested actions. Actions are kept
+// in the order they arrived, and an action whose ID the user already has is
+// ignored, so clients can safely resend a batch.
+type ActionStore interface {
+	// AppendActions stores the events that are new and returns them in
+	// order. Events repeating an action ID of the same user, whether stored
+	// before or earlier in events, are left out.
+	AppendActions(ctx context.Context, events []domain.ActionEvent) ([]domain.ActionEvent, error)
+}
+
+// ActionInvalidator forgets what it knows about a user's actions, e.g. a
+// cache in front of an ActionService.
+type ActionInvalidator interface {
+	Invalidate(userID string)
+}
+
+// ScoreUpdateScheduler keeps stored scores current as actions are ingested,
+// see ScoreUpdateQueue.
+type ScoreUpdateScheduler interface {
+	// Lock keeps the users' scores from being updated by anyone else until
+	// the returned function is called.
+	Lock(userIDs []string) (unlock func())
+	// Schedule has the users' scores brought up to date with their ingested
+	// actions later, without waiting for it.
+	Schedule(ctx context.Context, userIDs []string)
+}
+
+// IngestResult tells how many of the ingested actions were stored and how
+// many were already known.
+type IngestResult struct {
+	Accepted   int
+	Duplicates int
+}
+
+// ActionIngester validates actions and stores them in an ActionStore.
+type ActionIngester struct {
+	store        ActionStore
+	rules        RuleProvider
+	invalidators []ActionInvalidator
+	updates      ScoreUpdateScheduler
+}
+
+// IngestOption configures optional ActionIngester behavior.
+type IngestOption func(*ActionIngester)
+
+// WithIngestInvalidator makes the ingester invalidate the users whose
+// actions it stored.
+func WithIngestInvalidator(inv ActionInvalidator) IngestOption {
+	return func(in *ActionIngester) {
+		in.invalidators = append(in.invalidators, inv)
+	}
+}
+
+// WithScoreUpdates makes the ingester schedule updates of the scores of the
+// users whose actions it stored.
+func WithScoreUpdates(s ScoreUpdateScheduler) IngestOption {
+	return func(in *ActionIngester) {
+		in.updates = s
+	}
+}
+
+// NewActionIngester creates an ActionIngester. Action types are checked
+// against the rule set rules currently provides.
+func NewActionIngester(store ActionStore, rules RuleProvider, opts ...IngestOption) *ActionIngester {
+	in := &ActionIngester{
+		store: store,
+		rules: rules,
+	}
+	for _, opt := range opts {
+		opt(in)
+	}
+	return in
+}
+
+// Ingest stores the events. Every event needs a user ID, an action ID, a
+// type the scoring rules know, a positive amount and the time it occurred;
+// if any event is invalid, none is stored and ErrInvalidAction is returned.
+// The scores of users with stored events are updated after Ingest returns,
+// see WithScoreUpdates.
+func (in *ActionIngester) Ingest(ctx context.Context, events []domain.ActionEvent) (IngestResult, error) {
+	if len(events) == 0 {
+		return IngestResult{}, fmt.Errorf("%w: no actions", ErrInvalidAction)
+	}
+	rules := in.rules.Current().Rules
+	for i, event := range events {
+		if err := checkActionEvent(event, rules); err != nil {
+			return IngestResult{}, fmt.Errorf("%w: actions[%d]: %w", ErrInvalidAction, i, err)
+		}
+	}
+
+	appended, err := in.appendActions(ctx, events)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	if in.updates != nil && len(appended) > 0 {
+		in.updates.Schedule(ctx, userIDs(appended))
+	}
+
+	return IngestResult{Accepted: len(appended), Duplicates: len(events) - len(appended)}, nil
+}
+
+// appendActions stores the events and invalidates the users whose actions it
+// stored. The users' scores are kept from being updated meanwhile, so no
+// update sees actions that are stored but not yet invalidated.
+func (in *ActionIngester) appendActions(ctx context.Context, events []domain.ActionEvent) ([]domain.ActionEvent, error) {
+	if in.updates != nil {
+		unlock := in.updates.Lock(userIDs(events))
+		defer unlock()
+	}
+
+	appended, err := in.store.AppendActions(ctx, events)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs(appended) {
+		for _, inv := range in.invalidators {
+			inv.Invalidate(userID)
+		}
+	}
+	return appended, nil
+}
+
+// checkActionEvent reports why the event cannot be ingested.
+func checkActionEvent(event domain.ActionEvent, rules *ScoringRules) error {
+	switch {
+	case event.UserID == "":
+		return errors.New("empty user ID")
+	case event.ID == "":
+		return errors.New("empty action ID")
+	case event.OccurredAt.IsZero():
+		return errors.New("missing occurred_at")
+	case event.Amount <= 0:
+		return fmt.Errorf("amount must be positive, got %d", event.Amount)
+	}
+	if _, ok := rules.Rules[event.Type]; !ok {
+		return fmt.Errorf("unknown type %q", event.Type)
+	}
+	return nil
+}
+
+// userIDs returns the users of the events, each once, in the order they
+// first appear.
+func userIDs(events []domain.ActionEvent) []string {
+	var ids []string
+	seen := make(map[string]struct{}, len(events))
+	for _, event := range events {
+		if _, ok := seen[event.UserID]; ok {
+			continue
+		}
+		seen[event.UserID] = struct{}{}
+		ids = append(ids, event.UserID)
+	}
+	return ids
+}
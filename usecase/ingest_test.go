@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+// MockActionStore is a mock for ActionStore.
+type MockActionStore struct {
+	mock.Mock
+}
+
+func (m *MockActionStore) AppendActions(ctx context.Context, events []domain.ActionEvent) ([]domain.ActionEvent, error) {
+	args := m.Called(ctx, events)
+	return args.Get(0).([]domain.ActionEvent), args.Error(1)
+}
+
+// MockActionInvalidator is a mock for ActionInvalidator.
+type MockActionInvalidator struct {
+	mock.Mock
+}
+
+func (m *MockActionInvalidator) Invalidate(userID string) {
+	m.Called(userID)
+}
+
+// MockScoreUpdateScheduler is a mock for ScoreUpdateScheduler.
+type MockScoreUpdateScheduler struct {
+	mock.Mock
+}
+
+func (m *MockScoreUpdateScheduler) Lock(userIDs []string) func() {
+	args := m.Called(userIDs)
+	return args.Get(0).(func())
+}
+
+func (m *MockScoreUpdateScheduler) Schedule(ctx context.Context, userIDs []string) {
+	m.Called(ctx, userIDs)
+}
+
+func newEvent(userID, id, actionType string, amount int64) domain.ActionEvent {
+	return domain.ActionEvent{
+		UserID:     userID,
+		UserAction: domain.UserAction{ID: id, Type: actionType, Amount: amount, OccurredAt: calculatedAt},
+	}
+}
+
+func TestIngest_StoresActions(t *testing.T) {
+	store := new(MockActionStore)
+	invalidator := new(MockActionInvalidator)
+
+	events := []domain.ActionEvent{
+		newEvent("alice", "a1", "login", 1),
+		newEvent("alice", "a2", "quiz_answer", 3),
+		newEvent("bob", "b1", "login", 1),
+	}
+	// a1 was stored before
+	store.On("AppendActions", mock.Anything, events).Return(events[1:], nil)
+	invalidator.On("Invalidate", "alice").Once()
+	invalidator.On("Invalidate", "bob").Once()
+
+	ingester := NewActionIngester(store, NewRuleRegistry(DefaultScoringRules()), WithIngestInvalidator(invalidator))
+	result, err := ingester.Ingest(context.Background(), events)
+
+	require.NoError(t, err)
+	assert.Equal(t, IngestResult{Accepted: 2, Duplicates: 1}, result)
+	store.AssertExpectations(t)
+	invalidator.AssertExpectations(t)
+}
+
+func TestIngest_OnlyDuplicates(t *testing.T) {
+	store := new(MockActionStore)
+	invalidator := new(MockActionInvalidator)
+
+	events := []domain.ActionEvent{newEvent("alice", "a1", "login", 1)}
+	store.On("AppendActions", mock.Anything, events).Return([]domain.ActionEvent(nil), nil)
+
+	ingester := NewActionIngester(store, NewRuleRegistry(DefaultScoringRules()), WithIngestInvalidator(invalidator))
+	result, err := ingester.Ingest(context.Background(), events)
+
+	require.NoError(t, err)
+	assert.Equal(t, IngestResult{Duplicates: 1}, result)
+	invalidator.AssertNotCalled(t, "Invalidate", mock.Anything)
+}
+
+func TestIngest_InvalidActions(t *testing.T) {
+	noTime := newEvent("alice", "a1", "login", 1)
+	noTime.OccurredAt = time.Time{}
+
+	tests := []struct {
+		name   string
+		events []domain.ActionEvent
+		want   string
+	}{
+		{"no actions", nil, "invalid action: no actions"},
+		{"empty user ID", []domain.ActionEvent{newEvent("", "a1", "login", 1)}, "invalid action: actions[0]: empty user ID"},
+		{"empty action ID", []domain.ActionEvent{newEvent("alice", "", "login", 1)}, "invalid action: actions[0]: empty action ID"},
+		{"missing time", []domain.ActionEvent{noTime}, "invalid action: actions[0]: missing occurred_at"},
+		{"zero amount", []domain.ActionEvent{newEvent("alice", "a1", "login", 0)}, "invalid action: actions[0]: amount must be positive, got 0"},
+		{"negative amount", []domain.ActionEvent{newEvent("alice", "a1", "quiz_answer", -2)}, "invalid action: actions[0]: amount must be positive, got -2"},
+		{
+			"unknown type",
+			[]domain.ActionEvent{newEvent("alice", "a1", "login", 1), newEvent("alice", "a2", "referral", 1)},
+			`invalid action: actions[1]: unknown type "referral"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(MockActionStore)
+
+			ingester := NewActionIngester(store, NewRuleRegistry(DefaultScoringRules()))
+			_, err := ingester.Ingest(context.Background(), tt.events)
+
+			assert.ErrorIs(t, err, ErrInvalidAction)
+			assert.EqualError(t, err, tt.want)
+			// Nothing of a batch with an invalid action is stored
+			store.AssertNotCalled(t, "AppendActions", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestIngest_ChecksCurrentRules(t *testing.T) {
+	store := new(MockActionStore)
+	events := []domain.ActionEvent{newEvent("alice", "a1", "referral", 1)}
+	store.On("AppendActions", mock.Anything, events).Return(events, nil)
+
+	registry := NewRuleRegistry(DefaultScoringRules())
+	ingester := NewActionIngester(store, registry)
+
+	_, err := ingester.Ingest(context.Background(), events)
+	assert.ErrorIs(t, err, ErrInvalidAction)
+
+	rules := DefaultScoringRules()
+	rules.Rules["referral"] = Rule{Base: 5}
+	_, err = registry.Update(context.Background(), rules)
+	require.NoError(t, err)
+
+	result, err := ingester.Ingest(context.Background(), events)
+	require.NoError(t, err)
+	assert.Equal(t, IngestResult{Accepted: 1}, result)
+}
+
+func TestIngest_StoreFails(t *testing.T) {
+	store := new(MockActionStore)
+	invalidator := new(MockActionInvalidator)
+	storeErr := errors.New("disk full")
+
+	events := []domain.ActionEvent{newEvent("alice", "a1", "login", 1)}
+	store.On("AppendActions", mock.Anything, events).Return([]domain.ActionEvent(nil), storeErr)
+
+	ingester := NewActionIngester(store, NewRuleRegistry(DefaultScoringRules()), WithIngestInvalidator(invalidator))
+	_, err := ingester.Ingest(context.Background(), events)
+
+	assert.ErrorIs(t, err, storeErr)
+	invalidator.AssertNotCalled(t, "Invalidate", mock.Anything)
+}
+
+func TestIngest_SchedulesScoreUpdates(t *testing.T) {
+	store := new(MockActionStore)
+	updates := new(MockScoreUpdateScheduler)
+
+	events := []domain.ActionEvent{
+		newEvent("alice", "a1", "login", 1),
+		newEvent("bob", "b1", "login", 1),
+		newEvent("alice", "a2", "quiz_answer", 3),
+	}
+	unlocked := false
+	updates.On("Lock", []string{"alice", "bob"}).Return(func() { unlocked = true })
+	// Bob's action was stored before, so only Alice's score is out of date
+	store.On("AppendActions", mock.Anything, events).Return([]domain.ActionEvent{events[0], events[2]}, nil)
+	// The users are unlocked before their updates are scheduled, so the
+	// updates do not wait for the rest of the batch
+	updates.On("Schedule", mock.Anything, []string{"alice"}).Run(func(mock.Arguments) {
+		assert.True(t, unlocked)
+	}).Once()
+
+	ingester := NewActionIngester(store, NewRuleRegistry(DefaultScoringRules()), WithScoreUpdates(updates))
+	result, err := ingester.Ingest(context.Background(), events)
+
+	require.NoError(t, err)
+	assert.Equal(t, IngestResult{Accepted: 2, Duplicates: 1}, result)
+	updates.AssertExpectations(t)
+}
+
+func TestIngest_SchedulesNothingForDuplicates(t *testing.T) {
+	store := new(MockActionStore)
+	updates := new(MockScoreUpdateScheduler)
+
+	events := []domain.ActionEvent{newEvent("alice", "a1", "login", 1)}
+	updates.On("Lock", []string{"alice"}).Return(func() {})
+	store.On("AppendActions", mock.Anything, events).Return([]domain.ActionEvent(nil), nil)
+
+	ingester := NewActionIngester(store, NewRuleRegistry(DefaultScoringRules()), WithScoreUpdates(updates))
+	_, err := ingester.Ingest(context.Background(), events)
+
+	require.NoError(t, err)
+	updates.AssertNotCalled(t, "Schedule", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,510 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"scoreapp/domain"
+)
+
+var (
+	// ErrJobNotFound is returned when no job has the requested ID.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrInvalidJob is returned when a recalculation request is malformed.
+	ErrInvalidJob = errors.New("invalid job")
+)
+
+const (
+	// DefaultJobConcurrency is how many users of a job are recalculated at
+	// once when no concurrency is configured.
+	DefaultJobConcurrency = 4
+	// DefaultJobMaxAttempts is how often a user is tried before a job gives
+	// up on it when no limit is configured.
+	DefaultJobMaxAttempts = 3
+	// DefaultJobRetryBackoff is the wait before the first retry of a user
+	// when no backoff is configured. Each further retry waits twice as long.
+	DefaultJobRetryBackoff = time.Second
+	// DefaultJobMaxRetryBackoff caps the wait between retries of a user when
+	// no cap is configured.
+	DefaultJobMaxRetryBackoff = time.Minute
+)
+
+// checkpointInterval bounds how often the progress of a running job is saved.
+const checkpointInterval = time.Second
+
+// JobRepository persists recalculation jobs, so that unfinished jobs can be
+// resumed when the application restarts.
+type JobRepository interface {
+	// SaveJob creates or replaces the job with the same ID.
+	SaveJob(ctx context.Context, job domain.Job) error
+	// GetJob returns the job with the ID, or ErrJobNotFound.
+	GetJob(ctx context.Context, id string) (domain.Job, error)
+	// ListUnfinishedJobs returns the queued and running jobs, oldest first.
+	ListUnfinishedJobs(ctx context.Context) ([]domain.Job, error)
+}
+
+// UserLister lists every user with a stored score.
+type UserLister interface {
+	ListUserIDs(ctx context.Context) ([]string, error)
+}
+
+// ScoreRecalculator calculates and saves the score of a user.
+type ScoreRecalculator interface {
+	Calculate(ctx context.Context, userID string, window domain.Window) (domain.UserScore, error)
+}
+
+// RecalculationRequest describes the users a job recalculates. Exactly one
+// of UserIDs and AllUsers must be set.
+type RecalculationRequest struct {
+	UserIDs  []string
+	AllUsers bool
+	Window   domain.Window
+}
+
+// JobQueue runs recalculation jobs in the background, one at a time and in
+// the order they were submitted. Users of a job are recalculated several at
+// a time, and a user whose recalculation fails for a reason that may pass is
+// retried with exponential backoff.
+type JobQueue struct {
+	calculator  ScoreRecalculator
+	repo        JobRepository
+	users       UserLister
+	concurrency int
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	location    *time.Location
+	now         func() time.Time
+	sleep       func(ctx context.Context, d time.Duration) error
+	onError     func(error)
+
+	mu      sync.Mutex
+	pending []string
+	running map[string]*domain.Job
+	wake    chan struct{}
+}
+
+// JobOption configures optional JobQueue behavior.
+type JobOption func(*JobQueue)
+
+// WithJobConcurrency sets how many users of a job are recalculated at once.
+// Defaults to DefaultJobConcurrency.
+func WithJobConcurrency(n int) JobOption {
+	return func(q *JobQueue) {
+		q.concurrency = n
+	}
+}
+
+// WithJobRetries sets how often a user is tried and how long to wait before
+// the first retry. Defaults to DefaultJobMaxAttempts and
+// DefaultJobRetryBackoff.
+func WithJobRetries(maxAttempts int, backoff time.Duration) JobOption {
+	return func(q *JobQueue) {
+		q.maxAttempts = maxAttempts
+		q.backoff = backoff
+	}
+}
+
+// WithJobMaxRetryBackoff caps the wait between retries of a user. Defaults
+// to DefaultJobMaxRetryBackoff.
+func WithJobMaxRetryBackoff(d time.Duration) JobOption {
+	return func(q *JobQueue) {
+		q.maxBackoff = d
+	}
+}
+
+// WithJobClock sets the source of the current time. Defaults to time.Now.
+func WithJobClock(now func() time.Time) JobOption {
+	return func(q *JobQueue) {
+		q.now = now
+	}
+}
+
+// WithJobLocation sets the timezone calendar windows are aligned to when the
+// window does not specify one. Defaults to UTC.
+func WithJobLocation(loc *time.Location) JobOption {
+	return func(q *JobQueue) {
+		q.location = loc
+	}
+}
+
+// WithJobErrorHandler sets the function told about jobs that could not be
+// loaded or saved. Defaults to discarding the errors.
+func WithJobErrorHandler(onError func(error)) JobOption {
+	return func(q *JobQueue) {
+		q.onError = onError
+	}
+}
+
+// NewJobQueue constructs a JobQueue. users may be nil, in which case jobs
+// for all users are rejected.
+func NewJobQueue(calculator ScoreRecalculator, repo JobRepository, users UserLister, opts ...JobOption) *JobQueue {
+	q := &JobQueue{
+		calculator:  calculator,
+		repo:        repo,
+		users:       users,
+		concurrency: DefaultJobConcurrency,
+		maxAttempts: DefaultJobMaxAttempts,
+		backoff:     DefaultJobRetryBackoff,
+		maxBackoff:  DefaultJobMaxRetryBackoff,
+		location:    time.UTC,
+		now:         time.Now,
+		sleep:       sleep,
+		onError:     func(error) {},
+		running:     make(map[string]*domain.Job),
+		wake:        make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Submit saves a new job for the request and queues it.
+func (q *JobQueue) Submit(ctx context.Context, req RecalculationRequest) (domain.Job, error) {
+	switch {
+	case req.AllUsers && len(req.UserIDs) > 0:
+		return domain.Job{}, fmt.Errorf("%w: user IDs and all users are mutually exclusive", ErrInvalidJob)
+	case req.AllUsers && q.users == nil:
+		return domain.Job{}, fmt.Errorf("%w: listing all users is not supported", ErrInvalidJob)
+	case !req.AllUsers && len(req.UserIDs) == 0:
+		return domain.Job{}, fmt.Errorf("%w: no user IDs", ErrInvalidJob)
+	}
+	if err := checkUserIDs(req.UserIDs); err != nil {
+		return domain.Job{}, fmt.Errorf("%w: %w", ErrInvalidJob, err)
+	}
+
+	window := req.Window
+	if err := window.Validate(); err != nil {
+		return domain.Job{}, err
+	}
+	if window.Location == nil {
+		window.Location = q.location
+	}
+
+	job := domain.Job{
+		ID:        newJobID(),
+		Status:    domain.JobQueued,
+		Window:    window.Key(),
+		AllUsers:  req.AllUsers,
+		UserIDs:   slices.Clone(req.UserIDs),
+		CreatedAt: q.now(),
+	}
+	if err := q.repo.SaveJob(ctx, job); err != nil {
+		return domain.Job{}, fmt.Errorf("failed to save job: %w", err)
+	}
+	q.enqueue(job.ID)
+	return job, nil
+}
+
+// Get returns the job with the ID. The progress of a running job is current,
+// not as of its last checkpoint.
+func (q *JobQueue) Get(ctx context.Context, id string) (domain.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Job{}, err
+	}
+
+	q.mu.Lock()
+	job, running := q.running[id]
+	if running {
+		snapshot := cloneJob(*job)
+		q.mu.Unlock()
+		return snapshot, nil
+	}
+	q.mu.Unlock()
+
+	return q.repo.GetJob(ctx, id)
+}
+
+// Run queues the jobs left unfinished by a previous run and then runs jobs
+// until ctx is canceled. A job interrupted by the cancellation keeps its
+// progress and is resumed by the next Run.
+func (q *JobQueue) Run(ctx context.Context) error {
+	unfinished, err := q.repo.ListUnfinishedJobs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to resume jobs: %w", err)
+	}
+	// Unfinished jobs are older than any submitted since the queue was built
+	q.mu.Lock()
+	pending := make([]string, 0, len(unfinished)+len(q.pending))
+	for _, job := range unfinished {
+		pending = append(pending, job.ID)
+	}
+	for _, id := range q.pending {
+		if !slices.Contains(pending, id) {
+			pending = append(pending, id)
+		}
+	}
+	q.pending = pending
+	q.mu.Unlock()
+
+	for {
+		id, ok := q.next(ctx)
+		if !ok {
+			return nil
+		}
+		q.runJob(ctx, id)
+	}
+}
+
+func (q *JobQueue) enqueue(id string) {
+	q.mu.Lock()
+	if !slices.Contains(q.pending, id) {
+		q.pending = append(q.pending, id)
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next waits for a queued job and returns its ID, or false once ctx is done.
+func (q *JobQueue) next(ctx context.Context) (string, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			id := q.pending[0]
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+			return id, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.wake:
+		case <-ctx.Done():
+			return "", false
+		}
+	}
+}
+
+func (q *JobQueue) runJob(ctx context.Context, id string) {
+	job, err := q.repo.GetJob(ctx, id)
+	if err != nil {
+		q.onError(fmt.Errorf("failed to load job %s: %w", id, err))
+		return
+	}
+	if job.Status.Finished() {
+		return
+	}
+
+	window, err := domain.ParseWindowKey(job.Window)
+	if err != nil {
+		q.fail(ctx, &job, err)
+		return
+	}
+	if job.Status == domain.JobQueued {
+		job.Status = domain.JobRunning
+		job.StartedAt = q.now()
+	}
+	if job.AllUsers && job.UserIDs == nil {
+		if q.users == nil {
+			q.fail(ctx, &job, errors.New("listing all users is not supported"))
+			return
+		}
+		userIDs, err := q.users.ListUserIDs(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				q.fail(ctx, &job, fmt.Errorf("failed to list users: %w", err))
+			}
+			return
+		}
+		// A non-nil list marks the users as resolved, even when there are none
+		job.UserIDs = append([]string{}, userIDs...)
+	}
+
+	q.mu.Lock()
+	q.running[id] = &job
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, id)
+		q.mu.Unlock()
+	}()
+
+	q.save(ctx, job)
+	q.process(ctx, &job, window)
+}
+
+// outcome is how the recalculation of the user at index of a job ended.
+type outcome struct {
+	index    int
+	attempts int
+	err      error
+	// interrupted is set when the job was stopped before the user was
+	// done. The user is recalculated again when the job resumes.
+	interrupted bool
+}
+
+// process recalculates the users of the job that were not processed yet. The
+// job's progress only moves past a user once every user before it is done,
+// so a resumed job continues exactly where its saved progress ends.
+func (q *JobQueue) process(ctx context.Context, job *domain.Job, window domain.Window) {
+	userIDs := job.UserIDs
+	start := job.Processed
+
+	indexes := make(chan int)
+	outcomes := make(chan outcome)
+	var wg sync.WaitGroup
+	for range min(max(q.concurrency, 1), len(userIDs)-start) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				// The feeder may hand out one more user as the job stops
+				if ctx.Err() != nil {
+					outcomes <- outcome{index: i, err: ctx.Err(), interrupted: true}
+					continue
+				}
+				attempts, err := q.recalculate(ctx, userIDs[i], window)
+				outcomes <- outcome{index: i, attempts: attempts, err: err, interrupted: err != nil && ctx.Err() != nil}
+			}
+		}()
+	}
+	go func() {
+		defer close(outcomes)
+	feed:
+		for i := start; i < len(userIDs); i++ {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(indexes)
+		wg.Wait()
+	}()
+
+	done := make(map[int]outcome)
+	lastSave := q.now()
+	for o := range outcomes {
+		done[o.index] = o
+
+		q.mu.Lock()
+		for {
+			next, ok := done[job.Processed]
+			if !ok || next.interrupted {
+				break
+			}
+			delete(done, job.Processed)
+			job.Processed++
+			if next.err != nil {
+				job.Failures = append(job.Failures, domain.JobFailure{
+					UserID:   userIDs[next.index],
+					Error:    next.err.Error(),
+					Attempts: next.attempts,
+				})
+			} else {
+				job.Succeeded++
+			}
+		}
+		q.mu.Unlock()
+
+		if now := q.now(); now.Sub(lastSave) >= checkpointInterval {
+			q.save(ctx, q.snapshot(job))
+			lastSave = now
+		}
+	}
+
+	// Keep the progress of an interrupted job for the next run
+	if ctx.Err() != nil && job.Processed < len(userIDs) {
+		q.save(ctx, q.snapshot(job))
+		return
+	}
+
+	q.mu.Lock()
+	job.Status = domain.JobCompleted
+	job.FinishedAt = q.now()
+	q.mu.Unlock()
+	q.save(ctx, q.snapshot(job))
+}
+
+// recalculate calculates the user's score, retrying failures that may pass.
+// It returns the number of attempts made.
+func (q *JobQueue) recalculate(ctx context.Context, userID string, window domain.Window) (int, error) {
+	for attempt := 1; ; attempt++ {
+		_, err := q.calculator.Calculate(ctx, userID, window)
+		if err == nil || attempt >= q.maxAttempts || permanent(err) || ctx.Err() != nil {
+			return attempt, err
+		}
+		if err := q.sleep(ctx, q.retryBackoff(attempt)); err != nil {
+			return attempt, err
+		}
+	}
+}
+
+// retryBackoff returns the wait before retrying after the attempt.
+func (q *JobQueue) retryBackoff(attempt int) time.Duration {
+	d := q.backoff
+	for range attempt - 1 {
+		if d >= q.maxBackoff/2 {
+			return q.maxBackoff
+		}
+		d *= 2
+	}
+	return min(d, q.maxBackoff)
+}
+
+// permanent reports whether retrying a failed calculation cannot help.
+func permanent(err error) bool {
+	return errors.Is(err, ErrUserNotFound) ||
+		errors.Is(err, ErrUnknownActionType) ||
+		errors.Is(err, ErrScoreOverflow) ||
+		errors.Is(err, domain.ErrInvalidWindow)
+}
+
+func (q *JobQueue) fail(ctx context.Context, job *domain.Job, err error) {
+	job.Status = domain.JobFailed
+	job.Error = err.Error()
+	job.FinishedAt = q.now()
+	q.save(ctx, *job)
+}
+
+// save saves the job, even when ctx is canceled, so a stopping queue keeps
+// the progress it made.
+func (q *JobQueue) save(ctx context.Context, job domain.Job) {
+	if err := q.repo.SaveJob(context.WithoutCancel(ctx), job); err != nil {
+		q.onError(fmt.Errorf("failed to save job %s: %w", job.ID, err))
+	}
+}
+
+// snapshot returns a copy of a running job that is safe to use while the
+// job goes on.
+func (q *JobQueue) snapshot(job *domain.Job) domain.Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return cloneJob(*job)
+}
+
+func cloneJob(job domain.Job) domain.Job {
+	job.UserIDs = slices.Clone(job.UserIDs)
+	job.Failures = slices.Clone(job.Failures)
+	return job
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newJobID returns a random 128-bit job ID.
+func newJobID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,322 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+// MockScoreRecalculator is a mock for ScoreRecalculator.
+type MockScoreRecalculator struct {
+	mock.Mock
+}
+
+func (m *MockScoreRecalculator) Calculate(ctx context.Context, userID string, window domain.Window) (domain.UserScore, error) {
+	args := m.Called(ctx, userID, window)
+	return args.Get(0).(domain.UserScore), args.Error(1)
+}
+
+// MockUserLister is a mock for UserLister.
+type MockUserLister struct {
+	mock.Mock
+}
+
+func (m *MockUserLister) ListUserIDs(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// memoryJobs is an in-memory JobRepository.
+type memoryJobs struct {
+	mu   sync.Mutex
+	jobs map[string]domain.Job
+}
+
+func newMemoryJobs(jobs ...domain.Job) *memoryJobs {
+	r := &memoryJobs{jobs: make(map[string]domain.Job)}
+	for _, job := range jobs {
+		r.jobs[job.ID] = job
+	}
+	return r
+}
+
+func (r *memoryJobs) SaveJob(_ context.Context, job domain.Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.ID] = cloneJob(job)
+	return nil
+}
+
+func (r *memoryJobs) GetJob(_ context.Context, id string) (domain.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return domain.Job{}, ErrJobNotFound
+	}
+	return cloneJob(job), nil
+}
+
+func (r *memoryJobs) ListUnfinishedJobs(context.Context) ([]domain.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var jobs []domain.Job
+	for _, job := range r.jobs {
+		if !job.Status.Finished() {
+			jobs = append(jobs, cloneJob(job))
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs, nil
+}
+
+// startQueue runs the queue in the background. The returned function stops
+// it and waits for Run to return.
+func startQueue(t *testing.T, q *JobQueue) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- q.Run(ctx) }()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			require.NoError(t, <-done)
+		})
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+// waitForJob waits until the job is finished and returns it.
+func waitForJob(t *testing.T, q *JobQueue, id string) domain.Job {
+	t.Helper()
+	var job domain.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = q.Get(context.Background(), id)
+		return err == nil && job.Status.Finished()
+	}, time.Second, time.Millisecond)
+	return job
+}
+
+// recordSleeps makes the queue's backoff return at once and records the
+// waits it asked for.
+func recordSleeps(q *JobQueue) func() []time.Duration {
+	var mu sync.Mutex
+	var slept []time.Duration
+	q.sleep = func(_ context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		slept = append(slept, d)
+		return nil
+	}
+	return func() []time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return slept
+	}
+}
+
+func TestJobQueue_Completes(t *testing.T) {
+	mockCalculator := new(MockScoreRecalculator)
+	transient := errors.New("service unavailable")
+
+	mockCalculator.On("Calculate", mock.Anything, "alice", domain.AllTime()).Return(domain.UserScore{}, nil)
+	mockCalculator.On("Calculate", mock.Anything, "nobody", domain.AllTime()).
+		Return(domain.UserScore{}, fmt.Errorf("failed to get actions: %w", ErrUserNotFound))
+	// bob fails twice and then succeeds
+	mockCalculator.On("Calculate", mock.Anything, "bob", domain.AllTime()).Return(domain.UserScore{}, transient).Twice()
+	mockCalculator.On("Calculate", mock.Anything, "bob", domain.AllTime()).Return(domain.UserScore{}, nil).Once()
+
+	q := NewJobQueue(mockCalculator, newMemoryJobs(), nil, WithJobClock(fixedClock(calculatedAt)))
+	slept := recordSleeps(q)
+	startQueue(t, q)
+
+	submitted, err := q.Submit(context.Background(), RecalculationRequest{UserIDs: []string{"alice", "nobody", "bob"}, Window: domain.AllTime()})
+	require.NoError(t, err)
+	assert.Equal(t, domain.JobQueued, submitted.Status)
+	assert.NotEmpty(t, submitted.ID)
+
+	job := waitForJob(t, q, submitted.ID)
+
+	assert.Equal(t, domain.JobCompleted, job.Status)
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 2, job.Succeeded)
+	// Users that do not exist are not retried
+	assert.Equal(t, []domain.JobFailure{{UserID: "nobody", Error: "failed to get actions: user not found", Attempts: 1}}, job.Failures)
+	assert.Equal(t, calculatedAt, job.StartedAt)
+	assert.Equal(t, calculatedAt, job.FinishedAt)
+	assert.Equal(t, []time.Duration{DefaultJobRetryBackoff, 2 * DefaultJobRetryBackoff}, slept())
+	mockCalculator.AssertExpectations(t)
+}
+
+func TestJobQueue_GivesUpAfterMaxAttempts(t *testing.T) {
+	mockCalculator := new(MockScoreRecalculator)
+	mockCalculator.On("Calculate", mock.Anything, "alice", mock.Anything).Return(domain.UserScore{}, errors.New("service unavailable"))
+
+	q := NewJobQueue(mockCalculator, newMemoryJobs(), nil, WithJobRetries(2, time.Minute))
+	slept := recordSleeps(q)
+	startQueue(t, q)
+
+	submitted, err := q.Submit(context.Background(), RecalculationRequest{UserIDs: []string{"alice"}, Window: domain.AllTime()})
+	require.NoError(t, err)
+
+	job := waitForJob(t, q, submitted.ID)
+
+	assert.Equal(t, domain.JobCompleted, job.Status)
+	assert.Equal(t, 0, job.Succeeded)
+	assert.Equal(t, []domain.JobFailure{{UserID: "alice", Error: "service unavailable", Attempts: 2}}, job.Failures)
+	assert.Equal(t, []time.Duration{time.Minute}, slept())
+	mockCalculator.AssertNumberOfCalls(t, "Calculate", 2)
+}
+
+func TestJobQueue_AllUsers(t *testing.T) {
+	mockCalculator := new(MockScoreRecalculator)
+	mockUsers := new(MockUserLister)
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	require.NoError(t, err)
+
+	mockUsers.On("ListUserIDs", mock.Anything).Return([]string{"alice", "bob"}, nil)
+	mockCalculator.On("Calculate", mock.Anything, mock.Anything, mock.MatchedBy(func(w domain.Window) bool {
+		return w.Key() == "week@Europe/Istanbul"
+	})).Return(domain.UserScore{}, nil)
+
+	q := NewJobQueue(mockCalculator, newMemoryJobs(), mockUsers, WithJobLocation(istanbul))
+	startQueue(t, q)
+
+	// The window is aligned to the queue's timezone
+	submitted, err := q.Submit(context.Background(), RecalculationRequest{AllUsers: true, Window: domain.Window{Kind: domain.WindowWeek}})
+	require.NoError(t, err)
+	assert.Equal(t, "week@Europe/Istanbul", submitted.Window)
+	assert.Nil(t, submitted.UserIDs)
+
+	job := waitForJob(t, q, submitted.ID)
+
+	assert.Equal(t, domain.JobCompleted, job.Status)
+	assert.Equal(t, []string{"alice", "bob"}, job.UserIDs)
+	assert.Equal(t, 2, job.Succeeded)
+	mockCalculator.AssertNumberOfCalls(t, "Calculate", 2)
+}
+
+func TestJobQueue_ListUsersFails(t *testing.T) {
+	mockCalculator := new(MockScoreRecalculator)
+	mockUsers := new(MockUserLister)
+	mockUsers.On("ListUserIDs", mock.Anything).Return([]string(nil), errors.New("database is locked"))
+
+	q := NewJobQueue(mockCalculator, newMemoryJobs(), mockUsers)
+	startQueue(t, q)
+
+	submitted, err := q.Submit(context.Background(), RecalculationRequest{AllUsers: true, Window: domain.AllTime()})
+	require.NoError(t, err)
+
+	job := waitForJob(t, q, submitted.ID)
+
+	assert.Equal(t, domain.JobFailed, job.Status)
+	assert.Equal(t, "failed to list users: database is locked", job.Error)
+	mockCalculator.AssertNotCalled(t, "Calculate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestJobQueue_RetryBackoffIsCapped(t *testing.T) {
+	q := NewJobQueue(nil, newMemoryJobs(), nil, WithJobRetries(100, time.Second), WithJobMaxRetryBackoff(5*time.Second))
+
+	var waits []time.Duration
+	for _, attempt := range []int{1, 2, 3, 4, 70, 99} {
+		waits = append(waits, q.retryBackoff(attempt))
+	}
+
+	// Doubling stops at the cap instead of overflowing
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second}, waits)
+}
+
+func TestJobQueue_ResumesInterruptedJob(t *testing.T) {
+	repo := newMemoryJobs()
+	mockCalculator := new(MockScoreRecalculator)
+	started := make(chan struct{})
+
+	mockCalculator.On("Calculate", mock.Anything, "alice", mock.Anything).Return(domain.UserScore{}, nil)
+	// bob is still being recalculated when the queue stops
+	mockCalculator.On("Calculate", mock.Anything, "bob", mock.Anything).Run(func(args mock.Arguments) {
+		close(started)
+		<-args.Get(0).(context.Context).Done()
+	}).Return(domain.UserScore{}, context.Canceled)
+
+	q := NewJobQueue(mockCalculator, repo, nil, WithJobConcurrency(1))
+	recordSleeps(q)
+	stop := startQueue(t, q)
+
+	submitted, err := q.Submit(context.Background(), RecalculationRequest{UserIDs: []string{"alice", "bob", "carol"}, Window: domain.AllTime()})
+	require.NoError(t, err)
+	<-started
+	stop()
+
+	saved, err := repo.GetJob(context.Background(), submitted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.JobRunning, saved.Status)
+	assert.Equal(t, 1, saved.Processed)
+	assert.Equal(t, 1, saved.Succeeded)
+	assert.Empty(t, saved.Failures)
+
+	// A new queue, as after a restart, continues after alice
+	resumedCalculator := new(MockScoreRecalculator)
+	resumedCalculator.On("Calculate", mock.Anything, mock.Anything, mock.Anything).Return(domain.UserScore{}, nil)
+	resumed := NewJobQueue(resumedCalculator, repo, nil)
+	startQueue(t, resumed)
+
+	job := waitForJob(t, resumed, submitted.ID)
+
+	assert.Equal(t, domain.JobCompleted, job.Status)
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 3, job.Succeeded)
+	resumedCalculator.AssertNotCalled(t, "Calculate", mock.Anything, "alice", mock.Anything)
+	resumedCalculator.AssertNumberOfCalls(t, "Calculate", 2)
+}
+
+func TestJobQueue_InvalidRequest(t *testing.T) {
+	mockCalculator := new(MockScoreRecalculator)
+	repo := newMemoryJobs()
+	withUsers := NewJobQueue(mockCalculator, repo, new(MockUserLister))
+	withoutUsers := NewJobQueue(mockCalculator, repo, nil)
+
+	tests := []struct {
+		name     string
+		queue    *JobQueue
+		req      RecalculationRequest
+		expected error
+	}{
+		{"no users", withUsers, RecalculationRequest{Window: domain.AllTime()}, ErrInvalidJob},
+		{"users and all users", withUsers, RecalculationRequest{UserIDs: []string{"alice"}, AllUsers: true, Window: domain.AllTime()}, ErrInvalidJob},
+		{"empty user ID", withUsers, RecalculationRequest{UserIDs: []string{""}, Window: domain.AllTime()}, ErrInvalidJob},
+		{"duplicate user ID", withUsers, RecalculationRequest{UserIDs: []string{"alice", "alice"}, Window: domain.AllTime()}, ErrInvalidJob},
+		{"all users unsupported", withoutUsers, RecalculationRequest{AllUsers: true, Window: domain.AllTime()}, ErrInvalidJob},
+		{"invalid window", withUsers, RecalculationRequest{UserIDs: []string{"alice"}, Window: domain.Window{Kind: domain.WindowRolling}}, domain.ErrInvalidWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.queue.Submit(context.Background(), tt.req)
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+
+	assert.Empty(t, repo.jobs)
+}
+
+func TestJobQueue_GetMissing(t *testing.T) {
+	q := NewJobQueue(new(MockScoreRecalculator), newMemoryJobs(), nil)
+
+	_, err := q.Get(context.Background(), "nonexistent")
+
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
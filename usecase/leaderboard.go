@@ -0,0 +1,466 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"scoreapp/domain"
+)
+
+var (
+	// ErrNotRanked is returned when a user has no place on the leaderboard.
+	ErrNotRanked = errors.New("user is not ranked")
+	// ErrInvalidLeaderboardQuery is returned when a leaderboard query is malformed.
+	ErrInvalidLeaderboardQuery = errors.New("invalid leaderboard query")
+)
+
+const (
+	// DefaultLeaderboardLimit is the page size used when a query sets none.
+	DefaultLeaderboardLimit = 10
+	// MaxLeaderboardLimit is the largest page size a query may ask for.
+	MaxLeaderboardLimit = 100
+	// DefaultLeaderboardRadius is the number of neighbors shown on each side
+	// of a user when a query sets none.
+	DefaultLeaderboardRadius = 5
+	// MaxLeaderboardRadius is the largest radius a query may ask for.
+	MaxLeaderboardRadius = 50
+	// DefaultLeaderboardRetention is how long boards are kept after their
+	// period ends when no retention is configured.
+	DefaultLeaderboardRetention = 30 * 24 * time.Hour
+)
+
+// sweepInterval bounds how often expired boards are looked for.
+const sweepInterval = time.Minute
+
+// LeaderboardIndex keeps users ordered by score, highest first. Equal scores
+// are ordered by ReachedAt, earliest first, and then by user ID.
+type LeaderboardIndex interface {
+	// Upsert sets the user's score. A user whose score is unchanged keeps
+	// the time it was first reached.
+	Upsert(userID string, score int64, reachedAt time.Time)
+	// Remove drops the user from the index.
+	Remove(userID string)
+	// Rank returns the user's entry, or false if the user is not ranked.
+	Rank(userID string) (domain.LeaderboardEntry, bool)
+	// Range returns up to limit entries starting after the first offset ranks.
+	Range(offset, limit int) []domain.LeaderboardEntry
+	// Around returns the user's entry with up to radius entries on each
+	// side, or false if the user is not ranked.
+	Around(userID string, radius int) ([]domain.LeaderboardEntry, bool)
+	// Len returns the number of ranked users.
+	Len() int
+}
+
+// ScoreLister lists the scores a ScoreRepository has saved, so indexes over
+// them can be rebuilt when the application starts.
+type ScoreLister interface {
+	ListWindow(ctx context.Context, window string) ([]domain.UserScore, error)
+}
+
+// UserMetadataService returns attributes of a user, such as country, cohort
+// or team, that leaderboards are segmented by.
+type UserMetadataService interface {
+	GetMetadata(ctx context.Context, userID string) (map[string]string, error)
+}
+
+// LeaderboardQuery selects a leaderboard.
+type LeaderboardQuery struct {
+	// Segment is "<dimension>:<value>", or empty for every user.
+	Segment string
+	// Window is the scoring window that is ranked. The zero value means all
+	// time. Rolling windows have no periods and are not ranked.
+	Window domain.Window
+	// At selects the period of Window that contains it. Zero means the
+	// current period.
+	At time.Time
+}
+
+// LeaderboardPage is one page of a leaderboard.
+type LeaderboardPage struct {
+	Key     domain.LeaderboardKey
+	Entries []domain.LeaderboardEntry
+	// Total is the number of ranked users.
+	Total int
+	// ResetsAt is when the board's period ends. It is zero for all-time boards.
+	ResetsAt time.Time
+}
+
+// board is one leaderboard, created when a score is first recorded on it.
+type board struct {
+	index LeaderboardIndex
+	// end is when the board's period ends, or zero if it never does.
+	end time.Time
+	// segments holds the segments each user was last ranked on. Only the
+	// board of every user of a segmented leaderboard keeps them.
+	segments map[string][]string
+}
+
+// Leaderboard ranks users by score, on one board per segment, window and
+// window period. Boards are created when the first score is recorded on
+// them, so a new period starts empty, and are dropped once the retention
+// period has passed after their period ended.
+type Leaderboard struct {
+	newIndex   func() LeaderboardIndex
+	now        func() time.Time
+	location   *time.Location
+	metadata   UserMetadataService
+	dimensions []string
+	retention  time.Duration
+	onError    func(error)
+
+	mu        sync.Mutex
+	boards    map[string]*board
+	lastSweep time.Time
+}
+
+// LeaderboardOption configures optional Leaderboard behavior.
+type LeaderboardOption func(*Leaderboard)
+
+// WithLeaderboardClock sets the source of the current time, which decides
+// the current period of each window. Defaults to time.Now.
+func WithLeaderboardClock(now func() time.Time) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.now = now
+	}
+}
+
+// WithLeaderboardLocation sets the timezone calendar windows are aligned to
+// when the window does not specify one. Defaults to UTC.
+func WithLeaderboardLocation(loc *time.Location) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.location = loc
+	}
+}
+
+// WithSegments ranks users on a board per value of each metadata dimension,
+// besides the board of every user.
+func WithSegments(metadata UserMetadataService, dimensions ...string) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.metadata = metadata
+		l.dimensions = dimensions
+	}
+}
+
+// WithLeaderboardRetention sets how long boards are kept after their period
+// ends. Defaults to DefaultLeaderboardRetention.
+func WithLeaderboardRetention(d time.Duration) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.retention = d
+	}
+}
+
+// WithLeaderboardErrorHandler sets the function told about scores that could
+// not be ranked on every board. Defaults to discarding the errors.
+func WithLeaderboardErrorHandler(onError func(error)) LeaderboardOption {
+	return func(l *Leaderboard) {
+		l.onError = onError
+	}
+}
+
+// NewLeaderboard constructs a Leaderboard whose boards are built by newIndex.
+func NewLeaderboard(newIndex func() LeaderboardIndex, opts ...LeaderboardOption) *Leaderboard {
+	l := &Leaderboard{
+		newIndex:  newIndex,
+		now:       time.Now,
+		location:  time.UTC,
+		retention: DefaultLeaderboardRetention,
+		onError:   func(error) {},
+		boards:    make(map[string]*board),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Record ranks a saved score on the boards of its window and period: the
+// board of every user and the boards of the user's segments. A user whose
+// metadata changed is removed from the boards of the segments they left.
+// Scores of rolling windows are ignored.
+func (l *Leaderboard) Record(ctx context.Context, score domain.UserScore) {
+	window, err := domain.ParseWindowKey(score.WindowKey())
+	if err != nil {
+		l.onError(fmt.Errorf("failed to rank score of user %q: %w", score.UserID, err))
+		return
+	}
+	if window.Kind == domain.WindowRolling {
+		return
+	}
+
+	reachedAt := score.CalculatedAt
+	if reachedAt.IsZero() {
+		reachedAt = l.now()
+	}
+	period := window.Span(reachedAt)
+
+	key := domain.LeaderboardKey{Window: window.Key(), Period: period.Start}
+	all := l.board(key, period.End, true)
+	if all == nil {
+		return
+	}
+
+	segments, left := l.regroup(all, score.UserID, l.segments(ctx, score.UserID))
+	for _, segment := range left {
+		key.Segment = segment
+		if b := l.board(key, period.End, false); b != nil {
+			b.index.Remove(score.UserID)
+		}
+	}
+	for _, segment := range segments {
+		key.Segment = segment
+		if b := l.board(key, period.End, true); b != nil {
+			b.index.Upsert(score.UserID, score.Score, reachedAt)
+		}
+	}
+}
+
+// segments returns the segments of the user, starting with the segment of
+// every user, or nil if the user's metadata could not be read.
+func (l *Leaderboard) segments(ctx context.Context, userID string) []string {
+	segments := []string{""}
+	if l.metadata == nil || len(l.dimensions) == 0 {
+		return segments
+	}
+
+	metadata, err := l.metadata.GetMetadata(ctx, userID)
+	if err != nil {
+		l.onError(fmt.Errorf("failed to get metadata of user %q: %w", userID, err))
+		return nil
+	}
+	for _, dimension := range l.dimensions {
+		if value := metadata[dimension]; value != "" {
+			segments = append(segments, dimension+":"+value)
+		}
+	}
+	return segments
+}
+
+// regroup records the segments the user is ranked on in the board of every
+// user of a period, and returns them with the segments the user left since
+// the last score of the period. Nil segments keep the user on the segments
+// they were last ranked on.
+func (l *Leaderboard) regroup(all *board, userID string, segments []string) (current, left []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	last, known := all.segments[userID]
+	if segments == nil {
+		if known {
+			return last, nil
+		}
+		return []string{""}, nil
+	}
+	if len(l.dimensions) == 0 {
+		return segments, nil
+	}
+
+	for _, segment := range last {
+		if !slices.Contains(segments, segment) {
+			left = append(left, segment)
+		}
+	}
+	if all.segments == nil {
+		all.segments = make(map[string][]string)
+	}
+	all.segments[userID] = segments
+	return segments, left
+}
+
+// board returns the board of the key, creating it if create is set. It
+// returns nil if the board does not exist or its retention has passed.
+func (l *Leaderboard) board(key domain.LeaderboardKey, end time.Time, create bool) *board {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		for name, b := range l.boards {
+			if l.expired(b.end, now) {
+				delete(l.boards, name)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	if l.expired(end, now) {
+		return nil
+	}
+	name := key.String()
+	b, exists := l.boards[name]
+	if !exists && create {
+		b = &board{index: l.newIndex(), end: end}
+		l.boards[name] = b
+	}
+	return b
+}
+
+func (l *Leaderboard) expired(end, now time.Time) bool {
+	return !end.IsZero() && !now.Before(end.Add(l.retention))
+}
+
+// Load ranks every all-time score the lister holds. Scores loaded this way
+// count as reached when they were last calculated. Boards of bounded
+// windows fill up again as scores are recalculated.
+func (l *Leaderboard) Load(ctx context.Context, lister ScoreLister) error {
+	scores, err := lister.ListWindow(ctx, domain.AllTime().Key())
+	if err != nil {
+		return fmt.Errorf("failed to load scores: %w", err)
+	}
+	for _, score := range scores {
+		l.Record(ctx, score)
+	}
+	return nil
+}
+
+// resolve returns the key of the board the query selects, the board if it
+// exists, and when the board's period ends.
+func (l *Leaderboard) resolve(q LeaderboardQuery) (domain.LeaderboardKey, *board, time.Time, error) {
+	if q.Segment != "" {
+		dimension, value, ok := strings.Cut(q.Segment, ":")
+		if !ok || dimension == "" || value == "" {
+			return domain.LeaderboardKey{}, nil, time.Time{}, fmt.Errorf("%w: segment must be <dimension>:<value>", ErrInvalidLeaderboardQuery)
+		}
+	}
+
+	window := q.Window
+	if window.Kind == "" {
+		window = domain.AllTime()
+	}
+	if err := window.Validate(); err != nil {
+		return domain.LeaderboardKey{}, nil, time.Time{}, fmt.Errorf("%w: %w", ErrInvalidLeaderboardQuery, err)
+	}
+	if window.Kind == domain.WindowRolling {
+		return domain.LeaderboardKey{}, nil, time.Time{}, fmt.Errorf("%w: rolling windows are not ranked", ErrInvalidLeaderboardQuery)
+	}
+	if window.Location == nil {
+		window.Location = l.location
+	}
+
+	at := q.At
+	if at.IsZero() {
+		at = l.now()
+	}
+	period := window.Span(at)
+
+	key := domain.LeaderboardKey{Segment: q.Segment, Window: window.Key(), Period: period.Start}
+	return key, l.board(key, period.End, false), period.End, nil
+}
+
+// Top returns up to limit entries of the selected board, starting after the
+// first offset ranks. A zero limit means DefaultLeaderboardLimit.
+func (l *Leaderboard) Top(ctx context.Context, q LeaderboardQuery, offset, limit int) (LeaderboardPage, error) {
+	if err := ctx.Err(); err != nil {
+		return LeaderboardPage{}, err
+	}
+	switch {
+	case limit == 0:
+		limit = DefaultLeaderboardLimit
+	case limit < 0 || limit > MaxLeaderboardLimit:
+		return LeaderboardPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidLeaderboardQuery, MaxLeaderboardLimit)
+	}
+	if offset < 0 {
+		return LeaderboardPage{}, fmt.Errorf("%w: offset must not be negative", ErrInvalidLeaderboardQuery)
+	}
+
+	key, b, end, err := l.resolve(q)
+	if err != nil {
+		return LeaderboardPage{}, err
+	}
+
+	page := LeaderboardPage{Key: key, ResetsAt: end}
+	if b != nil {
+		page.Entries = b.index.Range(offset, limit)
+		page.Total = b.index.Len()
+	}
+	return page, nil
+}
+
+// Rank returns the user's entry on the selected board.
+func (l *Leaderboard) Rank(ctx context.Context, q LeaderboardQuery, userID string) (domain.LeaderboardEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.LeaderboardEntry{}, err
+	}
+
+	_, b, _, err := l.resolve(q)
+	if err != nil {
+		return domain.LeaderboardEntry{}, err
+	}
+	if b == nil {
+		return domain.LeaderboardEntry{}, ErrNotRanked
+	}
+
+	entry, ok := b.index.Rank(userID)
+	if !ok {
+		return domain.LeaderboardEntry{}, ErrNotRanked
+	}
+	return entry, nil
+}
+
+// Around returns the user's entry on the selected board with up to radius
+// entries ranked directly above and below it. A zero radius means
+// DefaultLeaderboardRadius.
+func (l *Leaderboard) Around(ctx context.Context, q LeaderboardQuery, userID string, radius int) (LeaderboardPage, error) {
+	if err := ctx.Err(); err != nil {
+		return LeaderboardPage{}, err
+	}
+	switch {
+	case radius == 0:
+		radius = DefaultLeaderboardRadius
+	case radius < 0 || radius > MaxLeaderboardRadius:
+		return LeaderboardPage{}, fmt.Errorf("%w: radius must be between 1 and %d", ErrInvalidLeaderboardQuery, MaxLeaderboardRadius)
+	}
+
+	key, b, end, err := l.resolve(q)
+	if err != nil {
+		return LeaderboardPage{}, err
+	}
+	if b == nil {
+		return LeaderboardPage{}, ErrNotRanked
+	}
+
+	entries, ok := b.index.Around(userID, radius)
+	if !ok {
+		return LeaderboardPage{}, ErrNotRanked
+	}
+	return LeaderboardPage{
+		Key:      key,
+		Entries:  entries,
+		Total:    b.index.Len(),
+		ResetsAt: end,
+	}, nil
+}
+
+// LeaderboardRecorder is a ScoreRepository that ranks every score it saves
+// on a Leaderboard.
+type LeaderboardRecorder struct {
+	repo        ScoreRepository
+	leaderboard *Leaderboard
+	locks       *UserLocks
+}
+
+// NewLeaderboardRecorder wraps repo so that saved scores are also ranked.
+func NewLeaderboardRecorder(repo ScoreRepository, leaderboard *Leaderboard) *LeaderboardRecorder {
+	return &LeaderboardRecorder{
+		repo:        repo,
+		leaderboard: leaderboard,
+		locks:       NewUserLocks(),
+	}
+}
+
+// Save saves the score and, once it is stored, ranks it. Saves of the same
+// user are saved and ranked one at a time, so the boards end up with the
+// score that was stored last.
+func (r *LeaderboardRecorder) Save(ctx context.Context, score domain.UserScore) error {
+	defer r.locks.Lock([]string{score.UserID})()
+
+	if err := r.repo.Save(ctx, score); err != nil {
+		return err
+	}
+	r.leaderboard.Record(ctx, score)
+	return nil
+}
@@ -0,0 +1,602 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+// MockLeaderboardIndex is a mock for LeaderboardIndex.
+type MockLeaderboardIndex struct {
+	mock.Mock
+}
+
+func (m *MockLeaderboardIndex) Upsert(userID string, score int64, reachedAt time.Time) {
+	m.Called(userID, score, reachedAt)
+}
+
+func (m *MockLeaderboardIndex) Remove(userID string) {
+	m.Called(userID)
+}
+
+func (m *MockLeaderboardIndex) Rank(userID string) (domain.LeaderboardEntry, bool) {
+	args := m.Called(userID)
+	return args.Get(0).(domain.LeaderboardEntry), args.Bool(1)
+}
+
+func (m *MockLeaderboardIndex) Range(offset, limit int) []domain.LeaderboardEntry {
+	args := m.Called(offset, limit)
+	return args.Get(0).([]domain.LeaderboardEntry)
+}
+
+func (m *MockLeaderboardIndex) Around(userID string, radius int) ([]domain.LeaderboardEntry, bool) {
+	args := m.Called(userID, radius)
+	return args.Get(0).([]domain.LeaderboardEntry), args.Bool(1)
+}
+
+func (m *MockLeaderboardIndex) Len() int {
+	return m.Called().Int(0)
+}
+
+// MockScoreLister is a mock for ScoreLister.
+type MockScoreLister struct {
+	mock.Mock
+}
+
+func (m *MockScoreLister) ListWindow(ctx context.Context, window string) ([]domain.UserScore, error) {
+	args := m.Called(ctx, window)
+	return args.Get(0).([]domain.UserScore), args.Error(1)
+}
+
+// MockUserMetadataService is a mock for UserMetadataService.
+type MockUserMetadataService struct {
+	mock.Mock
+}
+
+func (m *MockUserMetadataService) GetMetadata(ctx context.Context, userID string) (map[string]string, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+// sortedIndex is a LeaderboardIndex that sorts on every read, for tests that
+// look at several boards.
+type sortedIndex struct {
+	mu      sync.Mutex
+	entries map[string]domain.LeaderboardEntry
+}
+
+func newSortedIndex() LeaderboardIndex {
+	return &sortedIndex{entries: map[string]domain.LeaderboardEntry{}}
+}
+
+func (s *sortedIndex) Upsert(userID string, score int64, reachedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, exists := s.entries[userID]; exists && e.Score == score {
+		return
+	}
+	s.entries[userID] = domain.LeaderboardEntry{UserID: userID, Score: score, ReachedAt: reachedAt}
+}
+
+func (s *sortedIndex) Remove(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, userID)
+}
+
+func (s *sortedIndex) sorted() []domain.LeaderboardEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]domain.LeaderboardEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].UserID < entries[j].UserID
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+func (s *sortedIndex) Rank(userID string) (domain.LeaderboardEntry, bool) {
+	for _, e := range s.sorted() {
+		if e.UserID == userID {
+			return e, true
+		}
+	}
+	return domain.LeaderboardEntry{}, false
+}
+
+func (s *sortedIndex) Range(offset, limit int) []domain.LeaderboardEntry {
+	entries := s.sorted()
+	if offset >= len(entries) {
+		return nil
+	}
+	return entries[offset:min(offset+limit, len(entries))]
+}
+
+func (s *sortedIndex) Around(userID string, radius int) ([]domain.LeaderboardEntry, bool) {
+	entry, ok := s.Rank(userID)
+	if !ok {
+		return nil, false
+	}
+	start := max(entry.Rank-1-radius, 0)
+	return s.Range(start, entry.Rank+radius-start), true
+}
+
+func (s *sortedIndex) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// fakeClock is a clock tests move forward by hand.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// singleIndex returns an index factory that always hands out index, for
+// tests that use one board.
+func singleIndex(index LeaderboardIndex) func() LeaderboardIndex {
+	return func() LeaderboardIndex { return index }
+}
+
+func userIDsOf(entries []domain.LeaderboardEntry) []string {
+	var userIDs []string
+	for _, e := range entries {
+		userIDs = append(userIDs, e.UserID)
+	}
+	return userIDs
+}
+
+func TestLeaderboard_Record(t *testing.T) {
+	mockIndex := new(MockLeaderboardIndex)
+	leaderboard := NewLeaderboard(singleIndex(mockIndex), WithLeaderboardClock(fixedClock(calculatedAt)))
+
+	mockIndex.On("Upsert", "user", int64(42), calculatedAt).Return()
+	mockIndex.On("Upsert", "user", int64(7), calculatedAt).Return()
+
+	leaderboard.Record(context.Background(), domain.UserScore{UserID: "user", Score: 42, Window: "all_time", CalculatedAt: calculatedAt})
+	leaderboard.Record(context.Background(), domain.UserScore{UserID: "user", Score: 7, Window: "week@UTC", CalculatedAt: calculatedAt})
+	// Rolling windows have no periods and are not ranked
+	leaderboard.Record(context.Background(), domain.UserScore{UserID: "user", Score: 3, Window: "rolling_7d", CalculatedAt: calculatedAt})
+
+	mockIndex.AssertExpectations(t)
+	mockIndex.AssertNumberOfCalls(t, "Upsert", 2)
+}
+
+func TestLeaderboard_RecordWithoutCalculationTime(t *testing.T) {
+	mockIndex := new(MockLeaderboardIndex)
+	leaderboard := NewLeaderboard(singleIndex(mockIndex), WithLeaderboardClock(fixedClock(calculatedAt)))
+
+	mockIndex.On("Upsert", "user", int64(42), calculatedAt).Return()
+
+	leaderboard.Record(context.Background(), domain.UserScore{UserID: "user", Score: 42})
+
+	mockIndex.AssertExpectations(t)
+}
+
+func TestLeaderboard_RecordMalformedWindow(t *testing.T) {
+	mockIndex := new(MockLeaderboardIndex)
+	var errs []error
+	leaderboard := NewLeaderboard(singleIndex(mockIndex), WithLeaderboardErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+
+	leaderboard.Record(context.Background(), domain.UserScore{UserID: "user", Score: 42, Window: "fortnight"})
+
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], domain.ErrInvalidWindow)
+	mockIndex.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLeaderboard_Load(t *testing.T) {
+	mockIndex := new(MockLeaderboardIndex)
+	mockLister := new(MockScoreLister)
+
+	mockLister.On("ListWindow", mock.Anything, "all_time").Return([]domain.UserScore{
+		{UserID: "alice", Score: 10, Window: "all_time", CalculatedAt: calculatedAt},
+		{UserID: "bob", Score: 20, Window: "all_time", CalculatedAt: calculatedAt},
+	}, nil)
+	mockIndex.On("Upsert", "alice", int64(10), calculatedAt).Return()
+	mockIndex.On("Upsert", "bob", int64(20), calculatedAt).Return()
+
+	err := NewLeaderboard(singleIndex(mockIndex)).Load(context.Background(), mockLister)
+
+	assert.NoError(t, err)
+	mockIndex.AssertExpectations(t)
+}
+
+func TestLeaderboard_LoadError(t *testing.T) {
+	mockIndex := new(MockLeaderboardIndex)
+	mockLister := new(MockScoreLister)
+	listErr := errors.New("database unavailable")
+
+	mockLister.On("ListWindow", mock.Anything, "all_time").Return([]domain.UserScore(nil), listErr)
+
+	err := NewLeaderboard(singleIndex(mockIndex)).Load(context.Background(), mockLister)
+
+	assert.ErrorIs(t, err, listErr)
+	mockIndex.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLeaderboard_Top(t *testing.T) {
+	mockIndex := new(MockLeaderboardIndex)
+	entries := []domain.LeaderboardEntry{{Rank: 21, UserID: "user", Score: 5}}
+
+	mockIndex.On("Upsert", "user", int64(5), calculatedAt).Return()
+	mockIndex.On("Range", 20, 10).Return(entries)
+	mockIndex.On("Len").Return(21)
+
+	leaderboard := NewLeaderboard(singleIndex(mockIndex))
+	leaderboard.Record(context.Background(), domain.UserScore{UserID: "user", Score: 5, CalculatedAt: calculatedAt})
+
+	page, err := leaderboard.Top(context.Background(), LeaderboardQuery{}, 20, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, LeaderboardPage{Key: domain.LeaderboardKey{Window: "all_time"}, Entries: entries, Total: 21}, page)
+}
+
+func TestLeaderboard_TopOfMissingBoard(t *testing.T) {
+	clock := &fakeClock{now: calculatedAt}
+	leaderboard := NewLeaderboard(newSortedIndex, WithLeaderboardClock(clock.Now))
+
+	page, err := leaderboard.Top(context.Background(), LeaderboardQuery{
+		Segment: "country:TR",
+		Window:  domain.Window{Kind: domain.WindowWeek},
+	}, 0, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, LeaderboardPage{
+		Key: domain.LeaderboardKey{
+			Segment: "country:TR",
+			Window:  "week@UTC",
+			Period:  time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+		},
+		ResetsAt: time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC),
+	}, page)
+}
+
+func TestLeaderboard_Rank(t *testing.T) {
+	mockIndex := new(MockLeaderboardIndex)
+	entry := domain.LeaderboardEntry{Rank: 3, UserID: "user", Score: 5}
+
+	mockIndex.On("Upsert", "user", int64(5), calculatedAt).Return()
+	mockIndex.On("Rank", "user").Return(entry, true)
+	mockIndex.On("Rank", "nobody").Return(domain.LeaderboardEntry{}, false)
+
+	leaderboard := NewLeaderboard(singleIndex(mockIndex))
+	leaderboard.Record(context.Background(), domain.UserScore{UserID: "user", Score: 5, CalculatedAt: calculatedAt})
+
+	got, err := leaderboard.Rank(context.Background(), LeaderboardQuery{}, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, entry, got)
+
+	_, err = leaderboard.Rank(context.Background(), LeaderboardQuery{}, "nobody")
+	assert.ErrorIs(t, err, ErrNotRanked)
+
+	// Nobody is ranked on a board no score was recorded on
+	_, err = leaderboard.Rank(context.Background(), LeaderboardQuery{Segment: "team:red"}, "user")
+	assert.ErrorIs(t, err, ErrNotRanked)
+}
+
+func TestLeaderboard_Around(t *testing.T) {
+	mockIndex := new(MockLeaderboardIndex)
+	entries := []domain.LeaderboardEntry{{Rank: 1, UserID: "user", Score: 5}}
+
+	mockIndex.On("Upsert", "user", int64(5), calculatedAt).Return()
+	mockIndex.On("Around", "user", DefaultLeaderboardRadius).Return(entries, true)
+	mockIndex.On("Around", "nobody", 2).Return([]domain.LeaderboardEntry(nil), false)
+	mockIndex.On("Len").Return(1)
+
+	leaderboard := NewLeaderboard(singleIndex(mockIndex))
+	leaderboard.Record(context.Background(), domain.UserScore{UserID: "user", Score: 5, CalculatedAt: calculatedAt})
+
+	page, err := leaderboard.Around(context.Background(), LeaderboardQuery{}, "user", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, LeaderboardPage{Key: domain.LeaderboardKey{Window: "all_time"}, Entries: entries, Total: 1}, page)
+
+	_, err = leaderboard.Around(context.Background(), LeaderboardQuery{}, "nobody", 2)
+	assert.ErrorIs(t, err, ErrNotRanked)
+}
+
+func TestLeaderboard_InvalidQuery(t *testing.T) {
+	mockIndex := new(MockLeaderboardIndex)
+	leaderboard := NewLeaderboard(singleIndex(mockIndex))
+	ctx := context.Background()
+
+	_, err := leaderboard.Top(ctx, LeaderboardQuery{}, 0, MaxLeaderboardLimit+1)
+	assert.ErrorIs(t, err, ErrInvalidLeaderboardQuery)
+
+	_, err = leaderboard.Top(ctx, LeaderboardQuery{}, 0, -1)
+	assert.ErrorIs(t, err, ErrInvalidLeaderboardQuery)
+
+	_, err = leaderboard.Top(ctx, LeaderboardQuery{}, -1, 10)
+	assert.ErrorIs(t, err, ErrInvalidLeaderboardQuery)
+
+	_, err = leaderboard.Around(ctx, LeaderboardQuery{}, "user", MaxLeaderboardRadius+1)
+	assert.ErrorIs(t, err, ErrInvalidLeaderboardQuery)
+
+	for _, q := range []LeaderboardQuery{
+		{Segment: "TR"},
+		{Segment: "country:"},
+		{Window: domain.Window{Kind: domain.WindowRolling, Days: 7}},
+		{Window: domain.Window{Kind: "fortnight"}},
+	} {
+		_, err = leaderboard.Top(ctx, q, 0, 10)
+		assert.ErrorIs(t, err, ErrInvalidLeaderboardQuery, "%+v", q)
+		_, err = leaderboard.Rank(ctx, q, "user")
+		assert.ErrorIs(t, err, ErrInvalidLeaderboardQuery, "%+v", q)
+	}
+
+	mockIndex.AssertNotCalled(t, "Range", mock.Anything, mock.Anything)
+	mockIndex.AssertNotCalled(t, "Around", mock.Anything, mock.Anything)
+}
+
+func TestLeaderboard_Segments(t *testing.T) {
+	mockMetadata := new(MockUserMetadataService)
+	leaderboard := NewLeaderboard(newSortedIndex,
+		WithLeaderboardClock(fixedClock(calculatedAt)),
+		WithSegments(mockMetadata, "country", "team"))
+	ctx := context.Background()
+
+	mockMetadata.On("GetMetadata", mock.Anything, "alice").Return(map[string]string{"country": "TR", "team": "red"}, nil)
+	mockMetadata.On("GetMetadata", mock.Anything, "bob").Return(map[string]string{"country": "DE", "team": "red"}, nil)
+	// Users without a dimension are left off its boards
+	mockMetadata.On("GetMetadata", mock.Anything, "carol").Return(map[string]string{"country": "TR", "cohort": "2025"}, nil)
+
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 10, CalculatedAt: calculatedAt})
+	leaderboard.Record(ctx, domain.UserScore{UserID: "bob", Score: 30, CalculatedAt: calculatedAt})
+	leaderboard.Record(ctx, domain.UserScore{UserID: "carol", Score: 20, CalculatedAt: calculatedAt})
+
+	tests := []struct {
+		segment  string
+		expected []string
+	}{
+		{"", []string{"bob", "carol", "alice"}},
+		{"country:TR", []string{"carol", "alice"}},
+		{"country:DE", []string{"bob"}},
+		{"team:red", []string{"bob", "alice"}},
+		{"cohort:2025", nil},
+	}
+	for _, tt := range tests {
+		page, err := leaderboard.Top(ctx, LeaderboardQuery{Segment: tt.segment}, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, userIDsOf(page.Entries), tt.segment)
+		assert.Equal(t, tt.segment, page.Key.Segment)
+	}
+
+	entry, err := leaderboard.Rank(ctx, LeaderboardQuery{Segment: "country:TR"}, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 2, entry.Rank)
+}
+
+func TestLeaderboard_SegmentChange(t *testing.T) {
+	mockMetadata := new(MockUserMetadataService)
+	leaderboard := NewLeaderboard(newSortedIndex,
+		WithLeaderboardClock(fixedClock(calculatedAt)),
+		WithSegments(mockMetadata, "country", "team"))
+	ctx := context.Background()
+
+	mockMetadata.On("GetMetadata", mock.Anything, "alice").Return(map[string]string{"country": "TR", "team": "red"}, nil).Once()
+	mockMetadata.On("GetMetadata", mock.Anything, "alice").Return(map[string]string{"country": "DE", "team": "red"}, nil).Once()
+	mockMetadata.On("GetMetadata", mock.Anything, "alice").Return(map[string]string(nil), errors.New("directory unavailable"))
+
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 10, CalculatedAt: calculatedAt})
+	// alice moved to another country
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 20, CalculatedAt: calculatedAt})
+	// Unreadable metadata keeps alice on the segments last ranked on
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 30, CalculatedAt: calculatedAt})
+
+	tests := []struct {
+		segment  string
+		expected []string
+	}{
+		{"", []string{"alice"}},
+		{"country:TR", nil},
+		{"country:DE", []string{"alice"}},
+		{"team:red", []string{"alice"}},
+	}
+	for _, tt := range tests {
+		page, err := leaderboard.Top(ctx, LeaderboardQuery{Segment: tt.segment}, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, userIDsOf(page.Entries), tt.segment)
+		if len(page.Entries) > 0 {
+			assert.Equal(t, int64(30), page.Entries[0].Score, tt.segment)
+		}
+	}
+}
+
+func TestLeaderboard_MetadataError(t *testing.T) {
+	mockMetadata := new(MockUserMetadataService)
+	var errs []error
+	leaderboard := NewLeaderboard(newSortedIndex,
+		WithSegments(mockMetadata, "country"),
+		WithLeaderboardErrorHandler(func(err error) { errs = append(errs, err) }))
+	ctx := context.Background()
+	metadataErr := errors.New("directory unavailable")
+
+	mockMetadata.On("GetMetadata", mock.Anything, "alice").Return(map[string]string(nil), metadataErr)
+
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 10, CalculatedAt: calculatedAt})
+
+	// The score still counts on the board of every user
+	_, err := leaderboard.Rank(ctx, LeaderboardQuery{}, "alice")
+	assert.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], metadataErr)
+}
+
+func TestLeaderboard_WeekRollover(t *testing.T) {
+	// Sunday evening, the last day of the week
+	clock := &fakeClock{now: time.Date(2025, 6, 8, 20, 0, 0, 0, time.UTC)}
+	leaderboard := NewLeaderboard(newSortedIndex, WithLeaderboardClock(clock.Now))
+	ctx := context.Background()
+	weekly := LeaderboardQuery{Window: domain.Window{Kind: domain.WindowWeek}}
+
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 50, Window: "week@UTC", CalculatedAt: clock.Now()})
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 50, Window: "all_time", CalculatedAt: clock.Now()})
+
+	page, err := leaderboard.Top(ctx, weekly, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, userIDsOf(page.Entries))
+	assert.Equal(t, time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC), page.ResetsAt)
+
+	clock.Advance(6 * time.Hour)
+
+	// The new week starts empty
+	page, err = leaderboard.Top(ctx, weekly, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, page.Entries)
+	assert.Equal(t, time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC), page.Key.Period)
+	assert.Equal(t, time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC), page.ResetsAt)
+
+	leaderboard.Record(ctx, domain.UserScore{UserID: "bob", Score: 5, Window: "week@UTC", CalculatedAt: clock.Now()})
+
+	page, err = leaderboard.Top(ctx, weekly, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, userIDsOf(page.Entries))
+
+	// The last week can still be read while it is retained
+	lastWeek := weekly
+	lastWeek.At = clock.Now().AddDate(0, 0, -7)
+	page, err = leaderboard.Top(ctx, lastWeek, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, userIDsOf(page.Entries))
+
+	// All-time boards never reset
+	_, err = leaderboard.Rank(ctx, LeaderboardQuery{}, "alice")
+	assert.NoError(t, err)
+}
+
+func TestLeaderboard_Retention(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)}
+	leaderboard := NewLeaderboard(newSortedIndex,
+		WithLeaderboardClock(clock.Now),
+		WithLeaderboardRetention(24*time.Hour))
+	ctx := context.Background()
+	daily := LeaderboardQuery{Window: domain.Window{Kind: domain.WindowDay}, At: clock.Now()}
+
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 50, Window: "day@UTC", CalculatedAt: clock.Now()})
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 50, Window: "all_time", CalculatedAt: clock.Now()})
+
+	// The day ends at midnight and is retained until the next midnight
+	clock.Advance(35 * time.Hour)
+	_, err := leaderboard.Rank(ctx, daily, "alice")
+	assert.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	_, err = leaderboard.Rank(ctx, daily, "alice")
+	assert.ErrorIs(t, err, ErrNotRanked)
+	assert.Len(t, leaderboard.boards, 1, "only the all-time board is left")
+
+	// Late scores of an expired period do not bring its board back
+	leaderboard.Record(ctx, domain.UserScore{UserID: "bob", Score: 5, Window: "day@UTC", CalculatedAt: daily.At})
+	assert.Len(t, leaderboard.boards, 1)
+}
+
+func TestLeaderboard_BoardsInWindowTimezone(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	require.NoError(t, err)
+	// 22:00 UTC on Sunday is already Monday in Istanbul
+	clock := &fakeClock{now: time.Date(2025, 6, 8, 22, 0, 0, 0, time.UTC)}
+	leaderboard := NewLeaderboard(newSortedIndex,
+		WithLeaderboardClock(clock.Now),
+		WithLeaderboardLocation(istanbul))
+	ctx := context.Background()
+
+	leaderboard.Record(ctx, domain.UserScore{UserID: "alice", Score: 50, Window: "week@Europe/Istanbul", CalculatedAt: clock.Now()})
+
+	page, err := leaderboard.Top(ctx, LeaderboardQuery{Window: domain.Window{Kind: domain.WindowWeek}}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "week@Europe/Istanbul", page.Key.Window)
+	assert.True(t, page.Key.Period.Equal(time.Date(2025, 6, 9, 0, 0, 0, 0, istanbul)))
+	assert.Equal(t, []string{"alice"}, userIDsOf(page.Entries))
+}
+
+func TestLeaderboardRecorder_Save(t *testing.T) {
+	mockRepo := new(MockScoreRepository)
+	mockIndex := new(MockLeaderboardIndex)
+	score := domain.UserScore{UserID: "user", Score: 42, Window: "all_time", CalculatedAt: calculatedAt}
+
+	mockRepo.On("Save", mock.Anything, score).Return(nil)
+	mockIndex.On("Upsert", "user", int64(42), calculatedAt).Return()
+
+	err := NewLeaderboardRecorder(mockRepo, NewLeaderboard(singleIndex(mockIndex))).Save(context.Background(), score)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockIndex.AssertExpectations(t)
+}
+
+func TestLeaderboardRecorder_SavesInOrder(t *testing.T) {
+	mockRepo := new(MockScoreRepository)
+	index := newSortedIndex()
+	older := domain.UserScore{UserID: "user", Score: 10, Window: "all_time", CalculatedAt: calculatedAt}
+	newer := domain.UserScore{UserID: "user", Score: 20, Window: "all_time", CalculatedAt: calculatedAt.Add(time.Second)}
+
+	// The older score is stored but not ranked yet when the newer one is saved
+	stored := make(chan struct{})
+	release := make(chan struct{})
+	mockRepo.On("Save", mock.Anything, older).Run(func(mock.Arguments) {
+		close(stored)
+		<-release
+	}).Return(nil)
+	mockRepo.On("Save", mock.Anything, newer).Return(nil)
+
+	recorder := NewLeaderboardRecorder(mockRepo, NewLeaderboard(singleIndex(index)))
+
+	olderDone := make(chan error, 1)
+	go func() { olderDone <- recorder.Save(context.Background(), older) }()
+	<-stored
+
+	newerDone := make(chan error, 1)
+	go func() { newerDone <- recorder.Save(context.Background(), newer) }()
+
+	select {
+	case <-newerDone:
+		t.Fatal("a save of the same user was not held up")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-olderDone)
+	require.NoError(t, <-newerDone)
+
+	entry, ranked := index.Rank("user")
+	assert.True(t, ranked)
+	assert.Equal(t, int64(20), entry.Score)
+}
+
+func TestLeaderboardRecorder_SaveError(t *testing.T) {
+	mockRepo := new(MockScoreRepository)
+	mockIndex := new(MockLeaderboardIndex)
+	repoErr := errors.New("database unavailable")
+
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(repoErr)
+
+	err := NewLeaderboardRecorder(mockRepo, NewLeaderboard(singleIndex(mockIndex))).
+		Save(context.Background(), domain.UserScore{UserID: "user", Score: 42})
+
+	// A score that was not stored is not ranked either
+	assert.ErrorIs(t, err, repoErr)
+	mockIndex.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything, mock.Anything)
+}
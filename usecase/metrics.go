@@ -0,0 +1,16 @@
+package usecase
+
+import "scoreapp/domain"
+
+// Metrics receives scoring events worth monitoring, such as caps being hit
+// by users farming points.
+type Metrics interface {
+	// CapHit counts a cap that removed points. actionType is empty for caps
+	// that apply to the score as a whole.
+	CapHit(kind domain.CapKind, actionType string)
+}
+
+// noopMetrics discards all metrics.
+type noopMetrics struct{}
+
+func (noopMetrics) CapHit(domain.CapKind, string) {}
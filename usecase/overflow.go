@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrScoreOverflow is returned when a score does not fit in 64 bits and the
+// calculator is configured to fail instead of saturating.
+var ErrScoreOverflow = errors.New("score overflow")
+
+// OverflowPolicy controls what happens when score arithmetic overflows.
+type OverflowPolicy string
+
+const (
+	// OverflowError fails the calculation with ErrScoreOverflow.
+	OverflowError OverflowPolicy = "error"
+	// OverflowSaturate clamps the result to the int64 range and carries on.
+	OverflowSaturate OverflowPolicy = "saturate"
+)
+
+// checked performs saturating int64 arithmetic and remembers whether any
+// operation overflowed.
+type checked struct {
+	overflowed bool
+}
+
+func (c *checked) add(a, b int64) int64 {
+	r, ok := addInt64(a, b)
+	c.overflowed = c.overflowed || !ok
+	return r
+}
+
+func (c *checked) sub(a, b int64) int64 {
+	r, ok := subInt64(a, b)
+	c.overflowed = c.overflowed || !ok
+	return r
+}
+
+func (c *checked) mul(a, b int64) int64 {
+	r, ok := mulInt64(a, b)
+	c.overflowed = c.overflowed || !ok
+	return r
+}
+
+func (c *checked) scale(v int64, f float64) int64 {
+	r, ok := scaleInt64(v, f)
+	c.overflowed = c.overflowed || !ok
+	return r
+}
+
+// addInt64 returns a + b clamped to the int64 range, and whether the result
+// is exact.
+func addInt64(a, b int64) (int64, bool) {
+	r := a + b
+	switch {
+	case b > 0 && r < a:
+		return math.MaxInt64, false
+	case b < 0 && r > a:
+		return math.MinInt64, false
+	}
+	return r, true
+}
+
+// subInt64 returns a - b clamped to the int64 range, and whether the result
+// is exact.
+func subInt64(a, b int64) (int64, bool) {
+	r := a - b
+	switch {
+	case b < 0 && r < a:
+		return math.MaxInt64, false
+	case b > 0 && r > a:
+		return math.MinInt64, false
+	}
+	return r, true
+}
+
+// mulInt64 returns a × b clamped to the int64 range, and whether the result
+// is exact.
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	r := a * b
+	// MinInt64 × -1 wraps back to MinInt64, which the division check misses
+	if r/b != a || (a == math.MinInt64 && b == -1) {
+		if (a > 0) == (b > 0) {
+			return math.MaxInt64, false
+		}
+		return math.MinInt64, false
+	}
+	return r, true
+}
+
+// scaleInt64 returns v × f rounded to the nearest integer and clamped to the
+// int64 range, and whether the result fits.
+func scaleInt64(v int64, f float64) (int64, bool) {
+	if f == 1 {
+		// float64 cannot represent every int64, so skip the round trip
+		return v, true
+	}
+	r := math.Round(float64(v) * f)
+	switch {
+	case r >= math.MaxInt64:
+		return math.MaxInt64, false
+	case r < math.MinInt64:
+		return math.MinInt64, false
+	}
+	return int64(r), true
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       func(a, b int64) (int64, bool)
+		a, b     int64
+		expected int64
+		exact    bool
+	}{
+		{"add", addInt64, 40, 2, 42, true},
+		{"add to max", addInt64, math.MaxInt64 - 1, 1, math.MaxInt64, true},
+		{"add past max", addInt64, math.MaxInt64, 1, math.MaxInt64, false},
+		{"add past min", addInt64, math.MinInt64, -1, math.MinInt64, false},
+		{"sub", subInt64, 50, 8, 42, true},
+		{"sub to min", subInt64, math.MinInt64 + 1, 1, math.MinInt64, true},
+		{"sub past min", subInt64, math.MinInt64, 1, math.MinInt64, false},
+		{"sub past max", subInt64, math.MaxInt64, -1, math.MaxInt64, false},
+		{"mul", mulInt64, 6, 7, 42, true},
+		{"mul by zero", mulInt64, math.MaxInt64, 0, 0, true},
+		{"mul to max", mulInt64, math.MaxInt64, 1, math.MaxInt64, true},
+		{"mul past max", mulInt64, math.MaxInt64/2 + 1, 2, math.MaxInt64, false},
+		{"mul past min", mulInt64, math.MaxInt64, -2, math.MinInt64, false},
+		{"mul negatives past max", mulInt64, math.MinInt64, -2, math.MaxInt64, false},
+		{"negate min", mulInt64, math.MinInt64, -1, math.MaxInt64, false},
+		{"min times minus one", mulInt64, -1, math.MinInt64, math.MaxInt64, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, exact := tt.op(tt.a, tt.b)
+
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.exact, exact)
+		})
+	}
+}
+
+func TestScaleInt64(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        int64
+		f        float64
+		expected int64
+		exact    bool
+	}{
+		{"round", 5, 0.5, 3, true},
+		{"max unchanged", math.MaxInt64, 1, math.MaxInt64, true},
+		{"max halved", math.MaxInt64, 0.5, 1 << 62, true},
+		{"past max", math.MaxInt64 / 2, 3, math.MaxInt64, false},
+		{"past min", math.MinInt64 / 2, 3, math.MinInt64, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, exact := scaleInt64(tt.v, tt.f)
+
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.exact, exact)
+		})
+	}
+}
+
+func TestRule_Points_Overflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		amount   int64
+		expected int64
+	}{
+		{"multiplier", Rule{Multiplier: 2}, math.MaxInt64, math.MaxInt64},
+		{"base", Rule{Base: math.MaxInt64, Multiplier: 1}, 1, math.MaxInt64},
+		{"removed by max amount", Rule{Multiplier: 2, MaxAmount: 1}, math.MaxInt64, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, _, exact := tt.rule.Points(tt.amount)
+
+			assert.False(t, exact)
+			assert.Equal(t, tt.expected, points)
+		})
+	}
+}
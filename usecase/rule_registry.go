@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRuleSetNotFound is returned when no rule set has the requested version.
+var ErrRuleSetNotFound = errors.New("rule set not found")
+
+// RuleSet is an immutable, versioned snapshot of scoring rules.
+type RuleSet struct {
+	Version  int
+	Rules    *ScoringRules
+	LoadedAt time.Time
+}
+
+// RuleProvider supplies the rule set to use for a calculation.
+type RuleProvider interface {
+	Current() *RuleSet
+}
+
+// RuleSetStore persists every rule set a RuleRegistry activates, so versions
+// keep increasing across restarts and the rules behind a stored score can
+// still be looked up after they were replaced.
+type RuleSetStore interface {
+	// SaveRuleSet creates or replaces the rule set of the same version.
+	SaveRuleSet(ctx context.Context, ruleSet *RuleSet) error
+	// GetRuleSet returns the rule set of the version, or ErrRuleSetNotFound.
+	GetRuleSet(ctx context.Context, version int) (*RuleSet, error)
+	// LatestRuleSet returns the rule set of the highest version, or
+	// ErrRuleSetNotFound if none is stored.
+	LatestRuleSet(ctx context.Context) (*RuleSet, error)
+}
+
+// RuleRegistry holds the active rule set and swaps it atomically, so
+// calculations that already picked up a rule set finish with it while new
+// calculations see the replacement. Every rule set it activated can be
+// looked up by its version.
+type RuleRegistry struct {
+	mu      sync.Mutex // serializes updates so versions stay monotonic
+	current atomic.Pointer[RuleSet]
+	// store keeps the rule sets, or nil to keep them in versions only.
+	store    RuleSetStore
+	versions map[int]*RuleSet
+}
+
+// NewRuleRegistry creates a RuleRegistry with the initial rules as version 1,
+// keeping its rule sets in memory only. The initial rules are expected to be
+// valid, as returned by DefaultScoringRules or LoadScoringRules.
+func NewRuleRegistry(initial *ScoringRules) *RuleRegistry {
+	r := &RuleRegistry{versions: make(map[int]*RuleSet)}
+	r.activate(&RuleSet{
+		Version:  1,
+		Rules:    initial,
+		LoadedAt: time.Now(),
+	})
+	return r
+}
+
+// LoadRuleRegistry creates a RuleRegistry keeping its rule sets in store.
+// The initial rules continue the latest stored rule set if they are the
+// same, and become the version after it otherwise.
+func LoadRuleRegistry(ctx context.Context, store RuleSetStore, initial *ScoringRules) (*RuleRegistry, error) {
+	r := &RuleRegistry{store: store, versions: make(map[int]*RuleSet)}
+
+	latest, err := store.LatestRuleSet(ctx)
+	switch {
+	case errors.Is(err, ErrRuleSetNotFound):
+		latest = &RuleSet{}
+	case err != nil:
+		return nil, fmt.Errorf("failed to load rule sets: %w", err)
+	}
+	if latest.Rules != nil && sameRules(latest.Rules, initial) {
+		r.activate(latest)
+		return r, nil
+	}
+
+	ruleSet := &RuleSet{
+		Version:  latest.Version + 1,
+		Rules:    initial,
+		LoadedAt: time.Now(),
+	}
+	if err := store.SaveRuleSet(ctx, ruleSet); err != nil {
+		return nil, fmt.Errorf("failed to save rule set: %w", err)
+	}
+	r.activate(ruleSet)
+	return r, nil
+}
+
+// Current returns the active rule set.
+func (r *RuleRegistry) Current() *RuleSet {
+	return r.current.Load()
+}
+
+// Version returns the rule set of the version, which may have been replaced
+// since, or ErrRuleSetNotFound.
+func (r *RuleRegistry) Version(ctx context.Context, version int) (*RuleSet, error) {
+	r.mu.Lock()
+	ruleSet, ok := r.versions[version]
+	r.mu.Unlock()
+	if ok {
+		return ruleSet, nil
+	}
+	if r.store == nil {
+		return nil, ErrRuleSetNotFound
+	}
+	return r.store.GetRuleSet(ctx, version)
+}
+
+// Update validates the rules, stores them under the next version and
+// activates them. Invalid rules, and rules that cannot be stored, leave the
+// active rule set untouched.
+func (r *RuleRegistry) Update(ctx context.Context, rules *ScoringRules) (*RuleSet, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := &RuleSet{
+		Version:  r.current.Load().Version + 1,
+		Rules:    rules,
+		LoadedAt: time.Now(),
+	}
+	if r.store != nil {
+		if err := r.store.SaveRuleSet(ctx, next); err != nil {
+			return nil, fmt.Errorf("failed to save rule set: %w", err)
+		}
+	}
+	r.activate(next)
+
+	return next, nil
+}
+
+// activate makes the rule set the current one. Callers other than the
+// constructors must hold mu.
+func (r *RuleRegistry) activate(ruleSet *RuleSet) {
+	r.versions[ruleSet.Version] = ruleSet
+	r.current.Store(ruleSet)
+}
+
+// sameRules reports whether two rule sets score alike, comparing their
+// canonical encodings.
+func sameRules(a, b *ScoringRules) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// Upload parses a rule set in the given format and activates it.
+func (r *RuleRegistry) Upload(ctx context.Context, data []byte, format string) (*RuleSet, error) {
+	rules, err := ParseScoringRules(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Update(ctx, rules)
+}
+
+// Watch polls the rules file every interval and activates its contents
+// whenever they change. Load and validation errors are passed to onError
+// and the active rule set is kept. Watch blocks until ctx is done.
+func (r *RuleRegistry) Watch(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	var lastSum []byte
+	if data, err := os.ReadFile(path); err == nil {
+		sum := sha256.Sum256(data)
+		lastSum = sum[:]
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			onError(err)
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		if bytes.Equal(sum[:], lastSum) {
+			continue
+		}
+		lastSum = sum[:]
+
+		if _, err := r.Upload(ctx, data, filepath.Ext(path)); err != nil {
+			onError(err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRuleRegistry(t *testing.T) {
+	rules := DefaultScoringRules()
+	registry := NewRuleRegistry(rules)
+
+	current := registry.Current()
+	assert.Equal(t, 1, current.Version)
+	assert.Same(t, rules, current.Rules)
+	assert.False(t, current.LoadedAt.IsZero())
+}
+
+func TestRuleRegistry_Update(t *testing.T) {
+	registry := NewRuleRegistry(DefaultScoringRules())
+
+	updated := &ScoringRules{
+		Rules:         map[string]Rule{"login": {Base: 5}},
+		UnknownAction: UnknownActionIgnore,
+	}
+	ruleSet, err := registry.Update(context.Background(), updated)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, ruleSet.Version)
+	assert.Same(t, ruleSet, registry.Current())
+}
+
+func TestRuleRegistry_Update_InvalidKeepsCurrent(t *testing.T) {
+	registry := NewRuleRegistry(DefaultScoringRules())
+	before := registry.Current()
+
+	ruleSet, err := registry.Update(context.Background(), &ScoringRules{UnknownAction: UnknownActionIgnore})
+
+	assert.ErrorIs(t, err, ErrInvalidRules)
+	assert.Nil(t, ruleSet)
+	assert.Same(t, before, registry.Current())
+}
+
+func TestRuleRegistry_Upload(t *testing.T) {
+	registry := NewRuleRegistry(DefaultScoringRules())
+
+	ruleSet, err := registry.Upload(context.Background(), []byte("rules:\n  login:\n    base: 7\n"), "yaml")
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, ruleSet.Version)
+	assert.Equal(t, Rule{Base: 7}, registry.Current().Rules.Rules["login"])
+
+	_, err = registry.Upload(context.Background(), []byte("{"), "json")
+	assert.ErrorIs(t, err, ErrInvalidRules)
+	assert.Equal(t, 2, registry.Current().Version)
+}
+
+func TestRuleRegistry_ConcurrentUpdatesAreMonotonic(t *testing.T) {
+	registry := NewRuleRegistry(DefaultScoringRules())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = registry.Update(context.Background(), DefaultScoringRules())
+			_ = registry.Current()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 51, registry.Current().Version)
+}
+
+func TestRuleRegistry_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": {"login": {"base": 1}}}`), 0o600))
+
+	registry := NewRuleRegistry(DefaultScoringRules())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 10)
+	go registry.Watch(ctx, path, 5*time.Millisecond, func(err error) { errs <- err })
+
+	// Unchanged contents do not produce a new version
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 1, registry.Current().Version)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": {"login": {"base": 9}}}`), 0o600))
+	assert.Eventually(t, func() bool {
+		return registry.Current().Version == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, Rule{Base: 9}, registry.Current().Rules.Rules["login"])
+
+	// Invalid contents are reported and the active rule set is kept
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": {}}`), 0o600))
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, ErrInvalidRules)
+	case <-time.After(time.Second):
+		t.Fatal("expected reload error")
+	}
+	assert.Equal(t, 2, registry.Current().Version)
+}
+
+// memoryRuleSets is an in-memory RuleSetStore whose saves can be made to
+// fail.
+type memoryRuleSets struct {
+	mu      sync.Mutex
+	sets    map[int]*RuleSet
+	saveErr error
+}
+
+func newMemoryRuleSets() *memoryRuleSets {
+	return &memoryRuleSets{sets: make(map[int]*RuleSet)}
+}
+
+func (m *memoryRuleSets) SaveRuleSet(ctx context.Context, ruleSet *RuleSet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.sets[ruleSet.Version] = ruleSet
+	return nil
+}
+
+func (m *memoryRuleSets) GetRuleSet(ctx context.Context, version int) (*RuleSet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ruleSet, ok := m.sets[version]
+	if !ok {
+		return nil, ErrRuleSetNotFound
+	}
+	return ruleSet, nil
+}
+
+func (m *memoryRuleSets) LatestRuleSet(ctx context.Context) (*RuleSet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var latest *RuleSet
+	for _, ruleSet := range m.sets {
+		if latest == nil || ruleSet.Version > latest.Version {
+			latest = ruleSet
+		}
+	}
+	if latest == nil {
+		return nil, ErrRuleSetNotFound
+	}
+	return latest, nil
+}
+
+func TestRuleRegistry_Version(t *testing.T) {
+	registry := NewRuleRegistry(DefaultScoringRules())
+	first := registry.Current()
+	_, err := registry.Update(context.Background(), &ScoringRules{Rules: map[string]Rule{"login": {Base: 5}}, UnknownAction: UnknownActionIgnore})
+	require.NoError(t, err)
+
+	ruleSet, err := registry.Version(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Same(t, first, ruleSet)
+
+	_, err = registry.Version(context.Background(), 3)
+	assert.ErrorIs(t, err, ErrRuleSetNotFound)
+}
+
+func TestLoadRuleRegistry(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryRuleSets()
+
+	registry, err := LoadRuleRegistry(ctx, store, DefaultScoringRules())
+	require.NoError(t, err)
+	assert.Equal(t, 1, registry.Current().Version)
+
+	updated := &ScoringRules{Rules: map[string]Rule{"login": {Base: 5}}, UnknownAction: UnknownActionIgnore}
+	_, err = registry.Update(ctx, updated)
+	require.NoError(t, err)
+
+	// A restart with the rules last active continues their version
+	restarted, err := LoadRuleRegistry(ctx, store, &ScoringRules{Rules: map[string]Rule{"login": {Base: 5}}, UnknownAction: UnknownActionIgnore})
+	require.NoError(t, err)
+	assert.Equal(t, 2, restarted.Current().Version)
+
+	// Other rules get the next version instead of starting over
+	restarted, err = LoadRuleRegistry(ctx, store, DefaultScoringRules())
+	require.NoError(t, err)
+	assert.Equal(t, 3, restarted.Current().Version)
+
+	// Rule sets activated before the restart can still be looked up
+	ruleSet, err := restarted.Version(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, updated, ruleSet.Rules)
+}
+
+func TestRuleRegistry_UpdateNotStored(t *testing.T) {
+	store := newMemoryRuleSets()
+	registry, err := LoadRuleRegistry(context.Background(), store, DefaultScoringRules())
+	require.NoError(t, err)
+	before := registry.Current()
+
+	store.saveErr = errors.New("disk full")
+	ruleSet, err := registry.Update(context.Background(), &ScoringRules{Rules: map[string]Rule{"login": {Base: 5}}, UnknownAction: UnknownActionIgnore})
+
+	assert.ErrorContains(t, err, "failed to save rule set: disk full")
+	assert.Nil(t, ruleSet)
+	assert.Same(t, before, registry.Current())
+}
@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"scoreapp/domain"
+)
+
+const (
+	// DefaultScoreEventPollInterval is how often the outbox is checked for
+	// new events when no interval is configured.
+	DefaultScoreEventPollInterval = time.Second
+	// DefaultScoreEventBatchSize is how many events are read from the
+	// outbox at once when no size is configured.
+	DefaultScoreEventBatchSize = 100
+)
+
+// ScoreEventPublisher tells downstream systems about score changes.
+type ScoreEventPublisher interface {
+	// Publish delivers the event. An error means it may not have been
+	// delivered, and it is published again later.
+	Publish(ctx context.Context, event domain.ScoreChanged) error
+}
+
+// ScoreEventOutbox holds the events of score changes until they are
+// published. A ScoreRepository that is an outbox records the event of every
+// save that changes a score together with the score, so a crash or a failed
+// publish never loses an event of a saved score.
+type ScoreEventOutbox interface {
+	// PendingScoreEvents returns up to limit unacknowledged events, in the
+	// order of their IDs, which grow with every recorded event.
+	PendingScoreEvents(ctx context.Context, limit int) ([]domain.ScoreChanged, error)
+	// AckScoreEvents drops the events with IDs up to throughID.
+	AckScoreEvents(ctx context.Context, throughID int64) error
+}
+
+// ScoreEventRelay publishes the events of a ScoreEventOutbox in order,
+// acknowledging each one once it is published. An event whose publish fails
+// holds back the ones after it until it is retried on the next poll.
+//
+// Events are delivered at least once: an event published just before a
+// crash or a failed acknowledgement is published again, with the same ID.
+type ScoreEventRelay struct {
+	outbox    ScoreEventOutbox
+	publisher ScoreEventPublisher
+	interval  time.Duration
+	batchSize int
+	onError   func(error)
+}
+
+// ScoreEventOption configures optional ScoreEventRelay behavior.
+type ScoreEventOption func(*ScoreEventRelay)
+
+// WithScoreEventPollInterval sets how often Run checks the outbox for new
+// events. Defaults to DefaultScoreEventPollInterval.
+func WithScoreEventPollInterval(d time.Duration) ScoreEventOption {
+	return func(r *ScoreEventRelay) {
+		r.interval = d
+	}
+}
+
+// WithScoreEventBatchSize sets how many events are read from the outbox at
+// once. Defaults to DefaultScoreEventBatchSize.
+func WithScoreEventBatchSize(n int) ScoreEventOption {
+	return func(r *ScoreEventRelay) {
+		r.batchSize = n
+	}
+}
+
+// WithScoreEventErrorHandler sets the function Run tells about events that
+// could not be published or acknowledged. Defaults to discarding the errors.
+func WithScoreEventErrorHandler(onError func(error)) ScoreEventOption {
+	return func(r *ScoreEventRelay) {
+		r.onError = onError
+	}
+}
+
+// NewScoreEventRelay creates a ScoreEventRelay publishing the events of
+// outbox to publisher.
+func NewScoreEventRelay(outbox ScoreEventOutbox, publisher ScoreEventPublisher, opts ...ScoreEventOption) *ScoreEventRelay {
+	r := &ScoreEventRelay{
+		outbox:    outbox,
+		publisher: publisher,
+		interval:  DefaultScoreEventPollInterval,
+		batchSize: DefaultScoreEventBatchSize,
+		onError:   func(error) {},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.interval <= 0 {
+		r.interval = DefaultScoreEventPollInterval
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = DefaultScoreEventBatchSize
+	}
+	return r
+}
+
+// Run publishes pending events every poll interval until ctx is canceled.
+func (r *ScoreEventRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			r.onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes the pending events until the outbox is empty or an event
+// cannot be published, and returns how many were published.
+func (r *ScoreEventRelay) Flush(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		events, err := r.outbox.PendingScoreEvents(ctx, r.batchSize)
+		if err != nil {
+			return published, fmt.Errorf("failed to read score events: %w", err)
+		}
+		if len(events) == 0 {
+			return published, nil
+		}
+
+		n, err := r.publish(ctx, events)
+		published += n
+		if err != nil {
+			return published, err
+		}
+		if len(events) < r.batchSize {
+			return published, nil
+		}
+	}
+}
+
+// publish publishes the events in order and acknowledges the ones
+// published, stopping at the first that fails.
+func (r *ScoreEventRelay) publish(ctx context.Context, events []domain.ScoreChanged) (int, error) {
+	n := 0
+	var publishErr error
+	for _, event := range events {
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			publishErr = fmt.Errorf("failed to publish score event %d: %w", event.ID, err)
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, publishErr
+	}
+
+	// Acknowledge even when ctx is canceled, so a stopping relay does not
+	// publish the same events again on the next start
+	if err := r.outbox.AckScoreEvents(context.WithoutCancel(ctx), events[n-1].ID); err != nil {
+		return n, fmt.Errorf("failed to acknowledge score events: %w", err)
+	}
+	return n, publishErr
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+// MockScoreEventPublisher is a mock for ScoreEventPublisher.
+type MockScoreEventPublisher struct {
+	mock.Mock
+}
+
+func (m *MockScoreEventPublisher) Publish(ctx context.Context, event domain.ScoreChanged) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+// memoryOutbox is an in-memory ScoreEventOutbox whose acknowledgements can
+// be made to fail.
+type memoryOutbox struct {
+	mu      sync.Mutex
+	events  []domain.ScoreChanged
+	acks    []int64
+	ackErr  error
+	readErr error
+}
+
+func newMemoryOutbox(n int) *memoryOutbox {
+	o := &memoryOutbox{}
+	for i := 1; i <= n; i++ {
+		o.events = append(o.events, scoreEvent(int64(i)))
+	}
+	return o
+}
+
+func scoreEvent(id int64) domain.ScoreChanged {
+	return domain.ScoreChanged{ID: id, UserID: "alice", Window: "all_time", OldScore: id - 1, NewScore: id, Delta: 1, RuleVersion: 1}
+}
+
+func (o *memoryOutbox) PendingScoreEvents(ctx context.Context, limit int) ([]domain.ScoreChanged, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.readErr != nil {
+		return nil, o.readErr
+	}
+	events := o.events
+	if limit > 0 && limit < len(events) {
+		events = events[:limit]
+	}
+	return append([]domain.ScoreChanged(nil), events...), nil
+}
+
+func (o *memoryOutbox) AckScoreEvents(ctx context.Context, throughID int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.ackErr != nil {
+		return o.ackErr
+	}
+	o.acks = append(o.acks, throughID)
+	for len(o.events) > 0 && o.events[0].ID <= throughID {
+		o.events = o.events[1:]
+	}
+	return nil
+}
+
+func (o *memoryOutbox) pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.events)
+}
+
+func TestScoreEventRelay_Flush(t *testing.T) {
+	outbox := newMemoryOutbox(5)
+	publisher := new(MockScoreEventPublisher)
+	var published []int64
+	publisher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).(domain.ScoreChanged).ID)
+	}).Return(nil)
+	relay := NewScoreEventRelay(outbox, publisher, WithScoreEventBatchSize(2))
+
+	n, err := relay.Flush(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, published)
+	// Every batch is acknowledged once it is published
+	assert.Equal(t, []int64{2, 4, 5}, outbox.acks)
+	assert.Zero(t, outbox.pending())
+
+	n, err = relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestScoreEventRelay_PublishFails(t *testing.T) {
+	outbox := newMemoryOutbox(3)
+	publisher := new(MockScoreEventPublisher)
+	publisher.On("Publish", mock.Anything, scoreEvent(1)).Return(nil)
+	publisher.On("Publish", mock.Anything, scoreEvent(2)).Return(errors.New("webhook down")).Once()
+	relay := NewScoreEventRelay(outbox, publisher)
+
+	n, err := relay.Flush(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to publish score event 2: webhook down")
+	assert.Equal(t, 1, n)
+	// The published event is acknowledged; the failed one and those after it
+	// stay in the outbox, in order
+	assert.Equal(t, []int64{1}, outbox.acks)
+	assert.Equal(t, 2, outbox.pending())
+	publisher.AssertNotCalled(t, "Publish", mock.Anything, scoreEvent(3))
+
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(nil)
+	n, err = relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Zero(t, outbox.pending())
+}
+
+func TestScoreEventRelay_AckFails(t *testing.T) {
+	outbox := newMemoryOutbox(2)
+	outbox.ackErr = errors.New("disk full")
+	publisher := new(MockScoreEventPublisher)
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(nil)
+	relay := NewScoreEventRelay(outbox, publisher)
+
+	n, err := relay.Flush(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to acknowledge score events: disk full")
+	assert.Equal(t, 2, n)
+	// Unacknowledged events are published again
+	assert.Equal(t, 2, outbox.pending())
+}
+
+func TestScoreEventRelay_ReadFails(t *testing.T) {
+	outbox := newMemoryOutbox(1)
+	outbox.readErr = errors.New("database locked")
+	relay := NewScoreEventRelay(outbox, new(MockScoreEventPublisher))
+
+	_, err := relay.Flush(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read score events: database locked")
+}
+
+func TestScoreEventRelay_Run(t *testing.T) {
+	outbox := newMemoryOutbox(1)
+	publisher := new(MockScoreEventPublisher)
+	publisher.On("Publish", mock.Anything, scoreEvent(1)).Return(errors.New("webhook down")).Once()
+	publisher.On("Publish", mock.Anything, scoreEvent(1)).Return(nil)
+
+	errs := make(chan error, 10)
+	relay := NewScoreEventRelay(outbox, publisher,
+		WithScoreEventPollInterval(time.Millisecond),
+		WithScoreEventErrorHandler(func(err error) { errs <- err }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+
+	// The failed publish is reported and retried on the next poll
+	assert.ErrorContains(t, <-errs, "webhook down")
+	assert.Eventually(t, func() bool { return outbox.pending() == 0 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
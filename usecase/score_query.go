@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"scoreapp/domain"
+)
+
+// ErrScoreNotFound is returned when a user has no stored score for a window.
+var ErrScoreNotFound = errors.New("score not found")
+
+// ScoreReader reads the scores a ScoreRepository has saved.
+type ScoreReader interface {
+	// GetWindow returns the score saved for the user and window key, and
+	// whether there is one.
+	GetWindow(ctx context.Context, userID, window string) (domain.UserScore, bool, error)
+}
+
+// ScoreQuery reads stored scores without recalculating them.
+type ScoreQuery struct {
+	repo     ScoreReader
+	location *time.Location
+}
+
+// NewScoreQuery constructs a ScoreQuery over the given repository. Calendar
+// windows that do not specify a timezone are aligned to loc, as the
+// calculator does; a nil loc means UTC.
+func NewScoreQuery(repo ScoreReader, loc *time.Location) *ScoreQuery {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &ScoreQuery{
+		repo:     repo,
+		location: loc,
+	}
+}
+
+// Get returns the last score saved for the user in the window.
+func (q *ScoreQuery) Get(ctx context.Context, userID string, window domain.Window) (domain.UserScore, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.UserScore{}, err
+	}
+	if err := window.Validate(); err != nil {
+		return domain.UserScore{}, err
+	}
+	if window.Location == nil {
+		window.Location = q.location
+	}
+
+	score, exists, err := q.repo.GetWindow(ctx, userID, window.Key())
+	if err != nil {
+		return domain.UserScore{}, err
+	}
+	if !exists {
+		return domain.UserScore{}, ErrScoreNotFound
+	}
+	return score, nil
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"scoreapp/domain"
+)
+
+// MockScoreReader is a mock for ScoreReader.
+type MockScoreReader struct {
+	mock.Mock
+}
+
+func (m *MockScoreReader) GetWindow(ctx context.Context, userID, window string) (domain.UserScore, bool, error) {
+	args := m.Called(ctx, userID, window)
+	return args.Get(0).(domain.UserScore), args.Bool(1), args.Error(2)
+}
+
+func TestScoreQuery_Get(t *testing.T) {
+	mockRepo := new(MockScoreReader)
+	stored := domain.UserScore{UserID: "user", Score: 42, RuleVersion: 2, Window: "all_time", CalculatedAt: calculatedAt}
+
+	mockRepo.On("GetWindow", mock.Anything, "user", "all_time").Return(stored, true, nil)
+
+	score, err := NewScoreQuery(mockRepo, nil).Get(context.Background(), "user", domain.AllTime())
+
+	assert.NoError(t, err)
+	assert.Equal(t, stored, score)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestScoreQuery_NotFound(t *testing.T) {
+	mockRepo := new(MockScoreReader)
+
+	mockRepo.On("GetWindow", mock.Anything, "user", "all_time").Return(domain.UserScore{}, false, nil)
+
+	_, err := NewScoreQuery(mockRepo, nil).Get(context.Background(), "user", domain.AllTime())
+
+	assert.ErrorIs(t, err, ErrScoreNotFound)
+}
+
+func TestScoreQuery_ReadFails(t *testing.T) {
+	mockRepo := new(MockScoreReader)
+
+	mockRepo.On("GetWindow", mock.Anything, "user", "all_time").Return(domain.UserScore{}, false, errors.New("database is locked"))
+
+	_, err := NewScoreQuery(mockRepo, nil).Get(context.Background(), "user", domain.AllTime())
+
+	assert.EqualError(t, err, "database is locked")
+	assert.NotErrorIs(t, err, ErrScoreNotFound)
+}
+
+func TestScoreQuery_CalendarWindowUsesDefaultLocation(t *testing.T) {
+	mockRepo := new(MockScoreReader)
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	assert.NoError(t, err)
+
+	mockRepo.On("GetWindow", mock.Anything, "user", "week@Europe/Istanbul").Return(domain.UserScore{UserID: "user", Score: 5}, true, nil)
+	mockRepo.On("GetWindow", mock.Anything, "user", "week@UTC").Return(domain.UserScore{UserID: "user", Score: 7}, true, nil)
+
+	query := NewScoreQuery(mockRepo, istanbul)
+
+	score, err := query.Get(context.Background(), "user", domain.Window{Kind: domain.WindowWeek})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), score.Score)
+
+	// An explicit timezone wins over the default
+	score, err = query.Get(context.Background(), "user", domain.Window{Kind: domain.WindowWeek, Location: time.UTC})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), score.Score)
+}
+
+func TestScoreQuery_InvalidWindow(t *testing.T) {
+	mockRepo := new(MockScoreReader)
+
+	_, err := NewScoreQuery(mockRepo, nil).Get(context.Background(), "user", domain.Window{Kind: domain.WindowRolling})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidWindow)
+	mockRepo.AssertNotCalled(t, "GetWindow", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestScoreQuery_Canceled(t *testing.T) {
+	mockRepo := new(MockScoreReader)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewScoreQuery(mockRepo, nil).Get(ctx, "user", domain.AllTime())
+
+	assert.ErrorIs(t, err, context.Canceled)
+	mockRepo.AssertNotCalled(t, "GetWindow", mock.Anything, mock.Anything, mock.Anything)
+}
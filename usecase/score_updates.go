@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultScoreUpdateWorkers is how many users' scores are updated at
+	// once when no number is configured.
+	DefaultScoreUpdateWorkers = 4
+	// DefaultScoreUpdateTimeout bounds the update of a user's score when no
+	// timeout is configured.
+	DefaultScoreUpdateTimeout = 30 * time.Second
+)
+
+// ScoreUpdater brings stored scores up to date with ingested actions.
+type ScoreUpdater interface {
+	// Lock keeps the users' scores from being updated by anyone else until
+	// the returned function is called.
+	Lock(userIDs []string) (unlock func())
+	// Update brings the score of a locked user up to date with the user's
+	// ingested actions.
+	Update(ctx context.Context, userID string) error
+}
+
+// ScoreUpdateQueue updates the scores of scheduled users in the background,
+// so ingesting actions does not wait for them. A user scheduled again before
+// the update starts is updated once.
+//
+// Users still queued when Run stops are not updated; their scores catch up
+// on their next update, as the actions stay ingested.
+type ScoreUpdateQueue struct {
+	updater ScoreUpdater
+	workers int
+	timeout time.Duration
+	onError func(error)
+
+	mu sync.Mutex
+	// queued holds the request ID each queued user was last scheduled
+	// under, and order the queued users, first scheduled first.
+	queued map[string]string
+	order  []string
+	// ready is signaled when users are queued.
+	ready chan struct{}
+}
+
+// ScoreUpdateOption configures optional ScoreUpdateQueue behavior.
+type ScoreUpdateOption func(*ScoreUpdateQueue)
+
+// WithScoreUpdateWorkers sets how many users' scores are updated at once.
+// Defaults to DefaultScoreUpdateWorkers.
+func WithScoreUpdateWorkers(n int) ScoreUpdateOption {
+	return func(q *ScoreUpdateQueue) {
+		q.workers = n
+	}
+}
+
+// WithScoreUpdateTimeout bounds the update of a user's score. Defaults to
+// DefaultScoreUpdateTimeout.
+func WithScoreUpdateTimeout(d time.Duration) ScoreUpdateOption {
+	return func(q *ScoreUpdateQueue) {
+		q.timeout = d
+	}
+}
+
+// WithScoreUpdateErrorHandler sets the function told about scores that
+// could not be updated. Defaults to discarding the errors.
+func WithScoreUpdateErrorHandler(onError func(error)) ScoreUpdateOption {
+	return func(q *ScoreUpdateQueue) {
+		q.onError = onError
+	}
+}
+
+// NewScoreUpdateQueue creates a ScoreUpdateQueue updating scores with
+// updater.
+func NewScoreUpdateQueue(updater ScoreUpdater, opts ...ScoreUpdateOption) *ScoreUpdateQueue {
+	q := &ScoreUpdateQueue{
+		updater: updater,
+		workers: DefaultScoreUpdateWorkers,
+		timeout: DefaultScoreUpdateTimeout,
+		onError: func(error) {},
+		queued:  make(map[string]string),
+		ready:   make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.workers <= 0 {
+		q.workers = DefaultScoreUpdateWorkers
+	}
+	if q.timeout <= 0 {
+		q.timeout = DefaultScoreUpdateTimeout
+	}
+	return q
+}
+
+// Lock keeps the users' scores from being updated until the returned
+// function is called.
+func (q *ScoreUpdateQueue) Lock(userIDs []string) (unlock func()) {
+	return q.updater.Lock(userIDs)
+}
+
+// Schedule queues the users for an update of their scores and returns
+// without waiting for it. The scores record the request ID ctx carries.
+func (q *ScoreUpdateQueue) Schedule(ctx context.Context, userIDs []string) {
+	if len(userIDs) == 0 {
+		return
+	}
+	requestID := RequestIDFromContext(ctx)
+
+	q.mu.Lock()
+	for _, userID := range userIDs {
+		if _, ok := q.queued[userID]; !ok {
+			q.order = append(q.order, userID)
+		}
+		q.queued[userID] = requestID
+	}
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+// Run updates the scores of scheduled users until ctx is canceled. Updates
+// in progress by then are finished, within their timeout.
+func (q *ScoreUpdateQueue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range q.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *ScoreUpdateQueue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		userID, requestID, ok := q.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+			case <-q.ready:
+			}
+			continue
+		}
+		q.update(ctx, userID, requestID)
+	}
+}
+
+// next takes the user scheduled first off the queue.
+func (q *ScoreUpdateQueue) next() (userID, requestID string, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.order) == 0 {
+		return "", "", false
+	}
+	userID, q.order = q.order[0], q.order[1:]
+	requestID = q.queued[userID]
+	delete(q.queued, userID)
+
+	// Wake another worker for the users left
+	if len(q.order) > 0 {
+		q.signal()
+	}
+	return userID, requestID, true
+}
+
+func (q *ScoreUpdateQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *ScoreUpdateQueue) update(ctx context.Context, userID, requestID string) {
+	unlock := q.updater.Lock([]string{userID})
+	defer unlock()
+
+	// An update once started is finished even if Run stops
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), q.timeout)
+	defer cancel()
+	if requestID != "" {
+		ctx = ContextWithRequestID(ctx, requestID)
+	}
+
+	if err := q.updater.Update(ctx, userID); err != nil {
+		q.onError(fmt.Errorf("failed to update score of user %q: %w", userID, err))
+	}
+}
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockScoreUpdater is a mock for ScoreUpdater.
+type MockScoreUpdater struct {
+	mock.Mock
+}
+
+func (m *MockScoreUpdater) Lock(userIDs []string) func() {
+	args := m.Called(userIDs)
+	return args.Get(0).(func())
+}
+
+func (m *MockScoreUpdater) Update(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// runQueue runs the queue until the test ends.
+func runQueue(t *testing.T, q *ScoreUpdateQueue) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestScoreUpdateQueue_UpdatesScheduledUsers(t *testing.T) {
+	updater := new(MockScoreUpdater)
+	var updated sync.WaitGroup
+	updated.Add(2)
+
+	var mu sync.Mutex
+	var locked, order []string
+	updater.On("Lock", mock.Anything).Return(func() {}).Run(func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+		locked = append(locked, args.Get(0).([]string)...)
+	})
+	updater.On("Update", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+		ctx, userID := args.Get(0).(context.Context), args.String(1)
+		order = append(order, userID)
+		if userID == "alice" {
+			assert.Equal(t, "req-2", RequestIDFromContext(ctx))
+		}
+		updated.Done()
+	})
+
+	// Alice is scheduled again before the update starts, so the score is
+	// updated once, with the request that came last
+	q := NewScoreUpdateQueue(updater, WithScoreUpdateWorkers(1))
+	q.Schedule(ContextWithRequestID(context.Background(), "req-1"), []string{"alice", "bob"})
+	q.Schedule(ContextWithRequestID(context.Background(), "req-2"), []string{"alice"})
+	runQueue(t, q)
+	updated.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"alice", "bob"}, order)
+	assert.Equal(t, []string{"alice", "bob"}, locked)
+	updater.AssertNumberOfCalls(t, "Update", 2)
+}
+
+func TestScoreUpdateQueue_ReportsFailures(t *testing.T) {
+	updater := new(MockScoreUpdater)
+	updater.On("Lock", mock.Anything).Return(func() {})
+	updater.On("Update", mock.Anything, "alice").Return(errors.New("database is locked"))
+
+	errs := make(chan error, 1)
+	q := NewScoreUpdateQueue(updater, WithScoreUpdateErrorHandler(func(err error) { errs <- err }))
+	runQueue(t, q)
+	q.Schedule(context.Background(), []string{"alice"})
+
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, `failed to update score of user "alice": database is locked`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("update failure not reported")
+	}
+}
+
+func TestScoreUpdateQueue_FinishesUpdatesWhenStopped(t *testing.T) {
+	updater := new(MockScoreUpdater)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	updater.On("Lock", mock.Anything).Return(func() {})
+	updater.On("Update", mock.Anything, "alice").Return(nil).Run(func(args mock.Arguments) {
+		cancel()
+		updateCtx := args.Get(0).(context.Context)
+		assert.NoError(t, updateCtx.Err())
+		deadline, ok := updateCtx.Deadline()
+		if assert.True(t, ok) {
+			assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+		}
+	})
+
+	q := NewScoreUpdateQueue(updater, WithScoreUpdateTimeout(time.Minute))
+	q.Schedule(context.Background(), []string{"alice"})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.Run(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue did not stop")
+	}
+	updater.AssertExpectations(t)
+}
+
+func TestScoreUpdateQueue_Defaults(t *testing.T) {
+	q := NewScoreUpdateQueue(new(MockScoreUpdater), WithScoreUpdateWorkers(0), WithScoreUpdateTimeout(-time.Second))
+
+	require.NotNil(t, q.onError)
+	assert.Equal(t, DefaultScoreUpdateWorkers, q.workers)
+	assert.Equal(t, DefaultScoreUpdateTimeout, q.timeout)
+}
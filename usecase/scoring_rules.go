@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"scoreapp/domain"
+)
+
+// ErrUnknownActionType is returned when an action has no matching rule and
+// the rule set is configured to reject unknown actions.
+var ErrUnknownActionType = errors.New("unknown action type")
+
+// ErrInvalidRules is returned when a rule set fails validation.
+var ErrInvalidRules = errors.New("invalid scoring rules")
+
+// UnknownActionPolicy controls how actions without a matching rule are handled.
+type UnknownActionPolicy string
+
+const (
+	// UnknownActionIgnore skips actions without a matching rule.
+	UnknownActionIgnore UnknownActionPolicy = "ignore"
+	// UnknownActionReject fails the calculation on actions without a matching rule.
+	UnknownActionReject UnknownActionPolicy = "reject"
+)
+
+// Rule describes how a single action type is converted into points.
+//
+// Points are computed as Base + Multiplier × Amount. MaxAmount limits the
+// amount taken into account, MaxPoints limits the points of a single action
+// and MaxPointsPerDay limits the points all actions of the type earn on one
+// calendar day. A zero value disables the respective cap.
+type Rule struct {
+	Base            int64 `json:"base" yaml:"base"`
+	Multiplier      int64 `json:"multiplier" yaml:"multiplier"`
+	MaxAmount       int64 `json:"max_amount,omitempty" yaml:"max_amount,omitempty"`
+	MaxPoints       int64 `json:"max_points,omitempty" yaml:"max_points,omitempty"`
+	MaxPointsPerDay int64 `json:"max_points_per_day,omitempty" yaml:"max_points_per_day,omitempty"`
+}
+
+// ScoringRules maps action types to the rules used to score them.
+type ScoringRules struct {
+	Rules         map[string]Rule     `json:"rules" yaml:"rules"`
+	UnknownAction UnknownActionPolicy `json:"unknown_action" yaml:"unknown_action"`
+	// Decay optionally reduces the points of older actions.
+	Decay *DecayPolicy `json:"decay,omitempty" yaml:"decay,omitempty"`
+	// Streak optionally rewards activity on consecutive days.
+	Streak *StreakPolicy `json:"streak,omitempty" yaml:"streak,omitempty"`
+	// Combos optionally reward several actions within a short time.
+	Combos []ComboRule `json:"combos,omitempty" yaml:"combos,omitempty"`
+	// MaxTotalPoints optionally limits the score of a single calculation.
+	MaxTotalPoints int64 `json:"max_total_points,omitempty" yaml:"max_total_points,omitempty"`
+}
+
+// DefaultScoringRules returns the built-in rule set:
+// login → +1, challenge_completed → +10 × amount, quiz_answer → +2 × amount.
+func DefaultScoringRules() *ScoringRules {
+	return &ScoringRules{
+		Rules: map[string]Rule{
+			"login":               {Base: 1},
+			"challenge_completed": {Multiplier: 10},
+			"quiz_answer":         {Multiplier: 2},
+		},
+		UnknownAction: UnknownActionIgnore,
+	}
+}
+
+// LoadScoringRules reads a rule set from a JSON or YAML file. The format is
+// chosen by the file extension.
+func LoadScoringRules(path string) (*ScoringRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	return ParseScoringRules(data, filepath.Ext(path))
+}
+
+// ParseScoringRules decodes and validates a rule set. Format is one of
+// "json", "yaml" or "yml", with or without a leading dot.
+func ParseScoringRules(data []byte, format string) (*ScoringRules, error) {
+	rules := &ScoringRules{}
+
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(rules); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+		}
+	case "yaml", "yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(rules); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidRules, format)
+	}
+
+	if rules.UnknownAction == "" {
+		rules.UnknownAction = UnknownActionIgnore
+	}
+
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// Validate checks that the rule set is well formed.
+func (s *ScoringRules) Validate() error {
+	if len(s.Rules) == 0 {
+		return fmt.Errorf("%w: no rules defined", ErrInvalidRules)
+	}
+
+	switch s.UnknownAction {
+	case UnknownActionIgnore, UnknownActionReject:
+	default:
+		return fmt.Errorf("%w: unknown_action must be %q or %q", ErrInvalidRules, UnknownActionIgnore, UnknownActionReject)
+	}
+
+	if s.MaxTotalPoints < 0 {
+		return fmt.Errorf("%w: negative max_total_points", ErrInvalidRules)
+	}
+
+	if s.Decay != nil {
+		if err := s.Decay.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if s.Streak != nil {
+		if err := s.Streak.Validate(); err != nil {
+			return err
+		}
+	}
+
+	comboNames := make(map[string]struct{}, len(s.Combos))
+	for i := range s.Combos {
+		if err := s.Combos[i].Validate(); err != nil {
+			return err
+		}
+		if _, dup := comboNames[s.Combos[i].Name]; dup {
+			return fmt.Errorf("%w: duplicate combo %q", ErrInvalidRules, s.Combos[i].Name)
+		}
+		comboNames[s.Combos[i].Name] = struct{}{}
+	}
+
+	for actionType, rule := range s.Rules {
+		if strings.TrimSpace(actionType) == "" {
+			return fmt.Errorf("%w: empty action type", ErrInvalidRules)
+		}
+		// Actions only ever earn points; a negative rule would silently
+		// take them away
+		if rule.Base < 0 || rule.Multiplier < 0 {
+			return fmt.Errorf("%w: negative base or multiplier for %q", ErrInvalidRules, actionType)
+		}
+		if rule.MaxAmount < 0 || rule.MaxPoints < 0 || rule.MaxPointsPerDay < 0 {
+			return fmt.Errorf("%w: negative cap for %q", ErrInvalidRules, actionType)
+		}
+	}
+
+	return nil
+}
+
+// Match returns the rule for an action type, if any.
+func (s *ScoringRules) Match(actionType string) (Rule, bool) {
+	rule, ok := s.Rules[actionType]
+	return rule, ok
+}
+
+// Points applies the rule to an amount, honoring the per-action caps. It
+// returns the resulting points, the caps that removed points, if any, and
+// whether the arithmetic stayed within the int64 range. Results that do not
+// fit are clamped to it.
+func (r Rule) Points(amount int64) (int64, []domain.CapHit, bool) {
+	var c checked
+	var caps []domain.CapHit
+
+	if r.MaxAmount > 0 && amount > r.MaxAmount {
+		caps = append(caps, domain.CapHit{
+			Kind:    domain.CapMaxAmount,
+			Removed: c.mul(r.Multiplier, amount-r.MaxAmount),
+		})
+		amount = r.MaxAmount
+	}
+
+	points := c.add(r.Base, c.mul(r.Multiplier, amount))
+	if r.MaxPoints > 0 && points > r.MaxPoints {
+		caps = append(caps, domain.CapHit{
+			Kind:    domain.CapMaxPoints,
+			Removed: points - r.MaxPoints,
+		})
+		points = r.MaxPoints
+	}
+
+	return points, caps, !c.overflowed
+}
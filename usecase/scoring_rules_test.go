@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scoreapp/domain"
+)
+
+func TestScoringRules_Match(t *testing.T) {
+	rules := DefaultScoringRules()
+
+	rule, ok := rules.Match("challenge_completed")
+	assert.True(t, ok)
+	assert.Equal(t, Rule{Multiplier: 10}, rule)
+
+	rule, ok = rules.Match("unknown")
+	assert.False(t, ok)
+	assert.Equal(t, Rule{}, rule)
+}
+
+func TestDefaultScoringRules_Points(t *testing.T) {
+	rules := DefaultScoringRules()
+
+	tests := []struct {
+		actionType string
+		amount     int64
+		expected   int64
+	}{
+		{"login", 5, 1},
+		{"challenge_completed", 3, 30},
+		{"quiz_answer", 4, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.actionType, func(t *testing.T) {
+			rule, ok := rules.Match(tt.actionType)
+
+			points, caps, exact := rule.Points(tt.amount)
+
+			assert.True(t, ok)
+			assert.True(t, exact)
+			assert.Equal(t, tt.expected, points)
+			assert.Empty(t, caps)
+		})
+	}
+}
+
+func TestRule_Points_Caps(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         Rule
+		amount       int64
+		expected     int64
+		expectedCaps []domain.CapHit
+	}{
+		{"uncapped", Rule{Base: 5, Multiplier: 2}, 10, 25, nil},
+		{"max amount", Rule{Multiplier: 2, MaxAmount: 3}, 10, 6, []domain.CapHit{
+			{Kind: domain.CapMaxAmount, Removed: 14},
+		}},
+		{"max points", Rule{Multiplier: 10, MaxPoints: 50}, 10, 50, []domain.CapHit{
+			{Kind: domain.CapMaxPoints, Removed: 50},
+		}},
+		{"both caps", Rule{Base: 1, Multiplier: 10, MaxAmount: 4, MaxPoints: 30}, 10, 30, []domain.CapHit{
+			{Kind: domain.CapMaxAmount, Removed: 60},
+			{Kind: domain.CapMaxPoints, Removed: 11},
+		}},
+		{"under caps", Rule{Multiplier: 2, MaxAmount: 10, MaxPoints: 100}, 5, 10, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, caps, exact := tt.rule.Points(tt.amount)
+
+			assert.True(t, exact)
+			assert.Equal(t, tt.expected, points)
+			assert.Equal(t, tt.expectedCaps, caps)
+		})
+	}
+}
+
+func TestParseScoringRules_JSON(t *testing.T) {
+	data := []byte(`{
+		"unknown_action": "reject",
+		"rules": {
+			"login": {"base": 2},
+			"quiz_answer": {"multiplier": 3, "max_points": 30}
+		}
+	}`)
+
+	rules, err := ParseScoringRules(data, "json")
+
+	require.NoError(t, err)
+	assert.Equal(t, UnknownActionReject, rules.UnknownAction)
+	assert.Equal(t, Rule{Base: 2}, rules.Rules["login"])
+	assert.Equal(t, Rule{Multiplier: 3, MaxPoints: 30}, rules.Rules["quiz_answer"])
+}
+
+func TestParseScoringRules_YAML(t *testing.T) {
+	data := []byte(`
+rules:
+  login:
+    base: 1
+  challenge_completed:
+    multiplier: 10
+    max_amount: 5
+`)
+
+	rules, err := ParseScoringRules(data, ".yml")
+
+	require.NoError(t, err)
+	assert.Equal(t, UnknownActionIgnore, rules.UnknownAction)
+	assert.Equal(t, Rule{Multiplier: 10, MaxAmount: 5}, rules.Rules["challenge_completed"])
+}
+
+func TestParseScoringRules_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format string
+	}{
+		{"unsupported format", `rules: {}`, "toml"},
+		{"malformed json", `{"rules":`, "json"},
+		{"unknown field", `{"rules": {"login": {"bonus": 1}}}`, "json"},
+		{"no rules", `{"rules": {}}`, "json"},
+		{"bad policy", `{"unknown_action": "explode", "rules": {"login": {"base": 1}}}`, "json"},
+		{"negative base", `{"rules": {"login": {"base": -1}}}`, "json"},
+		{"negative multiplier", "rules:\n  quiz_answer:\n    multiplier: -2\n", "yaml"},
+		{"negative cap", `{"rules": {"login": {"base": 1, "max_points": -1}}}`, "json"},
+		{"negative daily cap", `{"rules": {"login": {"base": 1, "max_points_per_day": -1}}}`, "json"},
+		{"negative total cap", `{"rules": {"login": {"base": 1}}, "max_total_points": -5}`, "json"},
+		{"empty type", `{"rules": {" ": {"base": 1}}}`, "json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseScoringRules([]byte(tt.data), tt.format)
+
+			assert.ErrorIs(t, err, ErrInvalidRules)
+			assert.Nil(t, rules)
+		})
+	}
+}
+
+func TestLoadScoringRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("rules:\n  login:\n    base: 3\n"), 0o600))
+
+	rules, err := LoadScoringRules(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, Rule{Base: 3}, rules.Rules["login"])
+}
+
+func TestLoadScoringRules_MissingFile(t *testing.T) {
+	rules, err := LoadScoringRules(filepath.Join(t.TempDir(), "missing.json"))
+
+	assert.Error(t, err)
+	assert.Nil(t, rules)
+	assert.Contains(t, err.Error(), "failed to read rules file")
+}
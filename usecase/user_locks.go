@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"slices"
+	"sync"
+)
+
+// UserLocks serializes work on the scores of the same user, such as the
+// all-time calculations of a ScoreCalculator and the updates of an
+// IncrementalScorer, which would otherwise overwrite each other's saves.
+// The zero value is not usable; create one with NewUserLocks.
+type UserLocks struct {
+	mu    sync.Mutex
+	locks map[string]*userLock
+}
+
+// userLock serializes the work on one user. It is dropped once nobody holds
+// or waits for it.
+type userLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewUserLocks creates a UserLocks with no user locked.
+func NewUserLocks() *UserLocks {
+	return &UserLocks{locks: make(map[string]*userLock)}
+}
+
+// Lock waits until none of the users is locked and keeps them locked until
+// the returned function is called.
+func (l *UserLocks) Lock(userIDs []string) (unlock func()) {
+	// Always lock in the same order so two batches cannot deadlock
+	ids := slices.Clone(userIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	l.mu.Lock()
+	held := make([]*userLock, len(ids))
+	for i, id := range ids {
+		ul, ok := l.locks[id]
+		if !ok {
+			ul = &userLock{}
+			l.locks[id] = ul
+		}
+		ul.refs++
+		held[i] = ul
+	}
+	l.mu.Unlock()
+
+	for _, ul := range held {
+		ul.mu.Lock()
+	}
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, ul := range held {
+			ul.mu.Unlock()
+			if ul.refs--; ul.refs == 0 {
+				delete(l.locks, ids[i])
+			}
+		}
+	}
+}
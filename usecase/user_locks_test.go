@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserLocks(t *testing.T) {
+	locks := NewUserLocks()
+
+	unlock := locks.Lock([]string{"bob", "alice", "bob"})
+
+	locked := make(chan struct{})
+	go func() {
+		defer close(locked)
+		locks.Lock([]string{"alice"})()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("a locked user was locked again")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Other users are not held up
+	locks.Lock([]string{"carol"})()
+
+	unlock()
+	<-locked
+
+	locks.mu.Lock()
+	defer locks.mu.Unlock()
+	assert.Empty(t, locks.locks)
+}